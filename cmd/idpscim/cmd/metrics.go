@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/core"
+	"github.com/slashdevops/idp-scim-sync/internal/idp"
+	"github.com/slashdevops/idp-scim-sync/internal/metrics"
+	"github.com/slashdevops/idp-scim-sync/internal/scim"
+	"github.com/slashdevops/idp-scim-sync/pkg/aws"
+)
+
+// newMetricsRegistry returns a Prometheus registry with the metrics of the sync,
+// the identity providers and the SCIM providers.
+func newMetricsRegistry() (*prometheus.Registry, error) {
+	cs := make([]prometheus.Collector, 0)
+	cs = append(cs, core.Collectors()...)
+	cs = append(cs, idp.Collectors()...)
+	cs = append(cs, scim.Collectors()...)
+	cs = append(cs, aws.Collectors()...)
+
+	return metrics.NewRegistry(cs...)
+}
+
+// serveMetrics serves the metrics of reg on /metrics at the metrics address in the background,
+// the returned server must be closed when the metrics are not needed anymore.
+func serveMetrics(reg *prometheus.Registry) (*http.Server, error) {
+	srv, errc, err := listenAndServe(cfg.MetricsAddress, newMetricsMux(reg))
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		if err := <-errc; err != nil {
+			log.WithError(err).Error("cannot serve metrics")
+		}
+	}()
+
+	return srv, nil
+}
+
+// newMetricsMux returns a new http.ServeMux serving the metrics of reg on /metrics.
+func newMetricsMux(reg *prometheus.Registry) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler(reg))
+
+	return mux
+}
+
+// listenAndServe listens at addr and serves handler in the background, it returns the error of
+// the listener, like an address already in use, before serving. The returned channel receives
+// the error of the server once it stops, nil when it is closed, and the returned server must be
+// closed when it is not needed anymore.
+func listenAndServe(addr string, handler http.Handler) (*http.Server, <-chan error, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot listen at %s: %w", addr, err)
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		log.WithField("address", ln.Addr().String()).Info("serving http")
+		err := srv.Serve(ln)
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		errc <- err
+	}()
+
+	return srv, errc, nil
+}
+
+// pushMetrics pushes the metrics of reg to the Prometheus Pushgateway, the errors are only logged
+// to keep the result of the sync.
+func pushMetrics(reg *prometheus.Registry) {
+	if err := metrics.Push(nil, cfg.MetricsPushgatewayURL, cfg.MetricsJob, reg); err != nil {
+		log.WithError(err).Error("cannot push metrics")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"url": cfg.MetricsPushgatewayURL,
+		"job": cfg.MetricsJob,
+	}).Info("metrics pushed")
+}
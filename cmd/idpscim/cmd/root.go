@@ -3,12 +3,16 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/hashicorp/go-retryablehttp"
@@ -16,12 +20,18 @@ import (
 	"github.com/slashdevops/idp-scim-sync/internal/config"
 	"github.com/slashdevops/idp-scim-sync/internal/core"
 	"github.com/slashdevops/idp-scim-sync/internal/idp"
+	"github.com/slashdevops/idp-scim-sync/internal/metrics"
 	"github.com/slashdevops/idp-scim-sync/internal/repository"
 	"github.com/slashdevops/idp-scim-sync/internal/scim"
 	"github.com/slashdevops/idp-scim-sync/internal/utils"
 	"github.com/slashdevops/idp-scim-sync/internal/version"
 	"github.com/slashdevops/idp-scim-sync/pkg/aws"
+	"github.com/slashdevops/idp-scim-sync/pkg/file"
 	"github.com/slashdevops/idp-scim-sync/pkg/google"
+	"github.com/slashdevops/idp-scim-sync/pkg/ldap"
+	"github.com/slashdevops/idp-scim-sync/pkg/microsoft"
+	"github.com/slashdevops/idp-scim-sync/pkg/okta"
+	scimclient "github.com/slashdevops/idp-scim-sync/pkg/scim"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
@@ -75,9 +85,27 @@ func init() {
 		"aws-scim-endpoint-secret-name", "n", config.DefaultAWSSCIMEndpointSecretName,
 		"AWS Secrets Manager secret name for AWS SSO SCIM API Endpoint",
 	)
+	rootCmd.PersistentFlags().IntVar(&cfg.AWSSCIMConcurrency, "aws-scim-concurrency", config.DefaultAWSSCIMConcurrency,
+		"number of users, groups and groups members sent at the same time to the AWS SSO SCIM API, 1 means sequential requests",
+	)
+	rootCmd.PersistentFlags().Float64Var(&cfg.AWSSCIMRateLimit, "aws-scim-rate-limit", config.DefaultAWSSCIMRateLimit,
+		"maximum number of requests per second to the AWS SSO SCIM API, 0 means no limit",
+	)
 
 	rootCmd.PersistentFlags().StringVarP(&cfg.AWSS3BucketName, "aws-s3-bucket-name", "b", "", "AWS S3 Bucket name to store the state")
 	rootCmd.PersistentFlags().StringVarP(&cfg.AWSS3BucketKey, "aws-s3-bucket-key", "k", config.DefaultAWSS3BucketKey, "AWS S3 Bucket key to store the state")
+	rootCmd.PersistentFlags().StringVar(&cfg.StateBackend, "state-backend", config.DefaultStateBackend, "backend storing the state [s3|dynamodb]")
+	rootCmd.PersistentFlags().StringVar(&cfg.AWSDynamoDBTableName, "aws-dynamodb-table-name", "", "AWS DynamoDB table name to store the state, used by the dynamodb state backend")
+	rootCmd.PersistentFlags().StringVar(&cfg.AWSDynamoDBStateID, "aws-dynamodb-state-id", config.DefaultAWSDynamoDBStateID, "id of the state in the AWS DynamoDB table, to share the table between several syncs")
+	rootCmd.PersistentFlags().StringVar(&cfg.AWSDynamoDBEndpoint, "aws-dynamodb-endpoint", "", "AWS DynamoDB endpoint, empty means the AWS endpoint, example: http://localhost:8000 for DynamoDB Local")
+	rootCmd.PersistentFlags().DurationVar(&cfg.StateLockTTL, "state-lock-ttl", config.DefaultStateLockTTL, "time after which the lock of the state expires when the sync holding it didn't release it, must be longer than a sync")
+	rootCmd.PersistentFlags().StringVar(&cfg.StateKMSKeyID, "state-kms-key-id", "", "AWS KMS key id, ARN or alias to encrypt the state, example: alias/idpscim")
+	rootCmd.PersistentFlags().StringVar(&cfg.StateEncryptionKeyFile, "state-encryption-key-file", "", "file with a 32 bytes key, or its base64 encoding, to encrypt the state")
+	rootCmd.PersistentFlags().StringVar(&cfg.StateEncryptionPassphrase, "state-encryption-passphrase", "", "passphrase to encrypt the state")
+	rootCmd.PersistentFlags().StringVar(&cfg.StateEncryptionPassphraseSecretName, "state-encryption-passphrase-secret-name", "", "AWS Secrets Manager secret name with the passphrase to encrypt the state, read when using AWS Secrets Manager")
+	rootCmd.PersistentFlags().BoolVar(&cfg.StateAllowUnencrypted, "state-allow-unencrypted", config.DefaultStateAllowUnencrypted, "read the state stored without encryption when the state is encrypted, only to migrate it to the encryption")
+	rootCmd.PersistentFlags().IntVar(&cfg.StateHistory, "state-history", config.DefaultStateHistory, "number of previous states kept to roll back with idpscimcli, 0 disables the history")
+	rootCmd.PersistentFlags().StringVar(&cfg.StateCompression, "state-compression", config.DefaultStateCompression, "compression of the state stored [none|gzip|zstd], the states are read with any compression")
 
 	rootCmd.PersistentFlags().StringVarP(&cfg.GWSServiceAccountFile,
 		"gws-service-account-file", "s", config.DefaultGWSServiceAccountFile,
@@ -97,13 +125,155 @@ func init() {
 		"AWS Secrets Manager secret name for GWS user email with allowed access to the Google Workspace Service Account",
 	)
 
-	rootCmd.Flags().StringSliceVarP(
+	rootCmd.PersistentFlags().StringSliceVarP(
 		&cfg.GWSGroupsFilter, "gws-groups-filter", "q", []string{""},
 		"GWS Groups query parameter, example: --gws-groups-filter 'name:Admin* email:admin*' --gws-groups-filter 'name:Power* email:power*'",
 	)
 
-	rootCmd.PersistentFlags().StringVarP(&cfg.SyncMethod, "sync-method", "m", config.DefaultSyncMethod, "Sync method to use [groups]")
+	rootCmd.PersistentFlags().StringSliceVarP(
+		&cfg.GWSUsersFilter, "gws-users-filter", "r", []string{""},
+		"GWS Users query parameter, used by the sync methods [users|groups+users], example: --gws-users-filter 'name:John* email:admin*' --gws-users-filter 'orgUnitPath=/Engineering'",
+	)
+
+	rootCmd.PersistentFlags().IntVar(&cfg.GWSConcurrency, "gws-concurrency", config.DefaultGWSConcurrency,
+		"number of groups members and users requested at the same time to Google Workspace, 1 means sequential requests",
+	)
+	rootCmd.PersistentFlags().Float64Var(&cfg.GWSRateLimit, "gws-rate-limit", config.DefaultGWSRateLimit,
+		"maximum number of requests per second to Google Workspace, 0 means no limit",
+	)
+	rootCmd.PersistentFlags().StringVar(&cfg.GWSUserLookup, "gws-user-lookup", config.DefaultGWSUserLookup,
+		"how the groups members are resolved to users, get one request per member or list all the users at once [get|list]",
+	)
+
+	rootCmd.PersistentFlags().StringVar(&cfg.IdentityProvider, "identity-provider", config.DefaultIdentityProvider, "Identity provider to use [google|entra|okta|ldap|file]")
+	rootCmd.PersistentFlags().StringVar(&cfg.EntraTenantID, "entra-tenant-id", "", "Microsoft Entra ID tenant id, used by the entra identity provider")
+	rootCmd.PersistentFlags().StringVar(&cfg.EntraClientID, "entra-client-id", "", "Microsoft Entra ID application (client) id, used by the entra identity provider")
+	rootCmd.PersistentFlags().StringVar(&cfg.EntraClientSecret, "entra-client-secret", "", "Microsoft Entra ID application client secret, used by the entra identity provider")
+	rootCmd.PersistentFlags().StringVar(&cfg.EntraClientSecretSecretName,
+		"entra-client-secret-secret-name", config.DefaultEntraClientSecretSecretName,
+		"AWS Secrets Manager secret name for Microsoft Entra ID application client secret",
+	)
+
+	rootCmd.PersistentFlags().StringSliceVar(
+		&cfg.EntraGroupsFilter, "entra-groups-filter", []string{""},
+		"Microsoft Graph groups OData filter, example: --entra-groups-filter \"startswith(displayName,'AWS-')\" --entra-groups-filter \"displayName eq 'Admins'\"",
+	)
+
+	rootCmd.PersistentFlags().StringSliceVar(
+		&cfg.EntraUsersFilter, "entra-users-filter", []string{""},
+		"Microsoft Graph users OData filter, used by the sync methods [users|groups+users], example: --entra-users-filter \"department eq 'Engineering'\"",
+	)
+
+	rootCmd.PersistentFlags().StringVar(&cfg.OktaOrgURL, "okta-org-url", "", "Okta organization URL, example: https://my-company.okta.com, used by the okta identity provider")
+	rootCmd.PersistentFlags().StringVar(&cfg.OktaAPIToken, "okta-api-token", "", "Okta API token, used by the okta identity provider")
+	rootCmd.PersistentFlags().StringVar(&cfg.OktaAPITokenSecretName,
+		"okta-api-token-secret-name", config.DefaultOktaAPITokenSecretName,
+		"AWS Secrets Manager secret name for Okta API token",
+	)
+
+	rootCmd.PersistentFlags().StringSliceVar(
+		&cfg.OktaGroupsFilter, "okta-groups-filter", []string{""},
+		"Okta groups search expression, example: --okta-groups-filter 'profile.name sw \"AWS\"' --okta-groups-filter 'type eq \"APP_GROUP\"'",
+	)
+
+	rootCmd.PersistentFlags().StringSliceVar(
+		&cfg.OktaUsersFilter, "okta-users-filter", []string{""},
+		"Okta users search expression, used by the sync methods [users|groups+users], example: --okta-users-filter 'profile.department eq \"Engineering\"'",
+	)
+
+	rootCmd.PersistentFlags().StringVar(&cfg.LDAPURL, "ldap-url", "", "LDAP server URL, example: ldaps://ldap.my-company.com:636, used by the ldap identity provider")
+	rootCmd.PersistentFlags().StringVar(&cfg.LDAPBindDN, "ldap-bind-dn", "", "LDAP bind DN, an empty value uses an anonymous connection, used by the ldap identity provider")
+	rootCmd.PersistentFlags().StringVar(&cfg.LDAPBindPassword, "ldap-bind-password", "", "LDAP bind password, used by the ldap identity provider")
+	rootCmd.PersistentFlags().StringVar(&cfg.LDAPBindPasswordSecretName,
+		"ldap-bind-password-secret-name", config.DefaultLDAPBindPasswordSecretName,
+		"AWS Secrets Manager secret name for LDAP bind password",
+	)
+	rootCmd.PersistentFlags().StringVar(&cfg.LDAPBaseDN, "ldap-base-dn", "", "LDAP base DN where the users and groups are searched, example: dc=my-company,dc=com")
+	rootCmd.PersistentFlags().StringVar(&cfg.LDAPUserObjectFilter, "ldap-user-object-filter", "", "LDAP filter that selects the users entries (default \"(objectClass=person)\")")
+	rootCmd.PersistentFlags().StringVar(&cfg.LDAPGroupObjectFilter, "ldap-group-object-filter", "",
+		"LDAP filter that selects the groups entries (default \"(|(objectClass=group)(objectClass=groupOfNames))\")",
+	)
+	rootCmd.PersistentFlags().StringVar(&cfg.LDAPMembership, "ldap-membership", config.DefaultLDAPMembership,
+		"LDAP attribute used to resolve the members of the groups, the nested groups are expanded [member|memberOf]",
+	)
+	rootCmd.PersistentFlags().StringVar(&cfg.LDAPUserEmailAttribute, "ldap-user-email-attribute", "", "LDAP attribute with the user email (default \"mail\")")
+	rootCmd.PersistentFlags().StringVar(&cfg.LDAPUserGivenNameAttribute, "ldap-user-given-name-attribute", "", "LDAP attribute with the user given name (default \"givenName\")")
+	rootCmd.PersistentFlags().StringVar(&cfg.LDAPUserFamilyNameAttribute, "ldap-user-family-name-attribute", "", "LDAP attribute with the user family name (default \"sn\")")
+	rootCmd.PersistentFlags().StringVar(&cfg.LDAPUserDisplayNameAttribute, "ldap-user-display-name-attribute", "", "LDAP attribute with the user display name (default \"displayName\")")
+	rootCmd.PersistentFlags().StringVar(&cfg.LDAPGroupNameAttribute, "ldap-group-name-attribute", "", "LDAP attribute with the group name (default \"cn\")")
+	rootCmd.PersistentFlags().StringVar(&cfg.LDAPGroupEmailAttribute, "ldap-group-email-attribute", "", "LDAP attribute with the group email (default \"mail\")")
+
+	rootCmd.PersistentFlags().StringSliceVar(
+		&cfg.LDAPGroupsFilter, "ldap-groups-filter", []string{""},
+		"LDAP groups search filter, example: --ldap-groups-filter '(cn=AWS-*)' --ldap-groups-filter '(cn=Admins)'",
+	)
+
+	rootCmd.PersistentFlags().StringSliceVar(
+		&cfg.LDAPUsersFilter, "ldap-users-filter", []string{""},
+		"LDAP users search filter, used by the sync methods [users|groups+users], example: --ldap-users-filter '(department=Engineering)'",
+	)
+
+	rootCmd.PersistentFlags().StringVar(&cfg.FilePath, "file-path", "", "YAML, JSON or CSV file with the groups and users, used by the file identity provider")
+
+	rootCmd.PersistentFlags().StringSliceVar(
+		&cfg.FileGroupsFilter, "file-groups-filter", []string{""},
+		"File groups name pattern, example: --file-groups-filter 'AWS-*' --file-groups-filter 'Admins'",
+	)
+
+	rootCmd.PersistentFlags().StringSliceVar(
+		&cfg.FileUsersFilter, "file-users-filter", []string{""},
+		"File users email pattern, used by the sync methods [users|groups+users], example: --file-users-filter '*@my-company.com'",
+	)
+
+	rootCmd.PersistentFlags().StringVar(&cfg.SCIMProvider, "scim-provider", config.DefaultSCIMProvider, "SCIM provider to use [aws|generic]")
+	rootCmd.PersistentFlags().StringVar(&cfg.SCIMEndpoint, "scim-endpoint", "", "SCIM 2.0 API Endpoint, used by the generic SCIM provider")
+	rootCmd.PersistentFlags().StringVar(&cfg.SCIMEndpointSecretName,
+		"scim-endpoint-secret-name", config.DefaultSCIMEndpointSecretName,
+		"AWS Secrets Manager secret name for SCIM 2.0 API Endpoint, used by the generic SCIM provider",
+	)
+	rootCmd.PersistentFlags().StringVar(&cfg.SCIMAccessToken, "scim-access-token", "", "SCIM 2.0 API Access Token, used by the generic SCIM provider")
+	rootCmd.PersistentFlags().StringVar(&cfg.SCIMAccessTokenSecretName,
+		"scim-access-token-secret-name", config.DefaultSCIMAccessTokenSecretName,
+		"AWS Secrets Manager secret name for SCIM 2.0 API Access Token, used by the generic SCIM provider",
+	)
+	rootCmd.PersistentFlags().IntVar(&cfg.SCIMConcurrency, "scim-concurrency", config.DefaultSCIMConcurrency,
+		"number of users, groups and groups members sent at the same time to the generic SCIM provider, 1 means sequential requests",
+	)
+	rootCmd.PersistentFlags().Float64Var(&cfg.SCIMRateLimit, "scim-rate-limit", config.DefaultSCIMRateLimit,
+		"maximum number of requests per second to the generic SCIM provider, 0 means no limit",
+	)
+
+	rootCmd.PersistentFlags().StringVarP(&cfg.SyncMethod, "sync-method", "m", config.DefaultSyncMethod, "Sync method to use [groups|users|groups+users]")
 	rootCmd.PersistentFlags().BoolVarP(&cfg.UseSecretsManager, "use-secrets-manager", "g", config.DefaultUseSecretsManager, "use AWS Secrets Manager content or not (default false)")
+	rootCmd.PersistentFlags().IntVar(&cfg.MaxDeleteGroups, "max-delete-groups", config.DefaultMaxDelete, "abort the sync when more than this number of groups would be deleted, 0 means no limit")
+	rootCmd.PersistentFlags().Float64Var(&cfg.MaxDeleteGroupsPercentage, "max-delete-groups-percentage", config.DefaultMaxDeletePercentage,
+		"abort the sync when more than this percentage (0-100) of the existing groups would be deleted, 0 means no limit",
+	)
+	rootCmd.PersistentFlags().IntVar(&cfg.MaxDeleteUsers, "max-delete-users", config.DefaultMaxDelete, "abort the sync when more than this number of users would be deleted, 0 means no limit")
+	rootCmd.PersistentFlags().Float64Var(&cfg.MaxDeleteUsersPercentage, "max-delete-users-percentage", config.DefaultMaxDeletePercentage,
+		"abort the sync when more than this percentage (0-100) of the existing users would be deleted, 0 means no limit",
+	)
+	rootCmd.PersistentFlags().IntVar(&cfg.MaxDeleteGroupsMembers, "max-delete-groups-members", config.DefaultMaxDelete,
+		"abort the sync when more than this number of groups members would be removed, 0 means no limit",
+	)
+	rootCmd.PersistentFlags().Float64Var(&cfg.MaxDeleteGroupsMembersPercentage, "max-delete-groups-members-percentage", config.DefaultMaxDeletePercentage,
+		"abort the sync when more than this percentage (0-100) of the existing groups members would be removed, 0 means no limit",
+	)
+	rootCmd.PersistentFlags().BoolVar(&cfg.AllowEmptyIdentityProvider, "allow-empty-identity-provider", config.DefaultAllowEmptyIdentityProvider,
+		"continue the sync when the identity provider returns no groups or no users, this deletes all the groups or users stored in the state (default false)",
+	)
+	rootCmd.PersistentFlags().BoolVar(&cfg.ContinueOnError, "continue-on-error", config.DefaultContinueOnError,
+		"continue the sync when some resources can't be synced with the SCIM provider, storing the state of the resources synced (default false)",
+	)
+	rootCmd.PersistentFlags().StringVar(&cfg.ReportFile, "report-file", "", "write the report of the changes applied by the sync to this file")
+	rootCmd.PersistentFlags().StringVar(&cfg.ReportFormat, "report-format", config.DefaultReportFormat,
+		fmt.Sprintf("format of the report written to the report file [%s|%s]", config.ReportFormatJSON, config.ReportFormatMarkdown),
+	)
+	rootCmd.PersistentFlags().StringVar(&cfg.MetricsAddress, "metrics-address", "", "address where the Prometheus metrics are served on /metrics while running, example: :9090")
+	rootCmd.PersistentFlags().StringVar(&cfg.MetricsPushgatewayURL, "metrics-pushgateway-url", "", "Prometheus Pushgateway URL where the metrics are pushed at the end of the sync, example: http://pushgateway:9091")
+	rootCmd.PersistentFlags().StringVar(&cfg.MetricsJob, "metrics-job", config.DefaultMetricsJob, "job name of the metrics pushed to the Prometheus Pushgateway")
+	rootCmd.PersistentFlags().StringVar(&cfg.TracingEndpoint, "tracing-endpoint", "", "OTLP/HTTP endpoint where the OpenTelemetry traces are exported, example: http://localhost:4318")
+	rootCmd.PersistentFlags().BoolVar(&cfg.DryRun, "dry-run", config.DefaultDryRun, "show the changes to be applied as JSON without modifying the SCIM side or the state (default false)")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -116,16 +286,89 @@ func initConfig() {
 		"sync_method",
 		"aws_s3_bucket_name",
 		"aws_s3_bucket_key",
+		"state_backend",
+		"aws_dynamodb_table_name",
+		"aws_dynamodb_state_id",
+		"aws_dynamodb_endpoint",
+		"state_lock_ttl",
+		"state_history",
+		"state_compression",
+		"state_kms_key_id",
+		"state_encryption_key_file",
+		"state_encryption_passphrase",
+		"state_encryption_passphrase_secret_name",
+		"state_allow_unencrypted",
 		"gws_user_email",
 		"gws_user_email_secret_name",
 		"gws_service_account_file",
 		"gws_service_account_file_secret_name",
 		"gws_groups_filter",
+		"gws_users_filter",
+		"gws_concurrency",
+		"gws_rate_limit",
+		"gws_user_lookup",
+		"identity_provider",
+		"entra_tenant_id",
+		"entra_client_id",
+		"entra_client_secret",
+		"entra_client_secret_secret_name",
+		"entra_groups_filter",
+		"entra_users_filter",
+		"okta_org_url",
+		"okta_api_token",
+		"okta_api_token_secret_name",
+		"okta_groups_filter",
+		"okta_users_filter",
+		"ldap_url",
+		"ldap_bind_dn",
+		"ldap_bind_password",
+		"ldap_bind_password_secret_name",
+		"ldap_base_dn",
+		"ldap_user_object_filter",
+		"ldap_group_object_filter",
+		"ldap_membership",
+		"ldap_groups_filter",
+		"ldap_users_filter",
+		"ldap_user_email_attribute",
+		"ldap_user_given_name_attribute",
+		"ldap_user_family_name_attribute",
+		"ldap_user_display_name_attribute",
+		"ldap_group_name_attribute",
+		"ldap_group_email_attribute",
+		"file_path",
+		"file_groups_filter",
+		"file_users_filter",
 		"aws_scim_access_token",
 		"aws_scim_access_token_secret_name",
+		"aws_scim_concurrency",
 		"aws_scim_endpoint",
 		"aws_scim_endpoint_secret_name",
+		"aws_scim_rate_limit",
+		"scim_provider",
+		"scim_endpoint",
+		"scim_endpoint_secret_name",
+		"scim_access_token",
+		"scim_access_token_secret_name",
+		"scim_concurrency",
+		"scim_rate_limit",
 		"use_secrets_manager",
+		"dry_run",
+		"max_delete_groups",
+		"max_delete_groups_percentage",
+		"max_delete_users",
+		"max_delete_users_percentage",
+		"max_delete_groups_members",
+		"max_delete_groups_members_percentage",
+		"allow_empty_identity_provider",
+		"continue_on_error",
+		"report_file",
+		"report_format",
+		"metrics_address",
+		"metrics_pushgateway_url",
+		"metrics_job",
+		"tracing_endpoint",
+		"schedule",
+		"trigger_token",
 	}
 	for _, e := range envVars {
 		if err := viper.BindEnv(e); err != nil {
@@ -190,9 +433,84 @@ func initConfig() {
 		getSecrets()
 	}
 
-	// not implemented yet block
-	if cfg.SyncMethod != "groups" {
-		log.Fatal("only 'sync-method=groups' are implemented")
+	switch cfg.SyncMethod {
+	case config.SyncMethodGroups, config.SyncMethodUsers, config.SyncMethodGroupsAndUsers:
+	default:
+		log.Fatalf("unknown sync method: %s, valid values are: %s, %s, %s",
+			cfg.SyncMethod, config.SyncMethodGroups, config.SyncMethodUsers, config.SyncMethodGroupsAndUsers,
+		)
+	}
+
+	switch cfg.IdentityProvider {
+	case config.IdentityProviderGoogle, config.IdentityProviderEntra, config.IdentityProviderOkta, config.IdentityProviderLDAP, config.IdentityProviderFile:
+	default:
+		log.Fatalf("unknown identity provider: %s, valid values are: %s, %s, %s, %s, %s",
+			cfg.IdentityProvider, config.IdentityProviderGoogle, config.IdentityProviderEntra, config.IdentityProviderOkta, config.IdentityProviderLDAP, config.IdentityProviderFile,
+		)
+	}
+
+	if cfg.IdentityProvider == config.IdentityProviderGoogle {
+		switch cfg.GWSUserLookup {
+		case config.GWSUserLookupGet, config.GWSUserLookupList:
+		default:
+			log.Fatalf("unknown gws user lookup: %s, valid values are: %s, %s",
+				cfg.GWSUserLookup, config.GWSUserLookupGet, config.GWSUserLookupList,
+			)
+		}
+	}
+
+	if cfg.IdentityProvider == config.IdentityProviderLDAP {
+		switch cfg.LDAPMembership {
+		case config.LDAPMembershipMember, config.LDAPMembershipMemberOf:
+		default:
+			log.Fatalf("unknown ldap membership: %s, valid values are: %s, %s",
+				cfg.LDAPMembership, config.LDAPMembershipMember, config.LDAPMembershipMemberOf,
+			)
+		}
+	}
+
+	switch cfg.SCIMProvider {
+	case config.SCIMProviderAWS, config.SCIMProviderGeneric:
+	default:
+		log.Fatalf("unknown scim provider: %s, valid values are: %s, %s",
+			cfg.SCIMProvider, config.SCIMProviderAWS, config.SCIMProviderGeneric,
+		)
+	}
+
+	switch cfg.StateBackend {
+	case config.StateBackendS3, config.StateBackendDynamoDB:
+	default:
+		log.Fatalf("unknown state backend: %s, valid values are: %s, %s",
+			cfg.StateBackend, config.StateBackendS3, config.StateBackendDynamoDB,
+		)
+	}
+
+	switch cfg.ReportFormat {
+	case config.ReportFormatJSON, config.ReportFormatMarkdown:
+	default:
+		log.Fatalf("unknown report format: %s, valid values are: %s, %s",
+			cfg.ReportFormat, config.ReportFormatJSON, config.ReportFormatMarkdown,
+		)
+	}
+
+	if cfg.StateLockTTL <= 0 {
+		log.Fatalf("invalid state lock ttl: %s, it must be greater than 0", cfg.StateLockTTL)
+	}
+
+	if cfg.StateHistory < 0 {
+		log.Fatalf("invalid state history: %d, it must be greater than or equal to 0", cfg.StateHistory)
+	}
+
+	switch cfg.StateCompression {
+	case config.StateCompressionNone, config.StateCompressionGzip, config.StateCompressionZstd:
+	default:
+		log.Fatalf("unknown state compression: %s, valid values are: %s, %s, %s",
+			cfg.StateCompression, config.StateCompressionNone, config.StateCompressionGzip, config.StateCompressionZstd,
+		)
+	}
+
+	if countNotEmpty(cfg.StateKMSKeyID, cfg.StateEncryptionKeyFile, cfg.StateEncryptionPassphrase) > 1 {
+		log.Fatal("only one of state kms key id, state encryption key file and state encryption passphrase can be used")
 	}
 }
 
@@ -211,91 +529,181 @@ func getSecrets() {
 		log.Fatalf(errors.Wrap(err, "cannot create aws secrets manager service").Error())
 	}
 
-	log.WithField("name", cfg.GWSUserEmailSecretName).Debug("reading secret")
-	unwrap, err := secrets.GetSecretValue(context.Background(), cfg.GWSUserEmailSecretName)
-	if err != nil {
-		log.Fatalf(errors.Wrap(err, "cannot get secretmanager value").Error())
-	}
-	cfg.GWSUserEmail = unwrap
+	switch cfg.IdentityProvider {
+	case config.IdentityProviderEntra:
+		log.WithField("name", cfg.EntraClientSecretSecretName).Debug("reading secret")
+		unwrap, err := secrets.GetSecretValue(context.Background(), cfg.EntraClientSecretSecretName)
+		if err != nil {
+			log.Fatalf(errors.Wrap(err, "cannot get secretmanager value").Error())
+		}
+		cfg.EntraClientSecret = unwrap
+	case config.IdentityProviderOkta:
+		log.WithField("name", cfg.OktaAPITokenSecretName).Debug("reading secret")
+		unwrap, err := secrets.GetSecretValue(context.Background(), cfg.OktaAPITokenSecretName)
+		if err != nil {
+			log.Fatalf(errors.Wrap(err, "cannot get secretmanager value").Error())
+		}
+		cfg.OktaAPIToken = unwrap
+	case config.IdentityProviderLDAP:
+		// the anonymous connections don't use a password
+		if cfg.LDAPBindDN != "" {
+			log.WithField("name", cfg.LDAPBindPasswordSecretName).Debug("reading secret")
+			unwrap, err := secrets.GetSecretValue(context.Background(), cfg.LDAPBindPasswordSecretName)
+			if err != nil {
+				log.Fatalf(errors.Wrap(err, "cannot get secretmanager value").Error())
+			}
+			cfg.LDAPBindPassword = unwrap
+		}
+	case config.IdentityProviderFile:
+		// the directory file doesn't use secrets
+	default:
+		log.WithField("name", cfg.GWSUserEmailSecretName).Debug("reading secret")
+		unwrap, err := secrets.GetSecretValue(context.Background(), cfg.GWSUserEmailSecretName)
+		if err != nil {
+			log.Fatalf(errors.Wrap(err, "cannot get secretmanager value").Error())
+		}
+		cfg.GWSUserEmail = unwrap
 
-	log.WithField("name", cfg.GWSServiceAccountFileSecretName).Debug("reading secret")
-	unwrap, err = secrets.GetSecretValue(context.Background(), cfg.GWSServiceAccountFileSecretName)
-	if err != nil {
-		log.Fatalf(errors.Wrap(err, "cannot get secretmanager value").Error())
+		log.WithField("name", cfg.GWSServiceAccountFileSecretName).Debug("reading secret")
+		unwrap, err = secrets.GetSecretValue(context.Background(), cfg.GWSServiceAccountFileSecretName)
+		if err != nil {
+			log.Fatalf(errors.Wrap(err, "cannot get secretmanager value").Error())
+		}
+		cfg.GWSServiceAccountFile = unwrap
 	}
-	cfg.GWSServiceAccountFile = unwrap
 
-	log.WithField("name", cfg.AWSSCIMAccessTokenSecretName).Debug("reading secret")
-	unwrap, err = secrets.GetSecretValue(context.Background(), cfg.AWSSCIMAccessTokenSecretName)
-	if err != nil {
-		log.Fatalf(errors.Wrap(err, "cannot get secretmanager value").Error())
+	switch cfg.SCIMProvider {
+	case config.SCIMProviderGeneric:
+		log.WithField("name", cfg.SCIMAccessTokenSecretName).Debug("reading secret")
+		unwrap, err := secrets.GetSecretValue(context.Background(), cfg.SCIMAccessTokenSecretName)
+		if err != nil {
+			log.Fatalf(errors.Wrap(err, "cannot get secretmanager value").Error())
+		}
+		cfg.SCIMAccessToken = unwrap
+
+		log.WithField("name", cfg.SCIMEndpointSecretName).Debug("reading secret")
+		unwrap, err = secrets.GetSecretValue(context.Background(), cfg.SCIMEndpointSecretName)
+		if err != nil {
+			log.Fatalf(errors.Wrap(err, "cannot get secretmanager value").Error())
+		}
+		cfg.SCIMEndpoint = unwrap
+	default:
+		log.WithField("name", cfg.AWSSCIMAccessTokenSecretName).Debug("reading secret")
+		unwrap, err := secrets.GetSecretValue(context.Background(), cfg.AWSSCIMAccessTokenSecretName)
+		if err != nil {
+			log.Fatalf(errors.Wrap(err, "cannot get secretmanager value").Error())
+		}
+		cfg.AWSSCIMAccessToken = unwrap
+
+		log.WithField("name", cfg.AWSSCIMEndpointSecretName).Debug("reading secret")
+		unwrap, err = secrets.GetSecretValue(context.Background(), cfg.AWSSCIMEndpointSecretName)
+		if err != nil {
+			log.Fatalf(errors.Wrap(err, "cannot get secretmanager value").Error())
+		}
+		cfg.AWSSCIMEndpoint = unwrap
 	}
-	cfg.AWSSCIMAccessToken = unwrap
 
-	log.WithField("name", cfg.AWSSCIMEndpointSecretName).Debug("reading secret")
-	unwrap, err = secrets.GetSecretValue(context.Background(), cfg.AWSSCIMEndpointSecretName)
-	if err != nil {
-		log.Fatalf(errors.Wrap(err, "cannot get secretmanager value").Error())
+	// the state is not always encrypted with a passphrase
+	if cfg.StateEncryptionPassphraseSecretName != "" {
+		log.WithField("name", cfg.StateEncryptionPassphraseSecretName).Debug("reading secret")
+		unwrap, err := secrets.GetSecretValue(context.Background(), cfg.StateEncryptionPassphraseSecretName)
+		if err != nil {
+			log.Fatalf(errors.Wrap(err, "cannot get secretmanager value").Error())
+		}
+		cfg.StateEncryptionPassphrase = unwrap
 	}
-	cfg.AWSSCIMEndpoint = unwrap
 }
 
 func sync() error {
 	log.Tracef("viper config: %s", utils.ToJSON(viper.AllSettings()))
 
-	if cfg.SyncMethod == "groups" {
-		return syncGroups()
+	ctx := context.Background()
+
+	reg, err := newMetricsRegistry()
+	if err != nil {
+		return errors.Wrap(err, "cannot create metrics registry")
 	}
-	return fmt.Errorf("unknown sync method: %s", cfg.SyncMethod)
-}
 
-func syncGroups() error {
-	log.WithFields(
-		log.Fields{"codeVersion": version.Version},
-	).Info("starting sync groups")
-	timeStart := time.Now()
+	if cfg.MetricsAddress != "" {
+		srv, err := serveMetrics(reg)
+		if err != nil {
+			return errors.Wrap(err, "cannot serve metrics")
+		}
+		defer srv.Close()
+	}
 
-	// cfg.GWSServiceAccountFile could be a file path or a content of the file
-	gwsServiceAccountContent := []byte(cfg.GWSServiceAccountFile)
+	if cfg.MetricsPushgatewayURL != "" {
+		defer pushMetrics(reg)
+	}
 
-	if !cfg.IsLambda {
-		gwsServiceAccount, err := os.ReadFile(cfg.GWSServiceAccountFile)
+	if cfg.TracingEndpoint != "" {
+		tp, err := newTracerProvider(ctx)
 		if err != nil {
-			log.Fatalf(errors.Wrap(err, "cannot read service account file").Error())
+			return errors.Wrap(err, "cannot create tracer provider")
 		}
-		gwsServiceAccountContent = gwsServiceAccount
+		defer shutdownTracerProvider(tp)
 	}
 
-	gwsAPIScopes := []string{
-		"https://www.googleapis.com/auth/admin.directory.group.readonly",
-		"https://www.googleapis.com/auth/admin.directory.group.member.readonly",
-		"https://www.googleapis.com/auth/admin.directory.user.readonly",
-	}
+	return runSync(ctx)
+}
 
-	ctx := context.Background()
+// runSync creates the services of the configured providers and syncs them, or shows the changes
+// to be applied in dry-run mode.
+func runSync(ctx context.Context) error {
+	log.WithFields(
+		log.Fields{
+			"codeVersion":      version.Version,
+			"syncMethod":       cfg.SyncMethod,
+			"identityProvider": cfg.IdentityProvider,
+			"scimProvider":     cfg.SCIMProvider,
+		},
+	).Info("starting sync")
+	timeStart := time.Now()
 
-	// Google Client Service
-	gwsService, err := google.NewService(ctx, cfg.GWSUserEmail, gwsServiceAccountContent, gwsAPIScopes...)
+	runner, err := newSyncRunner(ctx)
 	if err != nil {
-		return errors.Wrap(err, "cannot create google service")
+		return err
 	}
+	defer runner.close()
 
-	// Google Directory Service
-	gwsDS, err := google.NewDirectoryService(gwsService)
-	if err != nil {
-		return errors.Wrap(err, "cannot create google directory service")
+	if cfg.DryRun {
+		plan, err := runner.plan(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "cannot plan sync method %s", cfg.SyncMethod)
+		}
+
+		fmt.Println(string(utils.ToJSON(plan)))
+
+		log.WithFields(log.Fields{
+			"duration": time.Since(timeStart).String(),
+		}).Info("sync plan completed")
+
+		return nil
 	}
 
-	// Identity Provider Service
-	idpService, err := idp.NewIdentityProvider(gwsDS)
+	report, err := runner.sync(ctx)
+	if cfg.ReportFile != "" {
+		if err := writeReport(report); err != nil {
+			log.WithError(err).Error("cannot write the sync report")
+		}
+	}
 	if err != nil {
-		return errors.Wrap(err, "cannot create identity provider service")
+		return errors.Wrapf(err, "cannot sync method %s", cfg.SyncMethod)
 	}
 
-	// httpClient
+	log.WithFields(log.Fields{
+		"duration": time.Since(timeStart).String(),
+	}).Info("sync completed")
+
+	return nil
+}
+
+// newRetryClient returns the HTTP client retrying the requests that failed.
+func newRetryClient() *retryablehttp.Client {
 	retryClient := retryablehttp.NewClient()
 	retryClient.RetryMax = 10
 	retryClient.RetryWaitMin = time.Millisecond * 100
+	retryClient.RequestLogHook = metrics.RetryHook
 
 	if cfg.Debug {
 		retryClient.Logger = log.StandardLogger()
@@ -303,45 +711,322 @@ func syncGroups() error {
 		retryClient.Logger = nil
 	}
 
-	httpClient := retryClient.StandardClient()
+	return retryClient
+}
+
+// syncRunner syncs the configured providers with the configured sync method,
+// close releases the resources of the providers when the sync finishes.
+type syncRunner struct {
+	sync  func(context.Context) (*core.SyncReport, error)
+	plan  func(context.Context) (*core.SyncPlan, error)
+	close func()
+}
 
-	// AWS SCIM Service
-	awsSCIM, err := aws.NewSCIMService(httpClient, cfg.AWSSCIMEndpoint, cfg.AWSSCIMAccessToken)
+// newSyncRunner creates the services of the configured providers and returns the syncRunner using them.
+func newSyncRunner(ctx context.Context) (*syncRunner, error) {
+	provider, err := newIdentityProvider(ctx, newRetryClient().StandardClient())
 	if err != nil {
-		return errors.Wrap(err, "cannot create aws scim service")
+		return nil, errors.Wrap(err, "cannot create identity provider service")
 	}
-	awsSCIM.UserAgent = "idp-scim-sync/" + version.Version
 
-	scimService, err := scim.NewProvider(awsSCIM)
+	runner := &syncRunner{close: provider.close}
+
+	scimService, err := newSCIMService(scim.NewRetryClient(cfg.Debug).StandardClient())
 	if err != nil {
-		return errors.Wrap(err, "cannot create scim provider")
+		runner.close()
+		return nil, errors.Wrap(err, "cannot create scim provider")
 	}
 
 	awsConf, err := aws.NewDefaultConf(context.Background())
 	if err != nil {
-		log.Fatalf(errors.Wrap(err, "cannot load aws config").Error())
+		runner.close()
+		return nil, errors.Wrap(err, "cannot load aws config")
 	}
 
-	s3Client := s3.NewFromConfig(awsConf)
-	repo, err := repository.NewS3Repository(s3Client, repository.WithBucket(cfg.AWSS3BucketName), repository.WithKey(cfg.AWSS3BucketKey))
+	repo, err := newStateRepository(awsConf)
 	if err != nil {
-		log.Fatalf(errors.Wrap(err, "cannot create s3 repository").Error())
+		runner.close()
+		return nil, errors.Wrap(err, "cannot create state repository")
 	}
 
-	ss, err := core.NewSyncService(idpService, scimService, repo, core.WithIdentityProviderGroupsFilter(cfg.GWSGroupsFilter))
+	ss, err := core.NewSyncService(
+		provider.service, scimService, repo,
+		core.WithIdentityProviderGroupsFilter(provider.groupsFilter),
+		core.WithIdentityProviderUsersFilter(provider.usersFilter),
+		core.WithGroupsDeleteThreshold(cfg.MaxDeleteGroups, cfg.MaxDeleteGroupsPercentage),
+		core.WithUsersDeleteThreshold(cfg.MaxDeleteUsers, cfg.MaxDeleteUsersPercentage),
+		core.WithGroupsMembersDeleteThreshold(cfg.MaxDeleteGroupsMembers, cfg.MaxDeleteGroupsMembersPercentage),
+		core.WithAllowEmptyIdentityProvider(cfg.AllowEmptyIdentityProvider),
+		core.WithContinueOnError(cfg.ContinueOnError),
+	)
 	if err != nil {
-		return errors.Wrap(err, "cannot create sync service")
+		runner.close()
+		return nil, errors.Wrap(err, "cannot create sync service")
 	}
 
 	log.Tracef("app config: %s", utils.ToJSON(cfg))
 
-	if err := ss.SyncGroupsAndTheirMembers(ctx); err != nil {
-		return errors.Wrap(err, "cannot sync groups and their members")
+	switch cfg.SyncMethod {
+	case config.SyncMethodGroups:
+		runner.sync, runner.plan = ss.SyncGroupsAndTheirMembers, ss.PlanGroupsAndTheirMembers
+	case config.SyncMethodUsers:
+		runner.sync, runner.plan = ss.SyncUsers, ss.PlanUsers
+	case config.SyncMethodGroupsAndUsers:
+		runner.sync, runner.plan = ss.SyncGroupsAndUsers, ss.PlanGroupsAndUsers
+	default:
+		runner.close()
+		return nil, fmt.Errorf("unknown sync method: %s", cfg.SyncMethod)
+	}
+
+	return runner, nil
+}
+
+// writeReport writes the sync report to the report file in the report format.
+func writeReport(report *core.SyncReport) error {
+	data := utils.ToJSON(report)
+	if cfg.ReportFormat == config.ReportFormatMarkdown {
+		data = []byte(report.Markdown())
+	}
+
+	if err := os.WriteFile(cfg.ReportFile, data, 0o644); err != nil {
+		return errors.Wrapf(err, "cannot write report file %s", cfg.ReportFile)
 	}
 
 	log.WithFields(log.Fields{
-		"duration": time.Since(timeStart).String(),
-	}).Info("sync groups completed")
+		"file":   cfg.ReportFile,
+		"format": cfg.ReportFormat,
+	}).Info("sync report written")
 
 	return nil
 }
+
+// newStateRepository returns the state repository for the configured state backend.
+func newStateRepository(awsConf awssdk.Config) (core.StateRepository, error) {
+	stateCipher, err := newStateCipher(awsConf)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create state cipher")
+	}
+
+	switch cfg.StateBackend {
+	case config.StateBackendDynamoDB:
+		dynamodbClient := dynamodb.NewFromConfig(awsConf, func(o *dynamodb.Options) {
+			if cfg.AWSDynamoDBEndpoint != "" {
+				o.BaseEndpoint = awssdk.String(cfg.AWSDynamoDBEndpoint)
+			}
+		})
+
+		return repository.NewDynamoDBRepository(
+			dynamodbClient,
+			repository.WithDynamoDBTable(cfg.AWSDynamoDBTableName),
+			repository.WithDynamoDBStateID(cfg.AWSDynamoDBStateID),
+			repository.WithDynamoDBLockTTL(cfg.StateLockTTL),
+			repository.WithDynamoDBHistory(cfg.StateHistory),
+			repository.WithDynamoDBCipher(stateCipher),
+			repository.WithDynamoDBAllowUnencrypted(cfg.StateAllowUnencrypted),
+			repository.WithDynamoDBCompression(cfg.StateCompression),
+		)
+	default:
+		s3Client := s3.NewFromConfig(awsConf)
+
+		return repository.NewS3Repository(
+			s3Client,
+			repository.WithBucket(cfg.AWSS3BucketName),
+			repository.WithKey(cfg.AWSS3BucketKey),
+			repository.WithLockTTL(cfg.StateLockTTL),
+			repository.WithHistory(cfg.StateHistory),
+			repository.WithCipher(stateCipher),
+			repository.WithAllowUnencrypted(cfg.StateAllowUnencrypted),
+			repository.WithCompression(cfg.StateCompression),
+		)
+	}
+}
+
+// newStateCipher returns the cipher encrypting the state, nil when the state is not encrypted.
+func newStateCipher(awsConf awssdk.Config) (repository.StateCipher, error) {
+	switch {
+	case cfg.StateKMSKeyID != "":
+		return repository.NewKMSCipher(kms.NewFromConfig(awsConf), cfg.StateKMSKeyID)
+	case cfg.StateEncryptionKeyFile != "":
+		return repository.NewKeyFileCipher(cfg.StateEncryptionKeyFile)
+	case cfg.StateEncryptionPassphrase != "":
+		return repository.NewPassphraseCipher(cfg.StateEncryptionPassphrase)
+	default:
+		return nil, nil
+	}
+}
+
+// countNotEmpty returns the number of values not empty.
+func countNotEmpty(values ...string) int {
+	n := 0
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+
+	return n
+}
+
+// newSCIMService returns the SCIM service for the configured SCIM provider.
+func newSCIMService(httpClient *http.Client) (core.SCIMService, error) {
+	switch cfg.SCIMProvider {
+	case config.SCIMProviderGeneric:
+		// Generic SCIM 2.0 Service
+		scimClient, err := scimclient.NewClient(httpClient, cfg.SCIMEndpoint, cfg.SCIMAccessToken)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create generic scim client")
+		}
+		scimClient.UserAgent = "idp-scim-sync/" + version.Version
+
+		return scim.NewGenericProvider(scimClient,
+			scim.WithConcurrency(cfg.SCIMConcurrency),
+			scim.WithRateLimit(cfg.SCIMRateLimit),
+			scim.WithContinueOnError(cfg.ContinueOnError),
+		)
+	default:
+		// AWS SCIM Service
+		awsSCIM, err := aws.NewSCIMService(httpClient, cfg.AWSSCIMEndpoint, cfg.AWSSCIMAccessToken)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create aws scim service")
+		}
+		awsSCIM.UserAgent = "idp-scim-sync/" + version.Version
+
+		return scim.NewProvider(awsSCIM,
+			scim.WithConcurrency(cfg.AWSSCIMConcurrency),
+			scim.WithRateLimit(cfg.AWSSCIMRateLimit),
+			scim.WithContinueOnError(cfg.ContinueOnError),
+		)
+	}
+}
+
+// identityProvider is the identity provider service configured with the groups and users filters used with it,
+// close releases the resources of the service when the sync finishes.
+type identityProvider struct {
+	service      core.IdentityProviderService
+	groupsFilter []string
+	usersFilter  []string
+	close        func()
+}
+
+// newIdentityProvider returns the identity provider service for the configured identity provider.
+func newIdentityProvider(ctx context.Context, httpClient *http.Client) (*identityProvider, error) {
+	switch cfg.IdentityProvider {
+	case config.IdentityProviderEntra:
+		// Microsoft Graph Client Service
+		graphClient, err := microsoft.NewHTTPClient(ctx, cfg.EntraTenantID, cfg.EntraClientID, cfg.EntraClientSecret)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create microsoft graph client")
+		}
+
+		// Microsoft Graph Directory Service
+		graphDS, err := microsoft.NewDirectoryService(graphClient, microsoft.DefaultGraphURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create microsoft graph directory service")
+		}
+
+		idpService, err := idp.NewEntraIdentityProvider(graphDS)
+		if err != nil {
+			return nil, err
+		}
+
+		return &identityProvider{service: idpService, groupsFilter: cfg.EntraGroupsFilter, usersFilter: cfg.EntraUsersFilter, close: func() {}}, nil
+	case config.IdentityProviderOkta:
+		// Okta Directory Service
+		oktaDS, err := okta.NewDirectoryService(httpClient, cfg.OktaOrgURL, cfg.OktaAPIToken)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create okta directory service")
+		}
+
+		idpService, err := idp.NewOktaIdentityProvider(oktaDS)
+		if err != nil {
+			return nil, err
+		}
+
+		return &identityProvider{service: idpService, groupsFilter: cfg.OktaGroupsFilter, usersFilter: cfg.OktaUsersFilter, close: func() {}}, nil
+	case config.IdentityProviderLDAP:
+		conn, err := ldap.Dial(cfg.LDAPURL, cfg.LDAPBindDN, cfg.LDAPBindPassword)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot connect to the ldap server")
+		}
+
+		// LDAP Directory Service
+		ldapDS, err := ldap.NewDirectoryService(conn, cfg.LDAPBaseDN,
+			ldap.WithUserObjectFilter(cfg.LDAPUserObjectFilter),
+			ldap.WithGroupObjectFilter(cfg.LDAPGroupObjectFilter),
+			ldap.WithMembership(cfg.LDAPMembership),
+			ldap.WithAttributeMap(ldap.AttributeMap{
+				UserEmail:       cfg.LDAPUserEmailAttribute,
+				UserGivenName:   cfg.LDAPUserGivenNameAttribute,
+				UserFamilyName:  cfg.LDAPUserFamilyNameAttribute,
+				UserDisplayName: cfg.LDAPUserDisplayNameAttribute,
+				GroupName:       cfg.LDAPGroupNameAttribute,
+				GroupEmail:      cfg.LDAPGroupEmailAttribute,
+			}),
+		)
+		if err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "cannot create ldap directory service")
+		}
+
+		idpService, err := idp.NewLDAPIdentityProvider(ldapDS)
+		if err != nil {
+			ldapDS.Close()
+			return nil, err
+		}
+
+		return &identityProvider{service: idpService, groupsFilter: cfg.LDAPGroupsFilter, usersFilter: cfg.LDAPUsersFilter, close: ldapDS.Close}, nil
+	case config.IdentityProviderFile:
+		// Directory File Service
+		fileDS, err := file.ReadFile(cfg.FilePath)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read the directory file")
+		}
+
+		idpService, err := idp.NewFileIdentityProvider(fileDS)
+		if err != nil {
+			return nil, err
+		}
+
+		return &identityProvider{service: idpService, groupsFilter: cfg.FileGroupsFilter, usersFilter: cfg.FileUsersFilter, close: func() {}}, nil
+	default:
+		// cfg.GWSServiceAccountFile could be a file path or a content of the file
+		gwsServiceAccountContent := []byte(cfg.GWSServiceAccountFile)
+
+		if !cfg.IsLambda {
+			gwsServiceAccount, err := os.ReadFile(cfg.GWSServiceAccountFile)
+			if err != nil {
+				log.Fatalf(errors.Wrap(err, "cannot read service account file").Error())
+			}
+			gwsServiceAccountContent = gwsServiceAccount
+		}
+
+		gwsAPIScopes := []string{
+			"https://www.googleapis.com/auth/admin.directory.group.readonly",
+			"https://www.googleapis.com/auth/admin.directory.group.member.readonly",
+			"https://www.googleapis.com/auth/admin.directory.user.readonly",
+		}
+
+		// Google Client Service
+		gwsService, err := google.NewService(ctx, cfg.GWSUserEmail, gwsServiceAccountContent, gwsAPIScopes...)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create google service")
+		}
+
+		// Google Directory Service
+		gwsDS, err := google.NewDirectoryService(gwsService)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create google directory service")
+		}
+
+		idpService, err := idp.NewIdentityProvider(gwsDS,
+			idp.WithConcurrency(cfg.GWSConcurrency),
+			idp.WithRateLimit(cfg.GWSRateLimit),
+			idp.WithUserLookup(cfg.GWSUserLookup),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return &identityProvider{service: idpService, groupsFilter: cfg.GWSGroupsFilter, usersFilter: cfg.GWSUsersFilter, close: func() {}}, nil
+	}
+}
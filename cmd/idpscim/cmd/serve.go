@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/config"
+	"github.com/slashdevops/idp-scim-sync/internal/core"
+	"github.com/slashdevops/idp-scim-sync/internal/scheduler"
+	"github.com/slashdevops/idp-scim-sync/internal/server"
+	"github.com/slashdevops/idp-scim-sync/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the sync on a schedule as a long-running process",
+	Long: `
+Run the sync at start and then on a schedule, exposing the health of the syncs on /healthz and /readyz
+and the metrics on /metrics, for example inside a container. A sync is never started while the previous
+one is still running. With --trigger-token a sync can be run on demand with POST /sync.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return serve()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&cfg.Schedule, "schedule", config.DefaultSchedule,
+		"schedule of the syncs, a cron expression like '*/30 * * * *', a descriptor like '@hourly' or an interval like '30m'",
+	)
+	serveCmd.Flags().StringVar(&cfg.TriggerToken, "trigger-token", "",
+		"bearer token of the requests to POST /sync running a sync on demand, empty means the endpoint is not served",
+	)
+}
+
+// serveShutdownTimeout is the time given to the http server to answer the requests in progress when stopping.
+const serveShutdownTimeout = 5 * time.Second
+
+func serve() error {
+	log.Tracef("viper config: %s", utils.ToJSON(viper.AllSettings()))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reg, err := newMetricsRegistry()
+	if err != nil {
+		return errors.Wrap(err, "cannot create metrics registry")
+	}
+
+	if cfg.TracingEndpoint != "" {
+		tp, err := newTracerProvider(ctx)
+		if err != nil {
+			return errors.Wrap(err, "cannot create tracer provider")
+		}
+		defer shutdownTracerProvider(tp)
+	}
+
+	s, err := scheduler.New(cfg.Schedule, func(ctx context.Context) error {
+		err := runSync(ctx)
+		if err != nil {
+			log.WithError(err).Error("sync failed")
+		}
+
+		if cfg.MetricsPushgatewayURL != "" {
+			pushMetrics(reg)
+		}
+
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "cannot create scheduler")
+	}
+
+	addr := cfg.MetricsAddress
+	if addr == "" {
+		addr = config.DefaultServeAddress
+	}
+
+	mux := newMetricsMux(reg)
+	mux.Handle("/healthz", s.HealthzHandler())
+	mux.Handle("/readyz", s.ReadyzHandler())
+
+	if cfg.TriggerToken != "" {
+		h, err := server.SyncHandler(s, cfg.TriggerToken, triggeredSync(reg), triggeredPlan)
+		if err != nil {
+			return errors.Wrap(err, "cannot create sync handler")
+		}
+		mux.Handle("/sync", h)
+	}
+
+	// the endpoints are listened before the first sync, so serve fails when they cannot be served
+	srv, errc, err := listenAndServe(addr, mux)
+	if err != nil {
+		return errors.Wrap(err, "cannot serve http")
+	}
+
+	// the scheduled syncs are stopped when the server fails, the probes would not answer anymore
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var serveErr error
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		if serveErr = <-errc; serveErr != nil {
+			log.WithError(serveErr).Error("cannot serve http, stopping the scheduled syncs")
+			cancel()
+		}
+	}()
+
+	log.WithFields(log.Fields{
+		"schedule": cfg.Schedule,
+		"address":  addr,
+	}).Info("starting scheduled syncs")
+
+	// blocks until a signal is received, or the server fails, and the sync in progress completes
+	s.Start(runCtx)
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer shutdownCancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.WithError(err).Error("cannot stop the http server")
+	}
+	<-served
+
+	return errors.Wrap(serveErr, "cannot serve http")
+}
+
+// triggeredSync returns the sync run on demand with POST /sync, which writes the report and pushes
+// the metrics like the scheduled syncs.
+func triggeredSync(reg *prometheus.Registry) server.SyncFunc {
+	return func(ctx context.Context) (*core.SyncReport, error) {
+		runner, err := newSyncRunner(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer runner.close()
+
+		report, err := runner.sync(ctx)
+		if cfg.ReportFile != "" {
+			if err := writeReport(report); err != nil {
+				log.WithError(err).Error("cannot write the sync report")
+			}
+		}
+		if err != nil {
+			log.WithError(err).Error("sync failed")
+		}
+
+		if cfg.MetricsPushgatewayURL != "" {
+			pushMetrics(reg)
+		}
+
+		return report, err
+	}
+}
+
+// triggeredPlan returns the plan requested with POST /sync?dryRun=true.
+func triggeredPlan(ctx context.Context) (*core.SyncPlan, error) {
+	runner, err := newSyncRunner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer runner.close()
+
+	return runner.plan(ctx)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/tracing"
+	"github.com/slashdevops/idp-scim-sync/internal/version"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// newTracerProvider returns the tracer provider exporting the spans of the sync, the identity providers
+// and the SCIM providers to the tracing endpoint.
+func newTracerProvider(ctx context.Context) (*sdktrace.TracerProvider, error) {
+	exp, err := tracing.NewOTLPExporter(ctx, cfg.TracingEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithField("endpoint", cfg.TracingEndpoint).Info("exporting traces")
+	return tracing.NewTracerProvider(exp, version.Version)
+}
+
+// shutdownTracerProvider exports the pending spans of tp, the errors are only logged
+// to keep the result of the sync.
+func shutdownTracerProvider(tp *sdktrace.TracerProvider) {
+	ctx, cancel := context.WithTimeout(context.Background(), tracing.DefaultExportTimeout)
+	defer cancel()
+
+	if err := tp.Shutdown(ctx); err != nil {
+		log.WithError(err).Error("cannot export traces")
+	}
+}
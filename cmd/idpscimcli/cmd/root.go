@@ -66,6 +66,28 @@ func initConfig() {
 		"gws_users_filter",
 		"aws_scim_access_token",
 		"aws_scim_endpoint",
+		"scim_provider",
+		"scim_access_token",
+		"scim_endpoint",
+		"aws_s3_bucket_name",
+		"aws_s3_bucket_key",
+		"state_backend",
+		"aws_dynamodb_table_name",
+		"aws_dynamodb_state_id",
+		"aws_dynamodb_endpoint",
+		"state_history",
+		"state_compression",
+		"state_kms_key_id",
+		"state_encryption_key_file",
+		"state_encryption_passphrase",
+		"state_allow_unencrypted",
+		"max_delete_groups",
+		"max_delete_groups_percentage",
+		"max_delete_users",
+		"max_delete_users_percentage",
+		"max_delete_groups_members",
+		"max_delete_groups_members_percentage",
+		"allow_empty_identity_provider",
 	}
 	for _, e := range envVars {
 		if err := viper.BindEnv(e); err != nil {
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/config"
+	"github.com/slashdevops/idp-scim-sync/internal/core"
+	"github.com/slashdevops/idp-scim-sync/internal/repository"
+	"github.com/slashdevops/idp-scim-sync/internal/scim"
+	"github.com/slashdevops/idp-scim-sync/internal/version"
+	"github.com/slashdevops/idp-scim-sync/pkg/aws"
+	scimclient "github.com/slashdevops/idp-scim-sync/pkg/scim"
+	"github.com/spf13/cobra"
+)
+
+// commands state
+var (
+	// base state command
+	stateCmd = &cobra.Command{
+		Use:   "state",
+		Short: "State commands",
+		Long:  `Available commands for the state stored by idpscim.`,
+	}
+
+	// state history command
+	stateHistoryCmd = &cobra.Command{
+		Use:     "history",
+		Aliases: []string{"h"},
+		Short:   "return the previous states",
+		Long:    `list the previous states kept in the history of the state backend, the newest first`,
+		RunE:    runStateHistory,
+	}
+
+	// state rollback command
+	stateRollbackCmd = &cobra.Command{
+		Use:   "rollback <id>",
+		Short: "roll back to a previous state",
+		Long: `re-apply the groups, users and groups members of a previous state to the SCIM side,
+the id is one of the ids returned by the history command`,
+		Args: cobra.ExactArgs(1),
+		RunE: runStateRollback,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(stateCmd)
+	stateCmd.AddCommand(stateHistoryCmd)
+	stateCmd.AddCommand(stateRollbackCmd)
+
+	stateCmd.PersistentFlags().StringVar(&cfg.StateBackend, "state-backend", config.DefaultStateBackend, "backend storing the state [s3|dynamodb]")
+	stateCmd.PersistentFlags().StringVar(&cfg.AWSS3BucketName, "aws-s3-bucket-name", "", "AWS S3 Bucket name to store the state")
+	stateCmd.PersistentFlags().StringVar(&cfg.AWSS3BucketKey, "aws-s3-bucket-key", config.DefaultAWSS3BucketKey, "AWS S3 Bucket key to store the state")
+	stateCmd.PersistentFlags().StringVar(&cfg.AWSDynamoDBTableName, "aws-dynamodb-table-name", "", "AWS DynamoDB table name to store the state, used by the dynamodb state backend")
+	stateCmd.PersistentFlags().StringVar(&cfg.AWSDynamoDBStateID, "aws-dynamodb-state-id", config.DefaultAWSDynamoDBStateID, "id of the state in the AWS DynamoDB table")
+	stateCmd.PersistentFlags().StringVar(&cfg.AWSDynamoDBEndpoint, "aws-dynamodb-endpoint", "", "AWS DynamoDB endpoint, empty means the AWS endpoint")
+	stateCmd.PersistentFlags().StringVar(&cfg.StateKMSKeyID, "state-kms-key-id", "", "AWS KMS key id, ARN or alias encrypting the state")
+	stateCmd.PersistentFlags().StringVar(&cfg.StateEncryptionKeyFile, "state-encryption-key-file", "", "file with the key encrypting the state")
+	stateCmd.PersistentFlags().StringVar(&cfg.StateEncryptionPassphrase, "state-encryption-passphrase", "", "passphrase encrypting the state")
+	stateCmd.PersistentFlags().BoolVar(&cfg.StateAllowUnencrypted, "state-allow-unencrypted", config.DefaultStateAllowUnencrypted, "read the states stored without encryption when the state is encrypted")
+	stateCmd.PersistentFlags().StringVar(&cfg.StateCompression, "state-compression", config.DefaultStateCompression, "compression of the state stored by the rollback [none|gzip|zstd]")
+	stateCmd.PersistentFlags().IntVar(&cfg.StateHistory, "state-history", config.DefaultStateHistory, "number of previous states kept in the history, the same used by idpscim")
+
+	stateRollbackCmd.Flags().StringVar(&cfg.SCIMProvider, "scim-provider", config.DefaultSCIMProvider, "SCIM provider [aws|generic]")
+	stateRollbackCmd.Flags().StringVar(&cfg.AWSSCIMAccessToken, "aws-scim-access-token", "", "AWS SSO SCIM API Access Token")
+	stateRollbackCmd.Flags().StringVar(&cfg.AWSSCIMEndpoint, "aws-scim-endpoint", "", "AWS SSO SCIM API Endpoint")
+	stateRollbackCmd.Flags().StringVar(&cfg.SCIMAccessToken, "scim-access-token", "", "SCIM API Access Token, used by the generic scim provider")
+	stateRollbackCmd.Flags().StringVar(&cfg.SCIMEndpoint, "scim-endpoint", "", "SCIM API Endpoint, used by the generic scim provider")
+	stateRollbackCmd.Flags().BoolVar(&cfg.DryRun, "dry-run", false, "return the changes without applying them to the SCIM side or the state")
+	stateRollbackCmd.Flags().IntVar(&cfg.MaxDeleteGroups, "max-delete-groups", config.DefaultMaxDelete, "abort the rollback when more than this number of groups would be deleted, 0 means no limit")
+	stateRollbackCmd.Flags().Float64Var(&cfg.MaxDeleteGroupsPercentage, "max-delete-groups-percentage", config.DefaultMaxDeletePercentage,
+		"abort the rollback when more than this percentage (0-100) of the existing groups would be deleted, 0 means no limit",
+	)
+	stateRollbackCmd.Flags().IntVar(&cfg.MaxDeleteUsers, "max-delete-users", config.DefaultMaxDelete, "abort the rollback when more than this number of users would be deleted, 0 means no limit")
+	stateRollbackCmd.Flags().Float64Var(&cfg.MaxDeleteUsersPercentage, "max-delete-users-percentage", config.DefaultMaxDeletePercentage,
+		"abort the rollback when more than this percentage (0-100) of the existing users would be deleted, 0 means no limit",
+	)
+	stateRollbackCmd.Flags().IntVar(&cfg.MaxDeleteGroupsMembers, "max-delete-groups-members", config.DefaultMaxDelete,
+		"abort the rollback when more than this number of groups members would be removed, 0 means no limit",
+	)
+	stateRollbackCmd.Flags().Float64Var(&cfg.MaxDeleteGroupsMembersPercentage, "max-delete-groups-members-percentage", config.DefaultMaxDeletePercentage,
+		"abort the rollback when more than this percentage (0-100) of the existing groups members would be removed, 0 means no limit",
+	)
+	stateRollbackCmd.Flags().BoolVar(&cfg.AllowEmptyIdentityProvider, "allow-empty-identity-provider", false, "allow to roll back to a state without groups and users, deleting everything in the SCIM side")
+}
+
+func runStateHistory(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), reqTimeout)
+	defer cancel()
+
+	repo, err := newStateRepository(ctx)
+	if err != nil {
+		log.Errorf("error creating state repository: %s", err.Error())
+		return err
+	}
+
+	versions, err := repo.ListStates(ctx)
+	if err != nil {
+		log.Errorf("error listing the states, error: %s", err.Error())
+		return err
+	}
+
+	show(outFormat, versions)
+
+	return nil
+}
+
+func runStateRollback(cmd *cobra.Command, args []string) error {
+	// the rollback is a whole sync, so only the requests have a timeout
+	ctx := context.Background()
+
+	// the same client as idpscim, so the rollback retries the requests that failed and the throttled ones
+	retryClient := scim.NewRetryClient(cfg.Debug)
+	retryClient.HTTPClient.Timeout = reqTimeout
+
+	scimService, err := newSCIMService(retryClient.StandardClient())
+	if err != nil {
+		log.Errorf("error creating SCIM service: %s", err.Error())
+		return err
+	}
+
+	repo, err := newStateRepository(ctx)
+	if err != nil {
+		log.Errorf("error creating state repository: %s", err.Error())
+		return err
+	}
+
+	opts := []core.SyncServiceOption{
+		core.WithGroupsDeleteThreshold(cfg.MaxDeleteGroups, cfg.MaxDeleteGroupsPercentage),
+		core.WithUsersDeleteThreshold(cfg.MaxDeleteUsers, cfg.MaxDeleteUsersPercentage),
+		core.WithGroupsMembersDeleteThreshold(cfg.MaxDeleteGroupsMembers, cfg.MaxDeleteGroupsMembersPercentage),
+		core.WithAllowEmptyIdentityProvider(cfg.AllowEmptyIdentityProvider),
+	}
+
+	if cfg.DryRun {
+		plan, err := core.PlanRollback(ctx, scimService, repo, args[0], opts...)
+		if err != nil {
+			log.Errorf("error planning the rollback, error: %s", err.Error())
+			return err
+		}
+
+		show(outFormat, plan)
+
+		return nil
+	}
+
+	report, err := core.Rollback(ctx, scimService, repo, args[0], opts...)
+	if err != nil {
+		log.Errorf("error rolling back the state, error: %s", err.Error())
+		if report != nil {
+			show(outFormat, report)
+		}
+		return err
+	}
+
+	show(outFormat, report)
+
+	return nil
+}
+
+// newStateRepository returns the state repository for the configured state backend.
+func newStateRepository(ctx context.Context) (core.StateRepository, error) {
+	awsConf, err := aws.NewDefaultConf(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load aws config")
+	}
+
+	stateCipher, err := newStateCipher(awsConf)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create state cipher")
+	}
+
+	switch cfg.StateBackend {
+	case config.StateBackendDynamoDB:
+		dynamodbClient := dynamodb.NewFromConfig(awsConf, func(o *dynamodb.Options) {
+			if cfg.AWSDynamoDBEndpoint != "" {
+				o.BaseEndpoint = awssdk.String(cfg.AWSDynamoDBEndpoint)
+			}
+		})
+
+		return repository.NewDynamoDBRepository(
+			dynamodbClient,
+			repository.WithDynamoDBTable(cfg.AWSDynamoDBTableName),
+			repository.WithDynamoDBStateID(cfg.AWSDynamoDBStateID),
+			repository.WithDynamoDBLockTTL(cfg.StateLockTTL),
+			repository.WithDynamoDBHistory(cfg.StateHistory),
+			repository.WithDynamoDBCipher(stateCipher),
+			repository.WithDynamoDBAllowUnencrypted(cfg.StateAllowUnencrypted),
+			repository.WithDynamoDBCompression(cfg.StateCompression),
+		)
+	default:
+		s3Client := s3.NewFromConfig(awsConf)
+
+		return repository.NewS3Repository(
+			s3Client,
+			repository.WithBucket(cfg.AWSS3BucketName),
+			repository.WithKey(cfg.AWSS3BucketKey),
+			repository.WithLockTTL(cfg.StateLockTTL),
+			repository.WithHistory(cfg.StateHistory),
+			repository.WithCipher(stateCipher),
+			repository.WithAllowUnencrypted(cfg.StateAllowUnencrypted),
+			repository.WithCompression(cfg.StateCompression),
+		)
+	}
+}
+
+// newStateCipher returns the cipher encrypting the state, nil when the state is not encrypted.
+func newStateCipher(awsConf awssdk.Config) (repository.StateCipher, error) {
+	switch {
+	case cfg.StateKMSKeyID != "":
+		return repository.NewKMSCipher(kms.NewFromConfig(awsConf), cfg.StateKMSKeyID)
+	case cfg.StateEncryptionKeyFile != "":
+		return repository.NewKeyFileCipher(cfg.StateEncryptionKeyFile)
+	case cfg.StateEncryptionPassphrase != "":
+		return repository.NewPassphraseCipher(cfg.StateEncryptionPassphrase)
+	default:
+		return nil, nil
+	}
+}
+
+// newSCIMService returns the SCIM service for the configured SCIM provider.
+func newSCIMService(httpClient *http.Client) (core.SCIMService, error) {
+	switch cfg.SCIMProvider {
+	case config.SCIMProviderGeneric:
+		scimClient, err := scimclient.NewClient(httpClient, cfg.SCIMEndpoint, cfg.SCIMAccessToken)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create generic scim client")
+		}
+		scimClient.UserAgent = "idp-scim-sync/" + version.Version
+
+		return scim.NewGenericProvider(scimClient,
+			scim.WithConcurrency(cfg.SCIMConcurrency),
+			scim.WithRateLimit(cfg.SCIMRateLimit),
+		)
+	default:
+		awsSCIM, err := aws.NewSCIMService(httpClient, cfg.AWSSCIMEndpoint, cfg.AWSSCIMAccessToken)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create aws scim service")
+		}
+		awsSCIM.UserAgent = "idp-scim-sync/" + version.Version
+
+		return scim.NewProvider(awsSCIM,
+			scim.WithConcurrency(cfg.AWSSCIMConcurrency),
+			scim.WithRateLimit(cfg.AWSSCIMRateLimit),
+		)
+	}
+}
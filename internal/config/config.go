@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 const (
 	// DefaultIsLambda is the progam execute as a lambda function?
 	DefaultIsLambda = false
@@ -18,12 +20,73 @@ const (
 	// DefaultGWSServiceAccountFile is the name of the file containing the service account credentials.
 	DefaultGWSServiceAccountFile = "credentials.json"
 
+	// DefaultGWSConcurrency is the default number of concurrent requests to the Google Directory API
+	DefaultGWSConcurrency = 10
+
+	// DefaultGWSRateLimit is the default maximum number of requests per second to the Google Directory API,
+	// under the default Admin SDK quota of 2400 queries per minute per user
+	DefaultGWSRateLimit = 20.0
+
+	// GWSUserLookupGet gets every member of the groups with a Users.Get request
+	GWSUserLookupGet = "get"
+
+	// GWSUserLookupList lists all the users with a single Users.List request and gets only the members not found,
+	// like the external members of the groups
+	GWSUserLookupList = "list"
+
+	// DefaultGWSUserLookup is the default way to resolve the members of the Google Workspace groups to users
+	DefaultGWSUserLookup = GWSUserLookupGet
+
+	// SyncMethodGroups syncs the groups matched by the groups filter and their members.
+	SyncMethodGroups = "groups"
+
+	// SyncMethodUsers syncs the users matched by the users filter, no groups are synced.
+	SyncMethodUsers = "users"
+
+	// SyncMethodGroupsAndUsers syncs the groups matched by the groups filter and their members,
+	// and the users matched by the users filter even when they are not members of the synced groups.
+	SyncMethodGroupsAndUsers = "groups+users"
+
 	// DefaultSyncMethod is the default sync method to use.
-	DefaultSyncMethod = "groups"
+	DefaultSyncMethod = SyncMethodGroups
 
 	// DefaultAWSS3BucketKey is the key of the AWS S3 bucket.
 	DefaultAWSS3BucketKey = "state.json"
 
+	// StateBackendS3 stores the state in an AWS S3 bucket.
+	StateBackendS3 = "s3"
+
+	// StateBackendDynamoDB stores the state in an AWS DynamoDB table.
+	StateBackendDynamoDB = "dynamodb"
+
+	// DefaultStateBackend is the default backend of the state.
+	DefaultStateBackend = StateBackendS3
+
+	// DefaultAWSDynamoDBStateID is the default id of the state in the AWS DynamoDB table.
+	DefaultAWSDynamoDBStateID = "state"
+
+	// DefaultStateLockTTL is the time after which the lock of the state expires when the sync holding it
+	// didn't release it, the maximum timeout of an AWS Lambda function.
+	DefaultStateLockTTL = 15 * time.Minute
+
+	// DefaultStateAllowUnencrypted is the default of reading the states stored without encryption when the state is encrypted.
+	DefaultStateAllowUnencrypted = false
+
+	// DefaultStateHistory is the default number of previous states kept in the history of the state backend.
+	DefaultStateHistory = 10
+
+	// StateCompressionNone stores the state without compression.
+	StateCompressionNone = "none"
+
+	// StateCompressionGzip stores the state compressed with gzip.
+	StateCompressionGzip = "gzip"
+
+	// StateCompressionZstd stores the state compressed with zstd.
+	StateCompressionZstd = "zstd"
+
+	// DefaultStateCompression is the default compression of the state.
+	DefaultStateCompression = StateCompressionNone
+
 	// DefaultConfigFile is the default config file name.
 	DefaultConfigFile = ".idpscim.yaml"
 
@@ -39,8 +102,106 @@ const (
 	// DefaultAWSSCIMAccessTokenSecretName is the name of the secret containing the SCIM access token.
 	DefaultAWSSCIMAccessTokenSecretName = "IDPSCIM_SCIMAccessToken"
 
+	// DefaultAWSSCIMConcurrency is the default number of concurrent requests to the AWS SSO SCIM API
+	DefaultAWSSCIMConcurrency = 5
+
+	// DefaultAWSSCIMRateLimit is the default maximum number of requests per second to the AWS SSO SCIM API
+	DefaultAWSSCIMRateLimit = 10.0
+
+	// SCIMProviderAWS is the AWS SSO SCIM API provider
+	SCIMProviderAWS = "aws"
+
+	// SCIMProviderGeneric is the provider for any SCIM 2.0 compliant service
+	SCIMProviderGeneric = "generic"
+
+	// DefaultSCIMProvider is the default SCIM provider
+	DefaultSCIMProvider = SCIMProviderAWS
+
+	// DefaultSCIMEndpointSecretName is the name of the secret containing the generic SCIM provider endpoint.
+	DefaultSCIMEndpointSecretName = "IDPSCIM_GenericSCIMEndpoint"
+
+	// DefaultSCIMAccessTokenSecretName is the name of the secret containing the generic SCIM provider access token.
+	DefaultSCIMAccessTokenSecretName = "IDPSCIM_GenericSCIMAccessToken"
+
+	// DefaultSCIMConcurrency is the default number of concurrent requests to the generic SCIM provider
+	DefaultSCIMConcurrency = 5
+
+	// DefaultSCIMRateLimit is the default maximum number of requests per second to the generic SCIM provider, no limit
+	DefaultSCIMRateLimit = 0.0
+
+	// IdentityProviderGoogle is the Google Workspace identity provider
+	IdentityProviderGoogle = "google"
+
+	// IdentityProviderEntra is the Microsoft Entra ID (Azure AD) identity provider
+	IdentityProviderEntra = "entra"
+
+	// IdentityProviderOkta is the Okta identity provider
+	IdentityProviderOkta = "okta"
+
+	// IdentityProviderLDAP is the LDAPv3 identity provider, like Active Directory or OpenLDAP
+	IdentityProviderLDAP = "ldap"
+
+	// IdentityProviderFile is the identity provider that reads the groups and users from a local YAML, JSON or CSV file
+	IdentityProviderFile = "file"
+
+	// DefaultIdentityProvider is the default identity provider
+	DefaultIdentityProvider = IdentityProviderGoogle
+
+	// DefaultEntraClientSecretSecretName is the name of the secret containing the Microsoft Entra ID application client secret.
+	DefaultEntraClientSecretSecretName = "IDPSCIM_EntraClientSecret"
+
+	// DefaultOktaAPITokenSecretName is the name of the secret containing the Okta API token.
+	DefaultOktaAPITokenSecretName = "IDPSCIM_OktaAPIToken"
+
+	// DefaultLDAPBindPasswordSecretName is the name of the secret containing the LDAP bind password.
+	DefaultLDAPBindPasswordSecretName = "IDPSCIM_LDAPBindPassword"
+
+	// LDAPMembershipMember resolves the members of the groups reading the member attribute of the groups
+	LDAPMembershipMember = "member"
+
+	// LDAPMembershipMemberOf resolves the members of the groups searching the entries with the memberOf attribute
+	LDAPMembershipMemberOf = "memberOf"
+
+	// DefaultLDAPMembership is the default way to resolve the members of the LDAP groups
+	DefaultLDAPMembership = LDAPMembershipMember
+
 	// DefaultUseSecretsManager determines if we will use the AWS Secrets Manager secrets or program parameter values
 	DefaultUseSecretsManager = false
+
+	// DefaultDryRun determines if the sync only computes the changes without applying them
+	DefaultDryRun = false
+
+	// DefaultMaxDelete is the default maximum number of resources of one type deleted in a single sync, 0 means no limit
+	DefaultMaxDelete = 0
+
+	// DefaultMaxDeletePercentage is the default maximum percentage of resources of one type deleted in a single sync, 0 means no limit
+	DefaultMaxDeletePercentage = 0.0
+
+	// DefaultAllowEmptyIdentityProvider determines if the sync continues when the identity provider returns no data
+	// and the state is not empty
+	DefaultAllowEmptyIdentityProvider = false
+
+	// DefaultContinueOnError determines if the sync continues when some resources can't be synced with the SCIM side
+	DefaultContinueOnError = false
+
+	// ReportFormatJSON writes the sync report as JSON
+	ReportFormatJSON = "json"
+
+	// ReportFormatMarkdown writes the sync report as Markdown
+	ReportFormatMarkdown = "markdown"
+
+	// DefaultReportFormat is the default format of the sync report
+	DefaultReportFormat = ReportFormatJSON
+
+	// DefaultMetricsJob is the default job name of the metrics pushed to the Prometheus Pushgateway
+	DefaultMetricsJob = "idpscim"
+
+	// DefaultSchedule is the default schedule of the syncs in serve mode
+	DefaultSchedule = "@every 1h"
+
+	// DefaultServeAddress is the default address of the health and metrics endpoints in serve mode
+	// when the metrics address is empty
+	DefaultServeAddress = ":9090"
 )
 
 // Config represents the configuration of the application.
@@ -59,36 +220,221 @@ type Config struct {
 	GWSGroupsFilter                 []string `mapstructure:"gws_groups_filter" json:"gws_groups_filter" yaml:"gws_groups_filter"`
 	GWSUsersFilter                  []string `mapstructure:"gws_users_filter" json:"gws_users_filter" yaml:"gws_users_filter"`
 
+	// GWSConcurrency is the number of groups members and users requested at the same time to Google Workspace,
+	// and GWSRateLimit the maximum number of requests per second, 0 means no limit
+	GWSConcurrency int     `mapstructure:"gws_concurrency" json:"gws_concurrency" yaml:"gws_concurrency"`
+	GWSRateLimit   float64 `mapstructure:"gws_rate_limit" json:"gws_rate_limit" yaml:"gws_rate_limit"`
+
+	// GWSUserLookup is the way the groups members are resolved to users [get|list]
+	GWSUserLookup string `mapstructure:"gws_user_lookup" json:"gws_user_lookup" yaml:"gws_user_lookup"`
+
+	// IdentityProvider allow to select the identity provider where the groups and users are read from
+	// possible values: "google", "entra", "okta", "ldap", "file"
+	IdentityProvider string `mapstructure:"identity_provider" json:"identity_provider" yaml:"identity_provider"`
+
+	// Entra* are used by the "entra" identity provider, the filters are OData $filter expressions
+	EntraTenantID               string   `mapstructure:"entra_tenant_id" json:"entra_tenant_id" yaml:"entra_tenant_id"`
+	EntraClientID               string   `mapstructure:"entra_client_id" json:"entra_client_id" yaml:"entra_client_id"`
+	EntraClientSecret           string   `mapstructure:"entra_client_secret" json:"entra_client_secret" yaml:"entra_client_secret"`
+	EntraClientSecretSecretName string   `mapstructure:"entra_client_secret_secret_name" json:"entra_client_secret_secret_name" yaml:"entra_client_secret_secret_name"`
+	EntraGroupsFilter           []string `mapstructure:"entra_groups_filter" json:"entra_groups_filter" yaml:"entra_groups_filter"`
+	EntraUsersFilter            []string `mapstructure:"entra_users_filter" json:"entra_users_filter" yaml:"entra_users_filter"`
+
+	// Okta* are used by the "okta" identity provider, the filters are Okta search expressions
+	OktaOrgURL             string   `mapstructure:"okta_org_url" json:"okta_org_url" yaml:"okta_org_url"`
+	OktaAPIToken           string   `mapstructure:"okta_api_token" json:"okta_api_token" yaml:"okta_api_token"`
+	OktaAPITokenSecretName string   `mapstructure:"okta_api_token_secret_name" json:"okta_api_token_secret_name" yaml:"okta_api_token_secret_name"`
+	OktaGroupsFilter       []string `mapstructure:"okta_groups_filter" json:"okta_groups_filter" yaml:"okta_groups_filter"`
+	OktaUsersFilter        []string `mapstructure:"okta_users_filter" json:"okta_users_filter" yaml:"okta_users_filter"`
+
+	// LDAP* are used by the "ldap" identity provider, the filters are LDAP search filters.
+	// The empty object filters and attribute names use the defaults valid for Active Directory and the inetOrgPerson schema
+	LDAPURL                    string   `mapstructure:"ldap_url" json:"ldap_url" yaml:"ldap_url"`
+	LDAPBindDN                 string   `mapstructure:"ldap_bind_dn" json:"ldap_bind_dn" yaml:"ldap_bind_dn"`
+	LDAPBindPassword           string   `mapstructure:"ldap_bind_password" json:"ldap_bind_password" yaml:"ldap_bind_password"`
+	LDAPBindPasswordSecretName string   `mapstructure:"ldap_bind_password_secret_name" json:"ldap_bind_password_secret_name" yaml:"ldap_bind_password_secret_name"`
+	LDAPBaseDN                 string   `mapstructure:"ldap_base_dn" json:"ldap_base_dn" yaml:"ldap_base_dn"`
+	LDAPUserObjectFilter       string   `mapstructure:"ldap_user_object_filter" json:"ldap_user_object_filter" yaml:"ldap_user_object_filter"`
+	LDAPGroupObjectFilter      string   `mapstructure:"ldap_group_object_filter" json:"ldap_group_object_filter" yaml:"ldap_group_object_filter"`
+	LDAPMembership             string   `mapstructure:"ldap_membership" json:"ldap_membership" yaml:"ldap_membership"`
+	LDAPGroupsFilter           []string `mapstructure:"ldap_groups_filter" json:"ldap_groups_filter" yaml:"ldap_groups_filter"`
+	LDAPUsersFilter            []string `mapstructure:"ldap_users_filter" json:"ldap_users_filter" yaml:"ldap_users_filter"`
+
+	// LDAP*Attribute are the names of the attributes mapped to the users and groups fields
+	LDAPUserEmailAttribute       string `mapstructure:"ldap_user_email_attribute" json:"ldap_user_email_attribute" yaml:"ldap_user_email_attribute"`
+	LDAPUserGivenNameAttribute   string `mapstructure:"ldap_user_given_name_attribute" json:"ldap_user_given_name_attribute" yaml:"ldap_user_given_name_attribute"`
+	LDAPUserFamilyNameAttribute  string `mapstructure:"ldap_user_family_name_attribute" json:"ldap_user_family_name_attribute" yaml:"ldap_user_family_name_attribute"`
+	LDAPUserDisplayNameAttribute string `mapstructure:"ldap_user_display_name_attribute" json:"ldap_user_display_name_attribute" yaml:"ldap_user_display_name_attribute"`
+	LDAPGroupNameAttribute       string `mapstructure:"ldap_group_name_attribute" json:"ldap_group_name_attribute" yaml:"ldap_group_name_attribute"`
+	LDAPGroupEmailAttribute      string `mapstructure:"ldap_group_email_attribute" json:"ldap_group_email_attribute" yaml:"ldap_group_email_attribute"`
+
+	// File* are used by the "file" identity provider, the filters are patterns matched with the groups names and the users emails
+	FilePath         string   `mapstructure:"file_path" json:"file_path" yaml:"file_path"`
+	FileGroupsFilter []string `mapstructure:"file_groups_filter" json:"file_groups_filter" yaml:"file_groups_filter"`
+	FileUsersFilter  []string `mapstructure:"file_users_filter" json:"file_users_filter" yaml:"file_users_filter"`
+
 	AWSSCIMEndpoint              string `mapstructure:"aws_scim_endpoint" json:"aws_scim_endpoint" yaml:"aws_scim_endpoint"`
 	AWSSCIMAccessToken           string `mapstructure:"aws_scim_access_token" json:"aws_scim_access_token" yaml:"aws_scim_access_token"`
 	AWSSCIMEndpointSecretName    string `mapstructure:"aws_scim_endpoint_secret_name" json:"aws_scim_endpoint_secret_name" yaml:"aws_scim_endpoint_secret_name"`
 	AWSSCIMAccessTokenSecretName string `mapstructure:"aws_scim_access_token_secret_name" json:"aws_scim_access_token_secret_name" yaml:"aws_scim_access_token_secret_name"`
 
+	// AWSSCIMConcurrency is the number of users, groups and groups members sent at the same time to the AWS SSO SCIM API,
+	// and AWSSCIMRateLimit the maximum number of requests per second, 0 means no limit
+	AWSSCIMConcurrency int     `mapstructure:"aws_scim_concurrency" json:"aws_scim_concurrency" yaml:"aws_scim_concurrency"`
+	AWSSCIMRateLimit   float64 `mapstructure:"aws_scim_rate_limit" json:"aws_scim_rate_limit" yaml:"aws_scim_rate_limit"`
+
+	// SCIMProvider allow to select the SCIM service where the groups and users are provisioned
+	// possible values: "aws", "generic"
+	SCIMProvider string `mapstructure:"scim_provider" json:"scim_provider" yaml:"scim_provider"`
+
+	// SCIMEndpoint and SCIMAccessToken are used by the "generic" SCIM provider
+	SCIMEndpoint              string `mapstructure:"scim_endpoint" json:"scim_endpoint" yaml:"scim_endpoint"`
+	SCIMAccessToken           string `mapstructure:"scim_access_token" json:"scim_access_token" yaml:"scim_access_token"`
+	SCIMEndpointSecretName    string `mapstructure:"scim_endpoint_secret_name" json:"scim_endpoint_secret_name" yaml:"scim_endpoint_secret_name"`
+	SCIMAccessTokenSecretName string `mapstructure:"scim_access_token_secret_name" json:"scim_access_token_secret_name" yaml:"scim_access_token_secret_name"`
+
+	// SCIMConcurrency is the number of users, groups and groups members sent at the same time to the "generic" SCIM provider,
+	// and SCIMRateLimit the maximum number of requests per second, 0 means no limit
+	SCIMConcurrency int     `mapstructure:"scim_concurrency" json:"scim_concurrency" yaml:"scim_concurrency"`
+	SCIMRateLimit   float64 `mapstructure:"scim_rate_limit" json:"scim_rate_limit" yaml:"scim_rate_limit"`
+
 	AWSS3BucketName string `mapstructure:"aws_s3_bucket_name" json:"aws_s3_bucket_name" yaml:"aws_s3_bucket_name"`
 	AWSS3BucketKey  string `mapstructure:"aws_s3_bucket_key" json:"aws_s3_bucket_key" yaml:"aws_s3_bucket_key"`
 
+	// StateBackend is where the state is stored
+	// possible values: "s3", "dynamodb"
+	StateBackend string `mapstructure:"state_backend" json:"state_backend" yaml:"state_backend"`
+
+	// AWSDynamoDBTableName is the DynamoDB table storing the state, AWSDynamoDBStateID the id of the state in the table,
+	// and AWSDynamoDBEndpoint the endpoint of DynamoDB, empty means the AWS endpoint, used for DynamoDB Local
+	AWSDynamoDBTableName string `mapstructure:"aws_dynamodb_table_name" json:"aws_dynamodb_table_name" yaml:"aws_dynamodb_table_name"`
+	AWSDynamoDBStateID   string `mapstructure:"aws_dynamodb_state_id" json:"aws_dynamodb_state_id" yaml:"aws_dynamodb_state_id"`
+	AWSDynamoDBEndpoint  string `mapstructure:"aws_dynamodb_endpoint" json:"aws_dynamodb_endpoint" yaml:"aws_dynamodb_endpoint"`
+
+	// StateLockTTL is the time after which the lock of the state expires when the sync holding it didn't release it,
+	// it must be longer than the duration of a sync
+	StateLockTTL time.Duration `mapstructure:"state_lock_ttl" json:"state_lock_ttl" yaml:"state_lock_ttl"`
+
+	// StateHistory is the number of previous states kept in the history of the state backend,
+	// used to roll back to a previous state, 0 disables the history
+	StateHistory int `mapstructure:"state_history" json:"state_history" yaml:"state_history"`
+
+	// StateCompression is the compression of the state stored, the states are read with any compression
+	// possible values: "none", "gzip", "zstd"
+	StateCompression string `mapstructure:"state_compression" json:"state_compression" yaml:"state_compression"`
+
+	// StateKMSKeyID is the AWS KMS key encrypting the state, StateEncryptionKeyFile a file with a 32 bytes key
+	// and StateEncryptionPassphrase a passphrase to encrypt it instead, empty means the state is not encrypted
+	StateKMSKeyID             string `mapstructure:"state_kms_key_id" json:"state_kms_key_id" yaml:"state_kms_key_id"`
+	StateEncryptionKeyFile    string `mapstructure:"state_encryption_key_file" json:"state_encryption_key_file" yaml:"state_encryption_key_file"`
+	StateEncryptionPassphrase string `mapstructure:"state_encryption_passphrase" json:"state_encryption_passphrase" yaml:"state_encryption_passphrase"`
+
+	// StateEncryptionPassphraseSecretName is the AWS Secrets Manager secret with the passphrase to encrypt the state,
+	// read instead of StateEncryptionPassphrase when using the secrets manager, empty means it is not read
+	StateEncryptionPassphraseSecretName string `mapstructure:"state_encryption_passphrase_secret_name" json:"state_encryption_passphrase_secret_name" yaml:"state_encryption_passphrase_secret_name"`
+
+	// StateAllowUnencrypted reads the state stored without encryption when the state is encrypted, to migrate
+	// the state stored before enabling the encryption, otherwise it is refused because anybody could have written it
+	StateAllowUnencrypted bool `mapstructure:"state_allow_unencrypted" json:"state_allow_unencrypted" yaml:"state_allow_unencrypted"`
+
 	// SyncMethod allow to defined the sync method used to get the user and groups from Google Workspace
+	// possible values: "groups", "users", "groups+users"
 	SyncMethod string `mapstructure:"sync_method" json:"sync_method" yaml:"sync_method"`
 
 	// UseSecretsManager determines if we will use the AWS Secrets Manager secrets or program parameter values
 	UseSecretsManager bool `mapstructure:"use_secrets_manager" json:"use_secrets_manager" yaml:"use_secrets_manager"`
+
+	// DryRun determines if the sync only computes the changes without applying them to the SCIM side or the state
+	DryRun bool `mapstructure:"dry_run" json:"dry_run" yaml:"dry_run"`
+
+	// MaxDelete* and MaxDelete*Percentage abort the sync before deleting resources when the resources to be deleted
+	// exceed the number or the percentage (0-100) of the existing resources, 0 means no limit
+	MaxDeleteGroups                  int     `mapstructure:"max_delete_groups" json:"max_delete_groups" yaml:"max_delete_groups"`
+	MaxDeleteGroupsPercentage        float64 `mapstructure:"max_delete_groups_percentage" json:"max_delete_groups_percentage" yaml:"max_delete_groups_percentage"`
+	MaxDeleteUsers                   int     `mapstructure:"max_delete_users" json:"max_delete_users" yaml:"max_delete_users"`
+	MaxDeleteUsersPercentage         float64 `mapstructure:"max_delete_users_percentage" json:"max_delete_users_percentage" yaml:"max_delete_users_percentage"`
+	MaxDeleteGroupsMembers           int     `mapstructure:"max_delete_groups_members" json:"max_delete_groups_members" yaml:"max_delete_groups_members"`
+	MaxDeleteGroupsMembersPercentage float64 `mapstructure:"max_delete_groups_members_percentage" json:"max_delete_groups_members_percentage" yaml:"max_delete_groups_members_percentage"`
+
+	// AllowEmptyIdentityProvider determines if the sync continues when the identity provider returns no groups or no users
+	// and the state has some, this deletes all the groups or all the users in the SCIM side
+	AllowEmptyIdentityProvider bool `mapstructure:"allow_empty_identity_provider" json:"allow_empty_identity_provider" yaml:"allow_empty_identity_provider"`
+
+	// ContinueOnError determines if the sync continues when some resources can't be synced with the SCIM side,
+	// storing the state of the resources synced and returning an error with the resources that failed
+	ContinueOnError bool `mapstructure:"continue_on_error" json:"continue_on_error" yaml:"continue_on_error"`
+
+	// ReportFile is the file where the report of the changes applied by the sync is written, empty means no report
+	// and ReportFormat the format of the report [json|markdown]
+	ReportFile   string `mapstructure:"report_file" json:"report_file" yaml:"report_file"`
+	ReportFormat string `mapstructure:"report_format" json:"report_format" yaml:"report_format"`
+
+	// MetricsAddress is the address where the Prometheus metrics are served on /metrics, empty means not served,
+	// MetricsPushgatewayURL the Prometheus Pushgateway where the metrics are pushed at the end of the sync, empty means not pushed,
+	// and MetricsJob the job name of the metrics pushed
+	MetricsAddress        string `mapstructure:"metrics_address" json:"metrics_address" yaml:"metrics_address"`
+	MetricsPushgatewayURL string `mapstructure:"metrics_pushgateway_url" json:"metrics_pushgateway_url" yaml:"metrics_pushgateway_url"`
+	MetricsJob            string `mapstructure:"metrics_job" json:"metrics_job" yaml:"metrics_job"`
+
+	// TracingEndpoint is the OTLP/HTTP endpoint where the OpenTelemetry traces are exported, empty means not exported
+	TracingEndpoint string `mapstructure:"tracing_endpoint" json:"tracing_endpoint" yaml:"tracing_endpoint"`
+
+	// Schedule is the cron expression, descriptor or interval of the syncs in serve mode
+	Schedule string `mapstructure:"schedule" json:"schedule" yaml:"schedule"`
+
+	// TriggerToken is the bearer token of the requests to POST /sync running a sync on demand in serve mode,
+	// empty means the endpoint is not served
+	TriggerToken string `mapstructure:"trigger_token" json:"trigger_token" yaml:"trigger_token"`
 }
 
 // New returns a new Config
 func New() Config {
 	return Config{
-		ConfigFile:                      DefaultConfigFile,
-		IsLambda:                        DefaultIsLambda,
-		Debug:                           DefaultDebug,
-		LogLevel:                        DefaultLogLevel,
-		LogFormat:                       DefaultLogFormat,
-		GWSServiceAccountFile:           DefaultGWSServiceAccountFile,
-		SyncMethod:                      DefaultSyncMethod,
-		AWSS3BucketKey:                  DefaultAWSS3BucketKey,
-		GWSServiceAccountFileSecretName: DefaultGWSServiceAccountFileSecretName,
-		GWSUserEmailSecretName:          DefaultGWSUserEmailSecretName,
-		AWSSCIMEndpointSecretName:       DefaultAWSSCIMEndpointSecretName,
-		AWSSCIMAccessTokenSecretName:    DefaultAWSSCIMAccessTokenSecretName,
-		UseSecretsManager:               DefaultUseSecretsManager,
+		ConfigFile:                       DefaultConfigFile,
+		IsLambda:                         DefaultIsLambda,
+		Debug:                            DefaultDebug,
+		LogLevel:                         DefaultLogLevel,
+		LogFormat:                        DefaultLogFormat,
+		GWSServiceAccountFile:            DefaultGWSServiceAccountFile,
+		GWSConcurrency:                   DefaultGWSConcurrency,
+		GWSRateLimit:                     DefaultGWSRateLimit,
+		GWSUserLookup:                    DefaultGWSUserLookup,
+		SyncMethod:                       DefaultSyncMethod,
+		AWSS3BucketKey:                   DefaultAWSS3BucketKey,
+		StateBackend:                     DefaultStateBackend,
+		AWSDynamoDBStateID:               DefaultAWSDynamoDBStateID,
+		StateLockTTL:                     DefaultStateLockTTL,
+		StateAllowUnencrypted:            DefaultStateAllowUnencrypted,
+		StateHistory:                     DefaultStateHistory,
+		StateCompression:                 DefaultStateCompression,
+		GWSServiceAccountFileSecretName:  DefaultGWSServiceAccountFileSecretName,
+		GWSUserEmailSecretName:           DefaultGWSUserEmailSecretName,
+		AWSSCIMEndpointSecretName:        DefaultAWSSCIMEndpointSecretName,
+		AWSSCIMAccessTokenSecretName:     DefaultAWSSCIMAccessTokenSecretName,
+		AWSSCIMConcurrency:               DefaultAWSSCIMConcurrency,
+		AWSSCIMRateLimit:                 DefaultAWSSCIMRateLimit,
+		IdentityProvider:                 DefaultIdentityProvider,
+		EntraClientSecretSecretName:      DefaultEntraClientSecretSecretName,
+		OktaAPITokenSecretName:           DefaultOktaAPITokenSecretName,
+		LDAPBindPasswordSecretName:       DefaultLDAPBindPasswordSecretName,
+		LDAPMembership:                   DefaultLDAPMembership,
+		SCIMProvider:                     DefaultSCIMProvider,
+		SCIMEndpointSecretName:           DefaultSCIMEndpointSecretName,
+		SCIMAccessTokenSecretName:        DefaultSCIMAccessTokenSecretName,
+		SCIMConcurrency:                  DefaultSCIMConcurrency,
+		SCIMRateLimit:                    DefaultSCIMRateLimit,
+		UseSecretsManager:                DefaultUseSecretsManager,
+		DryRun:                           DefaultDryRun,
+		MaxDeleteGroups:                  DefaultMaxDelete,
+		MaxDeleteGroupsPercentage:        DefaultMaxDeletePercentage,
+		MaxDeleteUsers:                   DefaultMaxDelete,
+		MaxDeleteUsersPercentage:         DefaultMaxDeletePercentage,
+		MaxDeleteGroupsMembers:           DefaultMaxDelete,
+		MaxDeleteGroupsMembersPercentage: DefaultMaxDeletePercentage,
+		AllowEmptyIdentityProvider:       DefaultAllowEmptyIdentityProvider,
+		ContinueOnError:                  DefaultContinueOnError,
+		ReportFormat:                     DefaultReportFormat,
+		MetricsJob:                       DefaultMetricsJob,
+		Schedule:                         DefaultSchedule,
 	}
 }
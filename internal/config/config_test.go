@@ -23,5 +23,38 @@ func TestNew(t *testing.T) {
 	assert.Equal(cfg.GWSUserEmailSecretName, DefaultGWSUserEmailSecretName)
 	assert.Equal(cfg.AWSSCIMEndpointSecretName, DefaultAWSSCIMEndpointSecretName)
 	assert.Equal(cfg.AWSSCIMAccessTokenSecretName, DefaultAWSSCIMAccessTokenSecretName)
+	assert.Equal(cfg.AWSSCIMConcurrency, DefaultAWSSCIMConcurrency)
+	assert.Equal(cfg.AWSSCIMRateLimit, DefaultAWSSCIMRateLimit)
+	assert.Equal(cfg.IdentityProvider, DefaultIdentityProvider)
+	assert.Equal(cfg.EntraClientSecretSecretName, DefaultEntraClientSecretSecretName)
+	assert.Equal(cfg.OktaAPITokenSecretName, DefaultOktaAPITokenSecretName)
+	assert.Equal(cfg.LDAPBindPasswordSecretName, DefaultLDAPBindPasswordSecretName)
+	assert.Equal(cfg.GWSConcurrency, DefaultGWSConcurrency)
+	assert.Equal(cfg.GWSRateLimit, DefaultGWSRateLimit)
+	assert.Equal(cfg.GWSUserLookup, DefaultGWSUserLookup)
+	assert.Equal(cfg.LDAPMembership, DefaultLDAPMembership)
+	assert.Equal(cfg.SCIMProvider, DefaultSCIMProvider)
+	assert.Equal(cfg.SCIMEndpointSecretName, DefaultSCIMEndpointSecretName)
+	assert.Equal(cfg.SCIMAccessTokenSecretName, DefaultSCIMAccessTokenSecretName)
+	assert.Equal(cfg.SCIMConcurrency, DefaultSCIMConcurrency)
+	assert.Equal(cfg.SCIMRateLimit, DefaultSCIMRateLimit)
 	assert.Equal(cfg.UseSecretsManager, DefaultUseSecretsManager)
+	assert.Equal(cfg.DryRun, DefaultDryRun)
+	assert.Equal(cfg.AllowEmptyIdentityProvider, DefaultAllowEmptyIdentityProvider)
+	assert.Equal(cfg.ContinueOnError, DefaultContinueOnError)
+	assert.Equal(cfg.ReportFormat, DefaultReportFormat)
+	assert.Equal(cfg.MetricsJob, DefaultMetricsJob)
+	assert.Equal(cfg.Schedule, DefaultSchedule)
+	assert.Equal(cfg.StateBackend, DefaultStateBackend)
+	assert.Equal(cfg.AWSDynamoDBStateID, DefaultAWSDynamoDBStateID)
+	assert.Equal(cfg.StateLockTTL, DefaultStateLockTTL)
+	assert.Equal(cfg.StateHistory, DefaultStateHistory)
+	assert.Equal(cfg.StateCompression, DefaultStateCompression)
+	assert.Equal(cfg.StateAllowUnencrypted, DefaultStateAllowUnencrypted)
+	assert.Equal(cfg.MaxDeleteGroups, DefaultMaxDelete)
+	assert.Equal(cfg.MaxDeleteGroupsPercentage, DefaultMaxDeletePercentage)
+	assert.Equal(cfg.MaxDeleteUsers, DefaultMaxDelete)
+	assert.Equal(cfg.MaxDeleteUsersPercentage, DefaultMaxDeletePercentage)
+	assert.Equal(cfg.MaxDeleteGroupsMembers, DefaultMaxDelete)
+	assert.Equal(cfg.MaxDeleteGroupsMembersPercentage, DefaultMaxDeletePercentage)
 }
@@ -10,10 +10,12 @@ import (
 )
 
 // scimSync executes the sync of the data on the SCIM side and
-// returns the datasets synced
+// returns the datasets synced, with the current SCIM version of the resources that failed in f
 func scimSync(
 	ctx context.Context,
 	scim SCIMService,
+	dt deleteThresholds,
+	f *failures,
 	idpGroupsResult *model.GroupsResult,
 	idpUsersResult *model.UsersResult,
 	idpGroupsMembersResult *model.GroupsMembersResult,
@@ -38,14 +40,6 @@ func scimSync(
 		return nil, nil, nil, fmt.Errorf("error reconciling groups: %w", err)
 	}
 
-	groupsCreated, groupsUpdated, err := reconcilingGroups(ctx, scim, groupsCreate, groupsUpdate, groupsDelete)
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("error reconciling groups: %w", err)
-	}
-
-	// groupsCreated + groupsUpdated + groupsEqual = groups total
-	totalGroupsResult = model.MergeGroupsResult(groupsCreated, groupsUpdated, groupsEqual)
-
 	log.Info("getting SCIM Users")
 	scimUsersResult, err := scim.GetUsers(ctx)
 	if err != nil {
@@ -61,19 +55,16 @@ func scimSync(
 		return nil, nil, nil, fmt.Errorf("error operating with users: %w", err)
 	}
 
-	usersCreated, usersUpdated, err := reconcilingUsers(ctx, scim, usersCreate, usersUpdate, usersDelete)
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("error reconciling users: %w", err)
-	}
-
-	// usersCreated + usersUpdated + usersEqual = users total
-	totalUsersResult = model.MergeUsersResult(usersCreated, usersUpdated, usersEqual)
-
 	log.Info("getting SCIM Groups Members")
+	// the members are read before the first write, only from the groups and users kept by the sync, because
+	// the groups and users created have no members yet and the members of the ones deleted go with them.
 	// unfortunately, the SCIM service does not support the getGroupsMembers method in and efficient way
 	// see: "Nor Supported" section in: https://docs.aws.amazon.com/singlesignon/latest/developerguide/listgroups.html
 	// scimGroupsMembersResult, err := scim.GetGroupsMembers(ctx, &totalGroupsResult) // not supported yet
-	scimGroupsMembersResult, err := scim.GetGroupsMembersBruteForce(ctx, totalGroupsResult, totalUsersResult)
+	scimGroupsMembersResult, err := scim.GetGroupsMembersBruteForce(ctx,
+		model.MergeGroupsResult(groupsUpdate, groupsEqual),
+		model.MergeUsersResult(usersUpdate, usersEqual),
+	)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("error getting groups members from the SCIM service: %w", err)
 	}
@@ -82,7 +73,44 @@ func scimSync(
 		"idp":  idpGroupsMembersResult.Items,
 		"scim": scimGroupsMembersResult.Items,
 	}).Info("reconciling groups members")
-	membersCreate, membersEqual, membersDelete, err := model.MembersOperations(idpGroupsMembersResult, scimGroupsMembersResult)
+	_, _, membersDelete, err := model.MembersOperations(idpGroupsMembersResult, scimGroupsMembersResult)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error reconciling groups members: %w", err)
+	}
+
+	// the thresholds are checked before the first write, so an aborted sync doesn't change the SCIM side
+	if err := dt.groups.check("groups", groupsDelete.Items, scimGroupsResult.Items); err != nil {
+		return nil, nil, nil, fmt.Errorf("error reconciling groups: %w", err)
+	}
+
+	if err := dt.users.check("users", usersDelete.Items, scimUsersResult.Items); err != nil {
+		return nil, nil, nil, fmt.Errorf("error reconciling users: %w", err)
+	}
+
+	if err := dt.groupsMembers.check("groups members", countMembers(membersDelete), countMembers(scimGroupsMembersResult)); err != nil {
+		return nil, nil, nil, fmt.Errorf("error reconciling groups members: %w", err)
+	}
+
+	groupsCreated, groupsUpdated, err := reconcilingGroups(ctx, scim, groupsCreate, groupsUpdate, groupsDelete)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error reconciling groups: %w", err)
+	}
+
+	// groupsCreated + groupsUpdated + groupsEqual = groups total
+	totalGroupsResult = f.retainGroups(model.MergeGroupsResult(groupsCreated, groupsUpdated, groupsEqual), scimGroupsResult)
+
+	usersCreated, usersUpdated, err := reconcilingUsers(ctx, scim, usersCreate, usersUpdate, usersDelete)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error reconciling users: %w", err)
+	}
+
+	// usersCreated + usersUpdated + usersEqual = users total
+	totalUsersResult = f.retainUsers(model.MergeUsersResult(usersCreated, usersUpdated, usersEqual), scimUsersResult)
+
+	// the groups created are added without members, so the members added to them get their SCIM ids
+	scimGroupsMembersResult = model.MergeGroupsMembersResult(scimGroupsMembersResult, groupsWithoutMembers(groupsCreated))
+
+	membersCreate, membersEqual, _, err := model.MembersOperations(idpGroupsMembersResult, scimGroupsMembersResult)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("error reconciling groups members: %w", err)
 	}
@@ -93,17 +121,29 @@ func scimSync(
 	}
 
 	// membersCreate + membersEqual = members total
-	totalGroupsMembersResult = model.MergeGroupsMembersResult(membersCreated, membersEqual)
+	totalGroupsMembersResult = f.retainGroupsMembers(model.MergeGroupsMembersResult(membersCreated, membersEqual), scimGroupsMembersResult)
 
 	return totalGroupsResult, totalUsersResult, totalGroupsMembersResult, nil
 }
 
+// groupsWithoutMembers returns the groups members of the groups, without any member.
+func groupsWithoutMembers(gr *model.GroupsResult) *model.GroupsMembersResult {
+	groupsMembers := make([]*model.GroupMembers, 0, len(gr.Resources))
+	for _, group := range gr.Resources {
+		groupsMembers = append(groupsMembers, model.GroupMembersBuilder().WithGroup(group).WithResources([]*model.Member{}).Build())
+	}
+
+	return model.GroupsMembersResultBuilder().WithResources(groupsMembers).Build()
+}
+
 // stateSync executes the sync of the data on the state side and
-// returns the datasets synced
+// returns the datasets synced, with the state version of the resources that failed in f
 func stateSync(
 	ctx context.Context,
 	state *model.State,
 	scim SCIMService,
+	dt deleteThresholds,
+	f *failures,
 	idpGroupsResult *model.GroupsResult,
 	idpUsersResult *model.UsersResult,
 	idpGroupsMembersResult *model.GroupsMembersResult,
@@ -123,60 +163,92 @@ func stateSync(
 		"since":    time.Since(lastSyncTime).String(),
 	}).Info("syncing from state")
 
-	if idpGroupsResult.HashCode == state.Resources.Groups.HashCode {
-		log.Info("provider groups and state groups are the same, nothing to do with groups")
-
-		totalGroupsResult = state.Resources.Groups
-	} else {
-		log.Info("provider groups and state groups are different")
-		// now here we have the google fresh data and the last sync data state
-		// we need to compare the data and decide what to do
-		// see differences between the two datasets
+	groupsChanged := idpGroupsResult.HashCode != state.Resources.Groups.HashCode
+	usersChanged := idpUsersResult.HashCode != state.Resources.Users.HashCode
+	membersChanged := idpGroupsMembersResult.HashCode != state.Resources.GroupsMembers.HashCode
 
+	// the operations of every resource are computed and checked against the delete thresholds
+	// before the first write, so an aborted sync doesn't change the SCIM side
+	var groupsCreate, groupsUpdate, groupsEqual, groupsDelete *model.GroupsResult
+	if groupsChanged {
 		log.WithFields(log.Fields{
 			"idp":   idpGroupsResult.Items,
 			"state": state.Resources.Groups.Items,
 		}).Info("reconciling groups")
-		groupsCreate, groupsUpdate, groupsEqual, groupsDelete, err := model.GroupsOperations(idpGroupsResult, state.Resources.Groups)
+
+		groupsCreate, groupsUpdate, groupsEqual, groupsDelete, err = model.GroupsOperations(idpGroupsResult, state.Resources.Groups)
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("error reconciling groups: %w", err)
 		}
 
+		if err := dt.groups.check("groups", groupsDelete.Items, state.Resources.Groups.Items); err != nil {
+			return nil, nil, nil, fmt.Errorf("error reconciling groups: %w", err)
+		}
+	}
+
+	var usersCreate, usersUpdate, usersEqual, usersDelete *model.UsersResult
+	if usersChanged {
+		log.WithFields(log.Fields{
+			"idp":   idpUsersResult.Items,
+			"state": state.Resources.Users.Items,
+		}).Info("reconciling users")
+
+		usersCreate, usersUpdate, usersEqual, usersDelete, err = model.UsersOperations(idpUsersResult, state.Resources.Users)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error operating with users: %w", err)
+		}
+
+		if err := dt.users.check("users", usersDelete.Items, state.Resources.Users.Items); err != nil {
+			return nil, nil, nil, fmt.Errorf("error reconciling users: %w", err)
+		}
+	}
+
+	if membersChanged {
+		// the members removed are the members of the state missing in the identity provider,
+		// they don't depend on the groups and users created during the sync
+		_, _, membersDelete, err := model.MembersOperations(idpGroupsMembersResult, state.Resources.GroupsMembers)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error reconciling groups members: %w", err)
+		}
+
+		if err := dt.groupsMembers.check("groups members", countMembers(membersDelete), countMembers(state.Resources.GroupsMembers)); err != nil {
+			return nil, nil, nil, fmt.Errorf("error reconciling groups members: %w", err)
+		}
+	}
+
+	if !groupsChanged {
+		log.Info("provider groups and state groups are the same, nothing to do with groups")
+
+		totalGroupsResult = state.Resources.Groups
+	} else {
+		log.Info("provider groups and state groups are different")
+
 		groupsCreated, groupsUpdated, err := reconcilingGroups(ctx, scim, groupsCreate, groupsUpdate, groupsDelete)
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("error reconciling groups: %w", err)
 		}
 
 		// merge in only one data structure the groups created, updated amd equals who has the SCIMID
-		totalGroupsResult = model.MergeGroupsResult(groupsCreated, groupsUpdated, groupsEqual)
+		totalGroupsResult = f.retainGroups(model.MergeGroupsResult(groupsCreated, groupsUpdated, groupsEqual), state.Resources.Groups)
 	}
 
-	if idpUsersResult.HashCode == state.Resources.Users.HashCode {
+	if !usersChanged {
 		log.Info("provider users and state users are the same, nothing to do with users")
 
 		totalUsersResult = state.Resources.Users
 	} else {
 		log.Info("provider users and state users are different")
 
-		log.WithFields(log.Fields{
-			"idp":   idpUsersResult.Items,
-			"state": state.Resources.Users.Items,
-		}).Info("reconciling users")
-		usersCreate, usersUpdate, usersEqual, usersDelete, err := model.UsersOperations(idpUsersResult, state.Resources.Users)
-		if err != nil {
-			return nil, nil, nil, fmt.Errorf("error operating with users: %w", err)
-		}
-
 		usersCreated, usersUpdated, err := reconcilingUsers(ctx, scim, usersCreate, usersUpdate, usersDelete)
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("error reconciling users: %w", err)
 		}
 
 		// usersCreated + usersUpdated + usersEqual = users total
-		totalUsersResult = model.MergeUsersResult(usersCreated, usersUpdated, usersEqual)
+		totalUsersResult = f.retainUsers(model.MergeUsersResult(usersCreated, usersUpdated, usersEqual), state.Resources.Users)
 	}
 
-	if idpGroupsMembersResult.HashCode == state.Resources.GroupsMembers.HashCode {
+	if !membersChanged {
 		log.Info("provider groups-members and state groups-members are the same, nothing to do with groups-members")
 
 		totalGroupsMembersResult = state.Resources.GroupsMembers
@@ -203,7 +275,7 @@ func stateSync(
 			return nil, nil, nil, fmt.Errorf("error reconciling groups members: %w", err)
 		}
 
-		totalGroupsMembersResult = model.MergeGroupsMembersResult(groupsMembers)
+		totalGroupsMembersResult = f.retainGroupsMembers(model.MergeGroupsMembersResult(groupsMembers), state.Resources.GroupsMembers)
 	}
 	return totalGroupsResult, totalUsersResult, totalGroupsMembersResult, nil
 }
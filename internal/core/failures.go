@@ -0,0 +1,261 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+)
+
+// PartialSyncError is returned when the sync continues on error and some of the resources
+// couldn't be synced. The state is stored anyway, so the resources synced are not synced again.
+type PartialSyncError struct {
+	Failures []*model.ResourceError
+}
+
+func (e *PartialSyncError) Error() string {
+	return fmt.Sprintf("%d resources couldn't be synced with the SCIM service", len(e.Failures))
+}
+
+// Unwrap returns the errors of the resources that couldn't be synced.
+func (e *PartialSyncError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f
+	}
+	return errs
+}
+
+// failures collects the resources that couldn't be synced when the sync continues on error.
+// A nil failures doesn't collect anything and keeps the datasets synced unchanged.
+type failures struct {
+	mu   sync.Mutex
+	errs []*model.ResourceError
+}
+
+// collect records the resources that failed in err and returns nil,
+// or returns err when it is not the error of some resources, like a network or context error.
+func (f *failures) collect(err error) error {
+	res := model.ResourceErrors(err)
+	if len(res) == 0 {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, re := range res {
+		log.WithFields(log.Fields{
+			"resource":  re.Resource,
+			"operation": re.Operation,
+			"name":      re.Name,
+		}).WithError(re.Err).Warn("resource not synced, continuing")
+	}
+
+	f.errs = append(f.errs, res...)
+	return nil
+}
+
+// names returns the names of the resources of the given kind that failed with any of the operations.
+func (f *failures) names(resource string, operations ...string) map[string]struct{} {
+	names := make(map[string]struct{})
+	if f == nil {
+		return names
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, re := range f.errs {
+		if re.Resource != resource {
+			continue
+		}
+		for _, op := range operations {
+			if re.Operation == op {
+				names[re.Name] = struct{}{}
+			}
+		}
+	}
+
+	return names
+}
+
+// logSummary logs every resource that couldn't be synced with the details of the SCIM service response.
+func (f *failures) logSummary() {
+	for _, re := range f.errs {
+		log.WithFields(log.Fields{
+			"resource":   re.Resource,
+			"operation":  re.Operation,
+			"name":       re.Name,
+			"statusCode": re.StatusCode,
+			"details":    re.Details,
+		}).Error("resource not synced")
+	}
+}
+
+// err returns a PartialSyncError with the resources that couldn't be synced, or nil.
+func (f *failures) err() error {
+	if f == nil || len(f.errs) == 0 {
+		return nil
+	}
+
+	return &PartialSyncError{Failures: f.errs}
+}
+
+// retainGroups replaces in total the groups that couldn't be updated or deleted by their current
+// version, the one in the state or in the SCIM side, so they are synced again the next time.
+// The groups that couldn't be created are not in total, so they are created again the next time.
+func (f *failures) retainGroups(total, current *model.GroupsResult) *model.GroupsResult {
+	failed := f.names(model.ResourceGroup, model.OperationUpdate, model.OperationDelete)
+	if len(failed) == 0 {
+		return total
+	}
+
+	groups := make([]*model.Group, 0, total.Items)
+	for _, group := range total.Resources {
+		if _, ok := failed[group.Name]; !ok {
+			groups = append(groups, group)
+		}
+	}
+	for _, group := range current.Resources {
+		if _, ok := failed[group.Name]; ok {
+			groups = append(groups, group)
+		}
+	}
+
+	return model.GroupsResultBuilder().WithResources(groups).Build()
+}
+
+// retainUsers replaces in total the users that couldn't be updated or deleted by their current
+// version, the one in the state or in the SCIM side, so they are synced again the next time.
+// The users that couldn't be created are not in total, so they are created again the next time.
+func (f *failures) retainUsers(total, current *model.UsersResult) *model.UsersResult {
+	failed := f.names(model.ResourceUser, model.OperationUpdate, model.OperationDelete)
+	if len(failed) == 0 {
+		return total
+	}
+
+	users := make([]*model.User, 0, total.Items)
+	for _, user := range total.Resources {
+		if _, ok := failed[user.Email]; !ok {
+			users = append(users, user)
+		}
+	}
+	for _, user := range current.Resources {
+		if _, ok := failed[user.Email]; ok {
+			users = append(users, user)
+		}
+	}
+
+	return model.UsersResultBuilder().WithResources(users).Build()
+}
+
+// retainGroupsMembers replaces in total the members of the groups whose members couldn't be added
+// or removed by their current members, so the changes are synced again the next time.
+func (f *failures) retainGroupsMembers(total, current *model.GroupsMembersResult) *model.GroupsMembersResult {
+	failed := f.names(model.ResourceGroupMembers, model.OperationAdd, model.OperationRemove)
+	if len(failed) == 0 {
+		return total
+	}
+
+	groupsMembers := make([]*model.GroupMembers, 0, total.Items)
+	for _, gm := range total.Resources {
+		if _, ok := failed[gm.Group.Name]; !ok {
+			groupsMembers = append(groupsMembers, gm)
+		}
+	}
+	for _, gm := range current.Resources {
+		if _, ok := failed[gm.Group.Name]; ok {
+			groupsMembers = append(groupsMembers, gm)
+		}
+	}
+
+	return model.GroupsMembersResultBuilder().WithResources(groupsMembers).Build()
+}
+
+// continueSCIMService wraps a SCIMService to continue the sync when some of the resources fail.
+// The errors of the write methods returning the resources synced together with the errors of the
+// resources that failed, wrapped in model.ResourceError, are collected in failures and not returned.
+type continueSCIMService struct {
+	SCIMService
+	failures *failures
+}
+
+// newContinueSCIMService returns a new continueSCIMService wrapping the given SCIMService.
+func newContinueSCIMService(scim SCIMService) *continueSCIMService {
+	return &continueSCIMService{
+		SCIMService: scim,
+		failures:    &failures{},
+	}
+}
+
+// CreateGroups creates the groups collecting the groups that fail.
+func (c *continueSCIMService) CreateGroups(ctx context.Context, gr *model.GroupsResult) (*model.GroupsResult, error) {
+	created, err := c.SCIMService.CreateGroups(ctx, gr)
+	if err != nil && created != nil {
+		err = c.failures.collect(err)
+	}
+	return created, err
+}
+
+// UpdateGroups updates the groups collecting the groups that fail.
+func (c *continueSCIMService) UpdateGroups(ctx context.Context, gr *model.GroupsResult) (*model.GroupsResult, error) {
+	updated, err := c.SCIMService.UpdateGroups(ctx, gr)
+	if err != nil && updated != nil {
+		err = c.failures.collect(err)
+	}
+	return updated, err
+}
+
+// DeleteGroups deletes the groups collecting the groups that fail.
+func (c *continueSCIMService) DeleteGroups(ctx context.Context, gr *model.GroupsResult) error {
+	if err := c.SCIMService.DeleteGroups(ctx, gr); err != nil {
+		return c.failures.collect(err)
+	}
+	return nil
+}
+
+// CreateUsers creates the users collecting the users that fail.
+func (c *continueSCIMService) CreateUsers(ctx context.Context, ur *model.UsersResult) (*model.UsersResult, error) {
+	created, err := c.SCIMService.CreateUsers(ctx, ur)
+	if err != nil && created != nil {
+		err = c.failures.collect(err)
+	}
+	return created, err
+}
+
+// UpdateUsers updates the users collecting the users that fail.
+func (c *continueSCIMService) UpdateUsers(ctx context.Context, ur *model.UsersResult) (*model.UsersResult, error) {
+	updated, err := c.SCIMService.UpdateUsers(ctx, ur)
+	if err != nil && updated != nil {
+		err = c.failures.collect(err)
+	}
+	return updated, err
+}
+
+// DeleteUsers deletes the users collecting the users that fail.
+func (c *continueSCIMService) DeleteUsers(ctx context.Context, ur *model.UsersResult) error {
+	if err := c.SCIMService.DeleteUsers(ctx, ur); err != nil {
+		return c.failures.collect(err)
+	}
+	return nil
+}
+
+// CreateGroupsMembers adds the groups members collecting the groups that fail.
+func (c *continueSCIMService) CreateGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) (*model.GroupsMembersResult, error) {
+	created, err := c.SCIMService.CreateGroupsMembers(ctx, gmr)
+	if err != nil && created != nil {
+		err = c.failures.collect(err)
+	}
+	return created, err
+}
+
+// DeleteGroupsMembers removes the groups members collecting the groups that fail.
+func (c *continueSCIMService) DeleteGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) error {
+	if err := c.SCIMService.DeleteGroupsMembers(ctx, gmr); err != nil {
+		return c.failures.collect(err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	mocks "github.com/slashdevops/idp-scim-sync/mocks/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncService_SyncUsers_ContinueOnError(t *testing.T) {
+	ctx := context.TODO()
+
+	newUser := func(id, email, familyName string) *model.User {
+		return model.UserBuilder().WithIPID(id).WithSCIMID("scim-" + id).WithEmail(email).WithGivenName("user").WithFamilyName(familyName).WithActive(true).Build()
+	}
+
+	user1 := newUser("user-1", "user.1@mail.com", "1")
+	user1Changed := newUser("user-1", "user.1@mail.com", "changed")
+	user2 := newUser("user-2", "user.2@mail.com", "2")
+	user3 := newUser("user-3", "user.3@mail.com", "3")
+	user4 := model.UserBuilder().WithIPID("user-4").WithEmail("user.4@mail.com").Build()
+	user5 := model.UserBuilder().WithIPID("user-5").WithEmail("user.5@mail.com").Build()
+	user5Created := newUser("user-5", "user.5@mail.com", "")
+
+	state := model.StateBuilder().
+		WithLastSync("2022-01-01T00:00:00Z").
+		WithGroups(model.GroupsResultBuilder().Build()).
+		WithUsers(model.UsersResultBuilder().WithResources([]*model.User{user1, user2, user3}).Build()).
+		WithGroupsMembers(model.GroupsMembersResultBuilder().Build()).
+		Build()
+
+	idpUsersResult := model.UsersResultBuilder().WithResources([]*model.User{user1Changed, user3, user4, user5}).Build()
+
+	errTest := errors.New("test error")
+	createErr := &model.ResourceError{Resource: model.ResourceUser, Operation: model.OperationCreate, Name: "user.4@mail.com", StatusCode: http.StatusConflict, Err: errTest}
+	updateErr := &model.ResourceError{Resource: model.ResourceUser, Operation: model.OperationUpdate, Name: "user.1@mail.com", Err: errTest}
+	deleteErr := &model.ResourceError{Resource: model.ResourceUser, Operation: model.OperationDelete, Name: "user.2@mail.com", Err: errTest}
+
+	t.Run("store the state of the users synced and the current version of the users that failed", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockProviderService.EXPECT().GetUsers(gomock.Any(), gomock.Any()).Return(idpUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+		mockSCIMService.EXPECT().CreateUsers(gomock.Any(), gomock.Any()).Return(model.UsersResultBuilder().WithResources([]*model.User{user5Created}).Build(), createErr).Times(1)
+		mockSCIMService.EXPECT().UpdateUsers(gomock.Any(), gomock.Any()).Return(model.UsersResultBuilder().Build(), updateErr).Times(1)
+		mockSCIMService.EXPECT().DeleteUsers(gomock.Any(), gomock.Any()).Return(deleteErr).Times(1)
+		mockStateRepository.EXPECT().SetState(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, s *model.State) error {
+			emails := make([]string, 0)
+			for _, u := range s.Resources.Users.Resources {
+				emails = append(emails, u.Email)
+			}
+			assert.ElementsMatch(t, []string{"user.1@mail.com", "user.2@mail.com", "user.3@mail.com", "user.5@mail.com"}, emails)
+
+			// the user not updated keeps the state version, so it is updated again the next time
+			for _, u := range s.Resources.Users.Resources {
+				if u.Email == "user.1@mail.com" {
+					assert.Equal(t, user1.HashCode, u.HashCode)
+				}
+			}
+			return nil
+		}).Times(1)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository, WithContinueOnError(true))
+		assert.NoError(t, err)
+
+		report, err := svc.SyncUsers(ctx)
+
+		var pse *PartialSyncError
+		assert.ErrorAs(t, err, &pse)
+		assert.Equal(t, []*model.ResourceError{createErr, updateErr, deleteErr}, pse.Failures)
+		assert.ErrorIs(t, err, errTest)
+
+		assert.Equal(t, pse.Failures, report.Failures)
+		assert.Equal(t, &SyncSummary{UsersCreated: 1, Failures: 3}, report.Summary)
+	})
+
+	t.Run("stop the sync when the error is not of some resources", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockProviderService.EXPECT().GetUsers(gomock.Any(), gomock.Any()).Return(idpUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+		mockSCIMService.EXPECT().CreateUsers(gomock.Any(), gomock.Any()).Return(nil, errTest).Times(1)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository, WithContinueOnError(true))
+		assert.NoError(t, err)
+
+		_, err = svc.SyncUsers(ctx)
+		assert.ErrorIs(t, err, errTest)
+
+		var pse *PartialSyncError
+		assert.False(t, errors.As(err, &pse))
+	})
+}
+
+func TestFailures_RetainGroupsMembers(t *testing.T) {
+	group1 := model.GroupBuilder().WithIPID("group-1").WithName("group 1").Build()
+	group2 := model.GroupBuilder().WithIPID("group-2").WithName("group 2").Build()
+	member1 := model.MemberBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").Build()
+	member2 := model.MemberBuilder().WithIPID("user-2").WithEmail("user.2@mail.com").Build()
+
+	total := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+		model.GroupMembersBuilder().WithGroup(group1).WithResources([]*model.Member{member1, member2}).Build(),
+		model.GroupMembersBuilder().WithGroup(group2).WithResources([]*model.Member{member1, member2}).Build(),
+	}).Build()
+	current := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+		model.GroupMembersBuilder().WithGroup(group1).WithResources([]*model.Member{member1}).Build(),
+		model.GroupMembersBuilder().WithGroup(group2).WithResources([]*model.Member{member1}).Build(),
+	}).Build()
+
+	t.Run("keep the total when there are no failures", func(t *testing.T) {
+		var f *failures
+		assert.Equal(t, total, f.retainGroupsMembers(total, current))
+		assert.NoError(t, f.err())
+	})
+
+	t.Run("keep the current members of the groups that failed", func(t *testing.T) {
+		f := &failures{}
+		assert.NoError(t, f.collect(&model.ResourceError{Resource: model.ResourceGroupMembers, Operation: model.OperationAdd, Name: "group 2", Err: errors.New("test error")}))
+
+		got := f.retainGroupsMembers(total, current)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, "group 1", got.Resources[0].Group.Name)
+		assert.Equal(t, 2, got.Resources[0].Items)
+		assert.Equal(t, "group 2", got.Resources[1].Group.Name)
+		assert.Equal(t, 1, got.Resources[1].Items)
+	})
+}
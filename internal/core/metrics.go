@@ -0,0 +1,117 @@
+package core
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/slashdevops/idp-scim-sync/internal/metrics"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+)
+
+// Results of the sync used as label of the metrics.
+const (
+	resultSuccess = "success"
+	resultPartial = "partial"
+	resultFailure = "failure"
+)
+
+var (
+	syncsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "sync",
+		Name:      "total",
+		Help:      "Number of syncs by result, success, partial when some resources failed, or failure.",
+	}, []string{"result"})
+
+	syncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "sync",
+		Name:      "duration_seconds",
+		Help:      "Duration of the syncs by result.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 900},
+	}, []string{"result"})
+
+	syncLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "sync",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time of the last sync completed without errors.",
+	})
+
+	syncResourcesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "sync",
+		Name:      "resources_total",
+		Help:      "Number of resources changed in the SCIM side by resource and operation, the groups members are counted by member.",
+	}, []string{"resource", "operation"})
+
+	syncResourcesFailedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "sync",
+		Name:      "resources_failed_total",
+		Help:      "Number of resources that couldn't be synced with the SCIM side by resource and operation.",
+	}, []string{"resource", "operation"})
+
+	syncResourcesSkippedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "sync",
+		Name:      "resources_skipped_total",
+		Help:      "Number of resources of the identity provider skipped by resource.",
+	}, []string{"resource"})
+)
+
+// Collectors returns the Prometheus collectors of the sync service.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		syncsTotal,
+		syncDuration,
+		syncLastSuccess,
+		syncResourcesTotal,
+		syncResourcesFailedTotal,
+		syncResourcesSkippedTotal,
+	}
+}
+
+// observeSync records the metrics of a finished sync with its report and error.
+func observeSync(r *SyncReport, err error) {
+	result := resultSuccess
+	if err != nil {
+		result = resultFailure
+
+		var pse *PartialSyncError
+		if errors.As(err, &pse) {
+			result = resultPartial
+		}
+	}
+
+	syncsTotal.WithLabelValues(result).Inc()
+	syncDuration.WithLabelValues(result).Observe(r.EndTime.Sub(r.StartTime).Seconds())
+	if result == resultSuccess {
+		syncLastSuccess.Set(float64(r.EndTime.Unix()))
+	}
+
+	resources := []struct {
+		resource, operation string
+		count               int
+	}{
+		{model.ResourceGroup, model.OperationCreate, r.Summary.GroupsCreated},
+		{model.ResourceGroup, model.OperationUpdate, r.Summary.GroupsUpdated},
+		{model.ResourceGroup, model.OperationDelete, r.Summary.GroupsDeleted},
+		{model.ResourceUser, model.OperationCreate, r.Summary.UsersCreated},
+		{model.ResourceUser, model.OperationUpdate, r.Summary.UsersUpdated},
+		{model.ResourceUser, model.OperationDelete, r.Summary.UsersDeleted},
+		{model.ResourceGroupMembers, model.OperationAdd, r.Summary.MembersAdded},
+		{model.ResourceGroupMembers, model.OperationRemove, r.Summary.MembersRemoved},
+	}
+	for _, res := range resources {
+		syncResourcesTotal.WithLabelValues(res.resource, res.operation).Add(float64(res.count))
+	}
+
+	for _, re := range r.Failures {
+		syncResourcesFailedTotal.WithLabelValues(re.Resource, re.Operation).Inc()
+	}
+
+	for _, s := range r.Skipped {
+		syncResourcesSkippedTotal.WithLabelValues(s.Resource).Inc()
+	}
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserveSync(t *testing.T) {
+	t.Run("success sync records the resources changed and the last success", func(t *testing.T) {
+		r := newSyncReport()
+		r.Users.Created = model.UsersResultBuilder().WithResources([]*model.User{
+			model.UserBuilder().WithEmail("user.1@mail.com").Build(),
+			model.UserBuilder().WithEmail("user.2@mail.com").Build(),
+		}).Build()
+		r.finish(nil, nil)
+
+		syncs := testutil.ToFloat64(syncsTotal.WithLabelValues(resultSuccess))
+		created := testutil.ToFloat64(syncResourcesTotal.WithLabelValues(model.ResourceUser, model.OperationCreate))
+
+		observeSync(r, nil)
+
+		assert.Equal(t, syncs+1, testutil.ToFloat64(syncsTotal.WithLabelValues(resultSuccess)))
+		assert.Equal(t, created+2, testutil.ToFloat64(syncResourcesTotal.WithLabelValues(model.ResourceUser, model.OperationCreate)))
+		assert.Equal(t, float64(r.EndTime.Unix()), testutil.ToFloat64(syncLastSuccess))
+	})
+
+	t.Run("partial sync records the failures and keeps the last success", func(t *testing.T) {
+		syncLastSuccess.Set(1)
+
+		f := &failures{}
+		assert.NoError(t, f.collect(&model.ResourceError{Resource: model.ResourceGroup, Operation: model.OperationDelete, Name: "group 1", Err: errors.New("test error")}))
+
+		err := f.err()
+		r := newSyncReport()
+		r.finish(f, err)
+
+		syncs := testutil.ToFloat64(syncsTotal.WithLabelValues(resultPartial))
+		failed := testutil.ToFloat64(syncResourcesFailedTotal.WithLabelValues(model.ResourceGroup, model.OperationDelete))
+
+		observeSync(r, err)
+
+		assert.Equal(t, syncs+1, testutil.ToFloat64(syncsTotal.WithLabelValues(resultPartial)))
+		assert.Equal(t, failed+1, testutil.ToFloat64(syncResourcesFailedTotal.WithLabelValues(model.ResourceGroup, model.OperationDelete)))
+		assert.Equal(t, float64(1), testutil.ToFloat64(syncLastSuccess))
+	})
+
+	t.Run("failed sync", func(t *testing.T) {
+		err := errors.New("test error")
+		r := newSyncReport()
+		r.finish(nil, err)
+
+		syncs := testutil.ToFloat64(syncsTotal.WithLabelValues(resultFailure))
+		observeSync(r, err)
+		assert.Equal(t, syncs+1, testutil.ToFloat64(syncsTotal.WithLabelValues(resultFailure)))
+	})
+}
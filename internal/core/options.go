@@ -19,3 +19,50 @@ func WithIdentityProviderUsersFilter(filter []string) SyncServiceOption {
 		ss.provUsersFilter = filter
 	}
 }
+
+// WithGroupsDeleteThreshold is a SyncServiceOption that can be used to abort the sync
+// before deleting groups when the groups to be deleted are more than maxCount or more than
+// maxPercentage (0-100) of the existing groups. A value of 0 disables the check.
+func WithGroupsDeleteThreshold(maxCount int, maxPercentage float64) SyncServiceOption {
+	return func(ss *SyncService) {
+		ss.deleteThresholds.groups = deleteThreshold{maxCount: maxCount, maxPercentage: maxPercentage}
+	}
+}
+
+// WithUsersDeleteThreshold is a SyncServiceOption that can be used to abort the sync
+// before deleting users when the users to be deleted are more than maxCount or more than
+// maxPercentage (0-100) of the existing users. A value of 0 disables the check.
+func WithUsersDeleteThreshold(maxCount int, maxPercentage float64) SyncServiceOption {
+	return func(ss *SyncService) {
+		ss.deleteThresholds.users = deleteThreshold{maxCount: maxCount, maxPercentage: maxPercentage}
+	}
+}
+
+// WithGroupsMembersDeleteThreshold is a SyncServiceOption that can be used to abort the sync
+// before removing members from groups when the members to be removed are more than maxCount or more than
+// maxPercentage (0-100) of the existing groups members. A value of 0 disables the check.
+func WithGroupsMembersDeleteThreshold(maxCount int, maxPercentage float64) SyncServiceOption {
+	return func(ss *SyncService) {
+		ss.deleteThresholds.groupsMembers = deleteThreshold{maxCount: maxCount, maxPercentage: maxPercentage}
+	}
+}
+
+// WithContinueOnError is a SyncServiceOption that can be used to continue the sync when some groups,
+// users or groups members can't be synced. The state stores the resources synced, and the current version
+// of the resources that failed so they are synced again the next time, and the sync returns a PartialSyncError.
+// The SCIM service must return the resources synced together with the errors of the resources that failed,
+// wrapped in model.ResourceError, otherwise the sync stops on the first error.
+func WithContinueOnError(continueOnError bool) SyncServiceOption {
+	return func(ss *SyncService) {
+		ss.continueOnError = continueOnError
+	}
+}
+
+// WithAllowEmptyIdentityProvider is a SyncServiceOption that can be used to continue the sync
+// when the identity provider returns no groups or no users and the state has some.
+// By default the sync is aborted in this case to avoid deleting all the groups or users in the SCIM side.
+func WithAllowEmptyIdentityProvider(allow bool) SyncServiceOption {
+	return func(ss *SyncService) {
+		ss.allowEmptyIdentityProvider = allow
+	}
+}
@@ -106,3 +106,28 @@ func TestWithIdentityProviderUsersFilter(t *testing.T) {
 		}
 	})
 }
+
+func TestWithDeleteThresholds(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	prov := mocks.NewMockIdentityProviderService(mockCtrl)
+	scim := mocks.NewMockSCIMService(mockCtrl)
+	repo := mocks.NewMockStateRepository(mockCtrl)
+
+	got, _ := NewSyncService(prov, scim, repo,
+		WithGroupsDeleteThreshold(1, 10),
+		WithUsersDeleteThreshold(2, 20),
+		WithGroupsMembersDeleteThreshold(3, 30),
+	)
+
+	want := deleteThresholds{
+		groups:        deleteThreshold{maxCount: 1, maxPercentage: 10},
+		users:         deleteThreshold{maxCount: 2, maxPercentage: 20},
+		groupsMembers: deleteThreshold{maxCount: 3, maxPercentage: 30},
+	}
+
+	if !reflect.DeepEqual(got.deleteThresholds, want) {
+		t.Errorf("got = %+v, want %+v", got.deleteThresholds, want)
+	}
+}
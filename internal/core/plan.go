@@ -0,0 +1,157 @@
+package core
+
+import (
+	"context"
+
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+)
+
+// SyncPlan represents the changes that a sync would apply over the SCIM side.
+type SyncPlan struct {
+	Groups        *GroupsPlan        `json:"groups"`
+	Users         *UsersPlan         `json:"users"`
+	GroupsMembers *GroupsMembersPlan `json:"groupsMembers"`
+}
+
+// GroupsPlan represents the groups that would be created, updated and deleted in the SCIM side.
+type GroupsPlan struct {
+	Create *model.GroupsResult `json:"create"`
+	Update *model.GroupsResult `json:"update"`
+	Delete *model.GroupsResult `json:"delete"`
+}
+
+// UsersPlan represents the users that would be created, updated and deleted in the SCIM side.
+type UsersPlan struct {
+	Create *model.UsersResult `json:"create"`
+	Update *model.UsersResult `json:"update"`
+	Delete *model.UsersResult `json:"delete"`
+}
+
+// GroupsMembersPlan represents the groups members that would be added and removed in the SCIM side.
+type GroupsMembersPlan struct {
+	Create *model.GroupsMembersResult `json:"create"`
+	Delete *model.GroupsMembersResult `json:"delete"`
+}
+
+// newSyncPlan returns an empty SyncPlan.
+func newSyncPlan() *SyncPlan {
+	return &SyncPlan{
+		Groups: &GroupsPlan{
+			Create: model.GroupsResultBuilder().Build(),
+			Update: model.GroupsResultBuilder().Build(),
+			Delete: model.GroupsResultBuilder().Build(),
+		},
+		Users: &UsersPlan{
+			Create: model.UsersResultBuilder().Build(),
+			Update: model.UsersResultBuilder().Build(),
+			Delete: model.UsersResultBuilder().Build(),
+		},
+		GroupsMembers: &GroupsMembersPlan{
+			Create: model.GroupsMembersResultBuilder().Build(),
+			Delete: model.GroupsMembersResultBuilder().Build(),
+		},
+	}
+}
+
+// planSCIMService implements the SCIMService interface.
+// The read methods are delegated to the wrapped SCIMService, and the write methods
+// are only recorded in the plan, so the SCIM side is never modified.
+type planSCIMService struct {
+	scim SCIMService
+	plan *SyncPlan
+}
+
+// newPlanSCIMService returns a new planSCIMService wrapping the given SCIMService.
+func newPlanSCIMService(scim SCIMService) *planSCIMService {
+	return &planSCIMService{
+		scim: scim,
+		plan: newSyncPlan(),
+	}
+}
+
+// GetGroups returns the groups from the wrapped SCIM service.
+func (p *planSCIMService) GetGroups(ctx context.Context) (*model.GroupsResult, error) {
+	return p.scim.GetGroups(ctx)
+}
+
+// CreateGroups records the groups to be created and returns them unchanged.
+func (p *planSCIMService) CreateGroups(ctx context.Context, gr *model.GroupsResult) (*model.GroupsResult, error) {
+	p.plan.Groups.Create = model.MergeGroupsResult(p.plan.Groups.Create, gr)
+	return model.MergeGroupsResult(gr), nil
+}
+
+// UpdateGroups records the groups to be updated and returns them unchanged.
+func (p *planSCIMService) UpdateGroups(ctx context.Context, gr *model.GroupsResult) (*model.GroupsResult, error) {
+	p.plan.Groups.Update = model.MergeGroupsResult(p.plan.Groups.Update, gr)
+	return model.MergeGroupsResult(gr), nil
+}
+
+// DeleteGroups records the groups to be deleted.
+func (p *planSCIMService) DeleteGroups(ctx context.Context, gr *model.GroupsResult) error {
+	p.plan.Groups.Delete = model.MergeGroupsResult(p.plan.Groups.Delete, gr)
+	return nil
+}
+
+// GetUsers returns the users from the wrapped SCIM service.
+func (p *planSCIMService) GetUsers(ctx context.Context) (*model.UsersResult, error) {
+	return p.scim.GetUsers(ctx)
+}
+
+// CreateUsers records the users to be created and returns them unchanged.
+func (p *planSCIMService) CreateUsers(ctx context.Context, ur *model.UsersResult) (*model.UsersResult, error) {
+	p.plan.Users.Create = model.MergeUsersResult(p.plan.Users.Create, ur)
+	return model.MergeUsersResult(ur), nil
+}
+
+// UpdateUsers records the users to be updated and returns them unchanged.
+func (p *planSCIMService) UpdateUsers(ctx context.Context, ur *model.UsersResult) (*model.UsersResult, error) {
+	p.plan.Users.Update = model.MergeUsersResult(p.plan.Users.Update, ur)
+	return model.MergeUsersResult(ur), nil
+}
+
+// DeleteUsers records the users to be deleted.
+func (p *planSCIMService) DeleteUsers(ctx context.Context, ur *model.UsersResult) error {
+	p.plan.Users.Delete = model.MergeUsersResult(p.plan.Users.Delete, ur)
+	return nil
+}
+
+// GetGroupsMembers returns the groups members from the wrapped SCIM service.
+func (p *planSCIMService) GetGroupsMembers(ctx context.Context, gr *model.GroupsResult) (*model.GroupsMembersResult, error) {
+	return p.scim.GetGroupsMembers(ctx, gr)
+}
+
+// GetGroupsMembersBruteForce returns the groups members from the wrapped SCIM service.
+// The groups and users that would be created during the sync don't exist in the SCIM side yet,
+// so they are not requested to the wrapped SCIM service.
+func (p *planSCIMService) GetGroupsMembersBruteForce(ctx context.Context, gr *model.GroupsResult, ur *model.UsersResult) (*model.GroupsMembersResult, error) {
+	groups := make([]*model.Group, 0)
+	for _, group := range gr.Resources {
+		if group.SCIMID != "" {
+			groups = append(groups, group)
+		}
+	}
+
+	users := make([]*model.User, 0)
+	for _, user := range ur.Resources {
+		if user.SCIMID != "" {
+			users = append(users, user)
+		}
+	}
+
+	return p.scim.GetGroupsMembersBruteForce(ctx,
+		model.GroupsResultBuilder().WithResources(groups).Build(),
+		model.UsersResultBuilder().WithResources(users).Build(),
+	)
+}
+
+// CreateGroupsMembers records the groups members to be added and returns them unchanged.
+func (p *planSCIMService) CreateGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) (*model.GroupsMembersResult, error) {
+	p.plan.GroupsMembers.Create = model.MergeGroupsMembersResult(p.plan.GroupsMembers.Create, gmr)
+	return model.MergeGroupsMembersResult(gmr), nil
+}
+
+// DeleteGroupsMembers records the groups members to be removed.
+func (p *planSCIMService) DeleteGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) error {
+	p.plan.GroupsMembers.Delete = model.MergeGroupsMembersResult(p.plan.GroupsMembers.Delete, gmr)
+	return nil
+}
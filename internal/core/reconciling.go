@@ -6,6 +6,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/slashdevops/idp-scim-sync/internal/model"
+	"github.com/slashdevops/idp-scim-sync/internal/tracing"
 )
 
 var (
@@ -38,6 +39,9 @@ var (
 // returns the lists of groups created and updated in the SCIM provider
 // with the ids of these groups.
 func reconcilingGroups(ctx context.Context, scim SCIMService, create, update, remove *model.GroupsResult) (created, updated *model.GroupsResult, e error) {
+	ctx, span := tracer.Start(ctx, "reconcile groups")
+	defer func() { tracing.End(span, e) }()
+
 	if scim == nil {
 		return nil, nil, ErrSCIMServiceNil
 	}
@@ -91,6 +95,9 @@ func reconcilingGroups(ctx context.Context, scim SCIMService, create, update, re
 // returns the lists of users created and updated in the SCIM provider
 // with the ids of these users.
 func reconcilingUsers(ctx context.Context, scim SCIMService, create, update, remove *model.UsersResult) (created, updated *model.UsersResult, e error) {
+	ctx, span := tracer.Start(ctx, "reconcile users")
+	defer func() { tracing.End(span, e) }()
+
 	if scim == nil {
 		return nil, nil, ErrSCIMServiceNil
 	}
@@ -144,6 +151,9 @@ func reconcilingUsers(ctx context.Context, scim SCIMService, create, update, rem
 // returns the lists of groups members created in the SCIM provider
 // with the ids of these groups members.
 func reconcilingGroupsMembers(ctx context.Context, scim SCIMService, create, remove *model.GroupsMembersResult) (created *model.GroupsMembersResult, e error) {
+	ctx, span := tracer.Start(ctx, "reconcile groups members")
+	defer func() { tracing.End(span, e) }()
+
 	if scim == nil {
 		return nil, ErrSCIMServiceNil
 	}
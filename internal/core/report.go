@@ -0,0 +1,367 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+)
+
+// Phases of the sync measured in the SyncReport.
+const (
+	PhaseIdentityProvider = "identity provider"
+	PhaseState            = "state"
+	PhaseReconcile        = "reconcile"
+	PhaseStoreState       = "store state"
+)
+
+// SyncReport represents the changes applied over the SCIM side by a sync.
+// It is returned also when the sync fails, with the changes applied until the error.
+type SyncReport struct {
+	StartTime     time.Time              `json:"startTime"`
+	EndTime       time.Time              `json:"endTime"`
+	Duration      string                 `json:"duration"`
+	Summary       *SyncSummary           `json:"summary"`
+	Phases        []*SyncPhase           `json:"phases"`
+	Groups        *GroupsReport          `json:"groups"`
+	Users         *UsersReport           `json:"users"`
+	GroupsMembers *GroupsMembersReport   `json:"groupsMembers"`
+	Skipped       []*SkippedResource     `json:"skipped"`
+	Failures      []*model.ResourceError `json:"failures"`
+	Error         string                 `json:"error,omitempty"`
+
+	mu sync.Mutex
+}
+
+// SyncSummary represents the number of resources changed by a sync.
+type SyncSummary struct {
+	GroupsCreated  int `json:"groupsCreated"`
+	GroupsUpdated  int `json:"groupsUpdated"`
+	GroupsDeleted  int `json:"groupsDeleted"`
+	UsersCreated   int `json:"usersCreated"`
+	UsersUpdated   int `json:"usersUpdated"`
+	UsersDeleted   int `json:"usersDeleted"`
+	MembersAdded   int `json:"membersAdded"`
+	MembersRemoved int `json:"membersRemoved"`
+	Skipped        int `json:"skipped"`
+	Failures       int `json:"failures"`
+}
+
+// SyncPhase represents the time spent in one of the phases of the sync.
+type SyncPhase struct {
+	Name     string `json:"name"`
+	Duration string `json:"duration"`
+}
+
+// GroupsReport represents the groups created, updated and deleted in the SCIM side.
+type GroupsReport struct {
+	Created *model.GroupsResult `json:"created"`
+	Updated *model.GroupsResult `json:"updated"`
+	Deleted *model.GroupsResult `json:"deleted"`
+}
+
+// UsersReport represents the users created, updated and deleted in the SCIM side.
+type UsersReport struct {
+	Created *model.UsersResult `json:"created"`
+	Updated *model.UsersResult `json:"updated"`
+	Deleted *model.UsersResult `json:"deleted"`
+}
+
+// GroupsMembersReport represents the groups members added and removed in the SCIM side.
+type GroupsMembersReport struct {
+	Added   *model.GroupsMembersResult `json:"added"`
+	Removed *model.GroupsMembersResult `json:"removed"`
+}
+
+// SkippedResource represents a resource of the identity provider that was not synced.
+type SkippedResource struct {
+	Resource string `json:"resource"`
+	Name     string `json:"name"`
+	Reason   string `json:"reason"`
+}
+
+// newSyncReport returns an empty SyncReport started now.
+func newSyncReport() *SyncReport {
+	return &SyncReport{
+		StartTime: time.Now(),
+		Summary:   &SyncSummary{},
+		Phases:    make([]*SyncPhase, 0),
+		Groups: &GroupsReport{
+			Created: model.GroupsResultBuilder().Build(),
+			Updated: model.GroupsResultBuilder().Build(),
+			Deleted: model.GroupsResultBuilder().Build(),
+		},
+		Users: &UsersReport{
+			Created: model.UsersResultBuilder().Build(),
+			Updated: model.UsersResultBuilder().Build(),
+			Deleted: model.UsersResultBuilder().Build(),
+		},
+		GroupsMembers: &GroupsMembersReport{
+			Added:   model.GroupsMembersResultBuilder().Build(),
+			Removed: model.GroupsMembersResultBuilder().Build(),
+		},
+		Skipped:  make([]*SkippedResource, 0),
+		Failures: make([]*model.ResourceError, 0),
+	}
+}
+
+// phase records the time spent in the phase started at start.
+func (r *SyncReport) phase(name string, start time.Time) {
+	r.Phases = append(r.Phases, &SyncPhase{
+		Name:     name,
+		Duration: time.Since(start).Round(time.Millisecond).String(),
+	})
+}
+
+// skip records a resource of the identity provider that was not synced, it is a model.SkipFunc.
+func (r *SyncReport) skip(resource, name, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Skipped = append(r.Skipped, &SkippedResource{Resource: resource, Name: name, Reason: reason})
+}
+
+// finish sets the end time, the failures, the error and the summary of the report.
+func (r *SyncReport) finish(f *failures, err error) {
+	r.EndTime = time.Now()
+	r.Duration = r.EndTime.Sub(r.StartTime).Round(time.Millisecond).String()
+
+	if f != nil {
+		r.Failures = append(r.Failures, f.errs...)
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	r.Summary = &SyncSummary{
+		GroupsCreated:  r.Groups.Created.Items,
+		GroupsUpdated:  r.Groups.Updated.Items,
+		GroupsDeleted:  r.Groups.Deleted.Items,
+		UsersCreated:   r.Users.Created.Items,
+		UsersUpdated:   r.Users.Updated.Items,
+		UsersDeleted:   r.Users.Deleted.Items,
+		MembersAdded:   countMembers(r.GroupsMembers.Added),
+		MembersRemoved: countMembers(r.GroupsMembers.Removed),
+		Skipped:        len(r.Skipped),
+		Failures:       len(r.Failures),
+	}
+}
+
+// Markdown returns the report as a Markdown document.
+func (r *SyncReport) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Sync report\n\n")
+	fmt.Fprintf(&b, "* Start: %s\n", r.StartTime.Format(time.RFC3339))
+	fmt.Fprintf(&b, "* End: %s\n", r.EndTime.Format(time.RFC3339))
+	fmt.Fprintf(&b, "* Duration: %s\n", r.Duration)
+	if r.Error != "" {
+		fmt.Fprintf(&b, "* Error: %s\n", r.Error)
+	}
+
+	fmt.Fprintf(&b, "\n## Summary\n\n")
+	fmt.Fprintf(&b, "| Resource | Created | Updated | Deleted |\n")
+	fmt.Fprintf(&b, "|---|---|---|---|\n")
+	fmt.Fprintf(&b, "| Groups | %d | %d | %d |\n", r.Summary.GroupsCreated, r.Summary.GroupsUpdated, r.Summary.GroupsDeleted)
+	fmt.Fprintf(&b, "| Users | %d | %d | %d |\n", r.Summary.UsersCreated, r.Summary.UsersUpdated, r.Summary.UsersDeleted)
+	fmt.Fprintf(&b, "| Groups members | %d | - | %d |\n", r.Summary.MembersAdded, r.Summary.MembersRemoved)
+	fmt.Fprintf(&b, "\nSkipped: %d, failures: %d\n", r.Summary.Skipped, r.Summary.Failures)
+
+	fmt.Fprintf(&b, "\n## Phases\n\n")
+	fmt.Fprintf(&b, "| Phase | Duration |\n")
+	fmt.Fprintf(&b, "|---|---|\n")
+	for _, p := range r.Phases {
+		fmt.Fprintf(&b, "| %s | %s |\n", p.Name, p.Duration)
+	}
+
+	writeGroups := func(title string, gr *model.GroupsResult) {
+		if gr.Items == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", title)
+		for _, group := range gr.Resources {
+			fmt.Fprintf(&b, "* %s (%s)\n", group.Name, group.Email)
+		}
+	}
+	writeGroups("Groups created", r.Groups.Created)
+	writeGroups("Groups updated", r.Groups.Updated)
+	writeGroups("Groups deleted", r.Groups.Deleted)
+
+	writeUsers := func(title string, ur *model.UsersResult) {
+		if ur.Items == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", title)
+		for _, user := range ur.Resources {
+			fmt.Fprintf(&b, "* %s (%s)\n", user.Email, user.DisplayName)
+		}
+	}
+	writeUsers("Users created", r.Users.Created)
+	writeUsers("Users updated", r.Users.Updated)
+	writeUsers("Users deleted", r.Users.Deleted)
+
+	writeMembers := func(title string, gmr *model.GroupsMembersResult) {
+		if gmr.Items == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", title)
+		for _, gm := range gmr.Resources {
+			for _, member := range gm.Resources {
+				fmt.Fprintf(&b, "* %s: %s\n", gm.Group.Name, member.Email)
+			}
+		}
+	}
+	writeMembers("Groups members added", r.GroupsMembers.Added)
+	writeMembers("Groups members removed", r.GroupsMembers.Removed)
+
+	if len(r.Skipped) > 0 {
+		fmt.Fprintf(&b, "\n## Skipped\n\n")
+		fmt.Fprintf(&b, "| Resource | Name | Reason |\n")
+		fmt.Fprintf(&b, "|---|---|---|\n")
+		for _, s := range r.Skipped {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", s.Resource, s.Name, s.Reason)
+		}
+	}
+
+	if len(r.Failures) > 0 {
+		fmt.Fprintf(&b, "\n## Failures\n\n")
+		fmt.Fprintf(&b, "| Resource | Operation | Name | Status code | Details |\n")
+		fmt.Fprintf(&b, "|---|---|---|---|---|\n")
+		for _, re := range r.Failures {
+			fmt.Fprintf(&b, "| %s | %s | %s | %d | %s |\n", re.Resource, re.Operation, re.Name, re.StatusCode, re.Details)
+		}
+	}
+
+	return b.String()
+}
+
+// reportSCIMService wraps a SCIMService to record in the report the changes applied over the SCIM side.
+// The resources returned by the write methods are recorded as changed, and the resources deleted
+// are recorded unless the wrapped SCIMService returns them in a model.ResourceError.
+type reportSCIMService struct {
+	SCIMService
+	report *SyncReport
+}
+
+// newReportSCIMService returns a new reportSCIMService wrapping the given SCIMService.
+func newReportSCIMService(scim SCIMService, report *SyncReport) *reportSCIMService {
+	return &reportSCIMService{
+		SCIMService: scim,
+		report:      report,
+	}
+}
+
+// CreateGroups creates the groups recording the groups created.
+func (r *reportSCIMService) CreateGroups(ctx context.Context, gr *model.GroupsResult) (*model.GroupsResult, error) {
+	created, err := r.SCIMService.CreateGroups(ctx, gr)
+	if created != nil {
+		r.report.Groups.Created = model.MergeGroupsResult(r.report.Groups.Created, created)
+	}
+	return created, err
+}
+
+// UpdateGroups updates the groups recording the groups updated.
+func (r *reportSCIMService) UpdateGroups(ctx context.Context, gr *model.GroupsResult) (*model.GroupsResult, error) {
+	updated, err := r.SCIMService.UpdateGroups(ctx, gr)
+	if updated != nil {
+		r.report.Groups.Updated = model.MergeGroupsResult(r.report.Groups.Updated, updated)
+	}
+	return updated, err
+}
+
+// DeleteGroups deletes the groups recording the groups deleted.
+func (r *reportSCIMService) DeleteGroups(ctx context.Context, gr *model.GroupsResult) error {
+	err := r.SCIMService.DeleteGroups(ctx, gr)
+	if failed, ok := failedNames(err, model.ResourceGroup); ok {
+		groups := make([]*model.Group, 0, gr.Items)
+		for _, group := range gr.Resources {
+			if _, ok := failed[group.Name]; !ok {
+				groups = append(groups, group)
+			}
+		}
+		r.report.Groups.Deleted = model.MergeGroupsResult(r.report.Groups.Deleted, model.GroupsResultBuilder().WithResources(groups).Build())
+	}
+	return err
+}
+
+// CreateUsers creates the users recording the users created.
+func (r *reportSCIMService) CreateUsers(ctx context.Context, ur *model.UsersResult) (*model.UsersResult, error) {
+	created, err := r.SCIMService.CreateUsers(ctx, ur)
+	if created != nil {
+		r.report.Users.Created = model.MergeUsersResult(r.report.Users.Created, created)
+	}
+	return created, err
+}
+
+// UpdateUsers updates the users recording the users updated.
+func (r *reportSCIMService) UpdateUsers(ctx context.Context, ur *model.UsersResult) (*model.UsersResult, error) {
+	updated, err := r.SCIMService.UpdateUsers(ctx, ur)
+	if updated != nil {
+		r.report.Users.Updated = model.MergeUsersResult(r.report.Users.Updated, updated)
+	}
+	return updated, err
+}
+
+// DeleteUsers deletes the users recording the users deleted.
+func (r *reportSCIMService) DeleteUsers(ctx context.Context, ur *model.UsersResult) error {
+	err := r.SCIMService.DeleteUsers(ctx, ur)
+	if failed, ok := failedNames(err, model.ResourceUser); ok {
+		users := make([]*model.User, 0, ur.Items)
+		for _, user := range ur.Resources {
+			if _, ok := failed[user.Email]; !ok {
+				users = append(users, user)
+			}
+		}
+		r.report.Users.Deleted = model.MergeUsersResult(r.report.Users.Deleted, model.UsersResultBuilder().WithResources(users).Build())
+	}
+	return err
+}
+
+// CreateGroupsMembers adds the groups members recording the members added.
+func (r *reportSCIMService) CreateGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) (*model.GroupsMembersResult, error) {
+	created, err := r.SCIMService.CreateGroupsMembers(ctx, gmr)
+	if created != nil {
+		r.report.GroupsMembers.Added = model.MergeGroupsMembersResult(r.report.GroupsMembers.Added, created)
+	}
+	return created, err
+}
+
+// DeleteGroupsMembers removes the groups members recording the members removed.
+func (r *reportSCIMService) DeleteGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) error {
+	err := r.SCIMService.DeleteGroupsMembers(ctx, gmr)
+	if failed, ok := failedNames(err, model.ResourceGroupMembers); ok {
+		groupsMembers := make([]*model.GroupMembers, 0, gmr.Items)
+		for _, gm := range gmr.Resources {
+			if _, ok := failed[gm.Group.Name]; !ok {
+				groupsMembers = append(groupsMembers, gm)
+			}
+		}
+		r.report.GroupsMembers.Removed = model.MergeGroupsMembersResult(r.report.GroupsMembers.Removed, model.GroupsMembersResultBuilder().WithResources(groupsMembers).Build())
+	}
+	return err
+}
+
+// failedNames returns the names of the resources of the given kind that failed in err,
+// and false when err is not only the error of some resources, so it is unknown what was applied.
+func failedNames(err error, resource string) (map[string]struct{}, bool) {
+	failed := make(map[string]struct{})
+	if err == nil {
+		return failed, true
+	}
+
+	res := model.ResourceErrors(err)
+	if len(res) == 0 {
+		return nil, false
+	}
+
+	for _, re := range res {
+		if re.Resource == resource {
+			failed[re.Name] = struct{}{}
+		}
+	}
+
+	return failed, true
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	mocks "github.com/slashdevops/idp-scim-sync/mocks/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncService_SyncUsers_Report(t *testing.T) {
+	ctx := context.TODO()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	user1 := model.UserBuilder().WithIPID("user-1").WithSCIMID("scim-user-1").WithEmail("user.1@mail.com").WithDisplayName("user 1").Build()
+	user1Changed := model.UserBuilder().WithIPID("user-1").WithSCIMID("scim-user-1").WithEmail("user.1@mail.com").WithDisplayName("user one").Build()
+	user2 := model.UserBuilder().WithIPID("user-2").WithSCIMID("scim-user-2").WithEmail("user.2@mail.com").WithDisplayName("user 2").Build()
+	user3 := model.UserBuilder().WithIPID("user-3").WithEmail("user.3@mail.com").WithDisplayName("user 3").Build()
+	user3Created := model.UserBuilder().WithIPID("user-3").WithSCIMID("scim-user-3").WithEmail("user.3@mail.com").WithDisplayName("user 3").Build()
+
+	state := model.StateBuilder().
+		WithLastSync("2022-01-01T00:00:00Z").
+		WithGroups(model.GroupsResultBuilder().Build()).
+		WithUsers(model.UsersResultBuilder().WithResources([]*model.User{user1, user2}).Build()).
+		WithGroupsMembers(model.GroupsMembersResultBuilder().Build()).
+		Build()
+
+	mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+	mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+	mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+	mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+	mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+	mockProviderService.EXPECT().GetUsers(gomock.Any(), gomock.Any()).Return(model.UsersResultBuilder().WithResources([]*model.User{user1Changed, user3}).Build(), nil).Times(1)
+	mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+	mockSCIMService.EXPECT().CreateUsers(gomock.Any(), gomock.Any()).Return(model.UsersResultBuilder().WithResources([]*model.User{user3Created}).Build(), nil).Times(1)
+	mockSCIMService.EXPECT().UpdateUsers(gomock.Any(), gomock.Any()).Return(model.UsersResultBuilder().WithResources([]*model.User{user1Changed}).Build(), nil).Times(1)
+	mockSCIMService.EXPECT().DeleteUsers(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	mockStateRepository.EXPECT().SetState(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+	assert.NoError(t, err)
+
+	report, err := svc.SyncUsers(ctx)
+	assert.NoError(t, err)
+	assert.NotNil(t, report)
+
+	assert.Equal(t, &SyncSummary{UsersCreated: 1, UsersUpdated: 1, UsersDeleted: 1}, report.Summary)
+	assert.Equal(t, "user.3@mail.com", report.Users.Created.Resources[0].Email)
+	assert.Equal(t, "user.1@mail.com", report.Users.Updated.Resources[0].Email)
+	assert.Equal(t, "user.2@mail.com", report.Users.Deleted.Resources[0].Email)
+	assert.Empty(t, report.Error)
+	assert.False(t, report.EndTime.Before(report.StartTime))
+
+	phases := make([]string, 0)
+	for _, p := range report.Phases {
+		phases = append(phases, p.Name)
+	}
+	assert.Equal(t, []string{PhaseIdentityProvider, PhaseState, PhaseReconcile, PhaseStoreState}, phases)
+
+	md := report.Markdown()
+	assert.Contains(t, md, "# Sync report")
+	assert.Contains(t, md, "| Users | 1 | 1 | 1 |")
+	assert.Contains(t, md, "## Users deleted\n\n* user.2@mail.com (user 2)\n")
+}
+
+func TestSyncService_Report_Error(t *testing.T) {
+	ctx := context.TODO()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	errTest := errors.New("test error")
+
+	mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+	mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+	mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+	mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+	mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+	mockProviderService.EXPECT().GetUsers(gomock.Any(), gomock.Any()).Return(nil, errTest).Times(1)
+
+	svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+	assert.NoError(t, err)
+
+	report, err := svc.SyncUsers(ctx)
+	assert.ErrorIs(t, err, errTest)
+	assert.NotNil(t, report)
+	assert.Contains(t, report.Error, "test error")
+	assert.Len(t, report.Phases, 1)
+}
+
+func TestSyncService_Report_Skipped(t *testing.T) {
+	ctx := context.TODO()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+	mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+	mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+	mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+	mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+	mockProviderService.EXPECT().GetUsers(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, filter []string) (*model.UsersResult, error) {
+		model.Skip(ctx, model.ResourceUser, "cn=user,dc=mail,dc=com", "user without email")
+		return model.UsersResultBuilder().Build(), nil
+	}).Times(1)
+	mockStateRepository.EXPECT().GetState(gomock.Any()).Return(model.StateBuilder().Build(), nil).Times(1)
+	mockSCIMService.EXPECT().GetGroups(gomock.Any()).Return(model.GroupsResultBuilder().Build(), nil).Times(1)
+	mockSCIMService.EXPECT().GetUsers(gomock.Any()).Return(model.UsersResultBuilder().Build(), nil).Times(1)
+	mockSCIMService.EXPECT().GetGroupsMembersBruteForce(gomock.Any(), gomock.Any(), gomock.Any()).Return(model.GroupsMembersResultBuilder().Build(), nil).Times(1)
+	mockStateRepository.EXPECT().SetState(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+	assert.NoError(t, err)
+
+	report, err := svc.SyncUsers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []*SkippedResource{{Resource: model.ResourceUser, Name: "cn=user,dc=mail,dc=com", Reason: "user without email"}}, report.Skipped)
+	assert.Equal(t, 1, report.Summary.Skipped)
+	assert.Contains(t, report.Markdown(), "| user | cn=user,dc=mail,dc=com | user without email |")
+}
+
+func TestReportSCIMService_DeleteUsers(t *testing.T) {
+	ctx := context.TODO()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ur := model.UsersResultBuilder().WithResources([]*model.User{
+		model.UserBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").Build(),
+		model.UserBuilder().WithIPID("user-2").WithEmail("user.2@mail.com").Build(),
+	}).Build()
+	errTest := errors.New("test error")
+
+	t.Run("record the users deleted except the ones that failed", func(t *testing.T) {
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockSCIMService.EXPECT().DeleteUsers(gomock.Any(), ur).Return(&model.ResourceError{Resource: model.ResourceUser, Operation: model.OperationDelete, Name: "user.2@mail.com", Err: errTest}).Times(1)
+
+		r := newSyncReport()
+		err := newReportSCIMService(mockSCIMService, r).DeleteUsers(ctx, ur)
+		assert.ErrorIs(t, err, errTest)
+		assert.Equal(t, 1, r.Users.Deleted.Items)
+		assert.Equal(t, "user.1@mail.com", r.Users.Deleted.Resources[0].Email)
+	})
+
+	t.Run("record nothing when it is unknown what was deleted", func(t *testing.T) {
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockSCIMService.EXPECT().DeleteUsers(gomock.Any(), ur).Return(errTest).Times(1)
+
+		r := newSyncReport()
+		err := newReportSCIMService(mockSCIMService, r).DeleteUsers(ctx, ur)
+		assert.ErrorIs(t, err, errTest)
+		assert.Equal(t, 0, r.Users.Deleted.Items)
+	})
+}
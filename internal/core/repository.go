@@ -17,4 +17,18 @@ type StateRepository interface {
 
 	// SetState sets the state of the synchronization process.
 	SetState(ctx context.Context, state *model.State) error
+
+	// Lock locks the state for the duration of a sync, so another sync running at the same time
+	// can't reconcile from the same state and overwrite the state stored by this one.
+	// It returns an error when the state is already locked by another sync.
+	Lock(ctx context.Context) error
+
+	// Unlock releases the lock acquired with Lock.
+	Unlock(ctx context.Context) error
+
+	// ListStates returns the previous states kept in the history of the repository, the newest first.
+	ListStates(ctx context.Context) ([]*model.StateVersion, error)
+
+	// GetStateVersion returns the state with the given id from the history of the repository.
+	GetStateVersion(ctx context.Context, id string) (*model.State, error)
 }
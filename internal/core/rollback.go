@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+)
+
+// Rollback re-applies the groups, users and groups members of the state with the given id in the history
+// of the state repository to the SCIM side, as if the identity provider returned them, and stores the
+// new state. It is a sync, so the state is locked and the delete thresholds and the other options apply.
+// The options filtering the identity provider data are ignored.
+func Rollback(ctx context.Context, scim SCIMService, repo StateRepository, id string, opts ...SyncServiceOption) (*SyncReport, error) {
+	ss, err := newRollbackService(scim, repo, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ss.sync(ctx, ss.getStateVersionData(id))
+}
+
+// PlanRollback computes the changes Rollback would apply without modifying the SCIM side
+// or the state repository.
+func PlanRollback(ctx context.Context, scim SCIMService, repo StateRepository, id string, opts ...SyncServiceOption) (*SyncPlan, error) {
+	ss, err := newRollbackService(scim, repo, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ss.plan(ctx, ss.getStateVersionData(id))
+}
+
+// newRollbackService returns a sync service without identity provider, the data comes from the state history.
+func newRollbackService(scim SCIMService, repo StateRepository, opts ...SyncServiceOption) (*SyncService, error) {
+	if scim == nil {
+		return nil, ErrSCIMServiceNil
+	}
+	if repo == nil {
+		return nil, ErrStateRepositoryNil
+	}
+
+	ss := &SyncService{
+		scim: scim,
+		repo: repo,
+	}
+
+	for _, opt := range opts {
+		opt(ss)
+	}
+
+	return ss, nil
+}
+
+// getStateVersionData returns the groups, users and groups members of the state with the given id
+// in the history, without their SCIM ids, which could have changed since it was stored.
+func (ss *SyncService) getStateVersionData(id string) identityProviderDataFunc {
+	return func(ctx context.Context) (*model.GroupsResult, *model.UsersResult, *model.GroupsMembersResult, error) {
+		log.WithField("id", id).Info("getting the state data from the history")
+
+		state, err := ss.repo.GetStateVersion(ctx, id)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error getting the state %s from the history: %w", id, err)
+		}
+
+		groups := make([]*model.Group, 0)
+		users := make([]*model.User, 0)
+		groupsMembers := make([]*model.GroupMembers, 0)
+
+		if state.Resources != nil {
+			if state.Resources.Groups != nil {
+				for _, g := range state.Resources.Groups.Resources {
+					groups = append(groups, withoutGroupSCIMID(g))
+				}
+			}
+
+			if state.Resources.Users != nil {
+				for _, u := range state.Resources.Users.Resources {
+					user := *u
+					user.SCIMID = ""
+					users = append(users, &user)
+				}
+			}
+
+			if state.Resources.GroupsMembers != nil {
+				for _, gm := range state.Resources.GroupsMembers.Resources {
+					members := make([]*model.Member, 0, len(gm.Resources))
+					for _, m := range gm.Resources {
+						member := *m
+						member.SCIMID = ""
+						members = append(members, &member)
+					}
+
+					groupsMembers = append(groupsMembers, model.GroupMembersBuilder().
+						WithGroup(withoutGroupSCIMID(gm.Group)).
+						WithResources(members).
+						Build())
+				}
+			}
+		}
+
+		log.WithFields(log.Fields{
+			"id":       id,
+			"lastSync": state.LastSync,
+			"groups":   len(groups),
+			"users":    len(users),
+		}).Info("rolling back to the state from the history")
+
+		return model.GroupsResultBuilder().WithResources(groups).Build(),
+			model.UsersResultBuilder().WithResources(users).Build(),
+			model.GroupsMembersResultBuilder().WithResources(groupsMembers).Build(),
+			nil
+	}
+}
+
+// withoutGroupSCIMID returns a copy of the group without its SCIM id.
+func withoutGroupSCIMID(g *model.Group) *model.Group {
+	if g == nil {
+		return nil
+	}
+
+	group := *g
+	group.SCIMID = ""
+
+	return &group
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	"github.com/slashdevops/idp-scim-sync/internal/repository"
+	mocks "github.com/slashdevops/idp-scim-sync/mocks/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollback(t *testing.T) {
+	ctx := context.TODO()
+
+	user1 := model.UserBuilder().WithIPID("user-1").WithSCIMID("scim-user-1").WithEmail("user.1@mail.com").WithGivenName("user").WithFamilyName("1").WithDisplayName("user 1").WithActive(true).Build()
+	user2Old := model.UserBuilder().WithIPID("user-2").WithSCIMID("scim-user-2-old").WithEmail("user.2@mail.com").WithGivenName("user").WithFamilyName("2").WithDisplayName("user 2").WithActive(true).Build()
+	user2Created := model.UserBuilder().WithIPID("user-2").WithSCIMID("scim-user-2").WithEmail("user.2@mail.com").WithGivenName("user").WithFamilyName("2").WithDisplayName("user 2").WithActive(true).Build()
+
+	// user 2 was deleted by the last sync
+	state := model.StateBuilder().
+		WithLastSync("2022-01-02T00:00:00Z").
+		WithGroups(model.GroupsResultBuilder().Build()).
+		WithUsers(model.UsersResultBuilder().WithResources([]*model.User{user1}).Build()).
+		WithGroupsMembers(model.GroupsMembersResultBuilder().Build()).
+		Build()
+
+	previous := model.StateBuilder().
+		WithLastSync("2022-01-01T00:00:00Z").
+		WithGroups(model.GroupsResultBuilder().Build()).
+		WithUsers(model.UsersResultBuilder().WithResources([]*model.User{user1, user2Old}).Build()).
+		WithGroupsMembers(model.GroupsMembersResultBuilder().Build()).
+		Build()
+
+	t.Run("re-apply the users of the previous state", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().GetStateVersion(gomock.Any(), "2").Return(previous, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+		mockSCIMService.EXPECT().CreateUsers(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ur *model.UsersResult) (*model.UsersResult, error) {
+			assert.Equal(t, 1, ur.Items)
+			assert.Equal(t, "user.2@mail.com", ur.Resources[0].Email)
+			assert.Empty(t, ur.Resources[0].SCIMID)
+			return model.UsersResultBuilder().WithResources([]*model.User{user2Created}).Build(), nil
+		}).Times(1)
+		mockStateRepository.EXPECT().SetState(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, s *model.State) error {
+			assert.Equal(t, 2, s.Resources.Users.Items)
+			return nil
+		}).Times(1)
+
+		report, err := Rollback(ctx, mockSCIMService, mockStateRepository, "2")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Users.Created.Items)
+
+		// the state in the history is not modified
+		assert.Equal(t, "scim-user-2-old", previous.Resources.Users.Resources[1].SCIMID)
+	})
+
+	t.Run("plan without locking the state or storing it", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		mockStateRepository.EXPECT().GetStateVersion(gomock.Any(), "2").Return(previous, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+
+		plan, err := PlanRollback(ctx, mockSCIMService, mockStateRepository, "2")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, plan.Users.Create.Items)
+		assert.Equal(t, 0, plan.Users.Delete.Items)
+	})
+
+	t.Run("return error when the state is not in the history", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().GetStateVersion(gomock.Any(), "9").Return(nil, repository.ErrStateVersionNotFound).Times(1)
+
+		_, err := Rollback(ctx, mockSCIMService, mockStateRepository, "9")
+		assert.ErrorIs(t, err, repository.ErrStateVersionNotFound)
+	})
+
+	t.Run("return error without SCIM service or state repository", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		_, err := Rollback(ctx, nil, mocks.NewMockStateRepository(mockCtrl), "2")
+		assert.ErrorIs(t, err, ErrSCIMServiceNil)
+
+		_, err = PlanRollback(ctx, mocks.NewMockSCIMService(mockCtrl), nil, "2")
+		assert.ErrorIs(t, err, ErrStateRepositoryNil)
+	})
+}
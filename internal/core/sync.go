@@ -10,6 +10,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/slashdevops/idp-scim-sync/internal/model"
 	"github.com/slashdevops/idp-scim-sync/internal/repository"
+	"github.com/slashdevops/idp-scim-sync/internal/tracing"
 	"github.com/slashdevops/idp-scim-sync/internal/version"
 )
 
@@ -22,6 +23,10 @@ var (
 
 	// ErrStateRepositoryNil is returned when the State Repository is nil
 	ErrStateRepositoryNil = errors.New("state repository cannot be nil")
+
+	// ErrIdentityProviderEmpty is returned when the Identity Provider returns no groups or no users
+	// and the state has some
+	ErrIdentityProviderEmpty = errors.New("identity provider returned no groups or no users but the state is not empty")
 )
 
 // SyncService represent the sync service and the core of the sync process
@@ -31,6 +36,13 @@ type SyncService struct {
 	prov             IdentityProviderService
 	scim             SCIMService
 	repo             StateRepository
+	deleteThresholds deleteThresholds
+
+	// allowEmptyIdentityProvider disables the protection against an empty identity provider dataset
+	allowEmptyIdentityProvider bool
+
+	// continueOnError continues the sync when some resources fail and stores the state of the resources synced
+	continueOnError bool
 }
 
 // NewSyncService creates a new sync service.
@@ -60,25 +72,222 @@ func NewSyncService(prov IdentityProviderService, scim SCIMService, repo StateRe
 	return ss, nil
 }
 
-// SyncGroupsAndTheirMembers the default sync method tha syncs groups and their members
-func (ss *SyncService) SyncGroupsAndTheirMembers(ctx context.Context) error {
+// identityProviderDataFunc returns the groups, users and groups members from the identity provider
+// that need to be synced, depending on the sync method used.
+type identityProviderDataFunc func(ctx context.Context) (*model.GroupsResult, *model.UsersResult, *model.GroupsMembersResult, error)
+
+// SyncGroupsAndTheirMembers the default sync method tha syncs groups and their members,
+// returns the report of the changes applied
+func (ss *SyncService) SyncGroupsAndTheirMembers(ctx context.Context) (*SyncReport, error) {
+	return ss.sync(ctx, ss.getGroupsAndTheirMembersData)
+}
+
+// SyncUsers syncs the users matched by the identity provider users filter,
+// no groups or groups members are synced using this method.
+func (ss *SyncService) SyncUsers(ctx context.Context) (*SyncReport, error) {
+	return ss.sync(ctx, ss.getUsersData)
+}
+
+// SyncGroupsAndUsers syncs groups and their members, and also the users matched by the
+// identity provider users filter even when they are not members of the synced groups.
+func (ss *SyncService) SyncGroupsAndUsers(ctx context.Context) (*SyncReport, error) {
+	return ss.sync(ctx, ss.getGroupsAndUsersData)
+}
+
+// PlanGroupsAndTheirMembers computes the changes the SyncGroupsAndTheirMembers method would apply
+// without modifying the SCIM side or the state repository.
+func (ss *SyncService) PlanGroupsAndTheirMembers(ctx context.Context) (*SyncPlan, error) {
+	return ss.plan(ctx, ss.getGroupsAndTheirMembersData)
+}
+
+// PlanUsers computes the changes the SyncUsers method would apply
+// without modifying the SCIM side or the state repository.
+func (ss *SyncService) PlanUsers(ctx context.Context) (*SyncPlan, error) {
+	return ss.plan(ctx, ss.getUsersData)
+}
+
+// PlanGroupsAndUsers computes the changes the SyncGroupsAndUsers method would apply
+// without modifying the SCIM side or the state repository.
+func (ss *SyncService) PlanGroupsAndUsers(ctx context.Context) (*SyncPlan, error) {
+	return ss.plan(ctx, ss.getGroupsAndUsersData)
+}
+
+// sync reconciles the SCIM side with the identity provider data returned by getData
+// and stores the new state in the state repository.
+// The report of the changes applied is returned also when the sync fails.
+func (ss *SyncService) sync(ctx context.Context, getData identityProviderDataFunc) (report *SyncReport, err error) {
+	report = newSyncReport()
+
+	ctx, span := tracer.Start(ctx, "sync")
+
+	var f *failures
+	defer func() {
+		report.finish(f, err)
+		observeSync(report, err)
+		tracing.End(span, err)
+	}()
+
+	// the state is locked from reading it until storing the new one, so a sync running at the same time
+	// can't reconcile from the same state and overwrite the state stored by this one
+	if err = ss.repo.Lock(ctx); err != nil {
+		return report, fmt.Errorf("error locking the state: %w", err)
+	}
+	defer func() {
+		if err := ss.repo.Unlock(ctx); err != nil {
+			log.WithError(err).Error("cannot unlock the state")
+		}
+	}()
+
+	// the identity providers record the resources they skip in the report
+	phaseCtx, endPhase := startPhase(ctx, report, PhaseIdentityProvider)
+	idpGroupsResult, idpUsersResult, idpGroupsMembersResult, err := getData(model.WithSkipFunc(phaseCtx, report.skip))
+	endPhase(err)
+	if err != nil {
+		return report, err
+	}
+
+	phaseCtx, endPhase = startPhase(ctx, report, PhaseState)
+	state, err := ss.getState(phaseCtx)
+	endPhase(err)
+	if err != nil {
+		return report, err
+	}
+
+	if err := ss.checkEmptyIdentityProvider(state, idpGroupsResult, idpUsersResult); err != nil {
+		return report, err
+	}
+
+	var scim SCIMService = newReportSCIMService(ss.scim, report)
+	if ss.continueOnError {
+		c := newContinueSCIMService(scim)
+		scim, f = c, c.failures
+	}
+
+	phaseCtx, endPhase = startPhase(ctx, report, PhaseReconcile)
+	totalGroupsResult, totalUsersResult, totalGroupsMembersResult, err := reconcile(
+		phaseCtx,
+		state,
+		scim,
+		ss.deleteThresholds,
+		f,
+		idpGroupsResult,
+		idpUsersResult,
+		idpGroupsMembersResult,
+	)
+	endPhase(err)
+	if err != nil {
+		return report, err
+	}
+
+	// after be sure all the SCIM side is aligned with the identity provider side
+	// we can update the state with the last data coming from the reconciliation
+	newState := model.StateBuilder().
+		WithCodeVersion(version.Version).
+		WithLastSync(time.Now().Format(time.RFC3339)).
+		WithGroups(totalGroupsResult).
+		WithUsers(totalUsersResult).
+		WithGroupsMembers(totalGroupsMembersResult).
+		Build()
+
+	log.WithFields(log.Fields{
+		"lastSync": newState.LastSync,
+		"groups":   totalGroupsResult.Items,
+		"users":    totalUsersResult.Items,
+	}).Info("storing the new state")
+
+	phaseCtx, endPhase = startPhase(ctx, report, PhaseStoreState)
+	err = ss.repo.SetState(phaseCtx, newState)
+	endPhase(err)
+	if err != nil {
+		return report, fmt.Errorf("error storing the state: %w", err)
+	}
+
+	if err := f.err(); err != nil {
+		f.logSummary()
+
+		log.WithFields(log.Fields{
+			"date":     time.Now().Format(time.RFC3339),
+			"failures": len(f.errs),
+		}).Error("sync completed with failures, the resources not synced will be synced again the next time")
+		return report, err
+	}
+
+	log.WithFields(log.Fields{
+		"date": time.Now().Format(time.RFC3339),
+	}).Info("sync completed")
+	return report, nil
+}
+
+// plan computes the changes needed to reconcile the SCIM side with the identity provider
+// data returned by getData, without modifying the SCIM side or the state repository.
+func (ss *SyncService) plan(ctx context.Context, getData identityProviderDataFunc) (*SyncPlan, error) {
+	log.Warn("dry-run mode, no changes will be applied to the SCIM side or the state")
+
+	idpGroupsResult, idpUsersResult, idpGroupsMembersResult, err := getData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := ss.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the plan is computed anyway, so it is possible to see what an intentional wipe would delete
+	if err := ss.checkEmptyIdentityProvider(state, idpGroupsResult, idpUsersResult); err != nil {
+		log.WithError(err).Warn("the sync would be aborted")
+	}
+
+	planner := newPlanSCIMService(ss.scim)
+
+	// the delete thresholds are not applied, so the plan always shows all the changes
+	if _, _, _, err := reconcile(
+		ctx,
+		state,
+		planner,
+		deleteThresholds{},
+		nil,
+		idpGroupsResult,
+		idpUsersResult,
+		idpGroupsMembersResult,
+	); err != nil {
+		return nil, fmt.Errorf("error planning the sync: %w", err)
+	}
+
+	log.WithFields(log.Fields{
+		"groups_create":  planner.plan.Groups.Create.Items,
+		"groups_update":  planner.plan.Groups.Update.Items,
+		"groups_delete":  planner.plan.Groups.Delete.Items,
+		"users_create":   planner.plan.Users.Create.Items,
+		"users_update":   planner.plan.Users.Update.Items,
+		"users_delete":   planner.plan.Users.Delete.Items,
+		"members_create": planner.plan.GroupsMembers.Create.Items,
+		"members_delete": planner.plan.GroupsMembers.Delete.Items,
+	}).Info("sync plan completed")
+
+	return planner.plan, nil
+}
+
+// getGroupsAndTheirMembersData returns the groups matched by the identity provider groups filter,
+// their members and the users who are members of these groups.
+func (ss *SyncService) getGroupsAndTheirMembersData(ctx context.Context) (*model.GroupsResult, *model.UsersResult, *model.GroupsMembersResult, error) {
 	log.WithFields(log.Fields{
 		"group_filter": ss.provGroupsFilter,
 	}).Info("getting identity provider data")
 
 	idpGroupsResult, err := ss.prov.GetGroups(ctx, ss.provGroupsFilter)
 	if err != nil {
-		return fmt.Errorf("error getting groups from the identity provider: %w", err)
+		return nil, nil, nil, fmt.Errorf("error getting groups from the identity provider: %w", err)
 	}
 
 	idpGroupsMembersResult, err := ss.prov.GetGroupsMembers(ctx, idpGroupsResult)
 	if err != nil {
-		return fmt.Errorf("error getting groups members: %w", err)
+		return nil, nil, nil, fmt.Errorf("error getting groups members: %w", err)
 	}
 
 	idpUsersResult, err := ss.prov.GetUsersByGroupsMembers(ctx, idpGroupsMembersResult)
 	if err != nil {
-		return fmt.Errorf("error getting users from the identity provider: %w", err)
+		return nil, nil, nil, fmt.Errorf("error getting users from the identity provider: %w", err)
 	}
 
 	if idpUsersResult.Items == 0 {
@@ -102,26 +311,130 @@ func (ss *SyncService) SyncGroupsAndTheirMembers(ctx context.Context) error {
 			}).Warn("there are no groups with members in the identity provider")
 	}
 
+	return idpGroupsResult, idpUsersResult, idpGroupsMembersResult, nil
+}
+
+// getUsersData returns the users matched by the identity provider users filter,
+// the groups and groups members returned are always empty.
+func (ss *SyncService) getUsersData(ctx context.Context) (*model.GroupsResult, *model.UsersResult, *model.GroupsMembersResult, error) {
+	log.WithFields(log.Fields{
+		"user_filter": ss.provUsersFilter,
+	}).Info("getting identity provider data")
+
+	idpUsersResult, err := ss.prov.GetUsers(ctx, ss.provUsersFilter)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error getting users from the identity provider: %w", err)
+	}
+
+	if idpUsersResult.Items == 0 {
+		log.WithFields(
+			log.Fields{
+				"user_filter": ss.provUsersFilter,
+			}).Warn("there are no users in the identity provider that match")
+	}
+
+	return model.GroupsResultBuilder().Build(), idpUsersResult, model.GroupsMembersResultBuilder().Build(), nil
+}
+
+// getGroupsAndUsersData returns the groups matched by the identity provider groups filter and their members,
+// and the users who are members of these groups plus the users matched by the identity provider users filter.
+func (ss *SyncService) getGroupsAndUsersData(ctx context.Context) (*model.GroupsResult, *model.UsersResult, *model.GroupsMembersResult, error) {
+	idpGroupsResult, idpGroupsUsersResult, idpGroupsMembersResult, err := ss.getGroupsAndTheirMembersData(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	_, idpFilterUsersResult, _, err := ss.getUsersData(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the same user could be member of a group and also match the users filter
+	idpUsersResult := mergeUniqueUsersResult(idpGroupsUsersResult, idpFilterUsersResult)
+
+	return idpGroupsResult, idpUsersResult, idpGroupsMembersResult, nil
+}
+
+// mergeUniqueUsersResult merges n UsersResult avoiding duplicated users by email.
+func mergeUniqueUsersResult(urs ...*model.UsersResult) *model.UsersResult {
+	uniqUsers := make(map[string]struct{})
+	users := make([]*model.User, 0)
+
+	for _, ur := range urs {
+		for _, user := range ur.Resources {
+			if _, ok := uniqUsers[user.Email]; !ok {
+				uniqUsers[user.Email] = struct{}{}
+				users = append(users, user)
+			}
+		}
+	}
+
+	return model.UsersResultBuilder().WithResources(users).Build()
+}
+
+// getState returns the state stored in the state repository,
+// or a new empty state when there is no state stored yet.
+func (ss *SyncService) getState(ctx context.Context) (*model.State, error) {
 	log.Info("getting state data")
 	state, err := ss.repo.GetState(ctx)
 	if err != nil {
 		var nsk *types.NoSuchKey
 		var StateFileEmpty *repository.ErrStateFileEmpty
 
-		if errors.As(err, &nsk) || errors.As(err, &StateFileEmpty) {
+		if errors.As(err, &nsk) || errors.As(err, &StateFileEmpty) || errors.Is(err, repository.ErrStateNotFound) {
 			log.Warn("no state file found in the state repository, creating a new one")
-			state = model.StateBuilder().Build()
-		} else {
-			return fmt.Errorf("error getting state data from the repository: %w", err)
+			return model.StateBuilder().Build(), nil
 		}
+		return nil, fmt.Errorf("error getting state data from the repository: %w", err)
 	}
 
-	var (
-		totalGroupsResult        *model.GroupsResult
-		totalUsersResult         *model.UsersResult
-		totalGroupsMembersResult *model.GroupsMembersResult
-	)
+	return state, nil
+}
+
+// checkEmptyIdentityProvider returns ErrIdentityProviderEmpty when the identity provider returned
+// no groups while the state has groups, or no users while the state has users, unless allowEmptyIdentityProvider is set.
+// Reconciling in this case deletes all the groups or all the users in the SCIM side, which is usually caused by
+// a wrong filter or a problem in the identity provider, e.g. the groups members can't be resolved to users,
+// and not by an intentional wipe.
+func (ss *SyncService) checkEmptyIdentityProvider(state *model.State, idpGroupsResult *model.GroupsResult, idpUsersResult *model.UsersResult) error {
+	if ss.allowEmptyIdentityProvider || state.Resources == nil {
+		return nil
+	}
 
+	emptyGroups := idpGroupsResult.Items == 0 && state.Resources.Groups.Items > 0
+	emptyUsers := idpUsersResult.Items == 0 && state.Resources.Users.Items > 0
+
+	if !emptyGroups && !emptyUsers {
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"idp_groups":   idpGroupsResult.Items,
+		"idp_users":    idpUsersResult.Items,
+		"state_groups": state.Resources.Groups.Items,
+		"state_users":  state.Resources.Users.Items,
+	}).Error("the identity provider returned no data, use the allow empty identity provider option for intentional wipes")
+
+	if emptyGroups {
+		return fmt.Errorf("%w: 0 groups, the state has %d", ErrIdentityProviderEmpty, state.Resources.Groups.Items)
+	}
+
+	return fmt.Errorf("%w: 0 users, the state has %d", ErrIdentityProviderEmpty, state.Resources.Users.Items)
+}
+
+// reconcile aligns the SCIM side with the identity provider data, using the SCIM side data
+// when this is the first time syncing, or the state data otherwise.
+// returns the datasets synced
+func reconcile(
+	ctx context.Context,
+	state *model.State,
+	scim SCIMService,
+	dt deleteThresholds,
+	f *failures,
+	idpGroupsResult *model.GroupsResult,
+	idpUsersResult *model.UsersResult,
+	idpGroupsMembersResult *model.GroupsMembersResult,
+) (*model.GroupsResult, *model.UsersResult, *model.GroupsMembersResult, error) {
 	// first time syncing
 	if state.LastSync == "" {
 		// Check SCIM side to see if there are elements to be reconciled.
@@ -132,52 +445,31 @@ func (ss *SyncService) SyncGroupsAndTheirMembers(ctx context.Context) error {
 		// - Groups names are equals on both sides, update only the external id (coming from the identity provider)
 		// - Users emails are equals on both sides, update only the external id (coming from the identity provider)
 		log.Warn("syncing from scim service, first time syncing")
-		totalGroupsResult, totalUsersResult, totalGroupsMembersResult, err = scimSync(
-			ctx, ss.scim,
+		totalGroupsResult, totalUsersResult, totalGroupsMembersResult, err := scimSync(
+			ctx, scim, dt, f,
 			idpGroupsResult,
 			idpUsersResult,
 			idpGroupsMembersResult,
 		)
 		if err != nil {
-			return fmt.Errorf("error doing the first sync: %w", err)
-		}
-	} else {
-		log.Warn("syncing from state, it's not the first time syncing")
-		totalGroupsResult, totalUsersResult, totalGroupsMembersResult, err = stateSync(
-			ctx,
-			state,
-			ss.scim,
-			idpGroupsResult,
-			idpUsersResult,
-			idpGroupsMembersResult,
-		)
-		if err != nil {
-			return fmt.Errorf("error syncing state: %w", err)
+			return nil, nil, nil, fmt.Errorf("error doing the first sync: %w", err)
 		}
+		return totalGroupsResult, totalUsersResult, totalGroupsMembersResult, nil
 	}
 
-	// after be sure all the SCIM side is aligned with the identity provider side
-	// we can update the state with the last data coming from the reconciliation
-	newState := model.StateBuilder().
-		WithCodeVersion(version.Version).
-		WithLastSync(time.Now().Format(time.RFC3339)).
-		WithGroups(totalGroupsResult).
-		WithUsers(totalUsersResult).
-		WithGroupsMembers(totalGroupsMembersResult).
-		Build()
-
-	log.WithFields(log.Fields{
-		"lastSync": newState.LastSync,
-		"groups":   totalGroupsResult.Items,
-		"users":    totalUsersResult.Items,
-	}).Info("storing the new state")
-
-	if err := ss.repo.SetState(ctx, newState); err != nil {
-		return fmt.Errorf("error storing the state: %w", err)
+	log.Warn("syncing from state, it's not the first time syncing")
+	totalGroupsResult, totalUsersResult, totalGroupsMembersResult, err := stateSync(
+		ctx,
+		state,
+		scim,
+		dt,
+		f,
+		idpGroupsResult,
+		idpUsersResult,
+		idpGroupsMembersResult,
+	)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error syncing state: %w", err)
 	}
-
-	log.WithFields(log.Fields{
-		"date": time.Now().Format(time.RFC3339),
-	}).Info("sync completed")
-	return nil
+	return totalGroupsResult, totalUsersResult, totalGroupsMembersResult, nil
 }
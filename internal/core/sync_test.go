@@ -3,11 +3,13 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	gomock "github.com/golang/mock/gomock"
@@ -17,6 +19,7 @@ import (
 	"github.com/slashdevops/idp-scim-sync/internal/scim"
 	mocks "github.com/slashdevops/idp-scim-sync/mocks/core"
 	"github.com/slashdevops/idp-scim-sync/pkg/aws"
+	"github.com/slashdevops/idp-scim-sync/pkg/file"
 	"github.com/slashdevops/idp-scim-sync/pkg/google"
 	"github.com/stretchr/testify/assert"
 	admin "google.golang.org/api/admin/directory/v1"
@@ -104,7 +107,7 @@ func TestSyncService_SyncGroupsAndTheirMembers(t *testing.T) {
 
 		svc := createService(t, ctx, svrIDP, svrSCIM, stateFile)
 
-		err = svc.SyncGroupsAndTheirMembers(ctx)
+		_, err = svc.SyncGroupsAndTheirMembers(ctx)
 		assert.NoError(t, err)
 
 		// check if state file is created
@@ -483,7 +486,7 @@ func TestSyncService_SyncGroupsAndTheirMembers(t *testing.T) {
 
 		svc := createService(t, ctx, svrIDP, svrSCIM, stateFile)
 
-		err = svc.SyncGroupsAndTheirMembers(ctx)
+		_, err = svc.SyncGroupsAndTheirMembers(ctx)
 		assert.NoError(t, err)
 
 		// check if state file is created
@@ -511,7 +514,8 @@ func TestSyncService_SyncGroupsAndTheirMembers(t *testing.T) {
 		// t.Logf("State: %s", utils.ToJSON(state))
 		assert.Equal(t, 2, len(state.Resources.Groups.Resources))
 		assert.Equal(t, 2, len(state.Resources.Users.Resources))
-		assert.Equal(t, 4, len(state.Resources.GroupsMembers.Resources))
+		// the groups created have no members in the SCIM side before the sync, so only the members added are stored
+		assert.Equal(t, 2, len(state.Resources.GroupsMembers.Resources))
 		assert.NotEqual(t, "", state.LastSync)
 		assert.NotEqual(t, "", state.HashCode)
 		assert.Equal(t, "", state.CodeVersion)
@@ -561,3 +565,668 @@ func createService(
 
 	return svc
 }
+
+func TestSyncService_PlanGroupsAndTheirMembers(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("plan from state without calling SCIM write methods or storing the state", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		group1 := model.GroupBuilder().WithIPID("group-1").WithName("group 1").WithEmail("group.1@mail.com").Build()
+		group2 := model.GroupBuilder().WithIPID("group-2").WithSCIMID("scim-group-2").WithName("group 2").WithEmail("group.2@mail.com").Build()
+		user1 := model.UserBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").WithGivenName("user").WithFamilyName("1").WithDisplayName("user 1").WithActive(true).Build()
+		user2 := model.UserBuilder().WithIPID("user-2").WithSCIMID("scim-user-2").WithEmail("user.2@mail.com").WithGivenName("user").WithFamilyName("2").WithDisplayName("user 2").WithActive(true).Build()
+		member1 := model.MemberBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").WithStatus("ACTIVE").Build()
+		member2 := model.MemberBuilder().WithIPID("user-2").WithSCIMID("scim-user-2").WithEmail("user.2@mail.com").WithStatus("ACTIVE").Build()
+
+		idpGroupsResult := model.GroupsResultBuilder().WithResources([]*model.Group{group1}).Build()
+		idpUsersResult := model.UsersResultBuilder().WithResources([]*model.User{user1}).Build()
+		idpGroupsMembersResult := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+			model.GroupMembersBuilder().WithGroup(group1).WithResources([]*model.Member{member1}).Build(),
+		}).Build()
+
+		state := model.StateBuilder().
+			WithLastSync("2022-01-01T00:00:00Z").
+			WithGroups(model.GroupsResultBuilder().WithResources([]*model.Group{group2}).Build()).
+			WithUsers(model.UsersResultBuilder().WithResources([]*model.User{user2}).Build()).
+			WithGroupsMembers(model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+				model.GroupMembersBuilder().WithGroup(group2).WithResources([]*model.Member{member2}).Build(),
+			}).Build()).
+			Build()
+
+		mockProviderService.EXPECT().GetGroups(gomock.Any(), gomock.Any()).Return(idpGroupsResult, nil).Times(1)
+		mockProviderService.EXPECT().GetGroupsMembers(gomock.Any(), idpGroupsResult).Return(idpGroupsMembersResult, nil).Times(1)
+		mockProviderService.EXPECT().GetUsersByGroupsMembers(gomock.Any(), idpGroupsMembersResult).Return(idpUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+		assert.NoError(t, err)
+
+		plan, err := svc.PlanGroupsAndTheirMembers(ctx)
+		assert.NoError(t, err)
+		assert.NotNil(t, plan)
+
+		assert.Equal(t, 1, plan.Groups.Create.Items)
+		assert.Equal(t, "group 1", plan.Groups.Create.Resources[0].Name)
+		assert.Equal(t, 0, plan.Groups.Update.Items)
+		assert.Equal(t, 1, plan.Groups.Delete.Items)
+		assert.Equal(t, "group 2", plan.Groups.Delete.Resources[0].Name)
+
+		assert.Equal(t, 1, plan.Users.Create.Items)
+		assert.Equal(t, "user.1@mail.com", plan.Users.Create.Resources[0].Email)
+		assert.Equal(t, 0, plan.Users.Update.Items)
+		assert.Equal(t, 1, plan.Users.Delete.Items)
+		assert.Equal(t, "user.2@mail.com", plan.Users.Delete.Resources[0].Email)
+
+		assert.Equal(t, 1, plan.GroupsMembers.Create.Items)
+		assert.Equal(t, "group 1", plan.GroupsMembers.Create.Resources[0].Group.Name)
+		assert.Equal(t, 1, plan.GroupsMembers.Delete.Items)
+		assert.Equal(t, "user.2@mail.com", plan.GroupsMembers.Delete.Resources[0].Resources[0].Email)
+	})
+
+	t.Run("plan first sync reading only the existing SCIM resources", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		group1 := model.GroupBuilder().WithIPID("group-1").WithName("group 1").WithEmail("group.1@mail.com").Build()
+		user1 := model.UserBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").WithGivenName("user").WithFamilyName("1").WithDisplayName("user 1").WithActive(true).Build()
+		member1 := model.MemberBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").WithStatus("ACTIVE").Build()
+
+		idpGroupsResult := model.GroupsResultBuilder().WithResources([]*model.Group{group1}).Build()
+		idpUsersResult := model.UsersResultBuilder().WithResources([]*model.User{user1}).Build()
+		idpGroupsMembersResult := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+			model.GroupMembersBuilder().WithGroup(group1).WithResources([]*model.Member{member1}).Build(),
+		}).Build()
+
+		emptyGroupsResult := model.GroupsResultBuilder().Build()
+		emptyUsersResult := model.UsersResultBuilder().Build()
+
+		mockProviderService.EXPECT().GetGroups(gomock.Any(), gomock.Any()).Return(idpGroupsResult, nil).Times(1)
+		mockProviderService.EXPECT().GetGroupsMembers(gomock.Any(), idpGroupsResult).Return(idpGroupsMembersResult, nil).Times(1)
+		mockProviderService.EXPECT().GetUsersByGroupsMembers(gomock.Any(), idpGroupsMembersResult).Return(idpUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(nil, &repository.ErrStateFileEmpty{}).Times(1)
+		mockSCIMService.EXPECT().GetGroups(gomock.Any()).Return(emptyGroupsResult, nil).Times(1)
+		mockSCIMService.EXPECT().GetUsers(gomock.Any()).Return(emptyUsersResult, nil).Times(1)
+		mockSCIMService.EXPECT().GetGroupsMembersBruteForce(gomock.Any(), emptyGroupsResult, emptyUsersResult).Return(model.GroupsMembersResultBuilder().Build(), nil).Times(1)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+		assert.NoError(t, err)
+
+		plan, err := svc.PlanGroupsAndTheirMembers(ctx)
+		assert.NoError(t, err)
+		assert.NotNil(t, plan)
+
+		assert.Equal(t, 1, plan.Groups.Create.Items)
+		assert.Equal(t, 1, plan.Users.Create.Items)
+		assert.Equal(t, 1, plan.GroupsMembers.Create.Items)
+		assert.Equal(t, 0, plan.Groups.Delete.Items)
+		assert.Equal(t, 0, plan.Users.Delete.Items)
+		assert.Equal(t, 0, plan.GroupsMembers.Delete.Items)
+	})
+}
+
+func TestSyncService_SyncUsers(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("sync users matched by the users filter from state", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		usersFilter := []string{"email:user*"}
+
+		user1 := model.UserBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").WithGivenName("user").WithFamilyName("1").WithDisplayName("user 1").WithActive(true).Build()
+		user1Created := model.UserBuilder().WithIPID("user-1").WithSCIMID("scim-user-1").WithEmail("user.1@mail.com").WithGivenName("user").WithFamilyName("1").WithDisplayName("user 1").WithActive(true).Build()
+
+		idpUsersResult := model.UsersResultBuilder().WithResources([]*model.User{user1}).Build()
+		createdUsersResult := model.UsersResultBuilder().WithResources([]*model.User{user1Created}).Build()
+
+		state := model.StateBuilder().WithLastSync("2022-01-01T00:00:00Z").Build()
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockProviderService.EXPECT().GetUsers(gomock.Any(), usersFilter).Return(idpUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+		mockSCIMService.EXPECT().CreateUsers(gomock.Any(), gomock.Any()).Return(createdUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().SetState(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, s *model.State) error {
+			assert.Equal(t, 0, s.Resources.Groups.Items)
+			assert.Equal(t, 0, s.Resources.GroupsMembers.Items)
+			assert.Equal(t, 1, s.Resources.Users.Items)
+			assert.Equal(t, "scim-user-1", s.Resources.Users.Resources[0].SCIMID)
+			return nil
+		}).Times(1)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository, WithIdentityProviderUsersFilter(usersFilter))
+		assert.NoError(t, err)
+
+		_, err = svc.SyncUsers(ctx)
+		assert.NoError(t, err)
+	})
+
+	t.Run("return error when the identity provider fails getting users", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockProviderService.EXPECT().GetUsers(gomock.Any(), gomock.Any()).Return(nil, errors.New("test error")).Times(1)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+		assert.NoError(t, err)
+
+		_, err = svc.SyncUsers(ctx)
+		assert.Error(t, err)
+	})
+}
+
+func TestSyncService_PlanGroupsAndUsers(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("plan users from groups and users filter without duplicates", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		group1 := model.GroupBuilder().WithIPID("group-1").WithName("group 1").WithEmail("group.1@mail.com").Build()
+		user1 := model.UserBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").WithGivenName("user").WithFamilyName("1").WithDisplayName("user 1").WithActive(true).Build()
+		user2 := model.UserBuilder().WithIPID("user-2").WithEmail("user.2@mail.com").WithGivenName("user").WithFamilyName("2").WithDisplayName("user 2").WithActive(true).Build()
+		member1 := model.MemberBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").WithStatus("ACTIVE").Build()
+
+		idpGroupsResult := model.GroupsResultBuilder().WithResources([]*model.Group{group1}).Build()
+		idpGroupsUsersResult := model.UsersResultBuilder().WithResources([]*model.User{user1}).Build()
+		idpFilterUsersResult := model.UsersResultBuilder().WithResources([]*model.User{user1, user2}).Build()
+		idpGroupsMembersResult := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+			model.GroupMembersBuilder().WithGroup(group1).WithResources([]*model.Member{member1}).Build(),
+		}).Build()
+
+		state := model.StateBuilder().WithLastSync("2022-01-01T00:00:00Z").Build()
+
+		mockProviderService.EXPECT().GetGroups(gomock.Any(), gomock.Any()).Return(idpGroupsResult, nil).Times(1)
+		mockProviderService.EXPECT().GetGroupsMembers(gomock.Any(), idpGroupsResult).Return(idpGroupsMembersResult, nil).Times(1)
+		mockProviderService.EXPECT().GetUsersByGroupsMembers(gomock.Any(), idpGroupsMembersResult).Return(idpGroupsUsersResult, nil).Times(1)
+		mockProviderService.EXPECT().GetUsers(gomock.Any(), gomock.Any()).Return(idpFilterUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+		assert.NoError(t, err)
+
+		plan, err := svc.PlanGroupsAndUsers(ctx)
+		assert.NoError(t, err)
+		assert.NotNil(t, plan)
+
+		assert.Equal(t, 1, plan.Groups.Create.Items)
+		assert.Equal(t, 2, plan.Users.Create.Items)
+		assert.Equal(t, 1, plan.GroupsMembers.Create.Items)
+	})
+}
+
+func TestSyncService_SyncGroupsAndTheirMembers_DeleteThreshold(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("abort the sync before deleting users when the threshold is exceeded", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		user1 := model.UserBuilder().WithIPID("user-1").WithSCIMID("scim-user-1").WithEmail("user.1@mail.com").WithGivenName("user").WithFamilyName("1").WithDisplayName("user 1").WithActive(true).Build()
+		user2 := model.UserBuilder().WithIPID("user-2").WithSCIMID("scim-user-2").WithEmail("user.2@mail.com").WithGivenName("user").WithFamilyName("2").WithDisplayName("user 2").WithActive(true).Build()
+
+		emptyGroupsResult := model.GroupsResultBuilder().Build()
+		emptyUsersResult := model.UsersResultBuilder().Build()
+		emptyGroupsMembersResult := model.GroupsMembersResultBuilder().Build()
+
+		state := model.StateBuilder().
+			WithLastSync("2022-01-01T00:00:00Z").
+			WithGroups(emptyGroupsResult).
+			WithUsers(model.UsersResultBuilder().WithResources([]*model.User{user1, user2}).Build()).
+			WithGroupsMembers(emptyGroupsMembersResult).
+			Build()
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockProviderService.EXPECT().GetGroups(gomock.Any(), gomock.Any()).Return(emptyGroupsResult, nil).Times(1)
+		mockProviderService.EXPECT().GetGroupsMembers(gomock.Any(), emptyGroupsResult).Return(emptyGroupsMembersResult, nil).Times(1)
+		mockProviderService.EXPECT().GetUsersByGroupsMembers(gomock.Any(), emptyGroupsMembersResult).Return(emptyUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository,
+			WithUsersDeleteThreshold(0, 50),
+			WithAllowEmptyIdentityProvider(true),
+		)
+		assert.NoError(t, err)
+
+		_, err = svc.SyncGroupsAndTheirMembers(ctx)
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrDeleteThresholdExceeded)
+	})
+
+	t.Run("abort the sync before writing the groups when the users threshold is exceeded", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		group1 := model.GroupBuilder().WithIPID("group-1").WithName("group 1").WithEmail("group.1@mail.com").Build()
+		group2 := model.GroupBuilder().WithIPID("group-2").WithSCIMID("scim-group-2").WithName("group 2").WithEmail("group.2@mail.com").Build()
+		user1 := model.UserBuilder().WithIPID("user-1").WithSCIMID("scim-user-1").WithEmail("user.1@mail.com").WithGivenName("user").WithFamilyName("1").WithDisplayName("user 1").WithActive(true).Build()
+		user2 := model.UserBuilder().WithIPID("user-2").WithSCIMID("scim-user-2").WithEmail("user.2@mail.com").WithGivenName("user").WithFamilyName("2").WithDisplayName("user 2").WithActive(true).Build()
+		user3 := model.UserBuilder().WithIPID("user-3").WithSCIMID("scim-user-3").WithEmail("user.3@mail.com").WithGivenName("user").WithFamilyName("3").WithDisplayName("user 3").WithActive(true).Build()
+		member1 := model.MemberBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").WithStatus("ACTIVE").Build()
+
+		idpGroupsResult := model.GroupsResultBuilder().WithResources([]*model.Group{group1}).Build()
+		idpUsersResult := model.UsersResultBuilder().WithResources([]*model.User{user1}).Build()
+		idpGroupsMembersResult := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+			model.GroupMembersBuilder().WithGroup(group1).WithResources([]*model.Member{member1}).Build(),
+		}).Build()
+
+		state := model.StateBuilder().
+			WithLastSync("2022-01-01T00:00:00Z").
+			WithGroups(model.GroupsResultBuilder().WithResources([]*model.Group{group2}).Build()).
+			WithUsers(model.UsersResultBuilder().WithResources([]*model.User{user1, user2, user3}).Build()).
+			WithGroupsMembers(model.GroupsMembersResultBuilder().Build()).
+			Build()
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockProviderService.EXPECT().GetGroups(gomock.Any(), gomock.Any()).Return(idpGroupsResult, nil).Times(1)
+		mockProviderService.EXPECT().GetGroupsMembers(gomock.Any(), idpGroupsResult).Return(idpGroupsMembersResult, nil).Times(1)
+		mockProviderService.EXPECT().GetUsersByGroupsMembers(gomock.Any(), idpGroupsMembersResult).Return(idpUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+		mockSCIMService.EXPECT().CreateGroups(gomock.Any(), gomock.Any()).Times(0)
+		mockSCIMService.EXPECT().DeleteGroups(gomock.Any(), gomock.Any()).Times(0)
+		mockSCIMService.EXPECT().DeleteUsers(gomock.Any(), gomock.Any()).Times(0)
+		mockStateRepository.EXPECT().SetState(gomock.Any(), gomock.Any()).Times(0)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository,
+			WithUsersDeleteThreshold(0, 50),
+		)
+		assert.NoError(t, err)
+
+		_, err = svc.SyncGroupsAndTheirMembers(ctx)
+		assert.ErrorIs(t, err, ErrDeleteThresholdExceeded)
+	})
+
+	t.Run("abort the first sync before writing the groups when the users threshold is exceeded", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		group1 := model.GroupBuilder().WithIPID("group-1").WithName("group 1").WithEmail("group.1@mail.com").Build()
+		group2 := model.GroupBuilder().WithSCIMID("scim-group-2").WithName("group 2").WithEmail("group.2@mail.com").Build()
+		user1 := model.UserBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").WithGivenName("user").WithFamilyName("1").WithDisplayName("user 1").WithActive(true).Build()
+		scimUser1 := model.UserBuilder().WithSCIMID("scim-user-1").WithEmail("user.1@mail.com").WithGivenName("user").WithFamilyName("1").WithDisplayName("user 1").WithActive(true).Build()
+		scimUser2 := model.UserBuilder().WithSCIMID("scim-user-2").WithEmail("user.2@mail.com").WithGivenName("user").WithFamilyName("2").WithDisplayName("user 2").WithActive(true).Build()
+		scimUser3 := model.UserBuilder().WithSCIMID("scim-user-3").WithEmail("user.3@mail.com").WithGivenName("user").WithFamilyName("3").WithDisplayName("user 3").WithActive(true).Build()
+		member1 := model.MemberBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").WithStatus("ACTIVE").Build()
+
+		idpGroupsResult := model.GroupsResultBuilder().WithResources([]*model.Group{group1}).Build()
+		idpUsersResult := model.UsersResultBuilder().WithResources([]*model.User{user1}).Build()
+		idpGroupsMembersResult := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+			model.GroupMembersBuilder().WithGroup(group1).WithResources([]*model.Member{member1}).Build(),
+		}).Build()
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockProviderService.EXPECT().GetGroups(gomock.Any(), gomock.Any()).Return(idpGroupsResult, nil).Times(1)
+		mockProviderService.EXPECT().GetGroupsMembers(gomock.Any(), idpGroupsResult).Return(idpGroupsMembersResult, nil).Times(1)
+		mockProviderService.EXPECT().GetUsersByGroupsMembers(gomock.Any(), idpGroupsMembersResult).Return(idpUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(nil, &repository.ErrStateFileEmpty{}).Times(1)
+		mockSCIMService.EXPECT().GetGroups(gomock.Any()).Return(model.GroupsResultBuilder().WithResources([]*model.Group{group2}).Build(), nil).Times(1)
+		mockSCIMService.EXPECT().GetUsers(gomock.Any()).Return(model.UsersResultBuilder().WithResources([]*model.User{scimUser1, scimUser2, scimUser3}).Build(), nil).Times(1)
+		mockSCIMService.EXPECT().GetGroupsMembersBruteForce(gomock.Any(), gomock.Any(), gomock.Any()).Return(model.GroupsMembersResultBuilder().Build(), nil).Times(1)
+		mockSCIMService.EXPECT().CreateGroups(gomock.Any(), gomock.Any()).Times(0)
+		mockSCIMService.EXPECT().DeleteGroups(gomock.Any(), gomock.Any()).Times(0)
+		mockSCIMService.EXPECT().DeleteUsers(gomock.Any(), gomock.Any()).Times(0)
+		mockStateRepository.EXPECT().SetState(gomock.Any(), gomock.Any()).Times(0)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository,
+			WithUsersDeleteThreshold(0, 50),
+		)
+		assert.NoError(t, err)
+
+		_, err = svc.SyncGroupsAndTheirMembers(ctx)
+		assert.ErrorIs(t, err, ErrDeleteThresholdExceeded)
+	})
+
+	t.Run("abort the first sync before writing the groups and users when the groups members threshold is exceeded", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		group1 := model.GroupBuilder().WithIPID("group-1").WithName("group 1").WithEmail("group.1@mail.com").Build()
+		group2 := model.GroupBuilder().WithIPID("group-2").WithName("group 2").WithEmail("group.2@mail.com").Build()
+		scimGroup1 := model.GroupBuilder().WithSCIMID("scim-group-1").WithName("group 1").WithEmail("group.1@mail.com").Build()
+		user1 := model.UserBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").WithGivenName("user").WithFamilyName("1").WithDisplayName("user 1").WithActive(true).Build()
+		user2 := model.UserBuilder().WithIPID("user-2").WithEmail("user.2@mail.com").WithGivenName("user").WithFamilyName("2").WithDisplayName("user 2").WithActive(true).Build()
+		scimUser1 := model.UserBuilder().WithSCIMID("scim-user-1").WithEmail("user.1@mail.com").WithGivenName("user").WithFamilyName("1").WithDisplayName("user 1").WithActive(true).Build()
+		scimUser2 := model.UserBuilder().WithSCIMID("scim-user-2").WithEmail("user.2@mail.com").WithGivenName("user").WithFamilyName("2").WithDisplayName("user 2").WithActive(true).Build()
+		member1 := model.MemberBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").WithStatus("ACTIVE").Build()
+		member2 := model.MemberBuilder().WithIPID("user-2").WithEmail("user.2@mail.com").WithStatus("ACTIVE").Build()
+		scimMember1 := model.MemberBuilder().WithSCIMID("scim-user-1").WithEmail("user.1@mail.com").WithStatus("ACTIVE").Build()
+		scimMember2 := model.MemberBuilder().WithSCIMID("scim-user-2").WithEmail("user.2@mail.com").WithStatus("ACTIVE").Build()
+
+		// the members move to the new group 2, so both are removed from group 1
+		idpGroupsResult := model.GroupsResultBuilder().WithResources([]*model.Group{group1, group2}).Build()
+		idpUsersResult := model.UsersResultBuilder().WithResources([]*model.User{user1, user2}).Build()
+		idpGroupsMembersResult := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+			model.GroupMembersBuilder().WithGroup(group1).WithResources([]*model.Member{}).Build(),
+			model.GroupMembersBuilder().WithGroup(group2).WithResources([]*model.Member{member1, member2}).Build(),
+		}).Build()
+		scimGroupsMembersResult := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+			model.GroupMembersBuilder().WithGroup(scimGroup1).WithResources([]*model.Member{scimMember1, scimMember2}).Build(),
+		}).Build()
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockProviderService.EXPECT().GetGroups(gomock.Any(), gomock.Any()).Return(idpGroupsResult, nil).Times(1)
+		mockProviderService.EXPECT().GetGroupsMembers(gomock.Any(), idpGroupsResult).Return(idpGroupsMembersResult, nil).Times(1)
+		mockProviderService.EXPECT().GetUsersByGroupsMembers(gomock.Any(), idpGroupsMembersResult).Return(idpUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(nil, &repository.ErrStateFileEmpty{}).Times(1)
+		mockSCIMService.EXPECT().GetGroups(gomock.Any()).Return(model.GroupsResultBuilder().WithResources([]*model.Group{scimGroup1}).Build(), nil).Times(1)
+		mockSCIMService.EXPECT().GetUsers(gomock.Any()).Return(model.UsersResultBuilder().WithResources([]*model.User{scimUser1, scimUser2}).Build(), nil).Times(1)
+		mockSCIMService.EXPECT().GetGroupsMembersBruteForce(gomock.Any(), gomock.Any(), gomock.Any()).Return(scimGroupsMembersResult, nil).Times(1)
+		mockSCIMService.EXPECT().CreateGroups(gomock.Any(), gomock.Any()).Times(0)
+		mockSCIMService.EXPECT().UpdateGroups(gomock.Any(), gomock.Any()).Times(0)
+		mockSCIMService.EXPECT().CreateUsers(gomock.Any(), gomock.Any()).Times(0)
+		mockSCIMService.EXPECT().UpdateUsers(gomock.Any(), gomock.Any()).Times(0)
+		mockSCIMService.EXPECT().DeleteGroupsMembers(gomock.Any(), gomock.Any()).Times(0)
+		mockStateRepository.EXPECT().SetState(gomock.Any(), gomock.Any()).Times(0)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository,
+			WithGroupsMembersDeleteThreshold(1, 0),
+		)
+		assert.NoError(t, err)
+
+		_, err = svc.SyncGroupsAndTheirMembers(ctx)
+		assert.ErrorIs(t, err, ErrDeleteThresholdExceeded)
+	})
+}
+
+func TestSyncService_SyncGroupsAndTheirMembers_EmptyIdentityProvider(t *testing.T) {
+	ctx := context.TODO()
+
+	user1 := model.UserBuilder().WithIPID("user-1").WithSCIMID("scim-user-1").WithEmail("user.1@mail.com").WithGivenName("user").WithFamilyName("1").WithDisplayName("user 1").WithActive(true).Build()
+
+	emptyGroupsResult := model.GroupsResultBuilder().Build()
+	emptyUsersResult := model.UsersResultBuilder().Build()
+	emptyGroupsMembersResult := model.GroupsMembersResultBuilder().Build()
+
+	state := model.StateBuilder().
+		WithLastSync("2022-01-01T00:00:00Z").
+		WithGroups(emptyGroupsResult).
+		WithUsers(model.UsersResultBuilder().WithResources([]*model.User{user1}).Build()).
+		WithGroupsMembers(emptyGroupsMembersResult).
+		Build()
+
+	t.Run("abort the sync when the identity provider is empty and the state is not", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockProviderService.EXPECT().GetGroups(gomock.Any(), gomock.Any()).Return(emptyGroupsResult, nil).Times(1)
+		mockProviderService.EXPECT().GetGroupsMembers(gomock.Any(), emptyGroupsResult).Return(emptyGroupsMembersResult, nil).Times(1)
+		mockProviderService.EXPECT().GetUsersByGroupsMembers(gomock.Any(), emptyGroupsMembersResult).Return(emptyUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+		assert.NoError(t, err)
+
+		_, err = svc.SyncGroupsAndTheirMembers(ctx)
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrIdentityProviderEmpty)
+	})
+
+	t.Run("delete everything when the empty identity provider is allowed", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockProviderService.EXPECT().GetGroups(gomock.Any(), gomock.Any()).Return(emptyGroupsResult, nil).Times(1)
+		mockProviderService.EXPECT().GetGroupsMembers(gomock.Any(), emptyGroupsResult).Return(emptyGroupsMembersResult, nil).Times(1)
+		mockProviderService.EXPECT().GetUsersByGroupsMembers(gomock.Any(), emptyGroupsMembersResult).Return(emptyUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+		mockSCIMService.EXPECT().DeleteUsers(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().SetState(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository, WithAllowEmptyIdentityProvider(true))
+		assert.NoError(t, err)
+
+		_, err = svc.SyncGroupsAndTheirMembers(ctx)
+		assert.NoError(t, err)
+	})
+
+	t.Run("abort the sync when the identity provider returns groups without users and the state has users", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		group1 := model.GroupBuilder().WithIPID("group-1").WithName("group 1").WithEmail("group.1@mail.com").Build()
+		idpGroupsResult := model.GroupsResultBuilder().WithResources([]*model.Group{group1}).Build()
+		idpGroupsMembersResult := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+			model.GroupMembersBuilder().WithGroup(group1).Build(),
+		}).Build()
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockProviderService.EXPECT().GetGroups(gomock.Any(), gomock.Any()).Return(idpGroupsResult, nil).Times(1)
+		mockProviderService.EXPECT().GetGroupsMembers(gomock.Any(), idpGroupsResult).Return(idpGroupsMembersResult, nil).Times(1)
+		mockProviderService.EXPECT().GetUsersByGroupsMembers(gomock.Any(), idpGroupsMembersResult).Return(emptyUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+		mockSCIMService.EXPECT().DeleteUsers(gomock.Any(), gomock.Any()).Times(0)
+		mockStateRepository.EXPECT().SetState(gomock.Any(), gomock.Any()).Times(0)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+		assert.NoError(t, err)
+
+		_, err = svc.SyncGroupsAndTheirMembers(ctx)
+		assert.ErrorIs(t, err, ErrIdentityProviderEmpty)
+	})
+
+	t.Run("abort the sync when the identity provider returns users without groups and the state has groups", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		group1 := model.GroupBuilder().WithIPID("group-1").WithSCIMID("scim-group-1").WithName("group 1").WithEmail("group.1@mail.com").Build()
+		idpUsersResult := model.UsersResultBuilder().WithResources([]*model.User{user1}).Build()
+
+		stateWithGroups := model.StateBuilder().
+			WithLastSync("2022-01-01T00:00:00Z").
+			WithGroups(model.GroupsResultBuilder().WithResources([]*model.Group{group1}).Build()).
+			WithUsers(model.UsersResultBuilder().WithResources([]*model.User{user1}).Build()).
+			WithGroupsMembers(emptyGroupsMembersResult).
+			Build()
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockProviderService.EXPECT().GetGroups(gomock.Any(), gomock.Any()).Return(emptyGroupsResult, nil).Times(1)
+		mockProviderService.EXPECT().GetGroupsMembers(gomock.Any(), emptyGroupsResult).Return(emptyGroupsMembersResult, nil).Times(1)
+		mockProviderService.EXPECT().GetUsersByGroupsMembers(gomock.Any(), emptyGroupsMembersResult).Return(idpUsersResult, nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(stateWithGroups, nil).Times(1)
+		mockSCIMService.EXPECT().DeleteGroups(gomock.Any(), gomock.Any()).Times(0)
+		mockStateRepository.EXPECT().SetState(gomock.Any(), gomock.Any()).Times(0)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+		assert.NoError(t, err)
+
+		_, err = svc.SyncGroupsAndTheirMembers(ctx)
+		assert.ErrorIs(t, err, ErrIdentityProviderEmpty)
+	})
+}
+
+func TestSyncService_PlanGroupsAndTheirMembers_FileIdentityProvider(t *testing.T) {
+	ctx := context.TODO()
+
+	directory := `
+groups:
+  - name: AWS-Admins
+    members: [user.1@mail.com]
+  - name: AWS-Developers
+    members: [user.1@mail.com, user.2@mail.com]
+  - name: Other
+    members: [user.3@mail.com]
+users:
+  - {email: user.1@mail.com, given_name: user, family_name: "1"}
+  - {email: user.2@mail.com, given_name: user, family_name: "2", active: false}
+  - {email: user.3@mail.com, given_name: user, family_name: "3"}
+`
+
+	t.Run("plan the changes declared in the directory file", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		d, err := file.Parse(strings.NewReader(directory), file.FormatYAML)
+		assert.NoError(t, err)
+
+		fileDS, err := file.NewDirectoryService(d)
+		assert.NoError(t, err)
+
+		idpService, err := idp.NewFileIdentityProvider(fileDS)
+		assert.NoError(t, err)
+
+		legacyGroup := model.GroupBuilder().WithIPID("legacy").WithSCIMID("scim-legacy").WithName("legacy").Build()
+		legacyUser := model.UserBuilder().WithIPID("legacy@mail.com").WithSCIMID("scim-legacy-user").WithEmail("legacy@mail.com").WithDisplayName("legacy").WithActive(true).Build()
+		legacyMember := model.MemberBuilder().WithIPID("legacy@mail.com").WithSCIMID("scim-legacy-user").WithEmail("legacy@mail.com").WithStatus("ACTIVE").Build()
+
+		state := model.StateBuilder().
+			WithLastSync("2022-01-01T00:00:00Z").
+			WithGroups(model.GroupsResultBuilder().WithResources([]*model.Group{legacyGroup}).Build()).
+			WithUsers(model.UsersResultBuilder().WithResources([]*model.User{legacyUser}).Build()).
+			WithGroupsMembers(model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+				model.GroupMembersBuilder().WithGroup(legacyGroup).WithResources([]*model.Member{legacyMember}).Build(),
+			}).Build()).
+			Build()
+
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+
+		svc, err := NewSyncService(idpService, mockSCIMService, mockStateRepository, WithIdentityProviderGroupsFilter([]string{"AWS-*"}))
+		assert.NoError(t, err)
+
+		plan, err := svc.PlanGroupsAndTheirMembers(ctx)
+		assert.NoError(t, err)
+		assert.NotNil(t, plan)
+
+		assert.Equal(t, 2, plan.Groups.Create.Items)
+		assert.Equal(t, "AWS-Admins", plan.Groups.Create.Resources[0].Name)
+		assert.Equal(t, "AWS-Developers", plan.Groups.Create.Resources[1].Name)
+		assert.Equal(t, 1, plan.Groups.Delete.Items)
+		assert.Equal(t, "legacy", plan.Groups.Delete.Resources[0].Name)
+
+		assert.Equal(t, 2, plan.Users.Create.Items)
+		assert.Equal(t, 1, plan.Users.Delete.Items)
+		assert.Equal(t, "legacy@mail.com", plan.Users.Delete.Resources[0].Email)
+
+		assert.Equal(t, 2, plan.GroupsMembers.Create.Items)
+		assert.Equal(t, 1, plan.GroupsMembers.Delete.Items)
+	})
+}
+
+func TestSyncService_Lock(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("abort the sync when the state is locked", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(repository.ErrStateLocked).Times(1)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+		assert.NoError(t, err)
+
+		report, err := svc.SyncUsers(ctx)
+		assert.ErrorIs(t, err, repository.ErrStateLocked)
+		assert.Contains(t, report.Error, "error locking the state")
+	})
+
+	t.Run("unlock the state when the sync fails", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		gomock.InOrder(
+			mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1),
+			mockProviderService.EXPECT().GetUsers(gomock.Any(), gomock.Any()).Return(model.UsersResultBuilder().Build(), nil).Times(1),
+			mockStateRepository.EXPECT().GetState(gomock.Any()).Return(nil, errors.New("test error")).Times(1),
+			mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1),
+		)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+		assert.NoError(t, err)
+
+		_, err = svc.SyncUsers(ctx)
+		assert.Error(t, err)
+	})
+
+	t.Run("plan without locking the state", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		mockProviderService.EXPECT().GetUsers(gomock.Any(), gomock.Any()).Return(nil, errors.New("test error")).Times(1)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+		assert.NoError(t, err)
+
+		_, err = svc.PlanUsers(ctx)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+)
+
+// ErrDeleteThresholdExceeded is returned when the resources to be deleted exceed the configured threshold
+var ErrDeleteThresholdExceeded = errors.New("delete threshold exceeded")
+
+// deleteThreshold defines the maximum number of resources of one type that can be deleted in a single sync.
+// maxCount is the maximum number of resources and maxPercentage is the maximum percentage (0-100)
+// of the existing resources, a value of 0 disables the check.
+type deleteThreshold struct {
+	maxCount      int
+	maxPercentage float64
+}
+
+// deleteThresholds groups the deleteThreshold of every resource type.
+type deleteThresholds struct {
+	groups        deleteThreshold
+	users         deleteThreshold
+	groupsMembers deleteThreshold
+}
+
+// check returns ErrDeleteThresholdExceeded when the number of resources to remove exceeds the threshold,
+// total is the number of existing resources in the SCIM side or in the state.
+func (dt deleteThreshold) check(resource string, remove, total int) error {
+	if dt.maxCount > 0 && remove > dt.maxCount {
+		return fmt.Errorf("%w: %d %s to delete, maximum allowed is %d", ErrDeleteThresholdExceeded, remove, resource, dt.maxCount)
+	}
+
+	if dt.maxPercentage > 0 && total > 0 {
+		percentage := float64(remove) * 100 / float64(total)
+
+		if percentage > dt.maxPercentage {
+			return fmt.Errorf("%w: %d of %d %s to delete (%.2f%%), maximum allowed is %.2f%%",
+				ErrDeleteThresholdExceeded, remove, total, resource, percentage, dt.maxPercentage,
+			)
+		}
+	}
+
+	return nil
+}
+
+// countMembers returns the number of members of all the groups in the GroupsMembersResult.
+func countMembers(gmr *model.GroupsMembersResult) int {
+	total := 0
+	for _, groupMembers := range gmr.Resources {
+		total += len(groupMembers.Resources)
+	}
+	return total
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteThreshold_check(t *testing.T) {
+	tests := []struct {
+		name    string
+		dt      deleteThreshold
+		remove  int
+		total   int
+		wantErr bool
+	}{
+		{name: "disabled threshold", dt: deleteThreshold{}, remove: 100, total: 100, wantErr: false},
+		{name: "count under the threshold", dt: deleteThreshold{maxCount: 10}, remove: 10, total: 100, wantErr: false},
+		{name: "count over the threshold", dt: deleteThreshold{maxCount: 10}, remove: 11, total: 100, wantErr: true},
+		{name: "percentage under the threshold", dt: deleteThreshold{maxPercentage: 20}, remove: 20, total: 100, wantErr: false},
+		{name: "percentage over the threshold", dt: deleteThreshold{maxPercentage: 20}, remove: 21, total: 100, wantErr: true},
+		{name: "percentage with no existing resources", dt: deleteThreshold{maxPercentage: 20}, remove: 0, total: 0, wantErr: false},
+		{name: "count under and percentage over the threshold", dt: deleteThreshold{maxCount: 50, maxPercentage: 20}, remove: 30, total: 100, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.dt.check("users", tt.remove, tt.total)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("deleteThreshold.check() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !errors.Is(err, ErrDeleteThresholdExceeded) {
+				t.Errorf("deleteThreshold.check() error = %v, want %v", err, ErrDeleteThresholdExceeded)
+			}
+		})
+	}
+}
+
+func TestCountMembers(t *testing.T) {
+	group1 := model.GroupBuilder().WithName("group 1").Build()
+	group2 := model.GroupBuilder().WithName("group 2").Build()
+	member1 := model.MemberBuilder().WithEmail("user.1@mail.com").Build()
+	member2 := model.MemberBuilder().WithEmail("user.2@mail.com").Build()
+
+	gmr := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+		model.GroupMembersBuilder().WithGroup(group1).WithResources([]*model.Member{member1, member2}).Build(),
+		model.GroupMembersBuilder().WithGroup(group2).WithResources([]*model.Member{member1}).Build(),
+	}).Build()
+
+	assert.Equal(t, 3, countMembers(gmr))
+	assert.Equal(t, 0, countMembers(model.GroupsMembersResultBuilder().Build()))
+}
@@ -0,0 +1,24 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/slashdevops/idp-scim-sync/internal/tracing"
+	"go.opentelemetry.io/otel"
+)
+
+// tracer creates the spans of the sync, the spans are not exported until a tracer provider is registered.
+var tracer = otel.Tracer("github.com/slashdevops/idp-scim-sync/internal/core")
+
+// startPhase starts the span of the phase name of the sync, the returned function ends the span
+// with the error of the phase and records the time spent in the report.
+func startPhase(ctx context.Context, report *SyncReport, name string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, name)
+
+	return ctx, func(err error) {
+		report.phase(name, start)
+		tracing.End(span, err)
+	}
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	mocks "github.com/slashdevops/idp-scim-sync/mocks/core"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans replaces the tracer of the package with one recording the spans until the test ends.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	previous := tracer
+	tracer = tp.Tracer("test")
+	t.Cleanup(func() { tracer = previous })
+
+	return sr
+}
+
+func TestSyncService_Tracing(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("should create a span for each phase of the sync", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		sr := recordSpans(t)
+
+		user1 := model.UserBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").WithDisplayName("user 1").Build()
+		user1Created := model.UserBuilder().WithIPID("user-1").WithSCIMID("scim-user-1").WithEmail("user.1@mail.com").WithDisplayName("user 1").Build()
+
+		state := model.StateBuilder().
+			WithLastSync("2022-01-01T00:00:00Z").
+			WithGroups(model.GroupsResultBuilder().Build()).
+			WithUsers(model.UsersResultBuilder().Build()).
+			WithGroupsMembers(model.GroupsMembersResultBuilder().Build()).
+			Build()
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockProviderService.EXPECT().GetUsers(gomock.Any(), gomock.Any()).Return(model.UsersResultBuilder().WithResources([]*model.User{user1}).Build(), nil).Times(1)
+		mockStateRepository.EXPECT().GetState(gomock.Any()).Return(state, nil).Times(1)
+		mockSCIMService.EXPECT().CreateUsers(gomock.Any(), gomock.Any()).Return(model.UsersResultBuilder().WithResources([]*model.User{user1Created}).Build(), nil).Times(1)
+		mockStateRepository.EXPECT().SetState(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+		assert.NoError(t, err)
+
+		_, err = svc.SyncUsers(ctx)
+		assert.NoError(t, err)
+
+		spans := make(map[string]sdktrace.ReadOnlySpan)
+		for _, s := range sr.Ended() {
+			spans[s.Name()] = s
+		}
+
+		root, ok := spans["sync"]
+		if !assert.True(t, ok) {
+			return
+		}
+		assert.False(t, root.Parent().IsValid())
+
+		for _, name := range []string{PhaseIdentityProvider, PhaseState, PhaseReconcile, PhaseStoreState} {
+			if assert.Contains(t, spans, name) {
+				assert.Equal(t, root.SpanContext().SpanID(), spans[name].Parent().SpanID(), name)
+			}
+		}
+
+		// only the users are reconciled syncing users
+		if assert.Contains(t, spans, "reconcile users") {
+			assert.Equal(t, spans[PhaseReconcile].SpanContext().SpanID(), spans["reconcile users"].Parent().SpanID())
+		}
+		assert.NotContains(t, spans, "reconcile groups")
+	})
+
+	t.Run("should record the error in the spans", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		sr := recordSpans(t)
+
+		mockProviderService := mocks.NewMockIdentityProviderService(mockCtrl)
+		mockSCIMService := mocks.NewMockSCIMService(mockCtrl)
+		mockStateRepository := mocks.NewMockStateRepository(mockCtrl)
+
+		mockStateRepository.EXPECT().Lock(gomock.Any()).Return(nil).Times(1)
+		mockStateRepository.EXPECT().Unlock(gomock.Any()).Return(nil).Times(1)
+		mockProviderService.EXPECT().GetUsers(gomock.Any(), gomock.Any()).Return(nil, errors.New("test error")).Times(1)
+
+		svc, err := NewSyncService(mockProviderService, mockSCIMService, mockStateRepository)
+		assert.NoError(t, err)
+
+		_, err = svc.SyncUsers(ctx)
+		assert.Error(t, err)
+
+		spans := sr.Ended()
+		assert.Len(t, spans, 2)
+		for _, s := range spans {
+			assert.Equal(t, codes.Error, s.Status().Code, s.Name())
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package idp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	"github.com/slashdevops/idp-scim-sync/pkg/microsoft"
+)
+
+// This implement core.IdentityProviderService interface for Microsoft Entra ID (Azure AD)
+
+//go:generate go run github.com/golang/mock/mockgen@v1.6.0 -package=mocks -destination=../../mocks/idp/entra_mocks.go -source=entra.go EntraProviderService
+
+// EntraProviderService is the interface that wraps the Microsoft Graph Service methods.
+type EntraProviderService interface {
+	ListUsers(ctx context.Context, filters []string) ([]*microsoft.User, error)
+	ListGroups(ctx context.Context, filters []string) ([]*microsoft.Group, error)
+	ListGroupTransitiveMembers(ctx context.Context, groupID string) ([]*microsoft.DirectoryObject, error)
+}
+
+// EntraIdentityProvider is the Identity Provider service that implements the core.IdentityProvider interface and consumes the pkg.microsoft methods.
+type EntraIdentityProvider struct {
+	ps      EntraProviderService
+	members *membersReader
+}
+
+// NewEntraIdentityProvider returns a new instance of the Microsoft Entra ID Identity Provider service.
+func NewEntraIdentityProvider(eps EntraProviderService) (*EntraIdentityProvider, error) {
+	if eps == nil {
+		return nil, ErrDirectoryServiceNil
+	}
+
+	i := &EntraIdentityProvider{
+		ps: eps,
+	}
+	i.members = newMembersReader(i.listGroupMembers, i.listUsers)
+
+	return i, nil
+}
+
+// GetGroups returns a list of groups from Microsoft Graph.
+//
+// The filter parameter is a list of OData filters, every filter is requested independently.
+//
+// This method checks the names of the groups and avoid the second, third, etc repetition of the same group name.
+func (i *EntraIdentityProvider) GetGroups(ctx context.Context, filter []string) (*model.GroupsResult, error) {
+	uniqueGroups := make(map[string]struct{})
+	syncGroups := make([]*model.Group, 0)
+
+	start := time.Now()
+	pGroups, err := i.ps.ListGroups(ctx, filter)
+	observeRequest(providerEntra, "groups.list", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("idp: error listing groups: %w", err)
+	}
+
+	for _, grp := range pGroups {
+		if _, ok := uniqueGroups[grp.DisplayName]; ok {
+			log.WithFields(log.Fields{
+				"id":    grp.ID,
+				"name":  grp.DisplayName,
+				"email": grp.Mail,
+			}).Warning("idp: group already exists with the same name, this group will be avoided, please make your groups uniques by name!")
+			model.Skip(ctx, model.ResourceGroup, grp.DisplayName, fmt.Sprintf("duplicated group name, group id: %s", grp.ID))
+			continue
+		}
+		uniqueGroups[grp.DisplayName] = struct{}{}
+
+		e := model.GroupBuilder().
+			WithIPID(grp.ID).
+			WithName(grp.DisplayName).
+			WithEmail(grp.Mail).
+			Build()
+
+		syncGroups = append(syncGroups, e)
+	}
+
+	syncResult := model.GroupsResultBuilder().WithResources(syncGroups).Build()
+
+	return syncResult, nil
+}
+
+// GetUsers returns a list of users from Microsoft Graph.
+//
+// The filter parameter is a list of OData filters, every filter is requested independently.
+//
+// This method avoids the second, third, etc repetition of the same user, this happens
+// when the same user matches more than one filter.
+func (i *EntraIdentityProvider) GetUsers(ctx context.Context, filter []string) (*model.UsersResult, error) {
+	uniqUsers := make(map[string]struct{})
+	syncUsers := make([]*model.User, 0)
+
+	start := time.Now()
+	pUsers, err := i.ps.ListUsers(ctx, filter)
+	observeRequest(providerEntra, "users.list", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("idp: error listing users: %w", err)
+	}
+
+	for _, usr := range pUsers {
+		if _, ok := uniqUsers[usr.Email()]; ok {
+			log.WithFields(log.Fields{
+				"id":    usr.ID,
+				"email": usr.Email(),
+			}).Trace("idp: user already listed, this user will be avoided")
+			continue
+		}
+		uniqUsers[usr.Email()] = struct{}{}
+
+		syncUsers = append(syncUsers, entraUserToModel(usr))
+	}
+
+	uResult := model.UsersResultBuilder().WithResources(syncUsers).Build()
+
+	return uResult, nil
+}
+
+// GetGroupMembers returns the transitive members of a group from Microsoft Graph.
+func (i *EntraIdentityProvider) GetGroupMembers(ctx context.Context, groupID string) (*model.MembersResult, error) {
+	return i.members.groupMembers(ctx, groupID)
+}
+
+// GetUsersByGroupsMembers returns the users of the groups members from Microsoft Graph,
+// the users are read with the members of the groups.
+func (i *EntraIdentityProvider) GetUsersByGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) (*model.UsersResult, error) {
+	return i.members.usersByGroupsMembers(ctx, gmr)
+}
+
+// GetGroupsMembers return the members of the groups
+func (i *EntraIdentityProvider) GetGroupsMembers(ctx context.Context, gr *model.GroupsResult) (*model.GroupsMembersResult, error) {
+	return i.members.groupsMembers(ctx, gr)
+}
+
+// listGroupMembers returns the users transitive members of a group with their users.
+func (i *EntraIdentityProvider) listGroupMembers(ctx context.Context, groupID string) ([]*directoryMember, error) {
+	start := time.Now()
+	pMembers, err := i.ps.ListGroupTransitiveMembers(ctx, groupID)
+	observeRequest(providerEntra, "groups.transitiveMembers", start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]*directoryMember, 0, len(pMembers))
+	for _, member := range pMembers {
+		// transitiveMembers returns the nested groups too, but their members are already included
+		if !member.IsUser() {
+			log.WithFields(log.Fields{
+				"id":   member.ID,
+				"type": member.ODataType,
+			}).Warn("skipping member because is not a user, but group members will be included")
+			continue
+		}
+
+		members = append(members, &directoryMember{
+			user:   entraUserToModel(&member.User),
+			status: memberStatus(member.AccountEnabled),
+		})
+	}
+
+	return members, nil
+}
+
+// listUsers returns all the users from Microsoft Graph.
+func (i *EntraIdentityProvider) listUsers(ctx context.Context) ([]*model.User, error) {
+	start := time.Now()
+	pUsers, err := i.ps.ListUsers(ctx, nil)
+	observeRequest(providerEntra, "users.list", start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*model.User, 0, len(pUsers))
+	for _, u := range pUsers {
+		users = append(users, entraUserToModel(u))
+	}
+
+	return users, nil
+}
+
+// entraUserToModel converts a Microsoft Graph user into a model.User.
+// Graph users don't always have givenName and surname, so the displayName is used when they are empty.
+func entraUserToModel(u *microsoft.User) *model.User {
+	displayName := strings.TrimSpace(fmt.Sprintf("%s %s", u.GivenName, u.Surname))
+	if displayName == "" {
+		displayName = u.DisplayName
+	}
+
+	return model.UserBuilder().
+		WithIPID(u.ID).
+		WithGivenName(u.GivenName).
+		WithFamilyName(u.Surname).
+		WithDisplayName(displayName).
+		WithEmail(u.Email()).
+		WithActive(u.AccountEnabled).
+		Build()
+}
+
+// memberStatus returns the member status used by the sync, the same values used by Google Workspace.
+func memberStatus(enabled bool) string {
+	if enabled {
+		return "ACTIVE"
+	}
+	return "SUSPENDED"
+}
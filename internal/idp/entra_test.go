@@ -0,0 +1,242 @@
+package idp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	mocks "github.com/slashdevops/idp-scim-sync/mocks/idp"
+	"github.com/slashdevops/idp-scim-sync/pkg/microsoft"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEntraIdentityProvider(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return EntraIdentityProvider and no error", func(t *testing.T) {
+		mockDS := mocks.NewMockEntraProviderService(mockCtrl)
+		svc, err := NewEntraIdentityProvider(mockDS)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, svc)
+	})
+
+	t.Run("Should return an error if no EntraProviderService is provided", func(t *testing.T) {
+		svc, err := NewEntraIdentityProvider(nil)
+
+		assert.ErrorIs(t, err, ErrDirectoryServiceNil)
+		assert.Nil(t, svc)
+	})
+}
+
+func TestEntraIdentityProvider_GetGroups(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return the groups avoiding the repeated names", func(t *testing.T) {
+		mockDS := mocks.NewMockEntraProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroups(context.TODO(), []string{"startswith(displayName,'aws-')"}).Return([]*microsoft.Group{
+			{ID: "1", DisplayName: "aws-group 1", Mail: "group.1@mail.com"},
+			{ID: "2", DisplayName: "aws-group 2"},
+			{ID: "3", DisplayName: "aws-group 1"},
+		}, nil)
+
+		svc, _ := NewEntraIdentityProvider(mockDS)
+		got, err := svc.GetGroups(context.TODO(), []string{"startswith(displayName,'aws-')"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, "1", got.Resources[0].IPID)
+		assert.Equal(t, "group.1@mail.com", got.Resources[0].Email)
+		assert.Equal(t, "2", got.Resources[1].IPID)
+	})
+
+	t.Run("Should return an error", func(t *testing.T) {
+		mockDS := mocks.NewMockEntraProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroups(context.TODO(), gomock.Any()).Return(nil, errors.New("test error"))
+
+		svc, _ := NewEntraIdentityProvider(mockDS)
+		got, err := svc.GetGroups(context.TODO(), nil)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestEntraIdentityProvider_GetUsers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return the users avoiding the repeated emails", func(t *testing.T) {
+		mockDS := mocks.NewMockEntraProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), gomock.Any()).Return([]*microsoft.User{
+			{ID: "1", GivenName: "user", Surname: "1", Mail: "user.1@mail.com", AccountEnabled: true},
+			{ID: "2", DisplayName: "user 2", UserPrincipalName: "user.2@mail.com"},
+			{ID: "1", GivenName: "user", Surname: "1", Mail: "user.1@mail.com", AccountEnabled: true},
+		}, nil)
+
+		svc, _ := NewEntraIdentityProvider(mockDS)
+		got, err := svc.GetUsers(context.TODO(), []string{"accountEnabled eq true", ""})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, "user 1", got.Resources[0].DisplayName)
+		assert.True(t, got.Resources[0].Active)
+		assert.Equal(t, "user.2@mail.com", got.Resources[1].Email)
+		assert.Equal(t, "user 2", got.Resources[1].DisplayName)
+		assert.False(t, got.Resources[1].Active)
+	})
+
+	t.Run("Should return an error", func(t *testing.T) {
+		mockDS := mocks.NewMockEntraProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), gomock.Any()).Return(nil, errors.New("test error"))
+
+		svc, _ := NewEntraIdentityProvider(mockDS)
+		got, err := svc.GetUsers(context.TODO(), nil)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestEntraIdentityProvider_GetGroupMembers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return only the users", func(t *testing.T) {
+		mockDS := mocks.NewMockEntraProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroupTransitiveMembers(context.TODO(), "1").Return([]*microsoft.DirectoryObject{
+			{User: microsoft.User{ODataType: microsoft.UserODataType, ID: "u1", Mail: "user.1@mail.com", AccountEnabled: true}},
+			{User: microsoft.User{ODataType: "#microsoft.graph.group", ID: "g2"}},
+			{User: microsoft.User{ODataType: microsoft.UserODataType, ID: "u2", UserPrincipalName: "user.2@mail.com"}},
+		}, nil)
+
+		svc, _ := NewEntraIdentityProvider(mockDS)
+		got, err := svc.GetGroupMembers(context.TODO(), "1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, "user.1@mail.com", got.Resources[0].Email)
+		assert.Equal(t, "ACTIVE", got.Resources[0].Status)
+		assert.Equal(t, "user.2@mail.com", got.Resources[1].Email)
+		assert.Equal(t, "SUSPENDED", got.Resources[1].Status)
+	})
+
+	t.Run("Should return an error when the group id is empty", func(t *testing.T) {
+		mockDS := mocks.NewMockEntraProviderService(mockCtrl)
+
+		svc, _ := NewEntraIdentityProvider(mockDS)
+		got, err := svc.GetGroupMembers(context.TODO(), "")
+
+		assert.ErrorIs(t, err, ErrGroupIDNil)
+		assert.Nil(t, got)
+	})
+}
+
+func TestEntraIdentityProvider_GetUsersByGroupsMembers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	member := model.MemberBuilder().WithIPID("u1").WithEmail("user.1@mail.com").Build()
+	gmr := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+		model.GroupMembersBuilder().WithGroup(model.GroupBuilder().WithIPID("1").Build()).WithResources([]*model.Member{member}).Build(),
+		model.GroupMembersBuilder().WithGroup(model.GroupBuilder().WithIPID("2").Build()).WithResources([]*model.Member{member}).Build(),
+	}).Build()
+
+	t.Run("Should join the users read with the groups members", func(t *testing.T) {
+		gr := model.GroupsResultBuilder().WithResources([]*model.Group{
+			model.GroupBuilder().WithIPID("1").Build(),
+			model.GroupBuilder().WithIPID("2").Build(),
+		}).Build()
+
+		mockDS := mocks.NewMockEntraProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroupTransitiveMembers(context.TODO(), "1").Return([]*microsoft.DirectoryObject{{User: microsoft.User{ODataType: microsoft.UserODataType, ID: "u1", Mail: "user.1@mail.com", AccountEnabled: true}}}, nil)
+		mockDS.EXPECT().ListGroupTransitiveMembers(context.TODO(), "2").Return([]*microsoft.DirectoryObject{{User: microsoft.User{ODataType: microsoft.UserODataType, ID: "u1", Mail: "user.1@mail.com", AccountEnabled: true}}}, nil)
+
+		svc, _ := NewEntraIdentityProvider(mockDS)
+		members, err := svc.GetGroupsMembers(context.TODO(), gr)
+		assert.NoError(t, err)
+
+		// no more requests, the users were read with the groups members
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), members)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.Items)
+		assert.Equal(t, "u1", got.Resources[0].IPID)
+		assert.Equal(t, "user.1@mail.com", got.Resources[0].Email)
+	})
+
+	t.Run("Should list the users once when the members were not read with the groups", func(t *testing.T) {
+		mockDS := mocks.NewMockEntraProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), nil).Return([]*microsoft.User{{ID: "u1", Mail: "user.1@mail.com", AccountEnabled: true}}, nil).Times(1)
+
+		svc, _ := NewEntraIdentityProvider(mockDS)
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), gmr)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.Items)
+		assert.Equal(t, "u1", got.Resources[0].IPID)
+	})
+
+	t.Run("Should return an error when the user is not found", func(t *testing.T) {
+		mockDS := mocks.NewMockEntraProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), nil).Return([]*microsoft.User{}, nil)
+
+		svc, _ := NewEntraIdentityProvider(mockDS)
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), gmr)
+
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Should return an error", func(t *testing.T) {
+		mockDS := mocks.NewMockEntraProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), nil).Return(nil, errors.New("test error"))
+
+		svc, _ := NewEntraIdentityProvider(mockDS)
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), gmr)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestEntraIdentityProvider_GetGroupsMembers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return the members of every group", func(t *testing.T) {
+		gr := model.GroupsResultBuilder().WithResources([]*model.Group{
+			model.GroupBuilder().WithIPID("1").WithName("group 1").Build(),
+			model.GroupBuilder().WithIPID("2").WithName("group 2").Build(),
+		}).Build()
+
+		mockDS := mocks.NewMockEntraProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroupTransitiveMembers(context.TODO(), "1").Return([]*microsoft.DirectoryObject{
+			{User: microsoft.User{ODataType: microsoft.UserODataType, ID: "u1", Mail: "user.1@mail.com", AccountEnabled: true}},
+		}, nil)
+		mockDS.EXPECT().ListGroupTransitiveMembers(context.TODO(), "2").Return([]*microsoft.DirectoryObject{}, nil)
+
+		svc, _ := NewEntraIdentityProvider(mockDS)
+		got, err := svc.GetGroupsMembers(context.TODO(), gr)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, 1, got.Resources[0].Items)
+		assert.Equal(t, 0, got.Resources[1].Items)
+		assert.NotEmpty(t, got.HashCode)
+	})
+
+	t.Run("Should return an error when the groups result is nil", func(t *testing.T) {
+		mockDS := mocks.NewMockEntraProviderService(mockCtrl)
+
+		svc, _ := NewEntraIdentityProvider(mockDS)
+		got, err := svc.GetGroupsMembers(context.TODO(), nil)
+
+		assert.ErrorIs(t, err, ErrGroupResultNil)
+		assert.Nil(t, got)
+	})
+}
@@ -0,0 +1,157 @@
+package idp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	"github.com/slashdevops/idp-scim-sync/pkg/file"
+)
+
+// This implement core.IdentityProviderService interface for the directories declared in a local file
+
+//go:generate go run github.com/golang/mock/mockgen@v1.6.0 -package=mocks -destination=../../mocks/idp/file_mocks.go -source=file.go FileProviderService
+
+// FileProviderService is the interface that wraps the directory file methods.
+type FileProviderService interface {
+	ListUsers(ctx context.Context, filters []string) ([]*file.User, error)
+	ListGroups(ctx context.Context, filters []string) ([]*file.Group, error)
+	ListGroupMembers(ctx context.Context, groupName string) ([]*file.User, error)
+}
+
+// FileIdentityProvider is the Identity Provider service that implements the core.IdentityProvider interface and consumes the pkg.file methods.
+// The directory file doesn't have ids, so the name of the groups and the email of the users are used as the identity provider id.
+type FileIdentityProvider struct {
+	ps      FileProviderService
+	members *membersReader
+}
+
+// NewFileIdentityProvider returns a new instance of the directory file Identity Provider service.
+func NewFileIdentityProvider(fps FileProviderService) (*FileIdentityProvider, error) {
+	if fps == nil {
+		return nil, ErrDirectoryServiceNil
+	}
+
+	i := &FileIdentityProvider{
+		ps: fps,
+	}
+	i.members = newMembersReader(i.listGroupMembers, i.listUsers)
+
+	return i, nil
+}
+
+// GetGroups returns a list of groups from the directory file.
+//
+// The filter parameter is a list of patterns matched with the group name, like "AWS-*".
+//
+// The groups names are unique in the directory file, so no repetition is possible.
+func (i *FileIdentityProvider) GetGroups(ctx context.Context, filter []string) (*model.GroupsResult, error) {
+	syncGroups := make([]*model.Group, 0)
+
+	pGroups, err := i.ps.ListGroups(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("idp: error listing groups: %w", err)
+	}
+
+	for _, grp := range pGroups {
+		e := model.GroupBuilder().
+			WithIPID(grp.Name).
+			WithName(grp.Name).
+			WithEmail(grp.Email).
+			Build()
+
+		syncGroups = append(syncGroups, e)
+	}
+
+	syncResult := model.GroupsResultBuilder().WithResources(syncGroups).Build()
+
+	return syncResult, nil
+}
+
+// GetUsers returns a list of users from the directory file.
+//
+// The filter parameter is a list of patterns matched with the user email, like "*@my-company.com".
+func (i *FileIdentityProvider) GetUsers(ctx context.Context, filter []string) (*model.UsersResult, error) {
+	syncUsers := make([]*model.User, 0)
+
+	pUsers, err := i.ps.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("idp: error listing users: %w", err)
+	}
+
+	for _, usr := range pUsers {
+		syncUsers = append(syncUsers, fileUserToModel(usr))
+	}
+
+	uResult := model.UsersResultBuilder().WithResources(syncUsers).Build()
+
+	return uResult, nil
+}
+
+// GetGroupMembers returns the members of a group from the directory file.
+func (i *FileIdentityProvider) GetGroupMembers(ctx context.Context, groupID string) (*model.MembersResult, error) {
+	return i.members.groupMembers(ctx, groupID)
+}
+
+// GetUsersByGroupsMembers returns the users of the groups members from the directory file,
+// the users are read with the members of the groups.
+func (i *FileIdentityProvider) GetUsersByGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) (*model.UsersResult, error) {
+	return i.members.usersByGroupsMembers(ctx, gmr)
+}
+
+// GetGroupsMembers return the members of the groups
+func (i *FileIdentityProvider) GetGroupsMembers(ctx context.Context, gr *model.GroupsResult) (*model.GroupsMembersResult, error) {
+	return i.members.groupsMembers(ctx, gr)
+}
+
+// listGroupMembers returns the members of a group with their users.
+func (i *FileIdentityProvider) listGroupMembers(ctx context.Context, groupID string) ([]*directoryMember, error) {
+	pMembers, err := i.ps.ListGroupMembers(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]*directoryMember, 0, len(pMembers))
+	for _, member := range pMembers {
+		members = append(members, &directoryMember{
+			user:   fileUserToModel(member),
+			status: memberStatus(member.IsActive()),
+		})
+	}
+
+	return members, nil
+}
+
+// listUsers returns all the users of the directory file.
+func (i *FileIdentityProvider) listUsers(ctx context.Context) ([]*model.User, error) {
+	pUsers, err := i.ps.ListUsers(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*model.User, 0, len(pUsers))
+	for _, u := range pUsers {
+		users = append(users, fileUserToModel(u))
+	}
+
+	return users, nil
+}
+
+// fileUserToModel converts a directory file user into a model.User.
+// The display name is optional, so the given and family names are used when it is empty.
+func fileUserToModel(u *file.User) *model.User {
+	displayName := u.DisplayName
+	if displayName == "" {
+		displayName = strings.TrimSpace(fmt.Sprintf("%s %s", u.GivenName, u.FamilyName))
+	}
+
+	return model.UserBuilder().
+		WithIPID(u.Email).
+		WithGivenName(u.GivenName).
+		WithFamilyName(u.FamilyName).
+		WithDisplayName(displayName).
+		WithEmail(u.Email).
+		WithActive(u.IsActive()).
+		Build()
+}
@@ -0,0 +1,237 @@
+package idp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	mocks "github.com/slashdevops/idp-scim-sync/mocks/idp"
+	"github.com/slashdevops/idp-scim-sync/pkg/file"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFileIdentityProvider(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return FileIdentityProvider and no error", func(t *testing.T) {
+		mockDS := mocks.NewMockFileProviderService(mockCtrl)
+		svc, err := NewFileIdentityProvider(mockDS)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, svc)
+	})
+
+	t.Run("Should return an error if no FileProviderService is provided", func(t *testing.T) {
+		svc, err := NewFileIdentityProvider(nil)
+
+		assert.ErrorIs(t, err, ErrDirectoryServiceNil)
+		assert.Nil(t, svc)
+	})
+}
+
+func TestFileIdentityProvider_GetGroups(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return the groups using the name as id", func(t *testing.T) {
+		mockDS := mocks.NewMockFileProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroups(context.TODO(), []string{"AWS-*"}).Return([]*file.Group{
+			{Name: "AWS-Admins", Email: "aws-admins@mail.com"},
+			{Name: "AWS-Developers"},
+		}, nil)
+
+		svc, _ := NewFileIdentityProvider(mockDS)
+		got, err := svc.GetGroups(context.TODO(), []string{"AWS-*"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, "AWS-Admins", got.Resources[0].IPID)
+		assert.Equal(t, "aws-admins@mail.com", got.Resources[0].Email)
+		assert.Equal(t, "AWS-Developers", got.Resources[1].Name)
+	})
+
+	t.Run("Should return an error", func(t *testing.T) {
+		mockDS := mocks.NewMockFileProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroups(context.TODO(), gomock.Any()).Return(nil, errors.New("test error"))
+
+		svc, _ := NewFileIdentityProvider(mockDS)
+		got, err := svc.GetGroups(context.TODO(), nil)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestFileIdentityProvider_GetUsers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should map the users", func(t *testing.T) {
+		inactive := false
+
+		mockDS := mocks.NewMockFileProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), gomock.Any()).Return([]*file.User{
+			{Email: "user.1@mail.com", GivenName: "user", FamilyName: "1"},
+			{Email: "user.2@mail.com", DisplayName: "User Two", Active: &inactive},
+		}, nil)
+
+		svc, _ := NewFileIdentityProvider(mockDS)
+		got, err := svc.GetUsers(context.TODO(), nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, "user.1@mail.com", got.Resources[0].IPID)
+		assert.Equal(t, "user 1", got.Resources[0].DisplayName)
+		assert.True(t, got.Resources[0].Active)
+		assert.Equal(t, "User Two", got.Resources[1].DisplayName)
+		assert.False(t, got.Resources[1].Active)
+	})
+
+	t.Run("Should return an error", func(t *testing.T) {
+		mockDS := mocks.NewMockFileProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), gomock.Any()).Return(nil, errors.New("test error"))
+
+		svc, _ := NewFileIdentityProvider(mockDS)
+		got, err := svc.GetUsers(context.TODO(), nil)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestFileIdentityProvider_GetGroupMembers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return the members with their status", func(t *testing.T) {
+		inactive := false
+
+		mockDS := mocks.NewMockFileProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroupMembers(context.TODO(), "AWS-Admins").Return([]*file.User{
+			{Email: "user.1@mail.com"},
+			{Email: "user.2@mail.com", Active: &inactive},
+		}, nil)
+
+		svc, _ := NewFileIdentityProvider(mockDS)
+		got, err := svc.GetGroupMembers(context.TODO(), "AWS-Admins")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, "user.1@mail.com", got.Resources[0].IPID)
+		assert.Equal(t, "ACTIVE", got.Resources[0].Status)
+		assert.Equal(t, "SUSPENDED", got.Resources[1].Status)
+	})
+
+	t.Run("Should return an error when the group id is empty", func(t *testing.T) {
+		mockDS := mocks.NewMockFileProviderService(mockCtrl)
+
+		svc, _ := NewFileIdentityProvider(mockDS)
+		got, err := svc.GetGroupMembers(context.TODO(), "")
+
+		assert.ErrorIs(t, err, ErrGroupIDNil)
+		assert.Nil(t, got)
+	})
+}
+
+func TestFileIdentityProvider_GetUsersByGroupsMembers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	member := model.MemberBuilder().WithIPID("user.1@mail.com").WithEmail("user.1@mail.com").Build()
+	gmr := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+		model.GroupMembersBuilder().WithGroup(model.GroupBuilder().WithIPID("AWS-Admins").Build()).WithResources([]*model.Member{member}).Build(),
+		model.GroupMembersBuilder().WithGroup(model.GroupBuilder().WithIPID("AWS-Developers").Build()).WithResources([]*model.Member{member}).Build(),
+	}).Build()
+
+	t.Run("Should join the users read with the groups members", func(t *testing.T) {
+		gr := model.GroupsResultBuilder().WithResources([]*model.Group{
+			model.GroupBuilder().WithIPID("AWS-Admins").Build(),
+			model.GroupBuilder().WithIPID("AWS-Developers").Build(),
+		}).Build()
+
+		mockDS := mocks.NewMockFileProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroupMembers(context.TODO(), "AWS-Admins").Return([]*file.User{{Email: "user.1@mail.com"}}, nil)
+		mockDS.EXPECT().ListGroupMembers(context.TODO(), "AWS-Developers").Return([]*file.User{{Email: "user.1@mail.com"}}, nil)
+
+		svc, _ := NewFileIdentityProvider(mockDS)
+		members, err := svc.GetGroupsMembers(context.TODO(), gr)
+		assert.NoError(t, err)
+
+		// no more requests, the users were read with the groups members
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), members)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.Items)
+		assert.Equal(t, "user.1@mail.com", got.Resources[0].IPID)
+		assert.Equal(t, "user.1@mail.com", got.Resources[0].Email)
+	})
+
+	t.Run("Should list the users once when the members were not read with the groups", func(t *testing.T) {
+		mockDS := mocks.NewMockFileProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), nil).Return([]*file.User{{Email: "user.1@mail.com"}}, nil).Times(1)
+
+		svc, _ := NewFileIdentityProvider(mockDS)
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), gmr)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.Items)
+		assert.Equal(t, "user.1@mail.com", got.Resources[0].IPID)
+	})
+
+	t.Run("Should return an error when the user is not found", func(t *testing.T) {
+		mockDS := mocks.NewMockFileProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), nil).Return([]*file.User{}, nil)
+
+		svc, _ := NewFileIdentityProvider(mockDS)
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), gmr)
+
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Should return an error", func(t *testing.T) {
+		mockDS := mocks.NewMockFileProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), nil).Return(nil, errors.New("test error"))
+
+		svc, _ := NewFileIdentityProvider(mockDS)
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), gmr)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestFileIdentityProvider_GetGroupsMembers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return the members of every group", func(t *testing.T) {
+		gr := model.GroupsResultBuilder().WithResources([]*model.Group{
+			model.GroupBuilder().WithIPID("AWS-Admins").WithName("AWS-Admins").Build(),
+		}).Build()
+
+		mockDS := mocks.NewMockFileProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroupMembers(context.TODO(), "AWS-Admins").Return([]*file.User{{Email: "user.1@mail.com"}}, nil)
+
+		svc, _ := NewFileIdentityProvider(mockDS)
+		got, err := svc.GetGroupsMembers(context.TODO(), gr)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.Items)
+		assert.Equal(t, 1, got.Resources[0].Items)
+		assert.NotEmpty(t, got.HashCode)
+	})
+
+	t.Run("Should return an error when the groups result is nil", func(t *testing.T) {
+		mockDS := mocks.NewMockFileProviderService(mockCtrl)
+
+		svc, _ := NewFileIdentityProvider(mockDS)
+		got, err := svc.GetGroupsMembers(context.TODO(), nil)
+
+		assert.ErrorIs(t, err, ErrGroupResultNil)
+		assert.Nil(t, got)
+	})
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/slashdevops/idp-scim-sync/internal/model"
+	"github.com/slashdevops/idp-scim-sync/internal/utils"
 	"github.com/slashdevops/idp-scim-sync/pkg/google"
 	admin "google.golang.org/api/admin/directory/v1"
 )
@@ -22,6 +25,12 @@ var (
 
 	// ErrGroupResultNil is returned when the group result is nil.
 	ErrGroupResultNil = errors.New("provider: group result is nil")
+
+	// ErrUserNotFound is returned when the user of a group member is not found.
+	ErrUserNotFound = errors.New("provider: user not found")
+
+	// ErrInvalidUserLookup is returned when the user lookup is not get or list.
+	ErrInvalidUserLookup = errors.New("provider: user lookup must be get or list")
 )
 
 //go:generate go run github.com/golang/mock/mockgen@v1.6.0 -package=mocks -destination=../../mocks/idp/idp_mocks.go -source=idp.go GoogleProviderService
@@ -34,20 +43,86 @@ type GoogleProviderService interface {
 	GetUser(ctx context.Context, userID string) (*admin.User, error)
 }
 
+const (
+	// DefaultConcurrency is the default number of concurrent requests to the Google Directory API,
+	// 1 means the requests are sequential.
+	DefaultConcurrency = 1
+
+	// UserLookupGet requests the user of every group member with Users.Get.
+	UserLookupGet = "get"
+
+	// UserLookupList lists all the users once with Users.List and joins them with the groups members,
+	// only the members not listed, like the external members, are requested with Users.Get.
+	UserLookupList = "list"
+
+	// DefaultUserLookup is the default way to get the users of the groups members.
+	DefaultUserLookup = UserLookupGet
+)
+
 // IdentityProvider is the Identity Provider service that implements the core.IdentityProvider interface and consumes the pkg.google methods.
 type IdentityProvider struct {
-	ps GoogleProviderService
+	ps          GoogleProviderService
+	concurrency int
+	rateLimit   float64
+	limiter     *utils.RateLimiter
+	userLookup  string
+}
+
+// IdentityProviderOption is a function that configures the IdentityProvider.
+type IdentityProviderOption func(*IdentityProvider)
+
+// WithConcurrency sets the number of groups members and users requested at the same time to the Google Directory API.
+// The results keep the same order of the sequential requests, so the hash codes are stable.
+func WithConcurrency(concurrency int) IdentityProviderOption {
+	return func(i *IdentityProvider) {
+		if concurrency > 0 {
+			i.concurrency = concurrency
+		}
+	}
+}
+
+// WithRateLimit sets the maximum number of requests per second to the Google Directory API,
+// used to stay under the Admin SDK quota, 0 means no limit.
+func WithRateLimit(perSecond float64) IdentityProviderOption {
+	return func(i *IdentityProvider) {
+		i.rateLimit = perSecond
+	}
+}
+
+// WithUserLookup sets how the users of the groups members are requested, UserLookupGet or UserLookupList.
+// UserLookupList saves one request by member when the groups have many members of the same domain.
+func WithUserLookup(lookup string) IdentityProviderOption {
+	return func(i *IdentityProvider) {
+		if lookup != "" {
+			i.userLookup = lookup
+		}
+	}
 }
 
 // NewIdentityProvider returns a new instance of the Identity Provider service.
-func NewIdentityProvider(gps GoogleProviderService) (*IdentityProvider, error) {
+func NewIdentityProvider(gps GoogleProviderService, opts ...IdentityProviderOption) (*IdentityProvider, error) {
 	if gps == nil {
 		return nil, ErrDirectoryServiceNil
 	}
 
-	return &IdentityProvider{
-		ps: gps,
-	}, nil
+	i := &IdentityProvider{
+		ps:          gps,
+		concurrency: DefaultConcurrency,
+		userLookup:  DefaultUserLookup,
+	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	if i.userLookup != UserLookupGet && i.userLookup != UserLookupList {
+		return nil, ErrInvalidUserLookup
+	}
+
+	// the burst allows every worker to send its first request without waiting
+	i.limiter = utils.NewRateLimiter(i.rateLimit, i.concurrency)
+
+	return i, nil
 }
 
 // GetGroups returns a list of groups from the Identity Provider API.
@@ -60,7 +135,9 @@ func (i *IdentityProvider) GetGroups(ctx context.Context, filter []string) (*mod
 	uniqueGroups := make(map[string]struct{})
 	syncGroups := make([]*model.Group, 0)
 
+	start := time.Now()
 	pGroups, err := i.ps.ListGroups(ctx, filter)
+	observeRequest(providerGoogle, "groups.list", start, err)
 	if err != nil {
 		return nil, fmt.Errorf("idp: error listing groups: %w", err)
 	}
@@ -83,6 +160,7 @@ func (i *IdentityProvider) GetGroups(ctx context.Context, filter []string) (*mod
 				"name":  grp.Name,
 				"email": grp.Email,
 			}).Warning("idp: group already exists with the same name, this group will be avoided, please make your groups uniques by name!")
+			model.Skip(ctx, model.ResourceGroup, grp.Name, fmt.Sprintf("duplicated group name, group id: %s", grp.Id))
 		}
 	}
 
@@ -95,25 +173,31 @@ func (i *IdentityProvider) GetGroups(ctx context.Context, filter []string) (*mod
 //
 // The filter parameter is a list of strings that can be used to filter the users
 // according to the Identity Provider API.
+//
+// This method avoids the second, third, etc repetition of the same user, this happens
+// when the same user matches more than one filter.
 func (i *IdentityProvider) GetUsers(ctx context.Context, filter []string) (*model.UsersResult, error) {
+	uniqUsers := make(map[string]struct{})
 	syncUsers := make([]*model.User, 0)
 
+	start := time.Now()
 	pUsers, err := i.ps.ListUsers(ctx, filter)
+	observeRequest(providerGoogle, "users.list", start, err)
 	if err != nil {
 		return nil, fmt.Errorf("idp: error listing users: %w", err)
 	}
 
 	for _, usr := range pUsers {
-		e := model.UserBuilder().
-			WithIPID(usr.Id).
-			WithGivenName(usr.Name.GivenName).
-			WithFamilyName(usr.Name.FamilyName).
-			WithDisplayName(fmt.Sprintf("%s %s", usr.Name.GivenName, usr.Name.FamilyName)).
-			WithEmail(usr.PrimaryEmail).
-			WithActive(!usr.Suspended).
-			Build()
+		if _, ok := uniqUsers[usr.PrimaryEmail]; ok {
+			log.WithFields(log.Fields{
+				"id":    usr.Id,
+				"email": usr.PrimaryEmail,
+			}).Trace("idp: user already listed, this user will be avoided")
+			continue
+		}
+		uniqUsers[usr.PrimaryEmail] = struct{}{}
 
-		syncUsers = append(syncUsers, e)
+		syncUsers = append(syncUsers, googleUserToModel(usr))
 	}
 
 	uResult := model.UsersResultBuilder().WithResources(syncUsers).Build()
@@ -129,7 +213,13 @@ func (i *IdentityProvider) GetGroupMembers(ctx context.Context, groupID string)
 
 	syncMembers := make([]*model.Member, 0)
 
+	if err := i.limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("idp: error listing group members: %w", err)
+	}
+
+	start := time.Now()
 	pMembers, err := i.ps.ListGroupMembers(ctx, groupID, google.WithIncludeDerivedMembership(true))
+	observeRequest(providerGoogle, "members.list", start, err)
 	if err != nil {
 		return nil, fmt.Errorf("idp: error listing group members: %w", err)
 	}
@@ -159,31 +249,104 @@ func (i *IdentityProvider) GetGroupMembers(ctx context.Context, groupID string)
 }
 
 // GetUsersByGroupsMembers returns a list of users from the Identity Provider API.
+//
+// Every member is requested once, even when it is member of many groups, and the users are
+// returned in the order of the first appearance of the member.
+// With UserLookupList all the users are listed once and only the members not listed are requested one by one.
 func (i *IdentityProvider) GetUsersByGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) (*model.UsersResult, error) {
-	pUsers := make([]*model.User, 0)
-	uniqUsers := make(map[string]struct{})
+	members := make([]*model.Member, 0)
+	uniqMembers := make(map[string]struct{})
 
 	for _, groupMembers := range gmr.Resources {
 		for _, member := range groupMembers.Resources {
-			u, err := i.ps.GetUser(ctx, member.Email)
-			if err != nil {
-				return nil, fmt.Errorf("idp: error getting user: %+v, email: %s, error: %w", member.IPID, member.Email, err)
+			if _, ok := uniqMembers[member.Email]; ok {
+				continue
 			}
+			uniqMembers[member.Email] = struct{}{}
+			members = append(members, member)
+		}
+	}
 
-			e := model.UserBuilder().
-				WithIPID(u.Id).
-				WithGivenName(u.Name.GivenName).
-				WithFamilyName(u.Name.FamilyName).
-				WithDisplayName(fmt.Sprintf("%s %s", u.Name.GivenName, u.Name.FamilyName)).
-				WithEmail(u.PrimaryEmail).
-				WithActive(!u.Suspended).
-				Build()
+	users := make([]*model.User, len(members))
+
+	// indexes of the members to request one by one
+	missing := make([]int, 0, len(members))
+
+	if i.userLookup == UserLookupList && len(members) > 0 {
+		if err := i.limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("idp: error listing users: %w", err)
+		}
+
+		start := time.Now()
+		listed, err := i.ps.ListUsers(ctx, nil)
+		observeRequest(providerGoogle, "users.list", start, err)
+		if err != nil {
+			return nil, fmt.Errorf("idp: error listing users: %w", err)
+		}
 
-			if _, ok := uniqUsers[e.Email]; !ok {
-				uniqUsers[e.Email] = struct{}{}
-				pUsers = append(pUsers, e)
+		byID := make(map[string]*admin.User, len(listed))
+		byEmail := make(map[string]*admin.User, len(listed))
+		for _, u := range listed {
+			byID[u.Id] = u
+			byEmail[strings.ToLower(u.PrimaryEmail)] = u
+		}
+
+		for idx, member := range members {
+			u, ok := byID[member.IPID]
+			if !ok {
+				u, ok = byEmail[strings.ToLower(member.Email)]
+			}
+
+			if ok {
+				users[idx] = googleUserToModel(u)
+			} else {
+				missing = append(missing, idx)
 			}
 		}
+
+		log.WithFields(log.Fields{
+			"listed":  len(listed),
+			"members": len(members),
+			"missing": len(missing),
+		}).Debug("idp: users of the groups members joined with the listed users")
+	} else {
+		for idx := range members {
+			missing = append(missing, idx)
+		}
+	}
+
+	err := utils.ForEach(ctx, len(missing), i.concurrency, func(ctx context.Context, m int) error {
+		idx := missing[m]
+		member := members[idx]
+
+		if err := i.limiter.Wait(ctx); err != nil {
+			return fmt.Errorf("idp: error getting user: %+v, email: %s, error: %w", member.IPID, member.Email, err)
+		}
+
+		start := time.Now()
+		u, err := i.ps.GetUser(ctx, member.Email)
+		observeRequest(providerGoogle, "users.get", start, err)
+		if err != nil {
+			return fmt.Errorf("idp: error getting user: %+v, email: %s, error: %w", member.IPID, member.Email, err)
+		}
+
+		users[idx] = googleUserToModel(u)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the members emails could be aliases of the same user
+	pUsers := make([]*model.User, 0)
+	uniqUsers := make(map[string]struct{})
+
+	for _, e := range users {
+		if _, ok := uniqUsers[e.Email]; !ok {
+			uniqUsers[e.Email] = struct{}{}
+			pUsers = append(pUsers, e)
+		}
 	}
 
 	pUsersResult := model.UsersResultBuilder().WithResources(pUsers).Build()
@@ -197,12 +360,15 @@ func (i *IdentityProvider) GetGroupsMembers(ctx context.Context, gr *model.Group
 		return nil, ErrGroupResultNil
 	}
 
-	groupMembers := make([]*model.GroupMembers, 0)
+	// every group members are stored in the position of the group, so the order doesn't depend on the concurrency
+	groupMembers := make([]*model.GroupMembers, len(gr.Resources))
+
+	err := utils.ForEach(ctx, len(gr.Resources), i.concurrency, func(ctx context.Context, idx int) error {
+		group := gr.Resources[idx]
 
-	for _, group := range gr.Resources {
 		members, err := i.GetGroupMembers(ctx, group.IPID)
 		if err != nil {
-			return nil, fmt.Errorf("idp: error getting group members: %w", err)
+			return fmt.Errorf("idp: error getting group members: %w", err)
 		}
 
 		e := model.GroupBuilder().
@@ -212,12 +378,15 @@ func (i *IdentityProvider) GetGroupsMembers(ctx context.Context, gr *model.Group
 			Build()
 
 		if members.Items > 0 {
-			groupMember := model.GroupMembersBuilder().WithGroup(e).WithResources(members.Resources).Build()
-			groupMembers = append(groupMembers, groupMember)
+			groupMembers[idx] = model.GroupMembersBuilder().WithGroup(e).WithResources(members.Resources).Build()
 		} else {
-			groupMember := model.GroupMembersBuilder().WithGroup(e).Build()
-			groupMembers = append(groupMembers, groupMember)
+			groupMembers[idx] = model.GroupMembersBuilder().WithGroup(e).Build()
 		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	groupsMembersResult := &model.GroupsMembersResult{
@@ -228,3 +397,15 @@ func (i *IdentityProvider) GetGroupsMembers(ctx context.Context, gr *model.Group
 
 	return groupsMembersResult, nil
 }
+
+// googleUserToModel converts a Google Directory user into a model.User.
+func googleUserToModel(u *admin.User) *model.User {
+	return model.UserBuilder().
+		WithIPID(u.Id).
+		WithGivenName(u.Name.GivenName).
+		WithFamilyName(u.Name.FamilyName).
+		WithDisplayName(fmt.Sprintf("%s %s", u.Name.GivenName, u.Name.FamilyName)).
+		WithEmail(u.PrimaryEmail).
+		WithActive(!u.Suspended).
+		Build()
+}
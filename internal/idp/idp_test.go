@@ -3,6 +3,7 @@ package idp
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -10,8 +11,12 @@ import (
 	"github.com/slashdevops/idp-scim-sync/internal/model"
 	"github.com/slashdevops/idp-scim-sync/internal/utils"
 	mocks "github.com/slashdevops/idp-scim-sync/mocks/idp"
+	"github.com/slashdevops/idp-scim-sync/pkg/google"
+	"github.com/slashdevops/idp-scim-sync/pkg/microsoft"
+	"github.com/slashdevops/idp-scim-sync/pkg/okta"
 	"github.com/stretchr/testify/assert"
 	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/googleapi"
 )
 
 func TestNewGoogleIdentityProvider(t *testing.T) {
@@ -32,6 +37,24 @@ func TestNewGoogleIdentityProvider(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, svc)
 	})
+
+	t.Run("Should use the default concurrency and no rate limit", func(t *testing.T) {
+		mockDS := mocks.NewMockGoogleProviderService(mockCtrl)
+		svc, err := NewIdentityProvider(mockDS, WithConcurrency(0))
+
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultConcurrency, svc.concurrency)
+		assert.Nil(t, svc.limiter)
+	})
+
+	t.Run("Should configure the concurrency and the rate limit", func(t *testing.T) {
+		mockDS := mocks.NewMockGoogleProviderService(mockCtrl)
+		svc, err := NewIdentityProvider(mockDS, WithConcurrency(10), WithRateLimit(20))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 10, svc.concurrency)
+		assert.NotNil(t, svc.limiter)
+	})
 }
 
 func TestGetGroups(t *testing.T) {
@@ -152,6 +175,30 @@ func TestGetGroups(t *testing.T) {
 	}
 }
 
+func TestGetGroups_Skipped(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	googleGroups := []*admin.Group{
+		{Email: "group1@mail.com", Id: "1", Name: "group 1"},
+		{Email: "group2@mail.com", Id: "2", Name: "group 1"}, // Repeated group name
+	}
+
+	mockDS := mocks.NewMockGoogleProviderService(mockCtrl)
+	mockDS.EXPECT().ListGroups(gomock.Any(), gomock.Any()).Return(googleGroups, nil).Times(1)
+
+	var skipped []string
+	ctx := model.WithSkipFunc(context.Background(), func(resource, name, reason string) {
+		skipped = append(skipped, fmt.Sprintf("%s: %s: %s", resource, name, reason))
+	})
+
+	g := &IdentityProvider{ps: mockDS}
+	got, err := g.GetGroups(ctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, got.Items)
+	assert.Equal(t, []string{"group: group 1: duplicated group name, group id: 2"}, skipped)
+}
+
 func TestGetUsers(t *testing.T) {
 	u1 := &model.User{IPID: "1", Name: model.Name{GivenName: "user", FamilyName: "1"}, DisplayName: "user 1", Active: true, Email: "user.1@mail.com"}
 	u1.SetHashCode()
@@ -207,6 +254,24 @@ func TestGetUsers(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Should return UsersResult without duplicated users and no error",
+			prepare: func(f *fields) {
+				ctx := context.Background()
+				googleUsers := make([]*admin.User, 0)
+				googleUsers = append(googleUsers, &admin.User{PrimaryEmail: "user.1@mail.com", Id: "1", Name: &admin.UserName{GivenName: "user", FamilyName: "1"}, Suspended: false})
+				googleUsers = append(googleUsers, &admin.User{PrimaryEmail: "user.2@mail.com", Id: "2", Name: &admin.UserName{GivenName: "user", FamilyName: "2"}, Suspended: true})
+				googleUsers = append(googleUsers, &admin.User{PrimaryEmail: "user.1@mail.com", Id: "1", Name: &admin.UserName{GivenName: "user", FamilyName: "1"}, Suspended: false})
+
+				f.ds.EXPECT().ListUsers(ctx, gomock.Eq([]string{"name:user*", "email:user.1*"})).Return(googleUsers, nil).Times(1)
+			},
+			args: args{ctx: context.Background(), filter: []string{"name:user*", "email:user.1*"}},
+			want: &model.UsersResult{
+				Items:     2,
+				Resources: []*model.User{u1, u2},
+			},
+			wantErr: false,
+		},
 		{
 			name: "Should return error",
 			prepare: func(f *fields) {
@@ -662,3 +727,185 @@ func TestGetGroupsMembers(t *testing.T) {
 		})
 	}
 }
+
+func TestGetGroupsMembers_Concurrency(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	groups := make([]*model.Group, 0)
+	for i := 0; i < 50; i++ {
+		groups = append(groups, model.GroupBuilder().WithIPID(fmt.Sprintf("%d", i)).WithName(fmt.Sprintf("group %d", i)).Build())
+	}
+	gr := model.GroupsResultBuilder().WithResources(groups).Build()
+
+	newDS := func() *mocks.MockGoogleProviderService {
+		mockDS := mocks.NewMockGoogleProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroupMembers(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, groupID string, _ ...google.GetGroupMembersOption) ([]*admin.Member, error) {
+				return []*admin.Member{
+					{Id: "u" + groupID, Email: "user." + groupID + "@mail.com", Status: "ACTIVE", Type: "USER"},
+				}, nil
+			},
+		).Times(len(groups))
+		return mockDS
+	}
+
+	sequential, _ := NewIdentityProvider(newDS())
+	want, err := sequential.GetGroupsMembers(context.Background(), gr)
+	assert.NoError(t, err)
+
+	concurrent, _ := NewIdentityProvider(newDS(), WithConcurrency(8), WithRateLimit(10000))
+	got, err := concurrent.GetGroupsMembers(context.Background(), gr)
+	assert.NoError(t, err)
+
+	assert.Equal(t, want, got)
+	assert.Equal(t, want.HashCode, got.HashCode)
+	for i, gm := range got.Resources {
+		assert.Equal(t, groups[i].IPID, gm.Group.IPID)
+	}
+}
+
+func TestGetUsersByGroupsMembers_Concurrency(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	members := make([]*model.Member, 0)
+	for i := 0; i < 30; i++ {
+		members = append(members, model.MemberBuilder().WithIPID(fmt.Sprintf("%d", i)).WithEmail(fmt.Sprintf("user.%d@mail.com", i)).Build())
+	}
+
+	// the same members in two groups, every user must be requested once
+	gmr := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+		model.GroupMembersBuilder().WithGroup(model.GroupBuilder().WithIPID("1").Build()).WithResources(members).Build(),
+		model.GroupMembersBuilder().WithGroup(model.GroupBuilder().WithIPID("2").Build()).WithResources(members).Build(),
+	}).Build()
+
+	t.Run("Should request every user once keeping the order", func(t *testing.T) {
+		mockDS := mocks.NewMockGoogleProviderService(mockCtrl)
+		mockDS.EXPECT().GetUser(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, email string) (*admin.User, error) {
+				return &admin.User{Id: email, PrimaryEmail: email, Name: &admin.UserName{GivenName: "user", FamilyName: email}}, nil
+			},
+		).Times(len(members))
+
+		svc, _ := NewIdentityProvider(mockDS, WithConcurrency(8))
+		got, err := svc.GetUsersByGroupsMembers(context.Background(), gmr)
+
+		assert.NoError(t, err)
+		assert.Equal(t, len(members), got.Items)
+		for i, u := range got.Resources {
+			assert.Equal(t, members[i].Email, u.Email)
+		}
+	})
+
+	t.Run("Should return the error", func(t *testing.T) {
+		mockDS := mocks.NewMockGoogleProviderService(mockCtrl)
+		mockDS.EXPECT().GetUser(gomock.Any(), gomock.Any()).Return(nil, errors.New("test error")).MinTimes(1)
+
+		svc, _ := NewIdentityProvider(mockDS, WithConcurrency(8))
+		got, err := svc.GetUsersByGroupsMembers(context.Background(), gmr)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestGetUsersByGroupsMembers_UserLookupList(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	member1 := model.MemberBuilder().WithIPID("1").WithEmail("user.1@mail.com").Build()
+	member2 := model.MemberBuilder().WithIPID("2").WithEmail("alias.2@mail.com").Build()
+	member3 := model.MemberBuilder().WithIPID("3").WithEmail("USER.3@mail.com").Build()
+	external := model.MemberBuilder().WithIPID("99").WithEmail("external@other.com").Build()
+
+	gmr := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+		model.GroupMembersBuilder().WithGroup(model.GroupBuilder().WithIPID("1").Build()).WithResources([]*model.Member{member1, member2}).Build(),
+		model.GroupMembersBuilder().WithGroup(model.GroupBuilder().WithIPID("2").Build()).WithResources([]*model.Member{member1, member3, external}).Build(),
+	}).Build()
+
+	listed := []*admin.User{
+		{Id: "1", PrimaryEmail: "user.1@mail.com", Name: &admin.UserName{GivenName: "user", FamilyName: "1"}},
+		{Id: "2", PrimaryEmail: "user.2@mail.com", Name: &admin.UserName{GivenName: "user", FamilyName: "2"}},
+		{Id: "other-id", PrimaryEmail: "user.3@mail.com", Name: &admin.UserName{GivenName: "user", FamilyName: "3"}, Suspended: true},
+		{Id: "4", PrimaryEmail: "user.4@mail.com", Name: &admin.UserName{GivenName: "user", FamilyName: "4"}},
+	}
+
+	t.Run("Should join the listed users and get only the missing members", func(t *testing.T) {
+		mockDS := mocks.NewMockGoogleProviderService(mockCtrl)
+		gomock.InOrder(
+			mockDS.EXPECT().ListUsers(gomock.Any(), gomock.Nil()).Return(listed, nil).Times(1),
+			mockDS.EXPECT().GetUser(gomock.Any(), "external@other.com").Return(
+				&admin.User{Id: "99", PrimaryEmail: "external@other.com", Name: &admin.UserName{GivenName: "external", FamilyName: "user"}}, nil,
+			).Times(1),
+		)
+
+		svc, err := NewIdentityProvider(mockDS, WithUserLookup(UserLookupList))
+		assert.NoError(t, err)
+
+		got, err := svc.GetUsersByGroupsMembers(context.Background(), gmr)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, got.Items)
+		assert.Equal(t, "user.1@mail.com", got.Resources[0].Email)
+		assert.Equal(t, "user.2@mail.com", got.Resources[1].Email)
+		assert.Equal(t, "user.3@mail.com", got.Resources[2].Email)
+		assert.False(t, got.Resources[2].Active)
+		assert.Equal(t, "external@other.com", got.Resources[3].Email)
+	})
+
+	t.Run("Should return the same users than the get lookup", func(t *testing.T) {
+		gmr := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+			model.GroupMembersBuilder().WithGroup(model.GroupBuilder().WithIPID("1").Build()).WithResources([]*model.Member{member1, member3}).Build(),
+		}).Build()
+
+		getDS := mocks.NewMockGoogleProviderService(mockCtrl)
+		getDS.EXPECT().GetUser(gomock.Any(), "user.1@mail.com").Return(listed[0], nil).Times(1)
+		getDS.EXPECT().GetUser(gomock.Any(), "USER.3@mail.com").Return(listed[2], nil).Times(1)
+		getSvc, _ := NewIdentityProvider(getDS)
+		want, err := getSvc.GetUsersByGroupsMembers(context.Background(), gmr)
+		assert.NoError(t, err)
+
+		listDS := mocks.NewMockGoogleProviderService(mockCtrl)
+		listDS.EXPECT().ListUsers(gomock.Any(), gomock.Nil()).Return(listed, nil).Times(1)
+		listSvc, _ := NewIdentityProvider(listDS, WithUserLookup(UserLookupList))
+		got, err := listSvc.GetUsersByGroupsMembers(context.Background(), gmr)
+		assert.NoError(t, err)
+
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("Should not list the users without members", func(t *testing.T) {
+		mockDS := mocks.NewMockGoogleProviderService(mockCtrl)
+
+		svc, _ := NewIdentityProvider(mockDS, WithUserLookup(UserLookupList))
+		got, err := svc.GetUsersByGroupsMembers(context.Background(), model.GroupsMembersResultBuilder().Build())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, got.Items)
+	})
+
+	t.Run("Should return an error when the list fails", func(t *testing.T) {
+		mockDS := mocks.NewMockGoogleProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(gomock.Any(), gomock.Nil()).Return(nil, errors.New("test error")).Times(1)
+
+		svc, _ := NewIdentityProvider(mockDS, WithUserLookup(UserLookupList))
+		got, err := svc.GetUsersByGroupsMembers(context.Background(), gmr)
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Should return an error when the user lookup is not valid", func(t *testing.T) {
+		mockDS := mocks.NewMockGoogleProviderService(mockCtrl)
+
+		svc, err := NewIdentityProvider(mockDS, WithUserLookup("batch"))
+		assert.ErrorIs(t, err, ErrInvalidUserLookup)
+		assert.Nil(t, svc)
+	})
+}
+
+func TestStatusCode(t *testing.T) {
+	assert.Equal(t, "200", statusCode(nil))
+	assert.Equal(t, "403", statusCode(fmt.Errorf("idp: error listing groups: %w", &googleapi.Error{Code: 403})))
+	assert.Equal(t, "429", statusCode(&microsoft.HTTPResponseError{StatusCode: 429}))
+	assert.Equal(t, "404", statusCode(&okta.HTTPResponseError{StatusCode: 404}))
+	assert.Equal(t, "error", statusCode(errors.New("test error")))
+}
@@ -0,0 +1,195 @@
+package idp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	"github.com/slashdevops/idp-scim-sync/pkg/ldap"
+)
+
+// This implement core.IdentityProviderService interface for LDAPv3 directories like Active Directory or OpenLDAP
+
+//go:generate go run github.com/golang/mock/mockgen@v1.6.0 -package=mocks -destination=../../mocks/idp/ldap_mocks.go -source=ldap.go LDAPProviderService
+
+// LDAPProviderService is the interface that wraps the LDAP directory methods.
+type LDAPProviderService interface {
+	ListUsers(ctx context.Context, filters []string) ([]*ldap.User, error)
+	ListGroups(ctx context.Context, filters []string) ([]*ldap.Group, error)
+	ListGroupMembers(ctx context.Context, groupDN string) ([]*ldap.User, error)
+}
+
+// LDAPIdentityProvider is the Identity Provider service that implements the core.IdentityProvider interface and consumes the pkg.ldap methods.
+// The DN of the entries is used as the identity provider id of the groups and users.
+type LDAPIdentityProvider struct {
+	ps      LDAPProviderService
+	members *membersReader
+}
+
+// NewLDAPIdentityProvider returns a new instance of the LDAP Identity Provider service.
+func NewLDAPIdentityProvider(lps LDAPProviderService) (*LDAPIdentityProvider, error) {
+	if lps == nil {
+		return nil, ErrDirectoryServiceNil
+	}
+
+	i := &LDAPIdentityProvider{
+		ps: lps,
+	}
+	i.members = newMembersReader(i.listGroupMembers, i.listUsers)
+
+	return i, nil
+}
+
+// GetGroups returns a list of groups from the LDAP directory.
+//
+// The filter parameter is a list of LDAP filters, every filter is requested independently.
+//
+// This method checks the names of the groups and avoid the second, third, etc repetition of the same group name.
+func (i *LDAPIdentityProvider) GetGroups(ctx context.Context, filter []string) (*model.GroupsResult, error) {
+	uniqueGroups := make(map[string]struct{})
+	syncGroups := make([]*model.Group, 0)
+
+	pGroups, err := i.ps.ListGroups(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("idp: error listing groups: %w", err)
+	}
+
+	for _, grp := range pGroups {
+		if _, ok := uniqueGroups[grp.Name]; ok {
+			log.WithFields(log.Fields{
+				"dn":    grp.DN,
+				"name":  grp.Name,
+				"email": grp.Email,
+			}).Warning("idp: group already exists with the same name, this group will be avoided, please make your groups uniques by name!")
+			model.Skip(ctx, model.ResourceGroup, grp.Name, fmt.Sprintf("duplicated group name, group id: %s", grp.DN))
+			continue
+		}
+		uniqueGroups[grp.Name] = struct{}{}
+
+		e := model.GroupBuilder().
+			WithIPID(grp.DN).
+			WithName(grp.Name).
+			WithEmail(grp.Email).
+			Build()
+
+		syncGroups = append(syncGroups, e)
+	}
+
+	syncResult := model.GroupsResultBuilder().WithResources(syncGroups).Build()
+
+	return syncResult, nil
+}
+
+// GetUsers returns a list of users from the LDAP directory.
+//
+// The filter parameter is a list of LDAP filters, every filter is requested independently.
+//
+// This method avoids the second, third, etc repetition of the same user, this happens
+// when the same user matches more than one filter, and the users without email.
+func (i *LDAPIdentityProvider) GetUsers(ctx context.Context, filter []string) (*model.UsersResult, error) {
+	uniqUsers := make(map[string]struct{})
+	syncUsers := make([]*model.User, 0)
+
+	pUsers, err := i.ps.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("idp: error listing users: %w", err)
+	}
+
+	for _, usr := range pUsers {
+		if usr.Email == "" {
+			log.WithField("dn", usr.DN).Warn("idp: user without email, this user will be avoided")
+			model.Skip(ctx, model.ResourceUser, usr.DN, "user without email")
+			continue
+		}
+
+		if _, ok := uniqUsers[usr.Email]; ok {
+			log.WithFields(log.Fields{
+				"dn":    usr.DN,
+				"email": usr.Email,
+			}).Trace("idp: user already listed, this user will be avoided")
+			continue
+		}
+		uniqUsers[usr.Email] = struct{}{}
+
+		syncUsers = append(syncUsers, ldapUserToModel(usr))
+	}
+
+	uResult := model.UsersResultBuilder().WithResources(syncUsers).Build()
+
+	return uResult, nil
+}
+
+// GetGroupMembers returns the members of a group from the LDAP directory,
+// the members of the nested groups are included.
+func (i *LDAPIdentityProvider) GetGroupMembers(ctx context.Context, groupID string) (*model.MembersResult, error) {
+	return i.members.groupMembers(ctx, groupID)
+}
+
+// GetUsersByGroupsMembers returns the users of the groups members from the LDAP directory,
+// the users are read with the members of the groups.
+func (i *LDAPIdentityProvider) GetUsersByGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) (*model.UsersResult, error) {
+	return i.members.usersByGroupsMembers(ctx, gmr)
+}
+
+// GetGroupsMembers return the members of the groups
+func (i *LDAPIdentityProvider) GetGroupsMembers(ctx context.Context, gr *model.GroupsResult) (*model.GroupsMembersResult, error) {
+	return i.members.groupsMembers(ctx, gr)
+}
+
+// listGroupMembers returns the members of a group with their users, the members without email are avoided.
+func (i *LDAPIdentityProvider) listGroupMembers(ctx context.Context, groupID string) ([]*directoryMember, error) {
+	pMembers, err := i.ps.ListGroupMembers(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]*directoryMember, 0, len(pMembers))
+	for _, member := range pMembers {
+		if member.Email == "" {
+			log.WithField("dn", member.DN).Warn("idp: member without email, this member will be avoided")
+			continue
+		}
+
+		members = append(members, &directoryMember{
+			user:   ldapUserToModel(member),
+			status: memberStatus(member.Active),
+		})
+	}
+
+	return members, nil
+}
+
+// listUsers returns all the users from the LDAP directory.
+func (i *LDAPIdentityProvider) listUsers(ctx context.Context) ([]*model.User, error) {
+	pUsers, err := i.ps.ListUsers(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*model.User, 0, len(pUsers))
+	for _, u := range pUsers {
+		users = append(users, ldapUserToModel(u))
+	}
+
+	return users, nil
+}
+
+// ldapUserToModel converts a LDAP user into a model.User.
+// The displayName is optional in most of the schemas, so the given and family names are used when it is empty.
+func ldapUserToModel(u *ldap.User) *model.User {
+	displayName := u.DisplayName
+	if displayName == "" {
+		displayName = strings.TrimSpace(fmt.Sprintf("%s %s", u.GivenName, u.FamilyName))
+	}
+
+	return model.UserBuilder().
+		WithIPID(u.DN).
+		WithGivenName(u.GivenName).
+		WithFamilyName(u.FamilyName).
+		WithDisplayName(displayName).
+		WithEmail(u.Email).
+		WithActive(u.Active).
+		Build()
+}
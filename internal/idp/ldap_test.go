@@ -0,0 +1,239 @@
+package idp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	mocks "github.com/slashdevops/idp-scim-sync/mocks/idp"
+	"github.com/slashdevops/idp-scim-sync/pkg/ldap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLDAPIdentityProvider(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return LDAPIdentityProvider and no error", func(t *testing.T) {
+		mockDS := mocks.NewMockLDAPProviderService(mockCtrl)
+		svc, err := NewLDAPIdentityProvider(mockDS)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, svc)
+	})
+
+	t.Run("Should return an error if no LDAPProviderService is provided", func(t *testing.T) {
+		svc, err := NewLDAPIdentityProvider(nil)
+
+		assert.ErrorIs(t, err, ErrDirectoryServiceNil)
+		assert.Nil(t, svc)
+	})
+}
+
+func TestLDAPIdentityProvider_GetGroups(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return the groups avoiding the repeated names", func(t *testing.T) {
+		mockDS := mocks.NewMockLDAPProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroups(context.TODO(), []string{"(cn=aws-*)"}).Return([]*ldap.Group{
+			{DN: "cn=aws-1,ou=groups,dc=example,dc=com", Name: "aws-1", Email: "aws-1@mail.com"},
+			{DN: "cn=aws-2,ou=groups,dc=example,dc=com", Name: "aws-2"},
+			{DN: "cn=aws-1,ou=other,dc=example,dc=com", Name: "aws-1"},
+		}, nil)
+
+		svc, _ := NewLDAPIdentityProvider(mockDS)
+		got, err := svc.GetGroups(context.TODO(), []string{"(cn=aws-*)"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, "cn=aws-1,ou=groups,dc=example,dc=com", got.Resources[0].IPID)
+		assert.Equal(t, "aws-1", got.Resources[0].Name)
+		assert.Equal(t, "aws-1@mail.com", got.Resources[0].Email)
+		assert.Equal(t, "aws-2", got.Resources[1].Name)
+	})
+
+	t.Run("Should return an error", func(t *testing.T) {
+		mockDS := mocks.NewMockLDAPProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroups(context.TODO(), gomock.Any()).Return(nil, errors.New("test error"))
+
+		svc, _ := NewLDAPIdentityProvider(mockDS)
+		got, err := svc.GetGroups(context.TODO(), nil)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestLDAPIdentityProvider_GetUsers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should map the users avoiding the repeated and the without email ones", func(t *testing.T) {
+		mockDS := mocks.NewMockLDAPProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), gomock.Any()).Return([]*ldap.User{
+			{DN: "cn=u1,dc=example,dc=com", Email: "user.1@mail.com", GivenName: "user", FamilyName: "1", Active: true},
+			{DN: "cn=u2,dc=example,dc=com", Email: "user.2@mail.com", DisplayName: "User Two"},
+			{DN: "cn=u3,dc=example,dc=com"},
+			{DN: "cn=u1,dc=example,dc=com", Email: "user.1@mail.com", Active: true},
+		}, nil)
+
+		svc, _ := NewLDAPIdentityProvider(mockDS)
+		got, err := svc.GetUsers(context.TODO(), nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, "cn=u1,dc=example,dc=com", got.Resources[0].IPID)
+		assert.Equal(t, "user 1", got.Resources[0].DisplayName)
+		assert.True(t, got.Resources[0].Active)
+		assert.Equal(t, "User Two", got.Resources[1].DisplayName)
+		assert.False(t, got.Resources[1].Active)
+	})
+
+	t.Run("Should return an error", func(t *testing.T) {
+		mockDS := mocks.NewMockLDAPProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), gomock.Any()).Return(nil, errors.New("test error"))
+
+		svc, _ := NewLDAPIdentityProvider(mockDS)
+		got, err := svc.GetUsers(context.TODO(), nil)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestLDAPIdentityProvider_GetGroupMembers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return the members with their status", func(t *testing.T) {
+		mockDS := mocks.NewMockLDAPProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroupMembers(context.TODO(), "cn=g1,dc=example,dc=com").Return([]*ldap.User{
+			{DN: "cn=u1,dc=example,dc=com", Email: "user.1@mail.com", Active: true},
+			{DN: "cn=u2,dc=example,dc=com", Email: "user.2@mail.com"},
+			{DN: "cn=u3,dc=example,dc=com"},
+		}, nil)
+
+		svc, _ := NewLDAPIdentityProvider(mockDS)
+		got, err := svc.GetGroupMembers(context.TODO(), "cn=g1,dc=example,dc=com")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, "ACTIVE", got.Resources[0].Status)
+		assert.Equal(t, "SUSPENDED", got.Resources[1].Status)
+	})
+
+	t.Run("Should return an error when the group id is empty", func(t *testing.T) {
+		mockDS := mocks.NewMockLDAPProviderService(mockCtrl)
+
+		svc, _ := NewLDAPIdentityProvider(mockDS)
+		got, err := svc.GetGroupMembers(context.TODO(), "")
+
+		assert.ErrorIs(t, err, ErrGroupIDNil)
+		assert.Nil(t, got)
+	})
+}
+
+func TestLDAPIdentityProvider_GetUsersByGroupsMembers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	member := model.MemberBuilder().WithIPID("cn=u1,dc=example,dc=com").WithEmail("user.1@mail.com").Build()
+	gmr := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+		model.GroupMembersBuilder().WithGroup(model.GroupBuilder().WithIPID("cn=g1,dc=example,dc=com").Build()).WithResources([]*model.Member{member}).Build(),
+		model.GroupMembersBuilder().WithGroup(model.GroupBuilder().WithIPID("cn=g2,dc=example,dc=com").Build()).WithResources([]*model.Member{member}).Build(),
+	}).Build()
+
+	t.Run("Should join the users read with the groups members", func(t *testing.T) {
+		gr := model.GroupsResultBuilder().WithResources([]*model.Group{
+			model.GroupBuilder().WithIPID("cn=g1,dc=example,dc=com").Build(),
+			model.GroupBuilder().WithIPID("cn=g2,dc=example,dc=com").Build(),
+		}).Build()
+
+		mockDS := mocks.NewMockLDAPProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroupMembers(context.TODO(), "cn=g1,dc=example,dc=com").Return([]*ldap.User{{DN: "cn=u1,dc=example,dc=com", Email: "user.1@mail.com", Active: true}}, nil)
+		mockDS.EXPECT().ListGroupMembers(context.TODO(), "cn=g2,dc=example,dc=com").Return([]*ldap.User{{DN: "cn=u1,dc=example,dc=com", Email: "user.1@mail.com", Active: true}}, nil)
+
+		svc, _ := NewLDAPIdentityProvider(mockDS)
+		members, err := svc.GetGroupsMembers(context.TODO(), gr)
+		assert.NoError(t, err)
+
+		// no more requests, the users were read with the groups members
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), members)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.Items)
+		assert.Equal(t, "cn=u1,dc=example,dc=com", got.Resources[0].IPID)
+		assert.Equal(t, "user.1@mail.com", got.Resources[0].Email)
+	})
+
+	t.Run("Should list the users once when the members were not read with the groups", func(t *testing.T) {
+		mockDS := mocks.NewMockLDAPProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), nil).Return([]*ldap.User{{DN: "cn=u1,dc=example,dc=com", Email: "user.1@mail.com", Active: true}}, nil).Times(1)
+
+		svc, _ := NewLDAPIdentityProvider(mockDS)
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), gmr)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.Items)
+		assert.Equal(t, "cn=u1,dc=example,dc=com", got.Resources[0].IPID)
+	})
+
+	t.Run("Should return an error when the user is not found", func(t *testing.T) {
+		mockDS := mocks.NewMockLDAPProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), nil).Return([]*ldap.User{}, nil)
+
+		svc, _ := NewLDAPIdentityProvider(mockDS)
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), gmr)
+
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Should return an error", func(t *testing.T) {
+		mockDS := mocks.NewMockLDAPProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), nil).Return(nil, errors.New("test error"))
+
+		svc, _ := NewLDAPIdentityProvider(mockDS)
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), gmr)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestLDAPIdentityProvider_GetGroupsMembers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return the members of every group", func(t *testing.T) {
+		gr := model.GroupsResultBuilder().WithResources([]*model.Group{
+			model.GroupBuilder().WithIPID("cn=g1,dc=example,dc=com").WithName("g1").Build(),
+		}).Build()
+
+		mockDS := mocks.NewMockLDAPProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroupMembers(context.TODO(), "cn=g1,dc=example,dc=com").Return([]*ldap.User{
+			{DN: "cn=u1,dc=example,dc=com", Email: "user.1@mail.com", Active: true},
+		}, nil)
+
+		svc, _ := NewLDAPIdentityProvider(mockDS)
+		got, err := svc.GetGroupsMembers(context.TODO(), gr)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.Items)
+		assert.Equal(t, 1, got.Resources[0].Items)
+		assert.NotEmpty(t, got.HashCode)
+	})
+
+	t.Run("Should return an error when the groups result is nil", func(t *testing.T) {
+		mockDS := mocks.NewMockLDAPProviderService(mockCtrl)
+
+		svc, _ := NewLDAPIdentityProvider(mockDS)
+		got, err := svc.GetGroupsMembers(context.TODO(), nil)
+
+		assert.ErrorIs(t, err, ErrGroupResultNil)
+		assert.Nil(t, got)
+	})
+}
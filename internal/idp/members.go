@@ -0,0 +1,190 @@
+package idp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+)
+
+// directoryMember is a member of a group, returned with its user by the identity providers
+// that list the users members of a group.
+type directoryMember struct {
+	user   *model.User
+	status string
+}
+
+// membersReader reads the groups members of the identity providers that list the users members of a group,
+// Microsoft Entra ID, Okta, LDAP and the directory file.
+//
+// The users read with the members are kept, so the users of the groups members are joined in memory
+// instead of requesting every one of them. The members not read with the groups are joined with a single
+// list of all the users.
+type membersReader struct {
+	listMembers func(ctx context.Context, groupID string) ([]*directoryMember, error)
+	listUsers   func(ctx context.Context) ([]*model.User, error)
+
+	mu    sync.Mutex
+	users map[string]*model.User
+}
+
+// newMembersReader returns a membersReader using the list functions of the identity provider.
+func newMembersReader(
+	listMembers func(ctx context.Context, groupID string) ([]*directoryMember, error),
+	listUsers func(ctx context.Context) ([]*model.User, error),
+) *membersReader {
+	return &membersReader{
+		listMembers: listMembers,
+		listUsers:   listUsers,
+		users:       make(map[string]*model.User),
+	}
+}
+
+// groupMembers returns the members of a group and keeps their users.
+func (r *membersReader) groupMembers(ctx context.Context, groupID string) (*model.MembersResult, error) {
+	if groupID == "" {
+		return nil, ErrGroupIDNil
+	}
+
+	pMembers, err := r.listMembers(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("idp: error listing group members: %w", err)
+	}
+
+	syncMembers := make([]*model.Member, 0, len(pMembers))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, member := range pMembers {
+		r.users[member.user.IPID] = member.user
+
+		e := model.MemberBuilder().
+			WithIPID(member.user.IPID).
+			WithEmail(member.user.Email).
+			WithStatus(member.status).
+			Build()
+
+		syncMembers = append(syncMembers, e)
+	}
+
+	syncMembersResult := model.MembersResultBuilder().WithResources(syncMembers).Build()
+
+	return syncMembersResult, nil
+}
+
+// groupsMembers returns the members of the groups, the users kept from a previous read are replaced.
+func (r *membersReader) groupsMembers(ctx context.Context, gr *model.GroupsResult) (*model.GroupsMembersResult, error) {
+	if gr == nil {
+		return nil, ErrGroupResultNil
+	}
+
+	r.mu.Lock()
+	r.users = make(map[string]*model.User)
+	r.mu.Unlock()
+
+	groupMembers := make([]*model.GroupMembers, 0)
+
+	for _, group := range gr.Resources {
+		members, err := r.groupMembers(ctx, group.IPID)
+		if err != nil {
+			return nil, fmt.Errorf("idp: error getting group members: %w", err)
+		}
+
+		e := model.GroupBuilder().
+			WithIPID(group.IPID).
+			WithName(group.Name).
+			WithEmail(group.Email).
+			Build()
+
+		groupMember := model.GroupMembersBuilder().WithGroup(e).WithResources(members.Resources).Build()
+		groupMembers = append(groupMembers, groupMember)
+	}
+
+	groupsMembersResult := model.GroupsMembersResultBuilder().WithResources(groupMembers).Build()
+
+	return groupsMembersResult, nil
+}
+
+// usersByGroupsMembers returns the users of the groups members, every user once and in the order
+// of the first appearance of the member.
+func (r *membersReader) usersByGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) (*model.UsersResult, error) {
+	members := make([]*model.Member, 0)
+	uniqMembers := make(map[string]struct{})
+
+	for _, groupMembers := range gmr.Resources {
+		for _, member := range groupMembers.Resources {
+			if _, ok := uniqMembers[member.Email]; ok {
+				continue
+			}
+			uniqMembers[member.Email] = struct{}{}
+			members = append(members, member)
+		}
+	}
+
+	users := make([]*model.User, len(members))
+	missing := make([]int, 0)
+
+	r.mu.Lock()
+	for idx, member := range members {
+		if u, ok := r.users[member.IPID]; ok {
+			users[idx] = u
+		} else {
+			missing = append(missing, idx)
+		}
+	}
+	r.mu.Unlock()
+
+	if len(missing) > 0 {
+		listed, err := r.listUsers(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("idp: error listing users: %w", err)
+		}
+
+		byID := make(map[string]*model.User, len(listed))
+		byEmail := make(map[string]*model.User, len(listed))
+		for _, u := range listed {
+			byID[u.IPID] = u
+			byEmail[strings.ToLower(u.Email)] = u
+		}
+
+		for _, idx := range missing {
+			member := members[idx]
+
+			u, ok := byID[member.IPID]
+			if !ok {
+				u, ok = byEmail[strings.ToLower(member.Email)]
+			}
+			if !ok {
+				return nil, fmt.Errorf("idp: error getting user: %+v, email: %s, error: %w", member.IPID, member.Email, ErrUserNotFound)
+			}
+
+			users[idx] = u
+		}
+
+		log.WithFields(log.Fields{
+			"listed":  len(listed),
+			"members": len(members),
+			"missing": len(missing),
+		}).Debug("idp: users of the groups members not read with the groups joined with the listed users")
+	}
+
+	// different members could be the same user
+	pUsers := make([]*model.User, 0, len(users))
+	uniqUsers := make(map[string]struct{})
+
+	for _, u := range users {
+		if _, ok := uniqUsers[u.Email]; ok {
+			continue
+		}
+		uniqUsers[u.Email] = struct{}{}
+		pUsers = append(pUsers, u)
+	}
+
+	pUsersResult := model.UsersResultBuilder().WithResources(pUsers).Build()
+
+	return pUsersResult, nil
+}
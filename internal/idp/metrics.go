@@ -0,0 +1,75 @@
+package idp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/slashdevops/idp-scim-sync/internal/metrics"
+	"github.com/slashdevops/idp-scim-sync/pkg/microsoft"
+	"github.com/slashdevops/idp-scim-sync/pkg/okta"
+	"google.golang.org/api/googleapi"
+)
+
+// Identity providers used as label of the metrics.
+const (
+	providerGoogle = "google"
+	providerEntra  = "entra"
+	providerOkta   = "okta"
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "idp",
+		Name:      "requests_total",
+		Help:      "Number of requests to the identity provider API by provider, endpoint and status code, listing all the pages is one request.",
+	}, []string{"provider", "endpoint", "code"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "idp",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the requests to the identity provider API by provider and endpoint, including the pagination.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider", "endpoint"})
+)
+
+// Collectors returns the Prometheus collectors of the requests to the identity providers.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{requestsTotal, requestDuration}
+}
+
+// observeRequest records the metrics of a request to the identity provider API sent at start
+// and finished with err.
+func observeRequest(provider, endpoint string, start time.Time, err error) {
+	requestsTotal.WithLabelValues(provider, endpoint, statusCode(err)).Inc()
+	requestDuration.WithLabelValues(provider, endpoint).Observe(time.Since(start).Seconds())
+}
+
+// statusCode returns the HTTP status code of the response of a request finished with err,
+// 200 when there is no error and "error" when the request failed without a response.
+func statusCode(err error) string {
+	if err == nil {
+		return strconv.Itoa(http.StatusOK)
+	}
+
+	var gErr *googleapi.Error
+	if errors.As(err, &gErr) {
+		return strconv.Itoa(gErr.Code)
+	}
+
+	var mErr *microsoft.HTTPResponseError
+	if errors.As(err, &mErr) {
+		return strconv.Itoa(mErr.StatusCode)
+	}
+
+	var oErr *okta.HTTPResponseError
+	if errors.As(err, &oErr) {
+		return strconv.Itoa(oErr.StatusCode)
+	}
+
+	return "error"
+}
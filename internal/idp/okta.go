@@ -0,0 +1,204 @@
+package idp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	"github.com/slashdevops/idp-scim-sync/pkg/okta"
+)
+
+// This implement core.IdentityProviderService interface for Okta
+
+//go:generate go run github.com/golang/mock/mockgen@v1.6.0 -package=mocks -destination=../../mocks/idp/okta_mocks.go -source=okta.go OktaProviderService
+
+// OktaProviderService is the interface that wraps the Okta Management API methods.
+type OktaProviderService interface {
+	ListUsers(ctx context.Context, searches []string) ([]*okta.User, error)
+	ListGroups(ctx context.Context, searches []string) ([]*okta.Group, error)
+	ListGroupUsers(ctx context.Context, groupID string) ([]*okta.User, error)
+}
+
+// OktaIdentityProvider is the Identity Provider service that implements the core.IdentityProvider interface and consumes the pkg.okta methods.
+type OktaIdentityProvider struct {
+	ps      OktaProviderService
+	members *membersReader
+}
+
+// NewOktaIdentityProvider returns a new instance of the Okta Identity Provider service.
+func NewOktaIdentityProvider(ops OktaProviderService) (*OktaIdentityProvider, error) {
+	if ops == nil {
+		return nil, ErrDirectoryServiceNil
+	}
+
+	i := &OktaIdentityProvider{
+		ps: ops,
+	}
+	i.members = newMembersReader(i.listGroupMembers, i.listUsers)
+
+	return i, nil
+}
+
+// GetGroups returns a list of groups from Okta.
+//
+// The filter parameter is a list of Okta search expressions, every expression is requested independently.
+//
+// This method checks the names of the groups and avoid the second, third, etc repetition of the same group name.
+func (i *OktaIdentityProvider) GetGroups(ctx context.Context, filter []string) (*model.GroupsResult, error) {
+	uniqueGroups := make(map[string]struct{})
+	syncGroups := make([]*model.Group, 0)
+
+	start := time.Now()
+	pGroups, err := i.ps.ListGroups(ctx, filter)
+	observeRequest(providerOkta, "groups.list", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("idp: error listing groups: %w", err)
+	}
+
+	for _, grp := range pGroups {
+		if grp.Profile == nil {
+			continue
+		}
+
+		if _, ok := uniqueGroups[grp.Profile.Name]; ok {
+			log.WithFields(log.Fields{
+				"id":   grp.ID,
+				"name": grp.Profile.Name,
+			}).Warning("idp: group already exists with the same name, this group will be avoided, please make your groups uniques by name!")
+			model.Skip(ctx, model.ResourceGroup, grp.Profile.Name, fmt.Sprintf("duplicated group name, group id: %s", grp.ID))
+			continue
+		}
+		uniqueGroups[grp.Profile.Name] = struct{}{}
+
+		// Okta groups don't have an email
+		e := model.GroupBuilder().
+			WithIPID(grp.ID).
+			WithName(grp.Profile.Name).
+			Build()
+
+		syncGroups = append(syncGroups, e)
+	}
+
+	syncResult := model.GroupsResultBuilder().WithResources(syncGroups).Build()
+
+	return syncResult, nil
+}
+
+// GetUsers returns a list of users from Okta.
+//
+// The filter parameter is a list of Okta search expressions, every expression is requested independently.
+//
+// This method avoids the second, third, etc repetition of the same user, this happens
+// when the same user matches more than one filter.
+func (i *OktaIdentityProvider) GetUsers(ctx context.Context, filter []string) (*model.UsersResult, error) {
+	uniqUsers := make(map[string]struct{})
+	syncUsers := make([]*model.User, 0)
+
+	start := time.Now()
+	pUsers, err := i.ps.ListUsers(ctx, filter)
+	observeRequest(providerOkta, "users.list", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("idp: error listing users: %w", err)
+	}
+
+	for _, usr := range pUsers {
+		if _, ok := uniqUsers[usr.Email()]; ok {
+			log.WithFields(log.Fields{
+				"id":    usr.ID,
+				"email": usr.Email(),
+			}).Trace("idp: user already listed, this user will be avoided")
+			continue
+		}
+		uniqUsers[usr.Email()] = struct{}{}
+
+		syncUsers = append(syncUsers, oktaUserToModel(usr))
+	}
+
+	uResult := model.UsersResultBuilder().WithResources(syncUsers).Build()
+
+	return uResult, nil
+}
+
+// GetGroupMembers returns the members of a group from Okta.
+func (i *OktaIdentityProvider) GetGroupMembers(ctx context.Context, groupID string) (*model.MembersResult, error) {
+	return i.members.groupMembers(ctx, groupID)
+}
+
+// GetUsersByGroupsMembers returns the users of the groups members from Okta,
+// the users are read with the members of the groups.
+func (i *OktaIdentityProvider) GetUsersByGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) (*model.UsersResult, error) {
+	return i.members.usersByGroupsMembers(ctx, gmr)
+}
+
+// GetGroupsMembers return the members of the groups
+func (i *OktaIdentityProvider) GetGroupsMembers(ctx context.Context, gr *model.GroupsResult) (*model.GroupsMembersResult, error) {
+	return i.members.groupsMembers(ctx, gr)
+}
+
+// listGroupMembers returns the members of a group with their users.
+func (i *OktaIdentityProvider) listGroupMembers(ctx context.Context, groupID string) ([]*directoryMember, error) {
+	start := time.Now()
+	pMembers, err := i.ps.ListGroupUsers(ctx, groupID)
+	observeRequest(providerOkta, "groups.users", start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]*directoryMember, 0, len(pMembers))
+	for _, member := range pMembers {
+		status := member.Status
+		if member.IsActive() {
+			status = okta.UserStatusActive
+		}
+
+		members = append(members, &directoryMember{
+			user:   oktaUserToModel(member),
+			status: status,
+		})
+	}
+
+	return members, nil
+}
+
+// listUsers returns all the users from Okta.
+func (i *OktaIdentityProvider) listUsers(ctx context.Context) ([]*model.User, error) {
+	start := time.Now()
+	pUsers, err := i.ps.ListUsers(ctx, nil)
+	observeRequest(providerOkta, "users.list", start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*model.User, 0, len(pUsers))
+	for _, u := range pUsers {
+		users = append(users, oktaUserToModel(u))
+	}
+
+	return users, nil
+}
+
+// oktaUserToModel converts an Okta user into a model.User.
+// The profile displayName is optional in Okta, so the first and last name are used when it is empty.
+func oktaUserToModel(u *okta.User) *model.User {
+	profile := u.Profile
+	if profile == nil {
+		profile = &okta.UserProfile{}
+	}
+
+	displayName := profile.DisplayName
+	if displayName == "" {
+		displayName = strings.TrimSpace(fmt.Sprintf("%s %s", profile.FirstName, profile.LastName))
+	}
+
+	return model.UserBuilder().
+		WithIPID(u.ID).
+		WithGivenName(profile.FirstName).
+		WithFamilyName(profile.LastName).
+		WithDisplayName(displayName).
+		WithEmail(u.Email()).
+		WithActive(u.IsActive()).
+		Build()
+}
@@ -0,0 +1,236 @@
+package idp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	mocks "github.com/slashdevops/idp-scim-sync/mocks/idp"
+	"github.com/slashdevops/idp-scim-sync/pkg/okta"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOktaIdentityProvider(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return OktaIdentityProvider and no error", func(t *testing.T) {
+		mockDS := mocks.NewMockOktaProviderService(mockCtrl)
+		svc, err := NewOktaIdentityProvider(mockDS)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, svc)
+	})
+
+	t.Run("Should return an error if no OktaProviderService is provided", func(t *testing.T) {
+		svc, err := NewOktaIdentityProvider(nil)
+
+		assert.ErrorIs(t, err, ErrDirectoryServiceNil)
+		assert.Nil(t, svc)
+	})
+}
+
+func TestOktaIdentityProvider_GetGroups(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return the groups avoiding the repeated names", func(t *testing.T) {
+		mockDS := mocks.NewMockOktaProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroups(context.TODO(), []string{`profile.name sw "AWS"`}).Return([]*okta.Group{
+			{ID: "g1", Profile: &okta.GroupProfile{Name: "AWS group 1"}},
+			{ID: "g2", Profile: &okta.GroupProfile{Name: "AWS group 2"}},
+			{ID: "g3", Profile: &okta.GroupProfile{Name: "AWS group 1"}},
+		}, nil)
+
+		svc, _ := NewOktaIdentityProvider(mockDS)
+		got, err := svc.GetGroups(context.TODO(), []string{`profile.name sw "AWS"`})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, "g1", got.Resources[0].IPID)
+		assert.Equal(t, "AWS group 1", got.Resources[0].Name)
+		assert.Equal(t, "g2", got.Resources[1].IPID)
+	})
+
+	t.Run("Should return an error", func(t *testing.T) {
+		mockDS := mocks.NewMockOktaProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroups(context.TODO(), gomock.Any()).Return(nil, errors.New("test error"))
+
+		svc, _ := NewOktaIdentityProvider(mockDS)
+		got, err := svc.GetGroups(context.TODO(), nil)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestOktaIdentityProvider_GetUsers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should map the status to active and avoid the repeated emails", func(t *testing.T) {
+		mockDS := mocks.NewMockOktaProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), gomock.Any()).Return([]*okta.User{
+			{ID: "u1", Status: okta.UserStatusActive, Profile: &okta.UserProfile{Login: "user.1@mail.com", Email: "user.1@mail.com", FirstName: "user", LastName: "1"}},
+			{ID: "u2", Status: okta.UserStatusSuspended, Profile: &okta.UserProfile{Login: "user.2@mail.com", DisplayName: "User Two"}},
+			{ID: "u1", Status: okta.UserStatusActive, Profile: &okta.UserProfile{Login: "user.1@mail.com", Email: "user.1@mail.com"}},
+		}, nil)
+
+		svc, _ := NewOktaIdentityProvider(mockDS)
+		got, err := svc.GetUsers(context.TODO(), nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, "user 1", got.Resources[0].DisplayName)
+		assert.True(t, got.Resources[0].Active)
+		assert.Equal(t, "user.2@mail.com", got.Resources[1].Email)
+		assert.Equal(t, "User Two", got.Resources[1].DisplayName)
+		assert.False(t, got.Resources[1].Active)
+	})
+
+	t.Run("Should return an error", func(t *testing.T) {
+		mockDS := mocks.NewMockOktaProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), gomock.Any()).Return(nil, errors.New("test error"))
+
+		svc, _ := NewOktaIdentityProvider(mockDS)
+		got, err := svc.GetUsers(context.TODO(), nil)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestOktaIdentityProvider_GetGroupMembers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return the members with their status", func(t *testing.T) {
+		mockDS := mocks.NewMockOktaProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroupUsers(context.TODO(), "g1").Return([]*okta.User{
+			{ID: "u1", Status: okta.UserStatusPasswordExpired, Profile: &okta.UserProfile{Login: "user.1@mail.com"}},
+			{ID: "u2", Status: okta.UserStatusDeprovisioned, Profile: &okta.UserProfile{Login: "user.2@mail.com"}},
+		}, nil)
+
+		svc, _ := NewOktaIdentityProvider(mockDS)
+		got, err := svc.GetGroupMembers(context.TODO(), "g1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, "ACTIVE", got.Resources[0].Status)
+		assert.Equal(t, "DEPROVISIONED", got.Resources[1].Status)
+	})
+
+	t.Run("Should return an error when the group id is empty", func(t *testing.T) {
+		mockDS := mocks.NewMockOktaProviderService(mockCtrl)
+
+		svc, _ := NewOktaIdentityProvider(mockDS)
+		got, err := svc.GetGroupMembers(context.TODO(), "")
+
+		assert.ErrorIs(t, err, ErrGroupIDNil)
+		assert.Nil(t, got)
+	})
+}
+
+func TestOktaIdentityProvider_GetUsersByGroupsMembers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	member := model.MemberBuilder().WithIPID("u1").WithEmail("user.1@mail.com").Build()
+	gmr := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+		model.GroupMembersBuilder().WithGroup(model.GroupBuilder().WithIPID("g1").Build()).WithResources([]*model.Member{member}).Build(),
+		model.GroupMembersBuilder().WithGroup(model.GroupBuilder().WithIPID("g2").Build()).WithResources([]*model.Member{member}).Build(),
+	}).Build()
+
+	t.Run("Should join the users read with the groups members", func(t *testing.T) {
+		gr := model.GroupsResultBuilder().WithResources([]*model.Group{
+			model.GroupBuilder().WithIPID("g1").Build(),
+			model.GroupBuilder().WithIPID("g2").Build(),
+		}).Build()
+
+		mockDS := mocks.NewMockOktaProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroupUsers(context.TODO(), "g1").Return([]*okta.User{{ID: "u1", Status: okta.UserStatusActive, Profile: &okta.UserProfile{Login: "user.1@mail.com", Email: "user.1@mail.com"}}}, nil)
+		mockDS.EXPECT().ListGroupUsers(context.TODO(), "g2").Return([]*okta.User{{ID: "u1", Status: okta.UserStatusActive, Profile: &okta.UserProfile{Login: "user.1@mail.com", Email: "user.1@mail.com"}}}, nil)
+
+		svc, _ := NewOktaIdentityProvider(mockDS)
+		members, err := svc.GetGroupsMembers(context.TODO(), gr)
+		assert.NoError(t, err)
+
+		// no more requests, the users were read with the groups members
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), members)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.Items)
+		assert.Equal(t, "u1", got.Resources[0].IPID)
+		assert.Equal(t, "user.1@mail.com", got.Resources[0].Email)
+	})
+
+	t.Run("Should list the users once when the members were not read with the groups", func(t *testing.T) {
+		mockDS := mocks.NewMockOktaProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), nil).Return([]*okta.User{{ID: "u1", Status: okta.UserStatusActive, Profile: &okta.UserProfile{Login: "user.1@mail.com", Email: "user.1@mail.com"}}}, nil).Times(1)
+
+		svc, _ := NewOktaIdentityProvider(mockDS)
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), gmr)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.Items)
+		assert.Equal(t, "u1", got.Resources[0].IPID)
+	})
+
+	t.Run("Should return an error when the user is not found", func(t *testing.T) {
+		mockDS := mocks.NewMockOktaProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), nil).Return([]*okta.User{}, nil)
+
+		svc, _ := NewOktaIdentityProvider(mockDS)
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), gmr)
+
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Should return an error", func(t *testing.T) {
+		mockDS := mocks.NewMockOktaProviderService(mockCtrl)
+		mockDS.EXPECT().ListUsers(context.TODO(), nil).Return(nil, errors.New("test error"))
+
+		svc, _ := NewOktaIdentityProvider(mockDS)
+		got, err := svc.GetUsersByGroupsMembers(context.TODO(), gmr)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestOktaIdentityProvider_GetGroupsMembers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return the members of every group", func(t *testing.T) {
+		gr := model.GroupsResultBuilder().WithResources([]*model.Group{
+			model.GroupBuilder().WithIPID("g1").WithName("group 1").Build(),
+		}).Build()
+
+		mockDS := mocks.NewMockOktaProviderService(mockCtrl)
+		mockDS.EXPECT().ListGroupUsers(context.TODO(), "g1").Return([]*okta.User{
+			{ID: "u1", Status: okta.UserStatusActive, Profile: &okta.UserProfile{Login: "user.1@mail.com"}},
+		}, nil)
+
+		svc, _ := NewOktaIdentityProvider(mockDS)
+		got, err := svc.GetGroupsMembers(context.TODO(), gr)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.Items)
+		assert.Equal(t, 1, got.Resources[0].Items)
+		assert.NotEmpty(t, got.HashCode)
+	})
+
+	t.Run("Should return an error when the groups result is nil", func(t *testing.T) {
+		mockDS := mocks.NewMockOktaProviderService(mockCtrl)
+
+		svc, _ := NewOktaIdentityProvider(mockDS)
+		got, err := svc.GetGroupsMembers(context.TODO(), nil)
+
+		assert.ErrorIs(t, err, ErrGroupResultNil)
+		assert.Nil(t, got)
+	})
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// Namespace is the namespace of all the metrics of idp-scim-sync.
+const Namespace = "idpscim"
+
+// DefaultPushTimeout is the default timeout pushing the metrics to the Prometheus Pushgateway.
+const DefaultPushTimeout = 10 * time.Second
+
+// ErrPushgatewayURLEmpty is returned when pushing the metrics without the Prometheus Pushgateway URL.
+var ErrPushgatewayURLEmpty = errors.New("metrics: pushgateway url may not be empty")
+
+var httpRetriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: Namespace,
+	Subsystem: "http",
+	Name:      "retries_total",
+	Help:      "Number of HTTP requests retried by host.",
+}, []string{"host"})
+
+// RetryHook is a retryablehttp.RequestLogHook counting the HTTP requests retried.
+func RetryHook(_ retryablehttp.Logger, req *http.Request, attempt int) {
+	if attempt > 0 {
+		httpRetriesTotal.WithLabelValues(req.URL.Host).Inc()
+	}
+}
+
+// NewRegistry returns a new Prometheus registry with the given collectors and the HTTP retries.
+func NewRegistry(cs ...prometheus.Collector) (*prometheus.Registry, error) {
+	reg := prometheus.NewRegistry()
+
+	for _, c := range append(cs, httpRetriesTotal) {
+		if err := reg.Register(c); err != nil {
+			return nil, fmt.Errorf("metrics: error registering collector: %w", err)
+		}
+	}
+
+	return reg, nil
+}
+
+// Handler returns an http.Handler serving the metrics of reg together with the Go runtime
+// and process metrics, in the Prometheus exposition format.
+func Handler(reg *prometheus.Registry) http.Handler {
+	runtime := prometheus.NewRegistry()
+	runtime.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return promhttp.HandlerFor(prometheus.Gatherers{reg, runtime}, promhttp.HandlerOpts{})
+}
+
+// Push pushes the metrics of g to the Prometheus Pushgateway at url, grouped by job,
+// replacing the metrics pushed before with the same job.
+func Push(httpClient *http.Client, url, job string, g prometheus.Gatherer) error {
+	if url == "" {
+		return ErrPushgatewayURLEmpty
+	}
+
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultPushTimeout}
+	}
+
+	if err := push.New(url, job).Client(httpClient).Gatherer(g).Push(); err != nil {
+		return fmt.Errorf("metrics: error pushing to %s: %w", url, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRegistry(t *testing.T) {
+	t.Run("Should register the collectors", func(t *testing.T) {
+		c := prometheus.NewCounter(prometheus.CounterOpts{Namespace: Namespace, Name: "test_total", Help: "test"})
+		c.Inc()
+
+		reg, err := NewRegistry(c)
+		assert.NoError(t, err)
+
+		count, err := testutil.GatherAndCount(reg, "idpscim_test_total")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("Should return error when a collector is registered twice", func(t *testing.T) {
+		c := prometheus.NewCounter(prometheus.CounterOpts{Namespace: Namespace, Name: "test_total", Help: "test"})
+
+		_, err := NewRegistry(c, c)
+		assert.Error(t, err)
+	})
+}
+
+func TestRetryHook(t *testing.T) {
+	req := &http.Request{URL: &url.URL{Host: "retry.example.com"}}
+	before := testutil.ToFloat64(httpRetriesTotal.WithLabelValues("retry.example.com"))
+
+	RetryHook(nil, req, 0)
+	RetryHook(nil, req, 1)
+	RetryHook(nil, req, 2)
+
+	assert.Equal(t, before+2, testutil.ToFloat64(httpRetriesTotal.WithLabelValues("retry.example.com")))
+}
+
+func TestHandler(t *testing.T) {
+	c := prometheus.NewCounter(prometheus.CounterOpts{Namespace: Namespace, Name: "handler_total", Help: "test"})
+	reg, err := NewRegistry(c)
+	assert.NoError(t, err)
+
+	svr := httptest.NewServer(Handler(reg))
+	defer svr.Close()
+
+	resp, err := http.Get(svr.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "idpscim_handler_total 0")
+	assert.Contains(t, string(body), "go_goroutines")
+}
+
+func TestPush(t *testing.T) {
+	t.Run("Should push the metrics grouped by job", func(t *testing.T) {
+		var method, path string
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method, path = r.Method, r.URL.Path
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer svr.Close()
+
+		c := prometheus.NewCounter(prometheus.CounterOpts{Namespace: Namespace, Name: "push_total", Help: "test"})
+		reg, err := NewRegistry(c)
+		assert.NoError(t, err)
+
+		assert.NoError(t, Push(nil, svr.URL, "idpscim", reg))
+		assert.Equal(t, http.MethodPut, method)
+		assert.Equal(t, "/metrics/job/idpscim", path)
+	})
+
+	t.Run("Should return error when the pushgateway fails", func(t *testing.T) {
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer svr.Close()
+
+		reg, err := NewRegistry()
+		assert.NoError(t, err)
+
+		assert.Error(t, Push(nil, svr.URL, "idpscim", reg))
+	})
+
+	t.Run("Should return error without url", func(t *testing.T) {
+		assert.ErrorIs(t, Push(nil, "", "idpscim", prometheus.NewRegistry()), ErrPushgatewayURLEmpty)
+	})
+}
@@ -0,0 +1,61 @@
+package model
+
+import "errors"
+
+// Resources and operations of the ResourceError.
+const (
+	ResourceGroup        = "group"
+	ResourceUser         = "user"
+	ResourceGroupMembers = "group members"
+
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+	OperationAdd    = "add"
+	OperationRemove = "remove"
+)
+
+// ResourceError is the error of a single resource, a group, a user or the members of a group,
+// that couldn't be synced with the SCIM service.
+//
+// Name is the group name or the user email, and StatusCode and Details are the HTTP response
+// status code and body when the SCIM service responded with an error.
+type ResourceError struct {
+	Resource   string `json:"resource"`
+	Operation  string `json:"operation"`
+	Name       string `json:"name"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Details    string `json:"details,omitempty"`
+	Err        error  `json:"-"`
+}
+
+func (e *ResourceError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the resource.
+func (e *ResourceError) Unwrap() error {
+	return e.Err
+}
+
+// ResourceErrors returns all the ResourceError wrapped by err, following the errors
+// that aggregate many errors with an Unwrap() []error method.
+func ResourceErrors(err error) []*ResourceError {
+	if err == nil {
+		return nil
+	}
+
+	if re, ok := err.(*ResourceError); ok {
+		return []*ResourceError{re}
+	}
+
+	if multi, ok := err.(interface{ Unwrap() []error }); ok {
+		res := make([]*ResourceError, 0)
+		for _, e := range multi.Unwrap() {
+			res = append(res, ResourceErrors(e)...)
+		}
+		return res
+	}
+
+	return ResourceErrors(errors.Unwrap(err))
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type multiError []error
+
+func (m multiError) Error() string   { return fmt.Sprintf("%d errors", len(m)) }
+func (m multiError) Unwrap() []error { return m }
+
+func TestResourceErrors(t *testing.T) {
+	errTest := errors.New("test error")
+	re1 := &ResourceError{Resource: ResourceUser, Operation: OperationCreate, Name: "user.1@mail.com", Err: errTest}
+	re2 := &ResourceError{Resource: ResourceGroup, Operation: OperationDelete, Name: "group 1", Err: errTest}
+
+	t.Run("should return nil without error", func(t *testing.T) {
+		assert.Nil(t, ResourceErrors(nil))
+	})
+
+	t.Run("should return empty when no resource error is wrapped", func(t *testing.T) {
+		assert.Empty(t, ResourceErrors(fmt.Errorf("wrapped: %w", errTest)))
+	})
+
+	t.Run("should return the wrapped resource error", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", re1)
+
+		assert.Equal(t, []*ResourceError{re1}, ResourceErrors(err))
+		assert.Equal(t, errTest.Error(), re1.Error())
+		assert.ErrorIs(t, err, errTest)
+	})
+
+	t.Run("should return the resource errors of the aggregated errors", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", multiError{re1, errTest, fmt.Errorf("wrapped: %w", re2)})
+
+		assert.Equal(t, []*ResourceError{re1, re2}, ResourceErrors(err))
+	})
+}
@@ -0,0 +1,21 @@
+package model
+
+import "context"
+
+// SkipFunc records a resource of the identity provider that is not synced, like a group
+// with the same name as another group, with the reason why it is skipped.
+type SkipFunc func(resource, name, reason string)
+
+type skipFuncKey struct{}
+
+// WithSkipFunc returns a copy of ctx where the resources skipped are recorded with fn.
+func WithSkipFunc(ctx context.Context, fn SkipFunc) context.Context {
+	return context.WithValue(ctx, skipFuncKey{}, fn)
+}
+
+// Skip records a resource skipped with the SkipFunc of ctx, if any.
+func Skip(ctx context.Context, resource, name, reason string) {
+	if fn, ok := ctx.Value(skipFuncKey{}).(SkipFunc); ok && fn != nil {
+		fn(resource, name, reason)
+	}
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkip(t *testing.T) {
+	t.Run("Should record the resources skipped with the SkipFunc of the context", func(t *testing.T) {
+		var got []string
+		ctx := WithSkipFunc(context.Background(), func(resource, name, reason string) {
+			got = append(got, resource+"/"+name+"/"+reason)
+		})
+
+		Skip(ctx, ResourceGroup, "group 1", "duplicated group name")
+		assert.Equal(t, []string{"group/group 1/duplicated group name"}, got)
+	})
+
+	t.Run("Should do nothing without a SkipFunc", func(t *testing.T) {
+		assert.NotPanics(t, func() { Skip(context.Background(), ResourceGroup, "group 1", "duplicated group name") })
+	})
+}
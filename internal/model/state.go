@@ -161,3 +161,9 @@ func (s *State) SetHashCode() {
 
 	s.HashCode = Hash(copyState)
 }
+
+// StateVersion identifies a state kept in the history of the state repository.
+type StateVersion struct {
+	ID       string `json:"id"`
+	LastSync string `json:"lastSync"`
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrStateSchemaVersionUnsupported is returned when the state has a schema version without migration
+// to the current one, usually because it was stored by a newer version of the program.
+var ErrStateSchemaVersionUnsupported = errors.New("state schema version is not supported")
+
+// stateMigration upgrades a state document from a schema version to the next one.
+type stateMigration struct {
+	from    string
+	to      string
+	migrate func(doc map[string]interface{}) error
+}
+
+// stateMigrations are the migrations from every previous schema version to the next one, when the schema
+// of the state changes, increase StateSchemaVersion and add here the migration from the previous version.
+var stateMigrations = []stateMigration{
+	{
+		// the states stored without schema version have the schema 1.0.0
+		from:    "",
+		to:      "1.0.0",
+		migrate: func(doc map[string]interface{}) error { return nil },
+	},
+}
+
+// UnmarshalJSON unmarshals the State from JSON, migrating it in memory to the current schema version
+// when it was stored with a previous one. It returns ErrStateSchemaVersionUnsupported when there is
+// no migration from the schema version of the state.
+func (s *State) UnmarshalJSON(data []byte) error {
+	// state is State without methods, to unmarshal it without calling this method again
+	type state State
+
+	if string(bytes.TrimSpace(data)) == "null" {
+		return nil
+	}
+
+	var header struct {
+		SchemaVersion string `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return err
+	}
+
+	if header.SchemaVersion != StateSchemaVersion {
+		migrated, err := migrateState(data, header.SchemaVersion, stateMigrations)
+		if err != nil {
+			return err
+		}
+		data = migrated
+	}
+
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return err
+	}
+	*s = State(st)
+
+	return nil
+}
+
+// migrateState applies the migrations from the given schema version to the current one to the state document.
+func migrateState(data []byte, version string, migrations []stateMigration) ([]byte, error) {
+	var doc map[string]interface{}
+
+	// the numbers are kept as they are
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	from := version
+	for version != StateSchemaVersion {
+		m, ok := findStateMigration(migrations, version)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q, the supported schema version is %q", ErrStateSchemaVersionUnsupported, version, StateSchemaVersion)
+		}
+
+		if err := m.migrate(doc); err != nil {
+			return nil, fmt.Errorf("error migrating the state from schema version %q to %q: %w", m.from, m.to, err)
+		}
+
+		version = m.to
+		doc["schemaVersion"] = version
+	}
+
+	log.WithFields(log.Fields{
+		"from": from,
+		"to":   version,
+	}).Info("state migrated to the current schema version")
+
+	return json.Marshal(doc)
+}
+
+// findStateMigration returns the migration from the given schema version.
+func findStateMigration(migrations []stateMigration, from string) (stateMigration, bool) {
+	for _, m := range migrations {
+		if m.from == from {
+			return m, true
+		}
+	}
+
+	return stateMigration{}, false
+}
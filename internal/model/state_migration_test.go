@@ -0,0 +1,75 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState_UnmarshalJSON(t *testing.T) {
+	t.Run("current schema version", func(t *testing.T) {
+		state := StateBuilder().WithCodeVersion("0.0.1").WithLastSync("2022-01-01T00:00:00Z").
+			WithUsers(UsersResultBuilder().WithResource(UserBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").Build()).Build()).
+			Build()
+
+		data, err := json.Marshal(state)
+		assert.NoError(t, err)
+
+		var got State
+		assert.NoError(t, json.Unmarshal(data, &got))
+		assert.Equal(t, state, &got)
+	})
+
+	t.Run("state without schema version", func(t *testing.T) {
+		var got State
+		assert.NoError(t, json.Unmarshal([]byte(`{"lastSync":"2022-01-01T00:00:00Z","resources":{"users":{"items":1,"resources":[{"email":"user.1@mail.com"}]}}}`), &got))
+		assert.Equal(t, StateSchemaVersion, got.SchemaVersion)
+		assert.Equal(t, "2022-01-01T00:00:00Z", got.LastSync)
+		assert.Equal(t, 1, got.Resources.Users.Items)
+		assert.Equal(t, "user.1@mail.com", got.Resources.Users.Resources[0].Email)
+	})
+
+	t.Run("newer schema version", func(t *testing.T) {
+		var got State
+		err := json.Unmarshal([]byte(`{"schemaVersion":"99.0.0","lastSync":"2022-01-01T00:00:00Z"}`), &got)
+		assert.ErrorIs(t, err, ErrStateSchemaVersionUnsupported)
+		assert.Contains(t, err.Error(), "99.0.0")
+	})
+
+	t.Run("null", func(t *testing.T) {
+		var got *State
+		assert.NoError(t, json.Unmarshal([]byte(`null`), &got))
+		assert.Nil(t, got)
+	})
+}
+
+func TestMigrateState(t *testing.T) {
+	migrations := []stateMigration{
+		{
+			from: "0.1.0",
+			to:   "0.2.0",
+			migrate: func(doc map[string]interface{}) error {
+				doc["lastSync"] = doc["last_sync"]
+				delete(doc, "last_sync")
+				return nil
+			},
+		},
+		{
+			from:    "0.2.0",
+			to:      StateSchemaVersion,
+			migrate: func(doc map[string]interface{}) error { return nil },
+		},
+	}
+
+	t.Run("apply the migrations in order", func(t *testing.T) {
+		data, err := migrateState([]byte(`{"schemaVersion":"0.1.0","last_sync":"2022-01-01T00:00:00Z","resources":{"users":{"items":12345678901}}}`), "0.1.0", migrations)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"schemaVersion":"`+StateSchemaVersion+`","lastSync":"2022-01-01T00:00:00Z","resources":{"users":{"items":12345678901}}}`, string(data))
+	})
+
+	t.Run("without migration from the schema version", func(t *testing.T) {
+		_, err := migrateState([]byte(`{"schemaVersion":"0.0.1"}`), "0.0.1", migrations)
+		assert.ErrorIs(t, err, ErrStateSchemaVersionUnsupported)
+	})
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressions of the state.
+const (
+	// CompressionNone stores the state as it is.
+	CompressionNone = "none"
+
+	// CompressionGzip stores the state compressed with gzip.
+	CompressionGzip = "gzip"
+
+	// CompressionZstd stores the state compressed with zstd.
+	CompressionZstd = "zstd"
+)
+
+// ErrCompressionUnsupported is returned when the state compression is not supported
+var ErrCompressionUnsupported = errors.New("repository: state compression is not supported")
+
+// Magic bytes at the beginning of the compressed states.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// validCompression returns ErrCompressionUnsupported when the compression is not supported,
+// empty means CompressionNone.
+func validCompression(compression string) error {
+	switch compression {
+	case "", CompressionNone, CompressionGzip, CompressionZstd:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrCompressionUnsupported, compression)
+	}
+}
+
+// compressState returns the state compressed with the compression.
+func compressState(compression string, data []byte) ([]byte, error) {
+	switch compression {
+	case "", CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+
+		if _, err := zw.Write(data); err != nil {
+			return nil, fmt.Errorf("repository: error compressing state: %w", err)
+		}
+
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("repository: error compressing state: %w", err)
+		}
+
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		zw, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, fmt.Errorf("repository: error compressing state: %w", err)
+		}
+		defer zw.Close()
+
+		return zw.EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrCompressionUnsupported, compression)
+	}
+}
+
+// decompressState returns the state decompressed when it is compressed, detected by its magic bytes,
+// so the states stored with any compression, or without it, are read.
+func decompressState(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("repository: error decompressing state: %w", err)
+		}
+		defer zr.Close()
+
+		plain, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("repository: error decompressing state: %w", err)
+		}
+
+		return plain, nil
+	case bytes.HasPrefix(data, zstdMagic):
+		zr, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, fmt.Errorf("repository: error decompressing state: %w", err)
+		}
+		defer zr.Close()
+
+		plain, err := zr.DecodeAll(data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("repository: error decompressing state: %w", err)
+		}
+
+		return plain, nil
+	default:
+		return data, nil
+	}
+}
+
+// contentEncoding returns the HTTP content encoding of the state stored with the compression,
+// empty when it is not compressed.
+func contentEncoding(compression string) string {
+	switch compression {
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZstd:
+		return "zstd"
+	default:
+		return ""
+	}
+}
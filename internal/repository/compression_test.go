@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/golang/mock/gomock"
+	mocks "github.com/slashdevops/idp-scim-sync/mocks/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressState(t *testing.T) {
+	data := []byte(`{"schemaVersion": "1.0.0"}`)
+
+	t.Run("none", func(t *testing.T) {
+		got, err := compressState(CompressionNone, data)
+		assert.NoError(t, err)
+		assert.Equal(t, data, got)
+
+		got, err = decompressState(got)
+		assert.NoError(t, err)
+		assert.Equal(t, data, got)
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		got, err := compressState(CompressionGzip, data)
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(got, gzipMagic))
+
+		got, err = decompressState(got)
+		assert.NoError(t, err)
+		assert.Equal(t, data, got)
+	})
+
+	t.Run("unsupported compression", func(t *testing.T) {
+		_, err := compressState("lz4", data)
+		assert.ErrorIs(t, err, ErrCompressionUnsupported)
+	})
+
+	t.Run("zstd", func(t *testing.T) {
+		got, err := compressState(CompressionZstd, data)
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(got, zstdMagic))
+
+		got, err = decompressState(got)
+		assert.NoError(t, err)
+		assert.Equal(t, data, got)
+	})
+
+	t.Run("corrupted gzip", func(t *testing.T) {
+		_, err := decompressState(append(gzipMagic, data...))
+		assert.Error(t, err)
+	})
+
+	t.Run("corrupted zstd", func(t *testing.T) {
+		_, err := decompressState(append(zstdMagic, data...))
+		assert.Error(t, err)
+	})
+}
+
+func TestStateRepository_Compression(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("Disk", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		repo, err := NewDiskRepository(&buf, WithDiskCompression(CompressionGzip))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.SetState(ctx, testState("2022-01-01T00:00:00Z")))
+		assert.True(t, bytes.HasPrefix(buf.Bytes(), gzipMagic))
+
+		// the repositories without compression read it too
+		plain, err := NewDiskRepository(bytes.NewBuffer(buf.Bytes()))
+		assert.NoError(t, err)
+
+		state, err := plain.GetState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, testState("2022-01-01T00:00:00Z"), state)
+	})
+
+	t.Run("Disk state stored without compression", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		plain, err := NewDiskRepository(&buf)
+		assert.NoError(t, err)
+		assert.NoError(t, plain.SetState(ctx, testState("2022-01-01T00:00:00Z")))
+
+		repo, err := NewDiskRepository(&buf, WithDiskCompression(CompressionGzip))
+		assert.NoError(t, err)
+
+		state, err := repo.GetState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, testState("2022-01-01T00:00:00Z"), state)
+	})
+
+	t.Run("Disk encrypted", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		c, err := NewKeyCipher(bytes.Repeat([]byte{1}, stateKeySize))
+		assert.NoError(t, err)
+
+		repo, err := NewDiskRepository(&buf, WithDiskCompression(CompressionGzip), WithDiskCipher(c))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.SetState(ctx, testState("2022-01-01T00:00:00Z")))
+
+		state, err := repo.GetState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, testState("2022-01-01T00:00:00Z"), state)
+	})
+
+	t.Run("Disk unsupported compression", func(t *testing.T) {
+		repo, err := NewDiskRepository(&bytes.Buffer{}, WithDiskCompression("lz4"))
+		assert.ErrorIs(t, err, ErrCompressionUnsupported)
+		assert.Nil(t, repo)
+	})
+
+	t.Run("S3", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		var stored []byte
+
+		mockS3Repository := mocks.NewMockS3ClientAPI(mockCtrl)
+		mockS3Repository.EXPECT().PutObject(ctx, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+				assert.Equal(t, "gzip", aws.ToString(params.ContentEncoding))
+
+				var err error
+				stored, err = io.ReadAll(params.Body)
+				return &s3.PutObjectOutput{}, err
+			}).Times(1)
+		mockS3Repository.EXPECT().GetObject(ctx, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(stored))}, nil
+			}).Times(1)
+
+		repo, err := NewS3Repository(mockS3Repository, WithBucket("MyBucket"), WithKey("MyKey"), WithCompression(CompressionGzip))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.SetState(ctx, testState("2022-01-01T00:00:00Z")))
+		assert.True(t, bytes.HasPrefix(stored, gzipMagic))
+
+		state, err := repo.GetState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, testState("2022-01-01T00:00:00Z"), state)
+	})
+
+	t.Run("DynamoDB", func(t *testing.T) {
+		db := newFakeDynamoDB()
+		repo, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"), WithDynamoDBCompression(CompressionGzip))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.SetState(ctx, testState("2022-01-01T00:00:00Z")))
+
+		plain, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"))
+		assert.NoError(t, err)
+
+		state, err := plain.GetState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, testState("2022-01-01T00:00:00Z"), state)
+	})
+}
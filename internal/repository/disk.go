@@ -1,30 +1,72 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"sync"
+	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/slashdevops/idp-scim-sync/internal/model"
 )
 
 // consume io.ReadWriter
 
-// DiskRepository represents a disk based state repository and implement core.StateRepository interface
+// DiskRepository represents a disk based state repository and implement core.StateRepository interface.
+// When the state file is a file, it is locked with a lock file next to it with the suffix .lock,
+// otherwise it can't be shared with other processes and it is locked only in this process.
+// When the history is enabled and the state file is a file, every state stored is also kept in numbered
+// files next to it, from the newest with the suffix .1 to the oldest with the suffix of the history size.
 type DiskRepository struct {
 	stateFile io.ReadWriter
+	lockFile  string
+	name      string
+	lockTTL   time.Duration
+	history   int
+	cipher    StateCipher
+
+	// compression of the state stored, the states are read with any compression
+	compression string
+
+	// allowUnencrypted reads the states not encrypted when there is a cipher, to migrate them
+	allowUnencrypted bool
+
+	mu   sync.Mutex
+	lock *stateLock
 }
 
 // NewDiskRepository creates a new disk based state repository
-func NewDiskRepository(stateFile io.ReadWriter) (*DiskRepository, error) {
+func NewDiskRepository(stateFile io.ReadWriter, opts ...DiskRepositoryOption) (*DiskRepository, error) {
 	if stateFile == nil {
 		return nil, &ErrStateFileNil{Message: "state file cannot be nil"}
 	}
 
-	return &DiskRepository{
+	dr := &DiskRepository{
 		stateFile: stateFile,
-	}, nil
+		lockTTL:   DefaultLockTTL,
+	}
+
+	for _, opt := range opts {
+		opt(dr)
+	}
+
+	if err := validCompression(dr.compression); err != nil {
+		return nil, err
+	}
+
+	if f, ok := stateFile.(interface{ Name() string }); ok {
+		dr.name = f.Name()
+		dr.lockFile = dr.name + ".lock"
+	}
+
+	return dr, nil
 }
 
 // GetState returns the state from the state file
@@ -42,26 +84,328 @@ func (dr *DiskRepository) GetState(ctx context.Context) (*model.State, error) {
 		return nil, &ErrStateFileEmpty{Message: "state file is empty"}
 	}
 
-	var state model.State
-	err = json.Unmarshal(data, &state)
-	if err != nil {
-		return nil, fmt.Errorf("disk: error unmarshalling state: %w", err)
-	}
-	return &state, nil
+	return dr.decodeState(ctx, data)
 }
 
 // SetState sets the state in the state file
 func (dr *DiskRepository) SetState(ctx context.Context, state *model.State) error {
-	enc := json.NewEncoder(dr.stateFile)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
 	enc.SetIndent("", "  ")
 	err := enc.Encode(state)
 	if err != nil {
 		return fmt.Errorf("disk: error encoding state: %w", err)
 	}
 
+	data, err := compressState(dr.compression, buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("disk: error compressing state: %w", err)
+	}
+
+	data, err = encryptState(ctx, dr.cipher, data)
+	if err != nil {
+		return fmt.Errorf("disk: error encrypting state: %w", err)
+	}
+
+	if _, err := dr.stateFile.Write(data); err != nil {
+		return fmt.Errorf("disk: error writing state: %w", err)
+	}
+
+	if dr.history > 0 && dr.name != "" {
+		dr.keepState(data)
+	}
+
+	return nil
+}
+
+// ListStates returns the states kept in the history, the newest first.
+func (dr *DiskRepository) ListStates(ctx context.Context) ([]*model.StateVersion, error) {
+	versions := make([]*model.StateVersion, 0)
+
+	if dr.name == "" {
+		return versions, nil
+	}
+
+	for i := 1; i <= dr.history; i++ {
+		id := strconv.Itoa(i)
+
+		state, err := dr.readStateFile(ctx, dr.historyFile(id))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				break
+			}
+			return nil, err
+		}
+
+		versions = append(versions, &model.StateVersion{ID: id, LastSync: state.LastSync})
+	}
+
+	return versions, nil
+}
+
+// GetStateVersion returns the state with the given id from the history, the number of the file
+// with the state, it returns ErrStateVersionNotFound when the state is not in the history.
+func (dr *DiskRepository) GetStateVersion(ctx context.Context, id string) (*model.State, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil || n < 1 || n > dr.history || dr.name == "" {
+		return nil, ErrStateVersionNotFound
+	}
+
+	state, err := dr.readStateFile(ctx, dr.historyFile(id))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrStateVersionNotFound
+		}
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// keepState moves the files of the history to the next number, removing the oldest one, and stores
+// the state in the first one, the state is already stored so the errors are only logged.
+func (dr *DiskRepository) keepState(data []byte) {
+	for i := dr.history; i > 0; i-- {
+		from := dr.historyFile(strconv.Itoa(i))
+
+		var err error
+		if i == dr.history {
+			err = os.Remove(from)
+		} else {
+			err = os.Rename(from, dr.historyFile(strconv.Itoa(i+1)))
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.WithError(err).WithField("file", from).Warn("cannot move the state in the history")
+			return
+		}
+	}
+
+	if err := os.WriteFile(dr.historyFile("1"), data, 0o644); err != nil {
+		log.WithError(err).Warn("cannot keep the state in the history")
+	}
+}
+
+// historyFile returns the name of the file with the state with the given id in the history.
+func (dr *DiskRepository) historyFile(id string) string {
+	return dr.name + "." + id
+}
+
+// Lock locks the state creating the lock file, it returns ErrStateLocked when the lock file exists
+// and it didn't expire. An expired lock file is replaced, only when another sync didn't replace it before.
+func (dr *DiskRepository) Lock(ctx context.Context) error {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	if dr.lock != nil {
+		return dr.lock.lockedError()
+	}
+
+	l, err := newStateLock(dr.lockTTL)
+	if err != nil {
+		return err
+	}
+
+	if dr.lockFile != "" {
+		if err := createLockFile(dr.lockFile, l); err != nil {
+			return err
+		}
+	}
+
+	dr.lock = l
+
 	return nil
 }
 
+// Unlock releases the lock removing the lock file, it returns ErrLockLost when the lock file
+// doesn't belong to this repository anymore because it expired and another sync took it over.
+func (dr *DiskRepository) Unlock(ctx context.Context) error {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	if dr.lock == nil {
+		return ErrStateNotLocked
+	}
+
+	lockID := dr.lock.ID
+	dr.lock = nil
+
+	if dr.lockFile == "" {
+		return nil
+	}
+
+	current, err := readLockFile(dr.lockFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrLockLost
+		}
+		return err
+	}
+
+	if current.ID != lockID {
+		return ErrLockLost
+	}
+
+	if err := os.Remove(dr.lockFile); err != nil {
+		return fmt.Errorf("disk: error removing lock file: %w", err)
+	}
+
+	return nil
+}
+
+// createLockFile creates the lock file only when it doesn't exist, or when it exists but it expired.
+// The expired lock file is renamed to a name of the new lock instead of removed, a file can be renamed only
+// once, so only one sync takes it over, and the lock file renamed is checked to be the expired one and not
+// the lock file of another sync that took it over after it was read.
+func createLockFile(name string, l *stateLock) error {
+	// the second attempt is after renaming an expired lock file
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			err = json.NewEncoder(f).Encode(l)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(name)
+				return fmt.Errorf("disk: error writing lock file: %w", err)
+			}
+
+			return nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("disk: error creating lock file: %w", err)
+		}
+
+		current, err := readLockFile(name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+
+		if !current.expired(time.Now()) {
+			return current.lockedError()
+		}
+
+		if err := takeLockFile(name, name+"."+l.ID, current); err != nil {
+			return err
+		}
+
+		log.WithFields(log.Fields{
+			"owner":     current.Owner,
+			"createdAt": current.CreatedAt.Format(time.RFC3339),
+			"expiresAt": current.ExpiresAt.Format(time.RFC3339),
+		}).Warn("the lock of the state expired without being released, taking it over")
+	}
+
+	return ErrStateLocked
+}
+
+// takeLockFile renames the expired lock file to the name taken and removes it, it returns ErrStateLocked
+// when another sync renamed it before, or when the lock file renamed is not the expired one, which is
+// put back.
+func takeLockFile(name, taken string, expired *stateLock) error {
+	if err := os.Rename(name, taken); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrStateLocked
+		}
+		return fmt.Errorf("disk: error renaming expired lock file: %w", err)
+	}
+
+	// a lock file that can't be read is the one of another sync still writing it
+	current, readErr := readLockFile(taken)
+	if readErr == nil && current.same(expired) {
+		if err := os.Remove(taken); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("disk: error removing expired lock file: %w", err)
+		}
+		return nil
+	}
+
+	// the link fails when another lock file was created meanwhile, then the sync that created it holds the lock,
+	// and the lock file renamed is left as it is because it is not the expired one
+	if err := os.Link(taken, name); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			log.WithField("file", taken).Warn("cannot restore the lock file of another sync, a new lock file was created meanwhile")
+			return ErrStateLocked
+		}
+		return fmt.Errorf("disk: error restoring lock file: %w", err)
+	}
+
+	// the lock file restored is the same file, so the name taken is only another link to it
+	if sameFile(name, taken) {
+		if err := os.Remove(taken); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("disk: error removing lock file link: %w", err)
+		}
+	}
+
+	if readErr != nil {
+		return ErrStateLocked
+	}
+	return current.lockedError()
+}
+
+// sameFile reports whether the files a and b are the same file, false when any of them can't be read.
+func sameFile(a, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+
+	fb, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(fa, fb)
+}
+
+// readStateFile returns the state stored in the file.
+func (dr *DiskRepository) readStateFile(ctx context.Context, name string) (*model.State, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("disk: error reading state file: %w", err)
+	}
+
+	return dr.decodeState(ctx, data)
+}
+
+// decodeState returns the state stored in data, decrypting and decompressing it when it is
+// encrypted or compressed.
+func (dr *DiskRepository) decodeState(ctx context.Context, data []byte) (*model.State, error) {
+	data, err := decryptState(ctx, dr.cipher, dr.allowUnencrypted, data)
+	if err != nil {
+		return nil, fmt.Errorf("disk: error decrypting state: %w", err)
+	}
+
+	data, err = decompressState(data)
+	if err != nil {
+		return nil, fmt.Errorf("disk: error decompressing state: %w", err)
+	}
+
+	var state model.State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("disk: error unmarshalling state: %w", err)
+	}
+
+	return &state, nil
+}
+
+// readLockFile returns the lock stored in the lock file.
+func readLockFile(name string) (*stateLock, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("disk: error reading lock file: %w", err)
+	}
+
+	var l stateLock
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("disk: error unmarshalling lock file: %w", err)
+	}
+
+	return &l, nil
+}
+
 // ErrStateFileEmpty, the state file is empty.
 type ErrStateFileEmpty struct {
 	Message string
@@ -0,0 +1,47 @@
+package repository
+
+import "time"
+
+// DiskRepositoryOption is a function that can be used to configure a DiskRepository
+// using the functional options pattern.
+type DiskRepositoryOption func(*DiskRepository)
+
+// WithDiskHistory sets the number of previous states kept in the history, 0 disables the history.
+// The history is kept only when the state file is a file.
+func WithDiskHistory(n int) DiskRepositoryOption {
+	return func(dr *DiskRepository) {
+		dr.history = n
+	}
+}
+
+// WithDiskLockTTL sets the time after which the lock of the state expires when the sync holding it
+// didn't release it.
+func WithDiskLockTTL(ttl time.Duration) DiskRepositoryOption {
+	return func(dr *DiskRepository) {
+		dr.lockTTL = ttl
+	}
+}
+
+// WithDiskCipher sets the cipher encrypting the state, a KeyCipher from a key file or a PassphraseCipher,
+// the states stored without encryption are refused unless WithDiskAllowUnencrypted is set.
+func WithDiskCipher(c StateCipher) DiskRepositoryOption {
+	return func(dr *DiskRepository) {
+		dr.cipher = c
+	}
+}
+
+// WithDiskAllowUnencrypted reads the states stored without encryption when there is a cipher,
+// to migrate the states stored before enabling the encryption.
+func WithDiskAllowUnencrypted(allow bool) DiskRepositoryOption {
+	return func(dr *DiskRepository) {
+		dr.allowUnencrypted = allow
+	}
+}
+
+// WithDiskCompression sets the compression of the state stored, [none|gzip|zstd], the states are read with
+// any compression, or without it.
+func WithDiskCompression(compression string) DiskRepositoryOption {
+	return func(dr *DiskRepository) {
+		dr.compression = compression
+	}
+}
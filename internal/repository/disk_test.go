@@ -1,10 +1,13 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/slashdevops/idp-scim-sync/internal/model"
 	"github.com/stretchr/testify/assert"
@@ -146,3 +149,208 @@ func TestStateRepository_SetState(t *testing.T) {
 		stateFile.Close()
 	})
 }
+
+func TestStateRepository_Lock(t *testing.T) {
+	t.Run("Lock file", func(t *testing.T) {
+		stateFile, err := os.Create(filepath.Join(t.TempDir(), stateFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stateFile.Close()
+
+		repo, err := NewDiskRepository(stateFile)
+		assert.NoError(t, err)
+
+		other, err := NewDiskRepository(stateFile)
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.Lock(context.TODO()))
+		assert.FileExists(t, stateFile.Name()+".lock")
+
+		assert.ErrorIs(t, other.Lock(context.TODO()), ErrStateLocked)
+		assert.ErrorIs(t, repo.Lock(context.TODO()), ErrStateLocked)
+
+		assert.NoError(t, repo.Unlock(context.TODO()))
+		assert.NoFileExists(t, stateFile.Name()+".lock")
+		assert.ErrorIs(t, repo.Unlock(context.TODO()), ErrStateNotLocked)
+
+		assert.NoError(t, other.Lock(context.TODO()))
+		assert.NoError(t, other.Unlock(context.TODO()))
+	})
+
+	t.Run("Expired lock file", func(t *testing.T) {
+		stateFile, err := os.Create(filepath.Join(t.TempDir(), stateFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stateFile.Close()
+
+		expired, err := newStateLock(-time.Minute)
+		assert.NoError(t, err)
+		assert.NoError(t, createLockFile(stateFile.Name()+".lock", expired))
+
+		repo, err := NewDiskRepository(stateFile)
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.Lock(context.TODO()))
+		assert.NoError(t, repo.Unlock(context.TODO()))
+
+		files, err := os.ReadDir(filepath.Dir(stateFile.Name()))
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+	})
+
+	t.Run("Lock file with the lock ttl", func(t *testing.T) {
+		stateFile, err := os.Create(filepath.Join(t.TempDir(), stateFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stateFile.Close()
+
+		repo, err := NewDiskRepository(stateFile, WithDiskLockTTL(time.Minute))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.Lock(context.TODO()))
+
+		l, err := readLockFile(stateFile.Name() + ".lock")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Minute, l.ExpiresAt.Sub(l.CreatedAt))
+		assert.NoError(t, repo.Unlock(context.TODO()))
+
+		// the lock of a sync that didn't release it expires after the ttl
+		repo, err = NewDiskRepository(stateFile, WithDiskLockTTL(-time.Minute))
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Lock(context.TODO()))
+
+		other, err := NewDiskRepository(stateFile)
+		assert.NoError(t, err)
+		assert.NoError(t, other.Lock(context.TODO()))
+		assert.NoError(t, other.Unlock(context.TODO()))
+	})
+
+	t.Run("Expired lock file taken over by another sync after it was read", func(t *testing.T) {
+		dir := t.TempDir()
+		name := filepath.Join(dir, stateFileName+".lock")
+
+		expired, err := newStateLock(-time.Minute)
+		assert.NoError(t, err)
+
+		// another sync took it over since it was read
+		other, err := newStateLock(time.Hour)
+		assert.NoError(t, err)
+		assert.NoError(t, createLockFile(name, other))
+
+		assert.ErrorIs(t, takeLockFile(name, name+".taken", expired), ErrStateLocked)
+
+		current, err := readLockFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, other.ID, current.ID)
+		assert.NoFileExists(t, name+".taken")
+
+		// the lock file was written again with another expiration
+		assert.NoError(t, os.Remove(name))
+		renewed := *expired
+		renewed.ExpiresAt = time.Now().Add(time.Hour)
+		assert.NoError(t, createLockFile(name, &renewed))
+
+		assert.ErrorIs(t, takeLockFile(name, name+".taken", expired), ErrStateLocked)
+
+		current, err = readLockFile(name)
+		assert.NoError(t, err)
+		assert.True(t, renewed.ExpiresAt.Equal(current.ExpiresAt))
+		assert.NoFileExists(t, name+".taken")
+
+		// another sync renamed it before
+		assert.NoError(t, os.Remove(name))
+		assert.ErrorIs(t, takeLockFile(name, name+".taken", expired), ErrStateLocked)
+	})
+
+	t.Run("Lock file taken over by another sync", func(t *testing.T) {
+		stateFile, err := os.Create(filepath.Join(t.TempDir(), stateFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stateFile.Close()
+
+		repo, err := NewDiskRepository(stateFile)
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.Lock(context.TODO()))
+
+		assert.NoError(t, os.Remove(stateFile.Name()+".lock"))
+		other, err := newStateLock(time.Hour)
+		assert.NoError(t, err)
+		assert.NoError(t, createLockFile(stateFile.Name()+".lock", other))
+
+		assert.ErrorIs(t, repo.Unlock(context.TODO()), ErrLockLost)
+		assert.FileExists(t, stateFile.Name()+".lock")
+	})
+
+	t.Run("State not stored in a file", func(t *testing.T) {
+		repo, err := NewDiskRepository(&bytes.Buffer{})
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.Lock(context.TODO()))
+		assert.ErrorIs(t, repo.Lock(context.TODO()), ErrStateLocked)
+		assert.NoError(t, repo.Unlock(context.TODO()))
+	})
+}
+
+func TestStateRepository_History(t *testing.T) {
+	t.Run("Keep the newest states", func(t *testing.T) {
+		stateFile, err := os.Create(filepath.Join(t.TempDir(), stateFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stateFile.Close()
+
+		repo, err := NewDiskRepository(stateFile, WithDiskHistory(2))
+		assert.NoError(t, err)
+
+		for _, lastSync := range []string{"2022-01-01T00:00:00Z", "2022-01-02T00:00:00Z", "2022-01-03T00:00:00Z"} {
+			assert.NoError(t, repo.SetState(context.TODO(), testState(lastSync)))
+		}
+
+		versions, err := repo.ListStates(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, []*model.StateVersion{
+			{ID: "1", LastSync: "2022-01-03T00:00:00Z"},
+			{ID: "2", LastSync: "2022-01-02T00:00:00Z"},
+		}, versions)
+		assert.NoFileExists(t, stateFile.Name()+".3")
+
+		state, err := repo.GetStateVersion(context.TODO(), "2")
+		assert.NoError(t, err)
+		assert.Equal(t, testState("2022-01-02T00:00:00Z"), state)
+
+		for _, id := range []string{"0", "3", "latest"} {
+			_, err = repo.GetStateVersion(context.TODO(), id)
+			assert.ErrorIs(t, err, ErrStateVersionNotFound)
+		}
+	})
+
+	t.Run("State not stored in a file", func(t *testing.T) {
+		repo, err := NewDiskRepository(&bytes.Buffer{}, WithDiskHistory(2))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.SetState(context.TODO(), testState("2022-01-01T00:00:00Z")))
+
+		versions, err := repo.ListStates(context.TODO())
+		assert.NoError(t, err)
+		assert.Empty(t, versions)
+
+		_, err = repo.GetStateVersion(context.TODO(), "1")
+		assert.ErrorIs(t, err, ErrStateVersionNotFound)
+	})
+}
+
+func TestStateRepository_GetState_SchemaVersion(t *testing.T) {
+	t.Run("Newer schema version", func(t *testing.T) {
+		repo, err := NewDiskRepository(bytes.NewBufferString(`{"schemaVersion":"99.0.0","lastSync":"2022-01-01T00:00:00Z"}`))
+		assert.NoError(t, err)
+
+		state, err := repo.GetState(context.TODO())
+		assert.ErrorIs(t, err, model.ErrStateSchemaVersionUnsupported)
+		assert.Nil(t, state)
+	})
+}
@@ -0,0 +1,606 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+)
+
+// Consume dynamodb.Client
+
+// DefaultDynamoDBChunkSize is the maximum size of the state stored in a single item, below the
+// DynamoDB item size limit of 400 KB. Bigger states are split in several chunk items.
+const DefaultDynamoDBChunkSize = 350 * 1024
+
+var (
+	// ErrDynamoDBClientNil is returned when dynamodb client is nil
+	ErrDynamoDBClientNil = errors.New("dynamodb: AWS DynamoDB Client is nil")
+
+	// ErrOptionWithDynamoDBTableNil is returned when WithDynamoDBTable option is nil
+	ErrOptionWithDynamoDBTableNil = errors.New("dynamodb: option WithDynamoDBTable is nil")
+
+	// ErrOptionWithDynamoDBStateIDNil is returned when WithDynamoDBStateID option is nil
+	ErrOptionWithDynamoDBStateIDNil = errors.New("dynamodb: option WithDynamoDBStateID is nil")
+
+	// ErrStateNotFound is returned when there is no state stored yet
+	ErrStateNotFound = errors.New("repository: state not found")
+
+	// ErrStateCorrupted is returned when the chunks of the state don't match the state item
+	ErrStateCorrupted = errors.New("repository: state chunks don't match the state")
+)
+
+// Attributes of the items stored in the DynamoDB table.
+const (
+	dynamoDBAttrID            = "id"
+	dynamoDBAttrVersion       = "version"
+	dynamoDBAttrChunks        = "chunks"
+	dynamoDBAttrChunksID      = "chunksId"
+	dynamoDBAttrSize          = "size"
+	dynamoDBAttrData          = "data"
+	dynamoDBAttrHashCode      = "hashCode"
+	dynamoDBAttrLastSync      = "lastSync"
+	dynamoDBAttrCodeVersion   = "codeVersion"
+	dynamoDBAttrSchemaVersion = "schemaVersion"
+	dynamoDBAttrLockID        = "lockId"
+	dynamoDBAttrOwner         = "owner"
+	dynamoDBAttrCreatedAt     = "createdAt"
+	dynamoDBAttrExpiresAt     = "expiresAt"
+)
+
+//go:generate go run github.com/golang/mock/mockgen@v1.6.0 -package=mocks -destination=../../mocks/repository/dynamodb_mocks.go -source=dynamodb.go DynamoDBClientAPI
+
+// DynamoDBClientAPI is an interface to consume DynamoDB client methods
+type DynamoDBClientAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+// DynamoDBRepository represent a repository that stores the state in a DynamoDB table and implements
+// core.StateRepository interface. The table must have a partition key named id of type string.
+//
+// The state is stored in chunk items, with the id of the state, a random chunks id and the number of the chunk,
+// and the state item, with the id of the state, pointing to the chunks of the current state.
+// The state item has a version that is incremented every time the state is stored, and it is written only
+// when the version didn't change since the state was read, so two syncs can't overwrite the state of each other.
+// The lock of the state is an item with the id of the state and the suffix #lock.
+// When the history is enabled, every state stored is also kept in a history item, with the id of the state,
+// the suffix #history# and the version of the state, pointing to its chunks, which are removed with it.
+type DynamoDBRepository struct {
+	table     string
+	stateID   string
+	lockTTL   time.Duration
+	chunkSize int
+	history   int
+	cipher    StateCipher
+	client    DynamoDBClientAPI
+
+	// compression of the state stored, the states are read with any compression
+	compression string
+
+	// allowUnencrypted reads the states not encrypted when there is a cipher, to migrate them
+	allowUnencrypted bool
+
+	// version, chunksID and chunks of the state read, used to store the next one
+	version  int64
+	chunksID string
+	chunks   int
+
+	// lockID is the id of the lock held by this repository, empty when it is not locked
+	lockID string
+}
+
+// NewDynamoDBRepository returns a new DynamoDBRepository
+func NewDynamoDBRepository(client DynamoDBClientAPI, opts ...DynamoDBRepositoryOption) (*DynamoDBRepository, error) {
+	if client == nil {
+		return nil, ErrDynamoDBClientNil
+	}
+
+	r := &DynamoDBRepository{
+		client:    client,
+		lockTTL:   DefaultLockTTL,
+		chunkSize: DefaultDynamoDBChunkSize,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.table == "" {
+		return nil, ErrOptionWithDynamoDBTableNil
+	}
+
+	if r.stateID == "" {
+		return nil, ErrOptionWithDynamoDBStateIDNil
+	}
+
+	if err := validCompression(r.compression); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetState returns the state from the repository, or ErrStateNotFound when there is no state stored yet
+func (r *DynamoDBRepository) GetState(ctx context.Context) (*model.State, error) {
+	item, err := r.getItem(ctx, r.stateID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		r.version, r.chunksID, r.chunks = 0, "", 0
+		return nil, ErrStateNotFound
+	}
+
+	version, err := numberAttr(item, dynamoDBAttrVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	state, chunks, err := r.readState(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+
+	r.version, r.chunksID, r.chunks = version, stringAttr(item, dynamoDBAttrChunksID), chunks
+
+	return state, nil
+}
+
+// SetState stores the state in the repository, it returns ErrStateConflict when the state was stored
+// by another sync after this repository read it with GetState
+func (r *DynamoDBRepository) SetState(ctx context.Context, state *model.State) error {
+	if state == nil {
+		return ErrStateNil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("dynamodb: error marshaling state: %w", err)
+	}
+
+	data, err = compressState(r.compression, data)
+	if err != nil {
+		return fmt.Errorf("dynamodb: error compressing state: %w", err)
+	}
+
+	data, err = encryptState(ctx, r.cipher, data)
+	if err != nil {
+		return fmt.Errorf("dynamodb: error encrypting state: %w", err)
+	}
+
+	chunksID, err := randomID()
+	if err != nil {
+		return fmt.Errorf("dynamodb: error generating chunks id: %w", err)
+	}
+
+	// the chunks are written before the state item pointing to them, so the state read is always complete
+	chunks := 0
+	for start := 0; start < len(data) || chunks == 0; start += r.chunkSize {
+		end := start + r.chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		_, err := r.client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(r.table),
+			Item: map[string]types.AttributeValue{
+				dynamoDBAttrID:   &types.AttributeValueMemberS{Value: r.chunkID(chunksID, chunks)},
+				dynamoDBAttrData: &types.AttributeValueMemberB{Value: data[start:end]},
+			},
+		})
+		if err != nil {
+			r.deleteChunks(ctx, chunksID, chunks)
+			return fmt.Errorf("dynamodb: error putting state chunk: %w", err)
+		}
+		chunks++
+	}
+
+	version := r.version + 1
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(r.table),
+		Item: map[string]types.AttributeValue{
+			dynamoDBAttrID:            &types.AttributeValueMemberS{Value: r.stateID},
+			dynamoDBAttrVersion:       &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
+			dynamoDBAttrChunksID:      &types.AttributeValueMemberS{Value: chunksID},
+			dynamoDBAttrChunks:        &types.AttributeValueMemberN{Value: strconv.Itoa(chunks)},
+			dynamoDBAttrSize:          &types.AttributeValueMemberN{Value: strconv.Itoa(len(data))},
+			dynamoDBAttrHashCode:      &types.AttributeValueMemberS{Value: state.HashCode},
+			dynamoDBAttrLastSync:      &types.AttributeValueMemberS{Value: state.LastSync},
+			dynamoDBAttrCodeVersion:   &types.AttributeValueMemberS{Value: state.CodeVersion},
+			dynamoDBAttrSchemaVersion: &types.AttributeValueMemberS{Value: state.SchemaVersion},
+		},
+	}
+
+	if r.version == 0 {
+		input.ConditionExpression = aws.String("attribute_not_exists(#id)")
+		input.ExpressionAttributeNames = map[string]string{"#id": dynamoDBAttrID}
+	} else {
+		input.ConditionExpression = aws.String("#version = :version")
+		input.ExpressionAttributeNames = map[string]string{"#version": dynamoDBAttrVersion}
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":version": &types.AttributeValueMemberN{Value: strconv.FormatInt(r.version, 10)},
+		}
+	}
+
+	if _, err := r.client.PutItem(ctx, input); err != nil {
+		r.deleteChunks(ctx, chunksID, chunks)
+
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrStateConflict
+		}
+		return fmt.Errorf("dynamodb: error putting state: %w", err)
+	}
+
+	// the chunks of the previous state are not used anymore, unless it is kept in the history
+	if !r.inHistory(ctx, r.version) {
+		r.deleteChunks(ctx, r.chunksID, r.chunks)
+	}
+
+	if r.history > 0 {
+		r.keepState(ctx, input.Item, version)
+	}
+
+	r.version, r.chunksID, r.chunks = version, chunksID, chunks
+
+	return nil
+}
+
+// ListStates returns the states kept in the history, the newest first.
+func (r *DynamoDBRepository) ListStates(ctx context.Context) ([]*model.StateVersion, error) {
+	versions := make([]*model.StateVersion, 0)
+
+	if r.history == 0 {
+		return versions, nil
+	}
+
+	item, err := r.getItem(ctx, r.stateID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return versions, nil
+	}
+
+	current, err := numberAttr(item, dynamoDBAttrVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	for v := current; v > 0 && v > current-int64(r.history); v-- {
+		item, err := r.getItem(ctx, r.historyItemID(v))
+		if err != nil {
+			return nil, err
+		}
+		if item == nil {
+			break
+		}
+
+		versions = append(versions, &model.StateVersion{
+			ID:       strconv.FormatInt(v, 10),
+			LastSync: stringAttr(item, dynamoDBAttrLastSync),
+		})
+	}
+
+	return versions, nil
+}
+
+// GetStateVersion returns the state with the given id from the history, the version of the state,
+// it returns ErrStateVersionNotFound when the state is not in the history.
+func (r *DynamoDBRepository) GetStateVersion(ctx context.Context, id string) (*model.State, error) {
+	v, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || v < 1 {
+		return nil, ErrStateVersionNotFound
+	}
+
+	item, err := r.getItem(ctx, r.historyItemID(v))
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, ErrStateVersionNotFound
+	}
+
+	state, _, err := r.readState(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// Lock locks the state creating the lock item, it returns ErrStateLocked when the lock item exists
+// and it didn't expire. An expired lock item is replaced.
+func (r *DynamoDBRepository) Lock(ctx context.Context) error {
+	l, err := newStateLock(r.lockTTL)
+	if err != nil {
+		return err
+	}
+
+	out, err := r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.table),
+		Item: map[string]types.AttributeValue{
+			dynamoDBAttrID:        &types.AttributeValueMemberS{Value: r.lockItemID()},
+			dynamoDBAttrLockID:    &types.AttributeValueMemberS{Value: l.ID},
+			dynamoDBAttrOwner:     &types.AttributeValueMemberS{Value: l.Owner},
+			dynamoDBAttrCreatedAt: &types.AttributeValueMemberN{Value: strconv.FormatInt(l.CreatedAt.Unix(), 10)},
+			dynamoDBAttrExpiresAt: &types.AttributeValueMemberN{Value: strconv.FormatInt(l.ExpiresAt.Unix(), 10)},
+		},
+		ConditionExpression:      aws.String("attribute_not_exists(#id) OR #expiresAt <= :now"),
+		ExpressionAttributeNames: map[string]string{"#id": dynamoDBAttrID, "#expiresAt": dynamoDBAttrExpiresAt},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)},
+		},
+		ReturnValues:                        types.ReturnValueAllOld,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return lockFromItem(ccf.Item).lockedError()
+		}
+		return fmt.Errorf("dynamodb: error putting lock: %w", err)
+	}
+
+	if len(out.Attributes) > 0 {
+		current := lockFromItem(out.Attributes)
+
+		log.WithFields(log.Fields{
+			"owner":     current.Owner,
+			"createdAt": current.CreatedAt.Format(time.RFC3339),
+			"expiresAt": current.ExpiresAt.Format(time.RFC3339),
+		}).Warn("the lock of the state expired without being released, taking it over")
+	}
+
+	r.lockID = l.ID
+
+	return nil
+}
+
+// Unlock releases the lock deleting the lock item, it returns ErrLockLost when the lock item
+// doesn't belong to this repository anymore because it expired and another sync took it over.
+func (r *DynamoDBRepository) Unlock(ctx context.Context) error {
+	if r.lockID == "" {
+		return ErrStateNotLocked
+	}
+
+	lockID := r.lockID
+	r.lockID = ""
+
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.table),
+		Key: map[string]types.AttributeValue{
+			dynamoDBAttrID: &types.AttributeValueMemberS{Value: r.lockItemID()},
+		},
+		ConditionExpression:      aws.String("#lockId = :lockId"),
+		ExpressionAttributeNames: map[string]string{"#lockId": dynamoDBAttrLockID},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":lockId": &types.AttributeValueMemberS{Value: lockID},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrLockLost
+		}
+		return fmt.Errorf("dynamodb: error deleting lock: %w", err)
+	}
+
+	return nil
+}
+
+// chunkID returns the id of the chunk item number i.
+func (r *DynamoDBRepository) chunkID(chunksID string, i int) string {
+	return fmt.Sprintf("%s#%s#%d", r.stateID, chunksID, i)
+}
+
+// historyItemID returns the id of the item of the state with the given version in the history.
+func (r *DynamoDBRepository) historyItemID(version int64) string {
+	return fmt.Sprintf("%s#history#%d", r.stateID, version)
+}
+
+// readState returns the state stored in the chunks the given state item points to,
+// and the number of chunks.
+func (r *DynamoDBRepository) readState(ctx context.Context, item map[string]types.AttributeValue) (*model.State, int, error) {
+	chunks, err := numberAttr(item, dynamoDBAttrChunks)
+	if err != nil {
+		return nil, 0, err
+	}
+	size, err := numberAttr(item, dynamoDBAttrSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	chunksID := stringAttr(item, dynamoDBAttrChunksID)
+
+	var data bytes.Buffer
+	for i := 0; i < int(chunks); i++ {
+		chunk, err := r.getItem(ctx, r.chunkID(chunksID, i))
+		if err != nil {
+			return nil, 0, err
+		}
+		if chunk == nil {
+			return nil, 0, fmt.Errorf("%w: chunk %d of %d not found", ErrStateCorrupted, i+1, chunks)
+		}
+
+		b, ok := chunk[dynamoDBAttrData].(*types.AttributeValueMemberB)
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: chunk %d of %d without data", ErrStateCorrupted, i+1, chunks)
+		}
+		data.Write(b.Value)
+	}
+
+	if int64(data.Len()) != size {
+		return nil, 0, fmt.Errorf("%w: size %d, expected %d", ErrStateCorrupted, data.Len(), size)
+	}
+
+	plaintext, err := decryptState(ctx, r.cipher, r.allowUnencrypted, data.Bytes())
+	if err != nil {
+		return nil, 0, fmt.Errorf("dynamodb: error decrypting state: %w", err)
+	}
+
+	plaintext, err = decompressState(plaintext)
+	if err != nil {
+		return nil, 0, fmt.Errorf("dynamodb: error decompressing state: %w", err)
+	}
+
+	var state model.State
+	if err := json.Unmarshal(plaintext, &state); err != nil {
+		return nil, 0, fmt.Errorf("dynamodb: error unmarshalling state: %w", err)
+	}
+
+	return &state, int(chunks), nil
+}
+
+// inHistory returns true when the state with the given version is kept in the history.
+func (r *DynamoDBRepository) inHistory(ctx context.Context, version int64) bool {
+	if r.history == 0 || version == 0 {
+		return false
+	}
+
+	item, err := r.getItem(ctx, r.historyItemID(version))
+	if err != nil {
+		// keeping chunks not used is better than removing the chunks of a state in the history
+		log.WithError(err).Warn("cannot check if the previous state is kept in the history")
+		return true
+	}
+
+	return item != nil
+}
+
+// keepState stores the history item of the state item with the given version and removes the state
+// older than the history size, the state is already stored so the errors are only logged.
+func (r *DynamoDBRepository) keepState(ctx context.Context, stateItem map[string]types.AttributeValue, version int64) {
+	item := make(map[string]types.AttributeValue, len(stateItem))
+	for k, v := range stateItem {
+		item[k] = v
+	}
+	item[dynamoDBAttrID] = &types.AttributeValueMemberS{Value: r.historyItemID(version)}
+
+	_, err := r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.table),
+		Item:      item,
+	})
+	if err != nil {
+		log.WithError(err).WithField("version", version).Warn("cannot keep the state in the history")
+	}
+
+	oldest := version - int64(r.history)
+	if oldest < 1 {
+		return
+	}
+
+	old, err := r.getItem(ctx, r.historyItemID(oldest))
+	if err != nil {
+		log.WithError(err).WithField("version", oldest).Warn("cannot remove the old state from the history")
+		return
+	}
+	if old == nil {
+		return
+	}
+
+	chunks, err := numberAttr(old, dynamoDBAttrChunks)
+	if err != nil {
+		log.WithError(err).WithField("version", oldest).Warn("cannot remove the old state from the history")
+		return
+	}
+	r.deleteChunks(ctx, stringAttr(old, dynamoDBAttrChunksID), int(chunks))
+
+	_, err = r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.table),
+		Key: map[string]types.AttributeValue{
+			dynamoDBAttrID: &types.AttributeValueMemberS{Value: r.historyItemID(oldest)},
+		},
+	})
+	if err != nil {
+		log.WithError(err).WithField("version", oldest).Warn("cannot remove the old state from the history")
+	}
+}
+
+// lockItemID returns the id of the lock item of the state.
+func (r *DynamoDBRepository) lockItemID() string {
+	return r.stateID + "#lock"
+}
+
+// getItem returns the item with the given id using a strongly consistent read, or nil when it doesn't exist.
+func (r *DynamoDBRepository) getItem(ctx context.Context, id string) (map[string]types.AttributeValue, error) {
+	out, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.table),
+		Key: map[string]types.AttributeValue{
+			dynamoDBAttrID: &types.AttributeValueMemberS{Value: id},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dynamodb: error getting item: table: %s, error: %w", r.table, err)
+	}
+
+	if len(out.Item) == 0 {
+		return nil, nil
+	}
+
+	return out.Item, nil
+}
+
+// deleteChunks deletes the chunk items, the errors are only logged because the chunks not
+// deleted are not used anymore.
+func (r *DynamoDBRepository) deleteChunks(ctx context.Context, chunksID string, chunks int) {
+	for i := 0; i < chunks; i++ {
+		_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(r.table),
+			Key: map[string]types.AttributeValue{
+				dynamoDBAttrID: &types.AttributeValueMemberS{Value: r.chunkID(chunksID, i)},
+			},
+		})
+		if err != nil {
+			log.WithError(err).WithField("id", r.chunkID(chunksID, i)).Warn("cannot delete the state chunk not used anymore")
+		}
+	}
+}
+
+// lockFromItem returns the lock stored in the lock item.
+func lockFromItem(item map[string]types.AttributeValue) *stateLock {
+	createdAt, _ := numberAttr(item, dynamoDBAttrCreatedAt)
+	expiresAt, _ := numberAttr(item, dynamoDBAttrExpiresAt)
+
+	return &stateLock{
+		ID:        stringAttr(item, dynamoDBAttrLockID),
+		Owner:     stringAttr(item, dynamoDBAttrOwner),
+		CreatedAt: time.Unix(createdAt, 0).UTC(),
+		ExpiresAt: time.Unix(expiresAt, 0).UTC(),
+	}
+}
+
+// stringAttr returns the value of the string attribute, empty when it doesn't exist.
+func stringAttr(item map[string]types.AttributeValue, name string) string {
+	if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+		return v.Value
+	}
+
+	return ""
+}
+
+// numberAttr returns the value of the number attribute.
+func numberAttr(item map[string]types.AttributeValue, name string) (int64, error) {
+	v, ok := item[name].(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, fmt.Errorf("dynamodb: attribute %s not found or not a number", name)
+	}
+
+	n, err := strconv.ParseInt(v.Value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("dynamodb: error parsing attribute %s: %w", name, err)
+	}
+
+	return n, nil
+}
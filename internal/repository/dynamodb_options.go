@@ -0,0 +1,60 @@
+package repository
+
+import "time"
+
+// DynamoDBRepositoryOption is a function that can be used to configure a DynamoDBRepository
+// using the functional options pattern.
+type DynamoDBRepositoryOption func(*DynamoDBRepository)
+
+// WithDynamoDBTable sets the name of the DynamoDB table.
+func WithDynamoDBTable(table string) DynamoDBRepositoryOption {
+	return func(r *DynamoDBRepository) {
+		r.table = table
+	}
+}
+
+// WithDynamoDBStateID sets the id of the state in the DynamoDB table, so several syncs can share the table.
+func WithDynamoDBStateID(id string) DynamoDBRepositoryOption {
+	return func(r *DynamoDBRepository) {
+		r.stateID = id
+	}
+}
+
+// WithDynamoDBLockTTL sets the time after which the lock of the state expires when the sync holding it
+// didn't release it.
+func WithDynamoDBLockTTL(ttl time.Duration) DynamoDBRepositoryOption {
+	return func(r *DynamoDBRepository) {
+		r.lockTTL = ttl
+	}
+}
+
+// WithDynamoDBHistory sets the number of previous states kept in the history, 0 disables the history.
+func WithDynamoDBHistory(n int) DynamoDBRepositoryOption {
+	return func(r *DynamoDBRepository) {
+		r.history = n
+	}
+}
+
+// WithDynamoDBCipher sets the cipher encrypting the state, the states stored without encryption are refused
+// unless WithDynamoDBAllowUnencrypted is set.
+func WithDynamoDBCipher(c StateCipher) DynamoDBRepositoryOption {
+	return func(r *DynamoDBRepository) {
+		r.cipher = c
+	}
+}
+
+// WithDynamoDBAllowUnencrypted reads the states stored without encryption when there is a cipher,
+// to migrate the states stored before enabling the encryption.
+func WithDynamoDBAllowUnencrypted(allow bool) DynamoDBRepositoryOption {
+	return func(r *DynamoDBRepository) {
+		r.allowUnencrypted = allow
+	}
+}
+
+// WithDynamoDBCompression sets the compression of the state stored, [none|gzip|zstd], the states are read with
+// any compression, or without it. The compressed states need fewer chunks.
+func WithDynamoDBCompression(compression string) DynamoDBRepositoryOption {
+	return func(r *DynamoDBRepository) {
+		r.compression = compression
+	}
+}
@@ -0,0 +1,337 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/golang/mock/gomock"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	mocks "github.com/slashdevops/idp-scim-sync/mocks/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDynamoDB is an in-memory DynamoDB table with a partition key id, evaluating only
+// the condition expressions used by DynamoDBRepository.
+type fakeDynamoDB struct {
+	mu    sync.Mutex
+	items map[string]map[string]types.AttributeValue
+}
+
+func newFakeDynamoDB() *fakeDynamoDB {
+	return &fakeDynamoDB{items: make(map[string]map[string]types.AttributeValue)}
+}
+
+func (f *fakeDynamoDB) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return &dynamodb.GetItemOutput{Item: f.items[stringAttr(params.Key, dynamoDBAttrID)]}, nil
+}
+
+func (f *fakeDynamoDB) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := stringAttr(params.Item, dynamoDBAttrID)
+	old := f.items[id]
+
+	if !f.condition(aws.ToString(params.ConditionExpression), old, params.ExpressionAttributeValues) {
+		e := &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		if params.ReturnValuesOnConditionCheckFailure == types.ReturnValuesOnConditionCheckFailureAllOld {
+			e.Item = old
+		}
+		return nil, e
+	}
+
+	f.items[id] = params.Item
+
+	out := &dynamodb.PutItemOutput{}
+	if params.ReturnValues == types.ReturnValueAllOld {
+		out.Attributes = old
+	}
+
+	return out, nil
+}
+
+func (f *fakeDynamoDB) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := stringAttr(params.Key, dynamoDBAttrID)
+
+	if !f.condition(aws.ToString(params.ConditionExpression), f.items[id], params.ExpressionAttributeValues) {
+		return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+	}
+
+	delete(f.items, id)
+
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) condition(expr string, item map[string]types.AttributeValue, values map[string]types.AttributeValue) bool {
+	switch expr {
+	case "":
+		return true
+	case "attribute_not_exists(#id)":
+		return item == nil
+	case "#version = :version":
+		return item != nil && stringOf(item[dynamoDBAttrVersion]) == stringOf(values[":version"])
+	case "attribute_not_exists(#id) OR #expiresAt <= :now":
+		if item == nil {
+			return true
+		}
+		expiresAt, _ := strconv.ParseInt(stringOf(item[dynamoDBAttrExpiresAt]), 10, 64)
+		now, _ := strconv.ParseInt(stringOf(values[":now"]), 10, 64)
+		return expiresAt <= now
+	case "#lockId = :lockId":
+		return item != nil && stringOf(item[dynamoDBAttrLockID]) == stringOf(values[":lockId"])
+	default:
+		panic("unexpected condition expression: " + expr)
+	}
+}
+
+// chunkItems returns the number of chunk items stored.
+func (f *fakeDynamoDB) chunkItems(stateID string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := 0
+	for id := range f.items {
+		if strings.HasPrefix(id, stateID+"#") && !strings.HasSuffix(id, "#lock") && !strings.HasPrefix(id, stateID+"#history#") {
+			n++
+		}
+	}
+
+	return n
+}
+
+func stringOf(v types.AttributeValue) string {
+	switch v := v.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberN:
+		return v.Value
+	default:
+		return ""
+	}
+}
+
+func testState(lastSync string) *model.State {
+	return model.StateBuilder().
+		WithCodeVersion("0.0.1").
+		WithLastSync(lastSync).
+		WithGroups(model.GroupsResultBuilder().WithResources([]*model.Group{
+			model.GroupBuilder().WithIPID("group-1").WithName("group 1").WithEmail("group.1@mail.com").Build(),
+		}).Build()).
+		WithUsers(model.UsersResultBuilder().WithResources([]*model.User{
+			model.UserBuilder().WithIPID("user-1").WithEmail("user.1@mail.com").WithDisplayName("user 1").Build(),
+		}).Build()).
+		WithGroupsMembers(model.GroupsMembersResultBuilder().Build()).
+		Build()
+}
+
+func TestNewDynamoDBRepository(t *testing.T) {
+	t.Run("Should return DynamoDBRepository and no error", func(t *testing.T) {
+		repo, err := NewDynamoDBRepository(newFakeDynamoDB(), WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"))
+		assert.NoError(t, err)
+		assert.NotNil(t, repo)
+	})
+
+	t.Run("Should return an error if no client is provided", func(t *testing.T) {
+		repo, err := NewDynamoDBRepository(nil)
+		assert.ErrorIs(t, err, ErrDynamoDBClientNil)
+		assert.Nil(t, repo)
+	})
+
+	t.Run("Should return an error if no opts WithDynamoDBTable is provided", func(t *testing.T) {
+		repo, err := NewDynamoDBRepository(newFakeDynamoDB(), WithDynamoDBStateID("state"))
+		assert.ErrorIs(t, err, ErrOptionWithDynamoDBTableNil)
+		assert.Nil(t, repo)
+	})
+
+	t.Run("Should return an error if no opts WithDynamoDBStateID is provided", func(t *testing.T) {
+		repo, err := NewDynamoDBRepository(newFakeDynamoDB(), WithDynamoDBTable("MyTable"))
+		assert.ErrorIs(t, err, ErrOptionWithDynamoDBStateIDNil)
+		assert.Nil(t, repo)
+	})
+}
+
+func TestDynamoDBRepository_State(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("Should store the state in chunks and read it", func(t *testing.T) {
+		db := newFakeDynamoDB()
+		repo, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"))
+		assert.NoError(t, err)
+		repo.chunkSize = 100
+
+		state, err := repo.GetState(ctx)
+		assert.ErrorIs(t, err, ErrStateNotFound)
+		assert.Nil(t, state)
+
+		assert.NoError(t, repo.SetState(ctx, testState("2022-01-01T00:00:00Z")))
+		assert.Greater(t, repo.chunks, 1)
+		assert.Equal(t, repo.chunks, db.chunkItems("state"))
+
+		state, err = repo.GetState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, testState("2022-01-01T00:00:00Z"), state)
+
+		// the chunks of the previous state are deleted
+		assert.NoError(t, repo.SetState(ctx, testState("2022-01-02T00:00:00Z")))
+		assert.Equal(t, repo.chunks, db.chunkItems("state"))
+		assert.Equal(t, int64(2), repo.version)
+
+		other, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"))
+		assert.NoError(t, err)
+
+		state, err = other.GetState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "2022-01-02T00:00:00Z", state.LastSync)
+	})
+
+	t.Run("Should return ErrStateConflict when the state was stored by another sync", func(t *testing.T) {
+		db := newFakeDynamoDB()
+		repo, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"))
+		assert.NoError(t, err)
+		other, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"))
+		assert.NoError(t, err)
+
+		_, err = repo.GetState(ctx)
+		assert.ErrorIs(t, err, ErrStateNotFound)
+		_, err = other.GetState(ctx)
+		assert.ErrorIs(t, err, ErrStateNotFound)
+
+		assert.NoError(t, repo.SetState(ctx, testState("2022-01-01T00:00:00Z")))
+		assert.ErrorIs(t, other.SetState(ctx, testState("2022-01-02T00:00:00Z")), ErrStateConflict)
+
+		// the chunks of the state not stored are deleted
+		assert.Equal(t, repo.chunks, db.chunkItems("state"))
+
+		state, err := other.GetState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "2022-01-01T00:00:00Z", state.LastSync)
+		assert.NoError(t, other.SetState(ctx, testState("2022-01-02T00:00:00Z")))
+	})
+
+	t.Run("Should return ErrStateCorrupted when a chunk is missing", func(t *testing.T) {
+		db := newFakeDynamoDB()
+		repo, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"))
+		assert.NoError(t, err)
+		repo.chunkSize = 100
+
+		assert.NoError(t, repo.SetState(ctx, testState("2022-01-01T00:00:00Z")))
+		delete(db.items, repo.chunkID(repo.chunksID, 1))
+
+		_, err = repo.GetState(ctx)
+		assert.ErrorIs(t, err, ErrStateCorrupted)
+	})
+
+	t.Run("Should return error with nil state", func(t *testing.T) {
+		repo, err := NewDynamoDBRepository(newFakeDynamoDB(), WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"))
+		assert.NoError(t, err)
+
+		assert.ErrorIs(t, repo.SetState(ctx, nil), ErrStateNil)
+	})
+
+	t.Run("Should return error", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockDynamoDB := mocks.NewMockDynamoDBClientAPI(mockCtrl)
+		mockDynamoDB.EXPECT().GetItem(ctx, gomock.Any()).Return(nil, errors.New("error"))
+		mockDynamoDB.EXPECT().PutItem(ctx, gomock.Any()).Return(nil, errors.New("error"))
+
+		repo, err := NewDynamoDBRepository(mockDynamoDB, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"))
+		assert.NoError(t, err)
+
+		_, err = repo.GetState(ctx)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrStateNotFound)
+
+		assert.Error(t, repo.SetState(ctx, testState("2022-01-01T00:00:00Z")))
+	})
+}
+
+func TestDynamoDBRepository_History(t *testing.T) {
+	ctx := context.TODO()
+
+	db := newFakeDynamoDB()
+	repo, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"), WithDynamoDBHistory(2))
+	assert.NoError(t, err)
+	repo.chunkSize = 100
+
+	versions, err := repo.ListStates(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+
+	for _, lastSync := range []string{"2022-01-01T00:00:00Z", "2022-01-02T00:00:00Z", "2022-01-03T00:00:00Z"} {
+		assert.NoError(t, repo.SetState(ctx, testState(lastSync)))
+	}
+
+	// the chunks of the oldest state are deleted with its history item
+	assert.Equal(t, 2*repo.chunks, db.chunkItems("state"))
+	assert.NotContains(t, db.items, "state#history#1")
+
+	versions, err = repo.ListStates(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.StateVersion{
+		{ID: "3", LastSync: "2022-01-03T00:00:00Z"},
+		{ID: "2", LastSync: "2022-01-02T00:00:00Z"},
+	}, versions)
+
+	state, err := repo.GetStateVersion(ctx, "2")
+	assert.NoError(t, err)
+	assert.Equal(t, testState("2022-01-02T00:00:00Z"), state)
+
+	for _, id := range []string{"1", "0", "latest"} {
+		_, err = repo.GetStateVersion(ctx, id)
+		assert.ErrorIs(t, err, ErrStateVersionNotFound)
+	}
+}
+
+func TestDynamoDBRepository_Lock(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("Should lock the state until it is unlocked", func(t *testing.T) {
+		db := newFakeDynamoDB()
+		repo, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"))
+		assert.NoError(t, err)
+		other, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.Lock(ctx))
+
+		err = other.Lock(ctx)
+		assert.ErrorIs(t, err, ErrStateLocked)
+		assert.Contains(t, err.Error(), stringAttr(db.items["state#lock"], dynamoDBAttrOwner))
+
+		assert.NoError(t, repo.Unlock(ctx))
+		assert.ErrorIs(t, repo.Unlock(ctx), ErrStateNotLocked)
+
+		assert.NoError(t, other.Lock(ctx))
+		assert.NoError(t, other.Unlock(ctx))
+	})
+
+	t.Run("Should take over the expired lock", func(t *testing.T) {
+		db := newFakeDynamoDB()
+		repo, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"), WithDynamoDBLockTTL(-time.Minute))
+		assert.NoError(t, err)
+		other, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.Lock(ctx))
+		assert.NoError(t, other.Lock(ctx))
+
+		assert.ErrorIs(t, repo.Unlock(ctx), ErrLockLost)
+		assert.NoError(t, other.Unlock(ctx))
+	})
+}
@@ -0,0 +1,355 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Consume kms.Client
+
+// stateKeySize is the size of the keys encrypting the state, AES-256.
+const stateKeySize = 32
+
+var (
+	// ErrStateEncrypted is returned when the state is encrypted and the repository has no cipher to decrypt it
+	ErrStateEncrypted = errors.New("repository: state is encrypted and there is no encryption key configured")
+
+	// ErrStateNotEncrypted is returned when the state is not encrypted and the repository has a cipher,
+	// unless the unencrypted states are allowed to migrate them
+	ErrStateNotEncrypted = errors.New("repository: state is not encrypted and there is an encryption key configured")
+
+	// ErrStateDecrypt is returned when the state can't be decrypted, usually because the key is not the one
+	// used to encrypt it or the state was modified
+	ErrStateDecrypt = errors.New("repository: cannot decrypt the state")
+
+	// ErrKMSClientNil is returned when kms client is nil
+	ErrKMSClientNil = errors.New("kms: AWS KMS Client is nil")
+
+	// ErrKMSKeyIDNil is returned when the KMS key id is empty
+	ErrKMSKeyIDNil = errors.New("kms: key id is empty")
+
+	// ErrInvalidKeySize is returned when the encryption key is not 32 bytes long
+	ErrInvalidKeySize = errors.New("repository: the encryption key must be 32 bytes long")
+
+	// ErrPassphraseEmpty is returned when the encryption passphrase is empty
+	ErrPassphraseEmpty = errors.New("repository: the encryption passphrase is empty")
+)
+
+// encryptedStateMagic is the beginning of the encrypted states, to tell them apart from the
+// states stored without encryption.
+var encryptedStateMagic = []byte("idpscim:encrypted:v1\n")
+
+// kmsEncryptionContext is the KMS encryption context of the data keys encrypting the states.
+var kmsEncryptionContext = map[string]string{"purpose": "idp-scim-sync-state"}
+
+// StateCipher encrypts and decrypts the state stored by the repositories.
+type StateCipher interface {
+	// Encrypt returns the encrypted state.
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+
+	// Decrypt returns the state encrypted with Encrypt.
+	Decrypt(ctx context.Context, data []byte) ([]byte, error)
+}
+
+// envelopeHeader is the header of the encrypted states, with what is needed to get the key back.
+// It is authenticated with the state, so it can't be modified.
+type envelopeHeader struct {
+	Cipher       string `json:"cipher"`
+	KeyID        string `json:"keyId,omitempty"`
+	EncryptedKey []byte `json:"encryptedKey,omitempty"`
+	Salt         []byte `json:"salt,omitempty"`
+	Nonce        []byte `json:"nonce"`
+}
+
+// Names of the ciphers in the header of the encrypted states.
+const (
+	cipherKMS        = "kms"
+	cipherKey        = "key"
+	cipherPassphrase = "passphrase"
+)
+
+//go:generate go run github.com/golang/mock/mockgen@v1.6.0 -package=mocks -destination=../../mocks/repository/kms_mocks.go -source=encryption.go KMSClientAPI
+
+// KMSClientAPI is an interface to consume KMS client methods
+type KMSClientAPI interface {
+	GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// KMSCipher encrypts the state with envelope encryption, every state with a new data key generated
+// by AWS KMS, stored encrypted with the state.
+type KMSCipher struct {
+	client KMSClientAPI
+	keyID  string
+}
+
+// NewKMSCipher returns a new KMSCipher using the KMS key with the given id, ARN or alias
+func NewKMSCipher(client KMSClientAPI, keyID string) (*KMSCipher, error) {
+	if client == nil {
+		return nil, ErrKMSClientNil
+	}
+
+	if keyID == "" {
+		return nil, ErrKMSKeyIDNil
+	}
+
+	return &KMSCipher{client: client, keyID: keyID}, nil
+}
+
+// Encrypt returns the state encrypted with a new data key
+func (c *KMSCipher) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	resp, err := c.client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(c.keyID),
+		KeySpec:           types.DataKeySpecAes256,
+		EncryptionContext: kmsEncryptionContext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kms: error generating data key: %w", err)
+	}
+
+	return seal(resp.Plaintext, &envelopeHeader{
+		Cipher:       cipherKMS,
+		KeyID:        aws.ToString(resp.KeyId),
+		EncryptedKey: resp.CiphertextBlob,
+	}, plaintext)
+}
+
+// Decrypt returns the state decrypting its data key with KMS
+func (c *KMSCipher) Decrypt(ctx context.Context, data []byte) ([]byte, error) {
+	h, header, body, err := openEnvelope(data, cipherKMS)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:             aws.String(c.keyID),
+		CiphertextBlob:    h.EncryptedKey,
+		EncryptionContext: kmsEncryptionContext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kms: error decrypting data key: %w", err)
+	}
+
+	return unseal(resp.Plaintext, h, header, body)
+}
+
+// KeyCipher encrypts the state with a local key.
+type KeyCipher struct {
+	key []byte
+}
+
+// NewKeyCipher returns a new KeyCipher using the given 32 bytes key
+func NewKeyCipher(key []byte) (*KeyCipher, error) {
+	if len(key) != stateKeySize {
+		return nil, ErrInvalidKeySize
+	}
+
+	return &KeyCipher{key: key}, nil
+}
+
+// NewKeyFileCipher returns a new KeyCipher using the key stored in the given file,
+// 32 bytes or their base64 encoding, e.g. created with: openssl rand -base64 32
+func NewKeyFileCipher(name string) (*KeyCipher, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("repository: error reading key file: %w", err)
+	}
+
+	if len(data) == stateKeySize {
+		return NewKeyCipher(data)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil {
+		return nil, ErrInvalidKeySize
+	}
+
+	return NewKeyCipher(key)
+}
+
+// Encrypt returns the state encrypted with the key
+func (c *KeyCipher) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	return seal(c.key, &envelopeHeader{Cipher: cipherKey}, plaintext)
+}
+
+// Decrypt returns the state decrypted with the key
+func (c *KeyCipher) Decrypt(ctx context.Context, data []byte) ([]byte, error) {
+	h, header, body, err := openEnvelope(data, cipherKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return unseal(c.key, h, header, body)
+}
+
+// PassphraseCipher encrypts the state with a key derived from a passphrase with scrypt,
+// using a new random salt for every state.
+type PassphraseCipher struct {
+	passphrase []byte
+}
+
+// NewPassphraseCipher returns a new PassphraseCipher using the given passphrase
+func NewPassphraseCipher(passphrase string) (*PassphraseCipher, error) {
+	if passphrase == "" {
+		return nil, ErrPassphraseEmpty
+	}
+
+	return &PassphraseCipher{passphrase: []byte(passphrase)}, nil
+}
+
+// Encrypt returns the state encrypted with a key derived from the passphrase
+func (c *PassphraseCipher) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("repository: error generating salt: %w", err)
+	}
+
+	key, err := c.deriveKey(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return seal(key, &envelopeHeader{Cipher: cipherPassphrase, Salt: salt}, plaintext)
+}
+
+// Decrypt returns the state decrypted with a key derived from the passphrase
+func (c *PassphraseCipher) Decrypt(ctx context.Context, data []byte) ([]byte, error) {
+	h, header, body, err := openEnvelope(data, cipherPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := c.deriveKey(h.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return unseal(key, h, header, body)
+}
+
+// deriveKey returns the key derived from the passphrase and the salt.
+func (c *PassphraseCipher) deriveKey(salt []byte) ([]byte, error) {
+	key, err := scrypt.Key(c.passphrase, salt, 1<<15, 8, 1, stateKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("repository: error deriving key: %w", err)
+	}
+
+	return key, nil
+}
+
+// encryptState returns the state encrypted with the cipher, or as it is without cipher.
+func encryptState(ctx context.Context, c StateCipher, data []byte) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+
+	return c.Encrypt(ctx, data)
+}
+
+// decryptState returns the state decrypted with the cipher when it is encrypted. A state not encrypted
+// is returned as it is without cipher, or with allowUnencrypted to migrate the states stored before enabling
+// the encryption, otherwise it returns ErrStateNotEncrypted because anybody could have written it.
+func decryptState(ctx context.Context, c StateCipher, allowUnencrypted bool, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, encryptedStateMagic) {
+		if c != nil && !allowUnencrypted {
+			return nil, ErrStateNotEncrypted
+		}
+		return data, nil
+	}
+
+	if c == nil {
+		return nil, ErrStateEncrypted
+	}
+
+	return c.Decrypt(ctx, data)
+}
+
+// seal returns the encrypted state, the magic, the header and the state encrypted with AES-GCM,
+// using the header as additional data.
+func seal(key []byte, h *envelopeHeader, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	h.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(h.Nonce); err != nil {
+		return nil, fmt.Errorf("repository: error generating nonce: %w", err)
+	}
+
+	header, err := json.Marshal(h)
+	if err != nil {
+		return nil, fmt.Errorf("repository: error marshaling encryption header: %w", err)
+	}
+
+	out := make([]byte, 0, len(encryptedStateMagic)+len(header)+1+len(plaintext)+aead.Overhead())
+	out = append(out, encryptedStateMagic...)
+	out = append(out, header...)
+	out = append(out, '\n')
+
+	return aead.Seal(out, h.Nonce, plaintext, header), nil
+}
+
+// openEnvelope returns the header, as it is and parsed, and the encrypted body of the encrypted state,
+// checking it was encrypted with the expected cipher.
+func openEnvelope(data []byte, expected string) (*envelopeHeader, []byte, []byte, error) {
+	rest := bytes.TrimPrefix(data, encryptedStateMagic)
+
+	i := bytes.IndexByte(rest, '\n')
+	if i < 0 {
+		return nil, nil, nil, fmt.Errorf("%w: missing header", ErrStateDecrypt)
+	}
+
+	var h envelopeHeader
+	if err := json.Unmarshal(rest[:i], &h); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: invalid header: %s", ErrStateDecrypt, err)
+	}
+
+	if h.Cipher != expected {
+		return nil, nil, nil, fmt.Errorf("%w: encrypted with the %s cipher, configured the %s cipher", ErrStateDecrypt, h.Cipher, expected)
+	}
+
+	return &h, rest[:i], rest[i+1:], nil
+}
+
+// unseal returns the state decrypted with the key, the header is the additional data.
+func unseal(key []byte, h *envelopeHeader, header []byte, body []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(h.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce", ErrStateDecrypt)
+	}
+
+	plaintext, err := aead.Open(nil, h.Nonce, body, header)
+	if err != nil {
+		return nil, ErrStateDecrypt
+	}
+
+	return plaintext, nil
+}
+
+// newAEAD returns the AES-GCM cipher with the key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("repository: error creating cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
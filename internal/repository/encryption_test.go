@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/golang/mock/gomock"
+	mocks "github.com/slashdevops/idp-scim-sync/mocks/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateCiphers(t *testing.T) {
+	ctx := context.TODO()
+	plaintext := []byte(`{"schemaVersion":"1.0.0","lastSync":"2022-01-01T00:00:00Z"}`)
+	key := bytes.Repeat([]byte{1}, stateKeySize)
+
+	keyCipher, err := NewKeyCipher(key)
+	assert.NoError(t, err)
+
+	passphraseCipher, err := NewPassphraseCipher("my secret passphrase")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		cipher StateCipher
+		other  StateCipher
+	}{
+		{name: "key", cipher: keyCipher, other: &KeyCipher{key: bytes.Repeat([]byte{2}, stateKeySize)}},
+		{name: "passphrase", cipher: passphraseCipher, other: &PassphraseCipher{passphrase: []byte("other passphrase")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.cipher.Encrypt(ctx, plaintext)
+			assert.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(data, encryptedStateMagic))
+			assert.NotContains(t, string(data), "lastSync")
+
+			got, err := tt.cipher.Decrypt(ctx, data)
+			assert.NoError(t, err)
+			assert.Equal(t, plaintext, got)
+
+			// a new nonce every time
+			again, err := tt.cipher.Encrypt(ctx, plaintext)
+			assert.NoError(t, err)
+			assert.NotEqual(t, data, again)
+
+			_, err = tt.other.Decrypt(ctx, data)
+			assert.ErrorIs(t, err, ErrStateDecrypt)
+
+			tampered := append([]byte{}, data...)
+			tampered[len(tampered)-1] ^= 0xff
+			_, err = tt.cipher.Decrypt(ctx, tampered)
+			assert.ErrorIs(t, err, ErrStateDecrypt)
+		})
+	}
+
+	t.Run("cipher not used to encrypt", func(t *testing.T) {
+		data, err := keyCipher.Encrypt(ctx, plaintext)
+		assert.NoError(t, err)
+
+		_, err = passphraseCipher.Decrypt(ctx, data)
+		assert.ErrorIs(t, err, ErrStateDecrypt)
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		_, err := NewKeyCipher([]byte("short"))
+		assert.ErrorIs(t, err, ErrInvalidKeySize)
+
+		_, err = NewPassphraseCipher("")
+		assert.ErrorIs(t, err, ErrPassphraseEmpty)
+
+		_, err = NewKMSCipher(nil, "alias/idpscim")
+		assert.ErrorIs(t, err, ErrKMSClientNil)
+	})
+}
+
+func TestNewKeyFileCipher(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, stateKeySize)
+	dir := t.TempDir()
+
+	raw := filepath.Join(dir, "raw.key")
+	assert.NoError(t, os.WriteFile(raw, key, 0o600))
+
+	encoded := filepath.Join(dir, "base64.key")
+	assert.NoError(t, os.WriteFile(encoded, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600))
+
+	invalid := filepath.Join(dir, "invalid.key")
+	assert.NoError(t, os.WriteFile(invalid, []byte("not a key"), 0o600))
+
+	for _, name := range []string{raw, encoded} {
+		c, err := NewKeyFileCipher(name)
+		assert.NoError(t, err)
+		assert.Equal(t, key, c.key)
+	}
+
+	_, err := NewKeyFileCipher(invalid)
+	assert.ErrorIs(t, err, ErrInvalidKeySize)
+
+	_, err = NewKeyFileCipher(filepath.Join(dir, "missing.key"))
+	assert.Error(t, err)
+}
+
+func TestKMSCipher(t *testing.T) {
+	ctx := context.TODO()
+	plaintext := []byte(`{"schemaVersion":"1.0.0"}`)
+	dataKey := bytes.Repeat([]byte{3}, stateKeySize)
+	encryptedKey := []byte("encrypted data key")
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockKMS := mocks.NewMockKMSClientAPI(mockCtrl)
+	mockKMS.EXPECT().GenerateDataKey(ctx, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+			assert.Equal(t, "alias/idpscim", aws.ToString(params.KeyId))
+			assert.Equal(t, kmsEncryptionContext, params.EncryptionContext)
+			return &kms.GenerateDataKeyOutput{
+				KeyId:          aws.String("arn:aws:kms:eu-west-1:123456789012:key/1234"),
+				Plaintext:      dataKey,
+				CiphertextBlob: encryptedKey,
+			}, nil
+		}).Times(1)
+	mockKMS.EXPECT().Decrypt(ctx, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+			assert.Equal(t, encryptedKey, params.CiphertextBlob)
+			assert.Equal(t, kmsEncryptionContext, params.EncryptionContext)
+			return &kms.DecryptOutput{Plaintext: dataKey}, nil
+		}).Times(1)
+
+	c, err := NewKMSCipher(mockKMS, "alias/idpscim")
+	assert.NoError(t, err)
+
+	data, err := c.Encrypt(ctx, plaintext)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), string(dataKey))
+
+	got, err := c.Decrypt(ctx, data)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, got)
+}
+
+func TestStateRepository_Encryption(t *testing.T) {
+	ctx := context.TODO()
+
+	c, err := NewKeyCipher(bytes.Repeat([]byte{1}, stateKeySize))
+	assert.NoError(t, err)
+
+	t.Run("Disk", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		repo, err := NewDiskRepository(&buf, WithDiskCipher(c))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.SetState(ctx, testState("2022-01-01T00:00:00Z")))
+		assert.NotContains(t, buf.String(), "user.1@mail.com")
+
+		stored := buf.Bytes()
+
+		plain, err := NewDiskRepository(bytes.NewBuffer(stored))
+		assert.NoError(t, err)
+		_, err = plain.GetState(ctx)
+		assert.ErrorIs(t, err, ErrStateEncrypted)
+
+		state, err := repo.GetState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, testState("2022-01-01T00:00:00Z"), state)
+	})
+
+	t.Run("Disk state stored without encryption", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		plain, err := NewDiskRepository(&buf)
+		assert.NoError(t, err)
+		assert.NoError(t, plain.SetState(ctx, testState("2022-01-01T00:00:00Z")))
+
+		stored := buf.Bytes()
+
+		repo, err := NewDiskRepository(bytes.NewBuffer(stored), WithDiskCipher(c))
+		assert.NoError(t, err)
+
+		_, err = repo.GetState(ctx)
+		assert.ErrorIs(t, err, ErrStateNotEncrypted)
+
+		repo, err = NewDiskRepository(bytes.NewBuffer(stored), WithDiskCipher(c), WithDiskAllowUnencrypted(true))
+		assert.NoError(t, err)
+
+		state, err := repo.GetState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, testState("2022-01-01T00:00:00Z"), state)
+	})
+
+	t.Run("S3", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		var stored []byte
+
+		mockS3Repository := mocks.NewMockS3ClientAPI(mockCtrl)
+		mockS3Repository.EXPECT().PutObject(ctx, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+				var err error
+				stored, err = io.ReadAll(params.Body)
+				return &s3.PutObjectOutput{}, err
+			}).Times(1)
+		mockS3Repository.EXPECT().GetObject(ctx, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(stored))}, nil
+			}).Times(1)
+
+		repo, err := NewS3Repository(mockS3Repository, WithBucket("MyBucket"), WithKey("MyKey"), WithCipher(c))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.SetState(ctx, testState("2022-01-01T00:00:00Z")))
+		assert.NotContains(t, string(stored), "user.1@mail.com")
+
+		state, err := repo.GetState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, testState("2022-01-01T00:00:00Z"), state)
+	})
+
+	t.Run("DynamoDB", func(t *testing.T) {
+		db := newFakeDynamoDB()
+		repo, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"), WithDynamoDBCipher(c))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.SetState(ctx, testState("2022-01-01T00:00:00Z")))
+
+		state, err := repo.GetState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, testState("2022-01-01T00:00:00Z"), state)
+
+		plain, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"))
+		assert.NoError(t, err)
+		_, err = plain.GetState(ctx)
+		assert.ErrorIs(t, err, ErrStateEncrypted)
+	})
+
+	t.Run("DynamoDB state stored without encryption", func(t *testing.T) {
+		db := newFakeDynamoDB()
+		plain, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"))
+		assert.NoError(t, err)
+		assert.NoError(t, plain.SetState(ctx, testState("2022-01-01T00:00:00Z")))
+
+		repo, err := NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"), WithDynamoDBCipher(c))
+		assert.NoError(t, err)
+		_, err = repo.GetState(ctx)
+		assert.ErrorIs(t, err, ErrStateNotEncrypted)
+
+		repo, err = NewDynamoDBRepository(db, WithDynamoDBTable("MyTable"), WithDynamoDBStateID("state"),
+			WithDynamoDBCipher(c), WithDynamoDBAllowUnencrypted(true))
+		assert.NoError(t, err)
+
+		state, err := repo.GetState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, testState("2022-01-01T00:00:00Z"), state)
+	})
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrStateVersionNotFound is returned when the state is not in the history of the repository
+var ErrStateVersionNotFound = errors.New("repository: state version not found")
+
+// historyTimeFormat is the format of the time in the ids of the states of the history,
+// sortable and valid in keys and file names.
+const historyTimeFormat = "20060102T150405Z"
+
+// historyID returns the id in the history of a state synced at lastSync,
+// the current time when lastSync is not a RFC3339 time.
+func historyID(lastSync string) string {
+	t, err := time.Parse(time.RFC3339, lastSync)
+	if err != nil {
+		t = time.Now()
+	}
+
+	return t.UTC().Format(historyTimeFormat)
+}
+
+// historyLastSync returns the time of the sync of the state with the given id in the history
+// as a RFC3339 time, empty when the id is not a time.
+func historyLastSync(id string) string {
+	t, err := time.Parse(historyTimeFormat, id)
+	if err != nil {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
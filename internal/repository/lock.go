@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultLockTTL is the time after which the lock of a sync that didn't release it, because it crashed
+// or timed out, expires and can be taken by another sync. It matches the maximum timeout of an AWS Lambda function.
+const DefaultLockTTL = 15 * time.Minute
+
+var (
+	// ErrStateLocked is returned when the state is locked by another sync
+	ErrStateLocked = errors.New("repository: state is locked by another sync")
+
+	// ErrStateConflict is returned when the state was stored by another sync after this one read it
+	ErrStateConflict = errors.New("repository: state was modified by another sync")
+
+	// ErrStateNotLocked is returned when unlocking a state that was not locked
+	ErrStateNotLocked = errors.New("repository: state is not locked")
+
+	// ErrLockLost is returned when unlocking a state whose lock expired and was taken or removed by another sync
+	ErrLockLost = errors.New("repository: state lock expired and was taken by another sync")
+)
+
+// stateLock is the content of the lock of the state, identifying the sync holding it.
+type stateLock struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"owner"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// newStateLock returns a lock with a random id owned by this process and expiring after ttl.
+func newStateLock(ttl time.Duration) (*stateLock, error) {
+	id, err := randomID()
+	if err != nil {
+		return nil, fmt.Errorf("repository: error generating lock id: %w", err)
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	now := time.Now().UTC()
+
+	return &stateLock{
+		ID:        id,
+		Owner:     fmt.Sprintf("%s:%d", host, os.Getpid()),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, nil
+}
+
+// expired returns true when the lock expired at the given time.
+func (l *stateLock) expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// same reports whether l is the lock other, with the same id, owner and expiration.
+func (l *stateLock) same(other *stateLock) bool {
+	return l.ID == other.ID && l.Owner == other.Owner && l.ExpiresAt.Equal(other.ExpiresAt)
+}
+
+// lockedError returns ErrStateLocked with the details of the sync holding the lock.
+func (l *stateLock) lockedError() error {
+	return fmt.Errorf("%w: owner: %s, created at: %s, expires at: %s",
+		ErrStateLocked, l.Owner, l.CreatedAt.Format(time.RFC3339), l.ExpiresAt.Format(time.RFC3339),
+	)
+}
+
+// randomID returns a random hex encoded id.
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -5,10 +5,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 	"github.com/slashdevops/idp-scim-sync/internal/model"
 )
 
@@ -34,13 +41,36 @@ var (
 type S3ClientAPI interface {
 	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
 	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 }
 
-// S3Repository represent a repository that stores state in S3 and implements model.Repository interface
+// S3Repository represent a repository that stores state in S3 and implements model.Repository interface.
+// The state is written only when its ETag didn't change since it was read, using the S3 conditional writes,
+// so two syncs can't overwrite the state of each other.
+// The state is locked with a lock object stored next to it, with the key of the state and the suffix .lock,
+// which is created only when it doesn't exist using the S3 conditional writes.
+// When the history is enabled, every state stored is also kept with the key of the state, the suffix .history/
+// and the time of its sync, e.g. state.json.history/20220101T100000Z, and only the newest ones are kept.
 type S3Repository struct {
-	bucket string
-	key    string
-	client S3ClientAPI
+	bucket  string
+	key     string
+	lockTTL time.Duration
+	history int
+	cipher  StateCipher
+	client  S3ClientAPI
+
+	// allowUnencrypted reads the states not encrypted when there is a cipher, to migrate them
+	allowUnencrypted bool
+
+	// compression of the state stored, the states are read with any compression
+	compression string
+
+	// lockID is the id of the lock held by this repository, empty when it is not locked
+	lockID string
+
+	// etag of the state read, used to store the next one, empty when there is no state stored yet
+	etag string
 }
 
 // NewS3Repository returns a new S3Repository
@@ -50,7 +80,8 @@ func NewS3Repository(client S3ClientAPI, opts ...S3RepositoryOption) (*S3Reposit
 	}
 
 	s3r := &S3Repository{
-		client: client,
+		client:  client,
+		lockTTL: DefaultLockTTL,
 	}
 
 	for _, opt := range opts {
@@ -65,49 +96,375 @@ func NewS3Repository(client S3ClientAPI, opts ...S3RepositoryOption) (*S3Reposit
 		return nil, ErrOptionWithKeyNil
 	}
 
+	if err := validCompression(s3r.compression); err != nil {
+		return nil, err
+	}
+
 	return s3r, nil
 }
 
 // GetState returns the state from the repository
 func (r *S3Repository) GetState(ctx context.Context) (*model.State, error) {
+	state, etag, err := r.getState(ctx, r.key)
+	r.etag = etag
+
+	return state, err
+}
+
+// SetState sets the state in the given repository, it returns ErrStateConflict when the state was stored
+// by another sync after this repository read it with GetState
+func (r *S3Repository) SetState(ctx context.Context, state *model.State) error {
+	if state == nil {
+		return ErrStateNil
+	}
+
+	jsonPayload, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("s3: error marshaling state: %w", err)
+	}
+
+	jsonPayload, err = compressState(r.compression, jsonPayload)
+	if err != nil {
+		return fmt.Errorf("s3: error compressing state: %w", err)
+	}
+
+	jsonPayload, err = encryptState(ctx, r.cipher, jsonPayload)
+	if err != nil {
+		return fmt.Errorf("s3: error encrypting state: %w", err)
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:          aws.String(r.bucket),
+		Key:             aws.String(r.key),
+		Body:            bytes.NewReader(jsonPayload),
+		ContentEncoding: r.contentEncoding(),
+	}
+
+	if r.etag == "" {
+		input.IfNoneMatch = aws.String("*")
+	} else {
+		input.IfMatch = aws.String(r.etag)
+	}
+
+	resp, err := r.client.PutObject(ctx, input)
+	if err != nil {
+		if isConditionFailed(err) {
+			return ErrStateConflict
+		}
+		return fmt.Errorf("s3: error putting S3 object: %w", err)
+	}
+
+	r.etag = aws.ToString(resp.ETag)
+
+	if r.history > 0 {
+		r.keepState(ctx, state, jsonPayload)
+	}
+
+	return nil
+}
+
+// ListStates returns the states kept in the history, the newest first.
+func (r *S3Repository) ListStates(ctx context.Context) ([]*model.StateVersion, error) {
+	keys, err := r.historyKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*model.StateVersion, 0, len(keys))
+	for _, key := range keys {
+		id := strings.TrimPrefix(key, r.historyPrefix())
+		versions = append(versions, &model.StateVersion{ID: id, LastSync: historyLastSync(id)})
+	}
+
+	return versions, nil
+}
+
+// GetStateVersion returns the state with the given id from the history,
+// it returns ErrStateVersionNotFound when the state is not in the history.
+func (r *S3Repository) GetStateVersion(ctx context.Context, id string) (*model.State, error) {
+	if historyLastSync(id) == "" {
+		return nil, ErrStateVersionNotFound
+	}
+
+	state, _, err := r.getState(ctx, r.historyPrefix()+id)
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, ErrStateVersionNotFound
+		}
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// Lock locks the state creating the lock object, it returns ErrStateLocked when the lock object exists
+// and it didn't expire. An expired lock object is replaced, only when another sync didn't replace it before.
+func (r *S3Repository) Lock(ctx context.Context) error {
+	l, err := newStateLock(r.lockTTL)
+	if err != nil {
+		return err
+	}
+
+	err = r.putLock(ctx, l, "")
+	if err == nil {
+		r.lockID = l.ID
+		return nil
+	}
+	if !isConditionFailed(err) {
+		return err
+	}
+
+	current, etag, err := r.getLock(ctx)
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) {
+			// released after the conditional put, try again the next sync
+			return ErrStateLocked
+		}
+		return err
+	}
+
+	if !current.expired(time.Now()) {
+		return current.lockedError()
+	}
+
+	// the sync holding the lock didn't release it, replace the lock object only if it didn't change
+	if err := r.putLock(ctx, l, etag); err != nil {
+		if isConditionFailed(err) {
+			return ErrStateLocked
+		}
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"owner":     current.Owner,
+		"createdAt": current.CreatedAt.Format(time.RFC3339),
+		"expiresAt": current.ExpiresAt.Format(time.RFC3339),
+	}).Warn("the lock of the state expired without being released, taking it over")
+
+	r.lockID = l.ID
+
+	return nil
+}
+
+// Unlock releases the lock deleting the lock object, it returns ErrLockLost when the lock object
+// doesn't belong to this repository anymore because it expired and another sync took it over.
+func (r *S3Repository) Unlock(ctx context.Context) error {
+	if r.lockID == "" {
+		return ErrStateNotLocked
+	}
+
+	lockID := r.lockID
+	r.lockID = ""
+
+	current, _, err := r.getLock(ctx)
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return ErrLockLost
+		}
+		return err
+	}
+
+	if current.ID != lockID {
+		return ErrLockLost
+	}
+
+	_, err = r.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(r.lockKey()),
+	})
+	if err != nil {
+		return fmt.Errorf("s3: error deleting lock object: %w", err)
+	}
+
+	return nil
+}
+
+// getState returns the state stored in the given key and its ETag, the ETag is returned
+// whenever the object exists, also when the state can't be read from it.
+func (r *S3Repository) getState(ctx context.Context, key string) (*model.State, string, error) {
 	resp, err := r.client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(r.bucket),
-		Key:    aws.String(r.key),
+		Key:    aws.String(key),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("s3: error getting S3 object: bucket: %s, error: %w", r.bucket, err)
+		return nil, "", fmt.Errorf("s3: error getting S3 object: bucket: %s, error: %w", r.bucket, err)
 	}
 	defer resp.Body.Close()
 
+	etag := aws.ToString(resp.ETag)
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, etag, fmt.Errorf("s3: error reading S3 object: %w", err)
+	}
+
+	data, err = decryptState(ctx, r.cipher, r.allowUnencrypted, data)
+	if err != nil {
+		return nil, etag, fmt.Errorf("s3: error decrypting S3 object: %w", err)
+	}
+
+	data, err = decompressState(data)
+	if err != nil {
+		return nil, etag, fmt.Errorf("s3: error decompressing S3 object: %w", err)
+	}
+
 	var state model.State
-	dec := json.NewDecoder(resp.Body)
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, etag, fmt.Errorf("s3: error decoding S3 object: %w", err)
+	}
+
+	return &state, etag, nil
+}
+
+// keepState stores the state in the history and removes the states older than the history size,
+// the state is already stored so the errors are only logged.
+func (r *S3Repository) keepState(ctx context.Context, state *model.State, jsonPayload []byte) {
+	key := r.historyPrefix() + historyID(state.LastSync)
+
+	_, err := r.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:          aws.String(r.bucket),
+		Key:             aws.String(key),
+		Body:            bytes.NewReader(jsonPayload),
+		ContentEncoding: r.contentEncoding(),
+	})
+	if err != nil {
+		log.WithError(err).WithField("key", key).Warn("cannot keep the state in the history")
+		return
+	}
 
-	if err = dec.Decode(&state); err != nil {
-		return nil, fmt.Errorf("s3: error decoding S3 object: %w", err)
+	keys, err := r.historyKeys(ctx)
+	if err != nil {
+		log.WithError(err).Warn("cannot remove the old states from the history")
+		return
 	}
 
-	return &state, nil
+	for i := r.history; i < len(keys); i++ {
+		_, err := r.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(r.bucket),
+			Key:    aws.String(keys[i]),
+		})
+		if err != nil {
+			log.WithError(err).WithField("key", keys[i]).Warn("cannot remove the old state from the history")
+		}
+	}
 }
 
-// SetState sets the state in the given repository
-func (r *S3Repository) SetState(ctx context.Context, state *model.State) error {
-	if state == nil {
-		return ErrStateNil
+// contentEncoding returns the content encoding of the state objects, nil when they are not compressed
+// or they are encrypted, because then the content is not only compressed.
+func (r *S3Repository) contentEncoding() *string {
+	if r.cipher != nil {
+		return nil
 	}
 
-	jsonPayload, err := json.MarshalIndent(state, "", "  ")
+	if ce := contentEncoding(r.compression); ce != "" {
+		return aws.String(ce)
+	}
+
+	return nil
+}
+
+// historyKeys returns the keys of the states kept in the history, the newest first.
+func (r *S3Repository) historyKeys(ctx context.Context) ([]string, error) {
+	keys := make([]string, 0)
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(r.bucket),
+		Prefix: aws.String(r.historyPrefix()),
+	}
+
+	for {
+		resp, err := r.client.ListObjectsV2(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("s3: error listing the history objects: %w", err)
+		}
+
+		for _, obj := range resp.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+
+		if !aws.ToBool(resp.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = resp.NextContinuationToken
+	}
+
+	// the ids are times that sort as strings
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+	return keys, nil
+}
+
+// historyPrefix returns the prefix of the keys of the states kept in the history.
+func (r *S3Repository) historyPrefix() string {
+	return r.key + ".history/"
+}
+
+// lockKey returns the key of the lock object of the state.
+func (r *S3Repository) lockKey() string {
+	return r.key + ".lock"
+}
+
+// putLock puts the lock object only when its ETag is etag, or only when it doesn't exist when etag is empty.
+func (r *S3Repository) putLock(ctx context.Context, l *stateLock, etag string) error {
+	jsonPayload, err := json.Marshal(l)
 	if err != nil {
-		return fmt.Errorf("s3: error marshaling state: %w", err)
+		return fmt.Errorf("s3: error marshaling lock: %w", err)
 	}
 
-	_, err = r.client.PutObject(ctx, &s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket: aws.String(r.bucket),
-		Key:    aws.String(r.key),
+		Key:    aws.String(r.lockKey()),
 		Body:   bytes.NewReader(jsonPayload),
-	})
+	}
+
+	if etag == "" {
+		input.IfNoneMatch = aws.String("*")
+	} else {
+		input.IfMatch = aws.String(etag)
+	}
+
+	_, err = r.client.PutObject(ctx, input)
 	if err != nil {
-		return fmt.Errorf("s3: error putting S3 object: %w", err)
+		return fmt.Errorf("s3: error putting lock object: %w", err)
 	}
 
 	return nil
 }
+
+// getLock returns the lock object and its ETag.
+func (r *S3Repository) getLock(ctx context.Context) (*stateLock, string, error) {
+	resp, err := r.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(r.lockKey()),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("s3: error getting lock object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var l stateLock
+	if err := json.NewDecoder(resp.Body).Decode(&l); err != nil {
+		return nil, "", fmt.Errorf("s3: error decoding lock object: %w", err)
+	}
+
+	return &l, aws.ToString(resp.ETag), nil
+}
+
+// isConditionFailed returns true when a conditional write failed because the object changed,
+// with 412 Precondition Failed, or because of a concurrent conditional write, with 409 Conflict.
+func isConditionFailed(err error) bool {
+	var ae smithy.APIError
+	if !errors.As(err, &ae) {
+		return false
+	}
+
+	switch ae.ErrorCode() {
+	case "PreconditionFailed", "ConditionalRequestConflict":
+		return true
+	default:
+		return false
+	}
+}
@@ -1,5 +1,7 @@
 package repository
 
+import "time"
+
 // S3RepositoryOption is a function that can be used to configure a S3Repository
 // using the functional options pattern.
 type S3RepositoryOption func(*S3Repository)
@@ -17,3 +19,42 @@ func WithKey(key string) S3RepositoryOption {
 		r.key = key
 	}
 }
+
+// WithLockTTL sets the time after which the lock of the state expires when the sync holding it
+// didn't release it.
+func WithLockTTL(ttl time.Duration) S3RepositoryOption {
+	return func(r *S3Repository) {
+		r.lockTTL = ttl
+	}
+}
+
+// WithHistory sets the number of previous states kept in the history, 0 disables the history.
+func WithHistory(n int) S3RepositoryOption {
+	return func(r *S3Repository) {
+		r.history = n
+	}
+}
+
+// WithCipher sets the cipher encrypting the state, the states stored without encryption are refused
+// unless WithAllowUnencrypted is set.
+func WithCipher(c StateCipher) S3RepositoryOption {
+	return func(r *S3Repository) {
+		r.cipher = c
+	}
+}
+
+// WithAllowUnencrypted reads the states stored without encryption when there is a cipher,
+// to migrate the states stored before enabling the encryption.
+func WithAllowUnencrypted(allow bool) S3RepositoryOption {
+	return func(r *S3Repository) {
+		r.allowUnencrypted = allow
+	}
+}
+
+// WithCompression sets the compression of the state stored, [none|gzip|zstd], the states are read with
+// any compression, or without it.
+func WithCompression(compression string) S3RepositoryOption {
+	return func(r *S3Repository) {
+		r.compression = compression
+	}
+}
@@ -3,11 +3,17 @@ package repository
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	"github.com/golang/mock/gomock"
 	"github.com/slashdevops/idp-scim-sync/internal/model"
 	mocks "github.com/slashdevops/idp-scim-sync/mocks/repository"
@@ -161,7 +167,7 @@ func TestS3SetState(t *testing.T) {
 			},
 		}
 
-		mockS3Repository.EXPECT().PutObject(context.TODO(), gomock.Any()).Return(nil, nil)
+		mockS3Repository.EXPECT().PutObject(context.TODO(), gomock.Any()).Return(&s3.PutObjectOutput{}, nil)
 
 		svc, err := NewS3Repository(mockS3Repository, WithBucket("MyBucket"), WithKey("MyKey"))
 		assert.NoError(t, err)
@@ -185,4 +191,308 @@ func TestS3SetState(t *testing.T) {
 		err = svc.SetState(context.TODO(), sObj)
 		assert.Error(t, err)
 	})
+
+	t.Run("Should store the state only when it didn't change since it was read", func(t *testing.T) {
+		mockS3Repository := mocks.NewMockS3ClientAPI(mockCtrl)
+
+		gomock.InOrder(
+			mockS3Repository.EXPECT().GetObject(context.TODO(), gomock.Any()).Return(&s3.GetObjectOutput{
+				Body: io.NopCloser(strings.NewReader(`{"schemaVersion":"1.0.0"}`)),
+				ETag: aws.String(`"etag-1"`),
+			}, nil),
+			mockS3Repository.EXPECT().PutObject(context.TODO(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+					assert.Equal(t, `"etag-1"`, aws.ToString(params.IfMatch))
+					assert.Nil(t, params.IfNoneMatch)
+					return &s3.PutObjectOutput{ETag: aws.String(`"etag-2"`)}, nil
+				}),
+			mockS3Repository.EXPECT().PutObject(context.TODO(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+					assert.Equal(t, `"etag-2"`, aws.ToString(params.IfMatch))
+					return &s3.PutObjectOutput{ETag: aws.String(`"etag-3"`)}, nil
+				}),
+		)
+
+		svc, err := NewS3Repository(mockS3Repository, WithBucket("MyBucket"), WithKey("MyKey"))
+		assert.NoError(t, err)
+
+		_, err = svc.GetState(context.TODO())
+		assert.NoError(t, err)
+		assert.NoError(t, svc.SetState(context.TODO(), testState("2022-01-01T00:00:00Z")))
+		assert.NoError(t, svc.SetState(context.TODO(), testState("2022-01-02T00:00:00Z")))
+	})
+
+	t.Run("Should store the state only when it doesn't exist when there was no state", func(t *testing.T) {
+		mockS3Repository := mocks.NewMockS3ClientAPI(mockCtrl)
+
+		mockS3Repository.EXPECT().GetObject(context.TODO(), gomock.Any()).Return(nil, &types.NoSuchKey{})
+		mockS3Repository.EXPECT().PutObject(context.TODO(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+				assert.Equal(t, "*", aws.ToString(params.IfNoneMatch))
+				assert.Nil(t, params.IfMatch)
+				return &s3.PutObjectOutput{}, nil
+			})
+
+		svc, err := NewS3Repository(mockS3Repository, WithBucket("MyBucket"), WithKey("MyKey"))
+		assert.NoError(t, err)
+
+		_, err = svc.GetState(context.TODO())
+		assert.Error(t, err)
+		assert.NoError(t, svc.SetState(context.TODO(), testState("2022-01-01T00:00:00Z")))
+	})
+
+	t.Run("Should return ErrStateConflict when another sync stored the state", func(t *testing.T) {
+		mockS3Repository := mocks.NewMockS3ClientAPI(mockCtrl)
+
+		errPrecondition := &smithy.GenericAPIError{Code: "PreconditionFailed", Message: "At least one of the pre-conditions you specified did not hold"}
+		mockS3Repository.EXPECT().PutObject(context.TODO(), gomock.Any()).Return(nil, errPrecondition)
+
+		svc, err := NewS3Repository(mockS3Repository, WithBucket("MyBucket"), WithKey("MyKey"))
+		assert.NoError(t, err)
+
+		assert.ErrorIs(t, svc.SetState(context.TODO(), testState("2022-01-01T00:00:00Z")), ErrStateConflict)
+	})
+}
+
+func TestS3History(t *testing.T) {
+	ctx := context.TODO()
+
+	// objects is an in-memory bucket
+	objects := make(map[string][]byte)
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockS3Repository := mocks.NewMockS3ClientAPI(mockCtrl)
+	mockS3Repository.EXPECT().PutObject(ctx, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			data, err := io.ReadAll(params.Body)
+			objects[aws.ToString(params.Key)] = data
+			return &s3.PutObjectOutput{}, err
+		}).AnyTimes()
+	mockS3Repository.EXPECT().GetObject(ctx, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			data, ok := objects[aws.ToString(params.Key)]
+			if !ok {
+				return nil, &types.NoSuchKey{}
+			}
+			return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+		}).AnyTimes()
+	mockS3Repository.EXPECT().DeleteObject(ctx, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+			delete(objects, aws.ToString(params.Key))
+			return &s3.DeleteObjectOutput{}, nil
+		}).AnyTimes()
+	mockS3Repository.EXPECT().ListObjectsV2(ctx, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			out := &s3.ListObjectsV2Output{}
+			for key := range objects {
+				if strings.HasPrefix(key, aws.ToString(params.Prefix)) {
+					out.Contents = append(out.Contents, types.Object{Key: aws.String(key)})
+				}
+			}
+			return out, nil
+		}).AnyTimes()
+
+	svc, err := NewS3Repository(mockS3Repository, WithBucket("MyBucket"), WithKey("MyKey"), WithHistory(2))
+	assert.NoError(t, err)
+
+	for _, lastSync := range []string{"2022-01-01T00:00:00Z", "2022-01-02T00:00:00+02:00", "2022-01-03T00:00:00Z"} {
+		assert.NoError(t, svc.SetState(ctx, testState(lastSync)))
+	}
+
+	assert.Len(t, objects, 3)
+	assert.Contains(t, objects, "MyKey.history/20220103T000000Z")
+
+	versions, err := svc.ListStates(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.StateVersion{
+		{ID: "20220103T000000Z", LastSync: "2022-01-03T00:00:00Z"},
+		{ID: "20220101T220000Z", LastSync: "2022-01-01T22:00:00Z"},
+	}, versions)
+
+	state, err := svc.GetStateVersion(ctx, "20220101T220000Z")
+	assert.NoError(t, err)
+	assert.Equal(t, testState("2022-01-02T00:00:00+02:00"), state)
+
+	for _, id := range []string{"20220101T000000Z", "../MyKey"} {
+		_, err = svc.GetStateVersion(ctx, id)
+		assert.ErrorIs(t, err, ErrStateVersionNotFound)
+	}
+}
+
+// lockObject returns the output of GetObject for the lock object.
+func lockObject(t *testing.T, l *stateLock, etag string) *s3.GetObjectOutput {
+	t.Helper()
+
+	b, err := json.Marshal(l)
+	assert.NoError(t, err)
+
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(b)), ETag: aws.String(etag)}
+}
+
+func TestS3Lock(t *testing.T) {
+	errPrecondition := &smithy.GenericAPIError{Code: "PreconditionFailed", Message: "At least one of the pre-conditions you specified did not hold"}
+
+	t.Run("Should create the lock object only when it doesn't exist", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockS3Repository := mocks.NewMockS3ClientAPI(mockCtrl)
+
+		mockS3Repository.EXPECT().PutObject(context.TODO(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+				assert.Equal(t, "MyKey.lock", aws.ToString(params.Key))
+				assert.Equal(t, "*", aws.ToString(params.IfNoneMatch))
+				return &s3.PutObjectOutput{}, nil
+			})
+
+		repo, err := NewS3Repository(mockS3Repository, WithBucket("MyBucket"), WithKey("MyKey"))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.Lock(context.TODO()))
+		assert.NotEmpty(t, repo.lockID)
+	})
+
+	t.Run("Should return ErrStateLocked when the lock object didn't expire", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockS3Repository := mocks.NewMockS3ClientAPI(mockCtrl)
+
+		current, err := newStateLock(time.Hour)
+		assert.NoError(t, err)
+
+		mockS3Repository.EXPECT().PutObject(context.TODO(), gomock.Any()).Return(nil, errPrecondition)
+		mockS3Repository.EXPECT().GetObject(context.TODO(), gomock.Any()).Return(lockObject(t, current, `"etag"`), nil)
+
+		repo, err := NewS3Repository(mockS3Repository, WithBucket("MyBucket"), WithKey("MyKey"))
+		assert.NoError(t, err)
+
+		err = repo.Lock(context.TODO())
+		assert.ErrorIs(t, err, ErrStateLocked)
+		assert.Contains(t, err.Error(), current.Owner)
+		assert.Empty(t, repo.lockID)
+	})
+
+	t.Run("Should take over the lock object when it expired", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockS3Repository := mocks.NewMockS3ClientAPI(mockCtrl)
+
+		current, err := newStateLock(-time.Minute)
+		assert.NoError(t, err)
+
+		gomock.InOrder(
+			mockS3Repository.EXPECT().PutObject(context.TODO(), gomock.Any()).Return(nil, errPrecondition),
+			mockS3Repository.EXPECT().GetObject(context.TODO(), gomock.Any()).Return(lockObject(t, current, `"etag"`), nil),
+			mockS3Repository.EXPECT().PutObject(context.TODO(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+					assert.Equal(t, `"etag"`, aws.ToString(params.IfMatch))
+					return &s3.PutObjectOutput{}, nil
+				}),
+		)
+
+		repo, err := NewS3Repository(mockS3Repository, WithBucket("MyBucket"), WithKey("MyKey"), WithLockTTL(time.Minute))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.Lock(context.TODO()))
+		assert.NotEmpty(t, repo.lockID)
+	})
+
+	t.Run("Should return ErrStateLocked when another sync took over the expired lock object", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockS3Repository := mocks.NewMockS3ClientAPI(mockCtrl)
+
+		current, err := newStateLock(-time.Minute)
+		assert.NoError(t, err)
+
+		mockS3Repository.EXPECT().PutObject(context.TODO(), gomock.Any()).Return(nil, errPrecondition).Times(2)
+		mockS3Repository.EXPECT().GetObject(context.TODO(), gomock.Any()).Return(lockObject(t, current, `"etag"`), nil)
+
+		repo, err := NewS3Repository(mockS3Repository, WithBucket("MyBucket"), WithKey("MyKey"))
+		assert.NoError(t, err)
+
+		assert.ErrorIs(t, repo.Lock(context.TODO()), ErrStateLocked)
+	})
+
+	t.Run("Should return error", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockS3Repository := mocks.NewMockS3ClientAPI(mockCtrl)
+
+		mockS3Repository.EXPECT().PutObject(context.TODO(), gomock.Any()).Return(nil, errors.New("error"))
+
+		repo, err := NewS3Repository(mockS3Repository, WithBucket("MyBucket"), WithKey("MyKey"))
+		assert.NoError(t, err)
+
+		err = repo.Lock(context.TODO())
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrStateLocked)
+	})
+}
+
+func TestS3Unlock(t *testing.T) {
+	t.Run("Should delete the lock object", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockS3Repository := mocks.NewMockS3ClientAPI(mockCtrl)
+
+		var current *stateLock
+		mockS3Repository.EXPECT().PutObject(context.TODO(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+				assert.NoError(t, json.NewDecoder(params.Body).Decode(&current))
+				return &s3.PutObjectOutput{}, nil
+			})
+		mockS3Repository.EXPECT().GetObject(context.TODO(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+				return lockObject(t, current, `"etag"`), nil
+			})
+		mockS3Repository.EXPECT().DeleteObject(context.TODO(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+				assert.Equal(t, "MyKey.lock", aws.ToString(params.Key))
+				return &s3.DeleteObjectOutput{}, nil
+			})
+
+		repo, err := NewS3Repository(mockS3Repository, WithBucket("MyBucket"), WithKey("MyKey"))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.Lock(context.TODO()))
+		assert.NoError(t, repo.Unlock(context.TODO()))
+		assert.Empty(t, repo.lockID)
+	})
+
+	t.Run("Should return ErrLockLost when the lock object belongs to another sync", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockS3Repository := mocks.NewMockS3ClientAPI(mockCtrl)
+
+		other, err := newStateLock(time.Hour)
+		assert.NoError(t, err)
+
+		mockS3Repository.EXPECT().PutObject(context.TODO(), gomock.Any()).Return(&s3.PutObjectOutput{}, nil)
+		mockS3Repository.EXPECT().GetObject(context.TODO(), gomock.Any()).Return(lockObject(t, other, `"etag"`), nil)
+
+		repo, err := NewS3Repository(mockS3Repository, WithBucket("MyBucket"), WithKey("MyKey"))
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.Lock(context.TODO()))
+		assert.ErrorIs(t, repo.Unlock(context.TODO()), ErrLockLost)
+	})
+
+	t.Run("Should return ErrStateNotLocked when it is not locked", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		repo, err := NewS3Repository(mocks.NewMockS3ClientAPI(mockCtrl), WithBucket("MyBucket"), WithKey("MyKey"))
+		assert.NoError(t, err)
+
+		assert.ErrorIs(t, repo.Unlock(context.TODO()), ErrStateNotLocked)
+	})
 }
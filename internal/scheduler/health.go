@@ -0,0 +1,37 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HealthzHandler returns an http.Handler answering the liveness probes with the status of the runs,
+// the process is alive while it answers, whatever the result of the last run.
+func (s *Scheduler) HealthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, http.StatusOK, s.Status())
+	})
+}
+
+// ReadyzHandler returns an http.Handler answering the readiness probes with the status of the runs,
+// it is ready when the last run completed without errors, and not ready before the first run
+// completes or when the last run failed.
+func (s *Scheduler) ReadyzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := s.Status()
+
+		code := http.StatusOK
+		if status.LastRun == nil || status.LastRun.Error != "" {
+			code = http.StatusServiceUnavailable
+		}
+
+		writeStatus(w, code, status)
+	})
+}
+
+// writeStatus writes the status as JSON with the status code.
+func writeStatus(w http.ResponseWriter, code int, status Status) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_Handlers(t *testing.T) {
+	var jobErr error
+	s, err := New("1h", func(ctx context.Context) error { return jobErr })
+	assert.NoError(t, err)
+
+	get := func(h http.Handler) (int, Status) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		var status Status
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		return rec.Code, status
+	}
+
+	t.Run("should not be ready before the first run", func(t *testing.T) {
+		code, _ := get(s.HealthzHandler())
+		assert.Equal(t, http.StatusOK, code)
+
+		code, status := get(s.ReadyzHandler())
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Nil(t, status.LastRun)
+	})
+
+	t.Run("should be ready after a run without errors", func(t *testing.T) {
+		assert.NoError(t, s.RunNow(context.Background()))
+
+		code, status := get(s.ReadyzHandler())
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotNil(t, status.LastSuccess)
+	})
+
+	t.Run("should not be ready after a failed run but alive", func(t *testing.T) {
+		jobErr = errors.New("test error")
+		assert.Error(t, s.RunNow(context.Background()))
+
+		code, status := get(s.ReadyzHandler())
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "test error", status.LastRun.Error)
+
+		code, _ = get(s.HealthzHandler())
+		assert.Equal(t, http.StatusOK, code)
+	})
+}
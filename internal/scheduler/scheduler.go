@@ -0,0 +1,199 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrScheduleEmpty is returned when the schedule is empty.
+	ErrScheduleEmpty = errors.New("scheduler: schedule may not be empty")
+
+	// ErrJobNil is returned when the job is nil.
+	ErrJobNil = errors.New("scheduler: job may not be nil")
+
+	// ErrRunning is returned when a run is requested while the job is still running.
+	ErrRunning = errors.New("scheduler: job is already running")
+)
+
+// Job is the function run by the Scheduler.
+type Job func(ctx context.Context) error
+
+// Status is the status of the runs of the job.
+type Status struct {
+	Running     bool       `json:"running"`
+	LastRun     *Run       `json:"last_run,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	NextRun     *time.Time `json:"next_run,omitempty"`
+}
+
+// Run is the result of a run of the job.
+type Run struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Duration  string    `json:"duration"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// every is a schedule running every d since the previous run.
+// Unlike cron.Every, it doesn't round d to seconds.
+type every time.Duration
+
+// Next returns the time of the next run after t.
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// Scheduler runs a job on a schedule, one run at a time.
+type Scheduler struct {
+	schedule cron.Schedule
+	job      Job
+
+	// running prevents overlapping runs
+	running sync.Mutex
+
+	mu     sync.RWMutex
+	status Status
+}
+
+// ParseSchedule parses a standard cron expression with five fields, like "*/30 * * * *",
+// a descriptor, like "@hourly" or "@every 30m", or a duration, like "30m".
+func ParseSchedule(spec string) (cron.Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, ErrScheduleEmpty
+	}
+
+	if d, err := time.ParseDuration(spec); err == nil {
+		if d <= 0 {
+			return nil, fmt.Errorf("scheduler: invalid schedule %q, the duration must be positive", spec)
+		}
+		return every(d), nil
+	}
+
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("scheduler: invalid schedule %q: %w", spec, err)
+	}
+
+	return schedule, nil
+}
+
+// New returns a new Scheduler running job on the schedule spec, see ParseSchedule for the formats.
+func New(spec string, job Job) (*Scheduler, error) {
+	if job == nil {
+		return nil, ErrJobNil
+	}
+
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Scheduler{
+		schedule: schedule,
+		job:      job,
+	}, nil
+}
+
+// Start runs the job once and then on the schedule until ctx is done. The scheduled runs are skipped
+// while the job is still running, and the run in progress when ctx is done is waited for.
+// The context passed to the job is not canceled when ctx is done, so the run is not interrupted.
+func (s *Scheduler) Start(ctx context.Context) {
+	jobCtx := context.Background()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	next := time.Now()
+	for {
+		s.setNextRun(next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.setNextRun(time.Time{})
+			log.Info("scheduler stopped")
+			return
+		case <-timer.C:
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.RunNow(jobCtx); errors.Is(err, ErrRunning) {
+				log.Warn("skipping the scheduled run, the previous run is still running")
+			}
+		}()
+
+		next = s.schedule.Next(time.Now())
+	}
+}
+
+// RunNow runs the job and returns its error, or ErrRunning without running it when it is already running.
+func (s *Scheduler) RunNow(ctx context.Context) error {
+	return s.Run(ctx, s.job)
+}
+
+// Run runs job, on demand instead of the scheduled job, and records its result in the status like
+// the scheduled runs. It returns ErrRunning without running job when a job is already running.
+func (s *Scheduler) Run(ctx context.Context, job Job) error {
+	if job == nil {
+		return ErrJobNil
+	}
+
+	if !s.running.TryLock() {
+		return ErrRunning
+	}
+	defer s.running.Unlock()
+
+	s.mu.Lock()
+	s.status.Running = true
+	s.mu.Unlock()
+
+	run := &Run{StartTime: time.Now()}
+	err := job(ctx)
+	run.EndTime = time.Now()
+	run.Duration = run.EndTime.Sub(run.StartTime).String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status.Running = false
+	s.status.LastRun = run
+	if err != nil {
+		run.Error = err.Error()
+	} else {
+		s.status.LastSuccess = &run.EndTime
+	}
+
+	return err
+}
+
+// Status returns the status of the runs of the job.
+func (s *Scheduler) Status() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.status
+}
+
+// setNextRun sets the time of the next scheduled run, a zero time means there are no more runs.
+func (s *Scheduler) setNextRun(next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if next.IsZero() {
+		s.status.NextRun = nil
+		return
+	}
+	s.status.NextRun = &next
+}
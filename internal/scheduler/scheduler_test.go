@@ -0,0 +1,187 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchedule(t *testing.T) {
+	now := time.Date(2022, 1, 1, 10, 10, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "duration", spec: "30m", want: now.Add(30 * time.Minute)},
+		{name: "every", spec: "@every 1h", want: now.Add(time.Hour)},
+		{name: "descriptor", spec: "@hourly", want: time.Date(2022, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{name: "cron", spec: "*/15 * * * *", want: time.Date(2022, 1, 1, 10, 15, 0, 0, time.UTC)},
+		{name: "cron with spaces", spec: " 0 2 * * * ", want: time.Date(2022, 1, 2, 2, 0, 0, 0, time.UTC)},
+		{name: "empty", spec: "", wantErr: true},
+		{name: "negative duration", spec: "-5m", wantErr: true},
+		{name: "invalid", spec: "every hour", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSchedule(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Next(now))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Run("should return an error when the job is nil", func(t *testing.T) {
+		s, err := New("1h", nil)
+		assert.ErrorIs(t, err, ErrJobNil)
+		assert.Nil(t, s)
+	})
+
+	t.Run("should return an error when the schedule is empty", func(t *testing.T) {
+		s, err := New("", func(ctx context.Context) error { return nil })
+		assert.ErrorIs(t, err, ErrScheduleEmpty)
+		assert.Nil(t, s)
+	})
+}
+
+func TestScheduler_RunNow(t *testing.T) {
+	t.Run("should record the status of the runs", func(t *testing.T) {
+		errTest := errors.New("test error")
+		var jobErr error
+
+		s, err := New("1h", func(ctx context.Context) error { return jobErr })
+		assert.NoError(t, err)
+
+		assert.Nil(t, s.Status().LastRun)
+
+		assert.NoError(t, s.RunNow(context.Background()))
+		status := s.Status()
+		assert.False(t, status.Running)
+		assert.Empty(t, status.LastRun.Error)
+		assert.Equal(t, status.LastRun.EndTime, *status.LastSuccess)
+
+		lastSuccess := *status.LastSuccess
+		jobErr = errTest
+
+		assert.ErrorIs(t, s.RunNow(context.Background()), errTest)
+		status = s.Status()
+		assert.Equal(t, "test error", status.LastRun.Error)
+		assert.Equal(t, lastSuccess, *status.LastSuccess)
+	})
+
+	t.Run("should not run the job while it is running", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+
+		s, err := New("1h", func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+		assert.NoError(t, err)
+
+		done := make(chan error)
+		go func() { done <- s.RunNow(context.Background()) }()
+
+		<-started
+		assert.True(t, s.Status().Running)
+		assert.ErrorIs(t, s.RunNow(context.Background()), ErrRunning)
+
+		close(release)
+		assert.NoError(t, <-done)
+		assert.False(t, s.Status().Running)
+	})
+}
+
+func TestScheduler_Run(t *testing.T) {
+	t.Run("should return an error when the job is nil", func(t *testing.T) {
+		s, err := New("1h", func(ctx context.Context) error { return nil })
+		assert.NoError(t, err)
+
+		assert.ErrorIs(t, s.Run(context.Background(), nil), ErrJobNil)
+	})
+
+	t.Run("should not run the given job while the scheduled job is running", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+
+		s, err := New("1h", func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+		assert.NoError(t, err)
+
+		done := make(chan error)
+		go func() { done <- s.RunNow(context.Background()) }()
+
+		<-started
+		var ran bool
+		assert.ErrorIs(t, s.Run(context.Background(), func(ctx context.Context) error { ran = true; return nil }), ErrRunning)
+		assert.False(t, ran)
+
+		close(release)
+		assert.NoError(t, <-done)
+
+		assert.NoError(t, s.Run(context.Background(), func(ctx context.Context) error { ran = true; return nil }))
+		assert.True(t, ran)
+		assert.NotNil(t, s.Status().LastSuccess)
+	})
+}
+
+func TestScheduler_Start(t *testing.T) {
+	t.Run("should run the job at start and on the schedule until the context is done", func(t *testing.T) {
+		var runs int32
+
+		s, err := New("10ms", func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		})
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+		defer cancel()
+
+		s.Start(ctx)
+
+		assert.GreaterOrEqual(t, atomic.LoadInt32(&runs), int32(3))
+		assert.Nil(t, s.Status().NextRun)
+	})
+
+	t.Run("should wait for the run in progress and not interrupt it", func(t *testing.T) {
+		started := make(chan struct{})
+		var completed int32
+
+		s, err := New("1h", func(ctx context.Context) error {
+			close(started)
+			time.Sleep(20 * time.Millisecond)
+			if ctx.Err() == nil {
+				atomic.StoreInt32(&completed, 1)
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-started
+			cancel()
+		}()
+
+		s.Start(ctx)
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&completed))
+		assert.NotNil(t, s.Status().LastSuccess)
+	})
+}
@@ -0,0 +1,40 @@
+package scim
+
+import (
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/metrics"
+)
+
+// Retries of the requests sent by NewRetryClient.
+const (
+	// DefaultRetryMax is the maximum number of retries of a request that failed.
+	DefaultRetryMax = 10
+
+	// DefaultRetryWaitMin is the minimum time waited before retrying a request that failed.
+	DefaultRetryWaitMin = 100 * time.Millisecond
+)
+
+// NewRetryClient returns the HTTP client of the SCIM Providers. It retries the requests that failed but the
+// throttled ones, see CheckRetry, returns the last response of the requests it gave up on, so the Providers
+// report their status code and body, counts the retries in the metrics and traces every request, see NewTransport.
+// The retries are logged when debug is true.
+func NewRetryClient(debug bool) *retryablehttp.Client {
+	retryClient := retryablehttp.NewClient()
+	retryClient.RetryMax = DefaultRetryMax
+	retryClient.RetryWaitMin = DefaultRetryWaitMin
+	retryClient.RequestLogHook = metrics.RetryHook
+	retryClient.CheckRetry = CheckRetry
+	retryClient.ErrorHandler = retryablehttp.PassthroughErrorHandler
+	retryClient.HTTPClient.Transport = NewTransport(retryClient.HTTPClient.Transport)
+
+	if debug {
+		retryClient.Logger = log.StandardLogger()
+	} else {
+		retryClient.Logger = nil
+	}
+
+	return retryClient
+}
@@ -0,0 +1,598 @@
+package scim
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	scimclient "github.com/slashdevops/idp-scim-sync/pkg/scim"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// This implement core.SCIMService interface for any SCIM 2.0 (RFC 7643/7644) compliant service
+
+//go:generate go run github.com/golang/mock/mockgen@v1.6.0 -package=mocks -destination=../../mocks/scim/generic_mocks.go -source=generic.go GenericSCIMProvider
+
+// GenericSCIMProvider interface to consume the generic SCIM client methods
+type GenericSCIMProvider interface {
+	// ListUsers lists users in SCIM Provider
+	ListUsers(ctx context.Context, filter string) (*scimclient.ListUsersResponse, error)
+
+	// CreateUser creates a user in SCIM Provider
+	CreateUser(ctx context.Context, user *scimclient.User) (*scimclient.User, error)
+
+	// PutUser replaces a user in SCIM Provider
+	PutUser(ctx context.Context, user *scimclient.User) (*scimclient.User, error)
+
+	// DeleteUser deletes a user in SCIM Provider
+	DeleteUser(ctx context.Context, id string) error
+
+	// ListGroups lists groups and their members in SCIM Provider
+	ListGroups(ctx context.Context, filter string) (*scimclient.ListGroupsResponse, error)
+
+	// CreateGroup creates a group in SCIM Provider
+	CreateGroup(ctx context.Context, group *scimclient.Group) (*scimclient.Group, error)
+
+	// PatchGroup patches a group in SCIM Provider
+	PatchGroup(ctx context.Context, id string, patch *scimclient.PatchOp) error
+
+	// DeleteGroup deletes a group in SCIM Provider
+	DeleteGroup(ctx context.Context, id string) error
+}
+
+// GenericProvider represents a generic SCIM 2.0 provider.
+// Unlike the AWS SSO SCIM API, a compliant SCIM service returns the members
+// of the groups, so they are read directly from the /Groups endpoint.
+type GenericProvider struct {
+	scim GenericSCIMProvider
+	sender
+}
+
+// NewGenericProvider creates a new generic SCIM 2.0 provider
+func NewGenericProvider(scim GenericSCIMProvider, opts ...ProviderOption) (*GenericProvider, error) {
+	if scim == nil {
+		return nil, ErrSCIMProviderNil
+	}
+
+	return &GenericProvider{
+		scim:   scim,
+		sender: newSender(opts...),
+	}, nil
+}
+
+// GetGroups returns groups from SCIM Provider
+func (s *GenericProvider) GetGroups(ctx context.Context) (*model.GroupsResult, error) {
+	groupsResponse, err := s.scim.ListGroups(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("scim: error listing groups: %w", err)
+	}
+
+	groups := make([]*model.Group, 0)
+	for _, group := range groupsResponse.Resources {
+		e := model.GroupBuilder().
+			WithSCIMID(group.ID).
+			WithName(group.DisplayName).
+			WithIPID(group.ExternalID).
+			Build()
+
+		groups = append(groups, e)
+	}
+
+	groupsResult := model.GroupsResultBuilder().WithResources(groups).Build()
+
+	return groupsResult, nil
+}
+
+// CreateGroups creates groups in SCIM Provider
+func (s *GenericProvider) CreateGroups(ctx context.Context, gr *model.GroupsResult) (*model.GroupsResult, error) {
+	groups := make([]*model.Group, len(gr.Resources))
+
+	err := s.forEach(ctx, len(gr.Resources), func(ctx context.Context, idx int) error {
+		group := gr.Resources[idx]
+
+		log.WithFields(log.Fields{
+			"group": group.Name,
+			"idpid": group.IPID,
+			"email": group.Email,
+		}).Trace("creating group (details)")
+
+		log.WithFields(log.Fields{
+			"group": group.Name,
+		}).Warn("creating group")
+
+		var r *scimclient.Group
+		err := s.call(ctx, func() (err error) {
+			r, err = s.scim.CreateGroup(ctx, &scimclient.Group{
+				DisplayName: group.Name,
+				ExternalID:  group.IPID,
+			})
+			return err
+		})
+		if err != nil {
+			return s.resourceError(model.ResourceGroup, model.OperationCreate, group.Name, fmt.Errorf("scim: error creating group: %s, %w", group.Name, err))
+		}
+
+		groups[idx] = model.GroupBuilder().
+			WithSCIMID(r.ID).
+			WithName(group.Name).
+			WithIPID(group.IPID).
+			WithEmail(group.Email).
+			Build()
+
+		return nil
+	})
+	if err != nil && !s.partial(ctx) {
+		return nil, err
+	}
+
+	groupsResult := model.GroupsResultBuilder().WithResources(compactGroups(groups)).Build()
+
+	return groupsResult, err
+}
+
+// UpdateGroups updates groups in SCIM Provider
+func (s *GenericProvider) UpdateGroups(ctx context.Context, gr *model.GroupsResult) (*model.GroupsResult, error) {
+	groups := make([]*model.Group, len(gr.Resources))
+
+	err := s.forEach(ctx, len(gr.Resources), func(ctx context.Context, idx int) error {
+		group := gr.Resources[idx]
+
+		log.WithFields(log.Fields{
+			"group":  group.Name,
+			"idpid":  group.IPID,
+			"scimid": group.SCIMID,
+			"email":  group.Email,
+		}).Trace("updating group (details)")
+
+		log.WithFields(log.Fields{
+			"group": group.Name,
+			"email": group.Email,
+		}).Warn("updating group")
+
+		patch := scimclient.NewPatchOp(&scimclient.Operation{
+			OP: "replace",
+			Value: map[string]string{
+				"displayName": group.Name,
+				"externalId":  group.IPID,
+			},
+		})
+
+		if err := s.call(ctx, func() error { return s.scim.PatchGroup(ctx, group.SCIMID, patch) }); err != nil {
+			return s.resourceError(model.ResourceGroup, model.OperationUpdate, group.Name, fmt.Errorf("scim: error updating groups: %w", err))
+		}
+
+		groups[idx] = model.GroupBuilder().
+			WithSCIMID(group.SCIMID).
+			WithName(group.Name).
+			WithIPID(group.IPID).
+			WithEmail(group.Email).
+			Build()
+
+		return nil
+	})
+	if err != nil && !s.partial(ctx) {
+		return nil, err
+	}
+
+	groupsResult := model.GroupsResultBuilder().WithResources(compactGroups(groups)).Build()
+
+	return groupsResult, err
+}
+
+// DeleteGroups deletes groups in SCIM Provider
+func (s *GenericProvider) DeleteGroups(ctx context.Context, gr *model.GroupsResult) error {
+	return s.forEach(ctx, len(gr.Resources), func(ctx context.Context, idx int) error {
+		group := gr.Resources[idx]
+
+		log.WithFields(log.Fields{
+			"group":  group.Name,
+			"idpid":  group.IPID,
+			"scimid": group.SCIMID,
+			"email":  group.Email,
+		}).Trace("deleting group (details)")
+
+		log.WithFields(log.Fields{
+			"group": group.Name,
+			"email": group.Email,
+		}).Warn("deleting group")
+
+		if err := s.call(ctx, func() error { return s.scim.DeleteGroup(ctx, group.SCIMID) }); err != nil {
+			return s.resourceError(model.ResourceGroup, model.OperationDelete, group.Name, fmt.Errorf("scim: error deleting group: %s, %w", group.SCIMID, err))
+		}
+
+		return nil
+	})
+}
+
+// GetUsers returns users from SCIM Provider
+func (s *GenericProvider) GetUsers(ctx context.Context) (*model.UsersResult, error) {
+	usersResponse, err := s.scim.ListUsers(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("scim: error listing users: %w", err)
+	}
+
+	users := make([]*model.User, 0)
+	for _, user := range usersResponse.Resources {
+		b := model.UserBuilder().
+			WithIPID(user.ExternalID).
+			WithSCIMID(user.ID).
+			WithDisplayName(user.DisplayName).
+			WithEmail(user.PrimaryEmail()).
+			WithActive(user.Active)
+
+		if user.Name != nil {
+			b = b.WithGivenName(user.Name.GivenName).WithFamilyName(user.Name.FamilyName)
+		}
+
+		users = append(users, b.Build())
+	}
+
+	usersResult := model.UsersResultBuilder().WithResources(users).Build()
+
+	return usersResult, nil
+}
+
+// toSCIMUser converts a model.User into a SCIM user resource.
+func toSCIMUser(user *model.User) *scimclient.User {
+	return &scimclient.User{
+		ID:          user.SCIMID,
+		ExternalID:  user.IPID,
+		UserName:    user.Email,
+		DisplayName: user.DisplayName,
+		Active:      user.Active,
+		Name: &scimclient.Name{
+			FamilyName: user.Name.FamilyName,
+			GivenName:  user.Name.GivenName,
+		},
+		Emails: []*scimclient.Email{
+			{
+				Value:   user.Email,
+				Type:    "work",
+				Primary: true,
+			},
+		},
+	}
+}
+
+// CreateUsers creates users in SCIM Provider
+func (s *GenericProvider) CreateUsers(ctx context.Context, ur *model.UsersResult) (*model.UsersResult, error) {
+	users := make([]*model.User, len(ur.Resources))
+
+	err := s.forEach(ctx, len(ur.Resources), func(ctx context.Context, idx int) error {
+		user := ur.Resources[idx]
+
+		log.WithFields(log.Fields{
+			"user":  user.DisplayName,
+			"email": user.Email,
+			"ipdid": user.IPID,
+		}).Trace("creating user (details)")
+
+		log.WithFields(log.Fields{
+			"user":  user.DisplayName,
+			"email": user.Email,
+		}).Warn("creating user")
+
+		userRequest := toSCIMUser(user)
+		userRequest.ID = ""
+
+		var r *scimclient.User
+		err := s.call(ctx, func() (err error) {
+			r, err = s.scim.CreateUser(ctx, userRequest)
+			return err
+		})
+		if err != nil {
+			return s.resourceError(model.ResourceUser, model.OperationCreate, user.Email, fmt.Errorf("scim: error creating user: %s, %w", user.Email, err))
+		}
+
+		users[idx] = model.UserBuilder().
+			WithIPID(user.IPID).
+			WithSCIMID(r.ID).
+			WithGivenName(user.Name.GivenName).
+			WithFamilyName(user.Name.FamilyName).
+			WithDisplayName(user.DisplayName).
+			WithEmail(user.Email).
+			WithActive(user.Active).
+			Build()
+
+		return nil
+	})
+	if err != nil && !s.partial(ctx) {
+		return nil, err
+	}
+
+	usersResult := model.UsersResultBuilder().WithResources(compactUsers(users)).Build()
+
+	return usersResult, err
+}
+
+// UpdateUsers updates users in SCIM Provider given a list of users
+func (s *GenericProvider) UpdateUsers(ctx context.Context, ur *model.UsersResult) (*model.UsersResult, error) {
+	users := make([]*model.User, len(ur.Resources))
+
+	err := s.forEach(ctx, len(ur.Resources), func(ctx context.Context, idx int) error {
+		user := ur.Resources[idx]
+
+		log.WithFields(log.Fields{
+			"user":   user.DisplayName,
+			"email":  user.Email,
+			"ipdid":  user.IPID,
+			"scimid": user.SCIMID,
+		}).Trace("updating user (details)")
+
+		log.WithFields(log.Fields{
+			"user":  user.DisplayName,
+			"email": user.Email,
+		}).Warn("updating user")
+
+		var r *scimclient.User
+		err := s.call(ctx, func() (err error) {
+			r, err = s.scim.PutUser(ctx, toSCIMUser(user))
+			return err
+		})
+		if err != nil {
+			return s.resourceError(model.ResourceUser, model.OperationUpdate, user.Email, fmt.Errorf("scim: error updating user: %s, %w", user.Email, err))
+		}
+
+		users[idx] = model.UserBuilder().
+			WithIPID(user.IPID).
+			WithSCIMID(r.ID).
+			WithGivenName(user.Name.GivenName).
+			WithFamilyName(user.Name.FamilyName).
+			WithDisplayName(user.DisplayName).
+			WithEmail(user.Email).
+			WithActive(user.Active).
+			Build()
+
+		return nil
+	})
+	if err != nil && !s.partial(ctx) {
+		return nil, err
+	}
+
+	usersResult := model.UsersResultBuilder().WithResources(compactUsers(users)).Build()
+
+	return usersResult, err
+}
+
+// DeleteUsers deletes users in SCIM Provider given a list of users
+func (s *GenericProvider) DeleteUsers(ctx context.Context, ur *model.UsersResult) error {
+	return s.forEach(ctx, len(ur.Resources), func(ctx context.Context, idx int) error {
+		user := ur.Resources[idx]
+
+		log.WithFields(log.Fields{
+			"user":   user.DisplayName,
+			"email":  user.Email,
+			"scimid": user.SCIMID,
+			"idpid":  user.IPID,
+		}).Trace("deleting user (details)")
+
+		log.WithFields(log.Fields{
+			"user":  user.DisplayName,
+			"email": user.Email,
+		}).Warn("deleting user")
+
+		if err := s.call(ctx, func() error { return s.scim.DeleteUser(ctx, user.SCIMID) }); err != nil {
+			return s.resourceError(model.ResourceUser, model.OperationDelete, user.Email, fmt.Errorf("scim: error deleting user: %s, %w", user.SCIMID, err))
+		}
+
+		return nil
+	})
+}
+
+// CreateGroupsMembers adds members to groups in SCIM Provider given a list of groups members.
+// The groups are patched at the same time, the requests of the members of every group are sequential.
+func (s *GenericProvider) CreateGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) (*model.GroupsMembersResult, error) {
+	groupsMembers := make([]*model.GroupMembers, len(gmr.Resources))
+
+	err := s.forEach(ctx, len(gmr.Resources), func(ctx context.Context, idx int) error {
+		groupMembers := gmr.Resources[idx]
+		members := make([]*model.Member, 0)
+		membersIDValue := make([]patchValue, 0)
+
+		for _, member := range groupMembers.Resources {
+			// the members are not modified, the same member could be in many of the groups patched at the same time
+			scimID := member.SCIMID
+			if scimID == "" {
+				var lur *scimclient.ListUsersResponse
+				err := s.call(ctx, func() (err error) {
+					lur, err = s.scim.ListUsers(ctx, fmt.Sprintf("userName eq %q", member.Email))
+					return err
+				})
+				if err != nil {
+					return s.resourceError(model.ResourceGroupMembers, model.OperationAdd, groupMembers.Group.Name, fmt.Errorf("scim: error getting user by email: %s, %w", member.Email, err))
+				}
+				if len(lur.Resources) == 0 {
+					return s.resourceError(model.ResourceGroupMembers, model.OperationAdd, groupMembers.Group.Name, fmt.Errorf("scim: user %s not found", member.Email))
+				}
+				scimID = lur.Resources[0].ID
+			}
+
+			membersIDValue = append(membersIDValue, patchValue{Value: scimID})
+
+			e := model.MemberBuilder().
+				WithIPID(member.IPID).
+				WithSCIMID(scimID).
+				WithEmail(member.Email).
+				WithStatus(member.Status).
+				Build()
+
+			members = append(members, e)
+
+			log.WithFields(log.Fields{
+				"group":  groupMembers.Group.Name,
+				"idpid":  member.IPID,
+				"scimid": scimID,
+				"email":  member.Email,
+				"status": member.Status,
+			}).Trace("adding member to group (details)")
+
+			log.WithFields(log.Fields{
+				"group": groupMembers.Group.Name,
+				"email": member.Email,
+			}).Warn("adding member to group")
+		}
+
+		for i := 0; i < len(membersIDValue); i += MaxPatchGroupMembersPerRequest {
+			end := i + MaxPatchGroupMembersPerRequest
+			if end > len(membersIDValue) {
+				end = len(membersIDValue)
+			}
+
+			patch := scimclient.NewPatchOp(&scimclient.Operation{
+				OP:    "add",
+				Path:  "members",
+				Value: membersIDValue[i:end],
+			})
+
+			if err := s.call(ctx, func() error { return s.scim.PatchGroup(ctx, groupMembers.Group.SCIMID, patch) }); err != nil {
+				return s.resourceError(model.ResourceGroupMembers, model.OperationAdd, groupMembers.Group.Name, fmt.Errorf("scim: error patching group: %s, %w", groupMembers.Group.Name, err))
+			}
+		}
+
+		groupsMembers[idx] = model.GroupMembersBuilder().
+			WithGroup(groupMembers.Group).
+			WithResources(members).
+			Build()
+
+		return nil
+	})
+	if err != nil && !s.partial(ctx) {
+		return nil, err
+	}
+
+	groupsMembersResult := model.GroupsMembersResultBuilder().WithResources(compactGroupsMembers(groupsMembers)).Build()
+
+	return groupsMembersResult, err
+}
+
+// DeleteGroupsMembers removes members from groups in SCIM Provider given a list of groups members.
+// Every member is removed using a value filter in the path, which is the form
+// defined by the RFC and supported by all the SCIM services.
+// The groups are patched at the same time, the requests of every group are sequential.
+// reference: https://datatracker.ietf.org/doc/html/rfc7644#section-3.5.2.2
+func (s *GenericProvider) DeleteGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) error {
+	return s.forEach(ctx, len(gmr.Resources), func(ctx context.Context, idx int) error {
+		groupMembers := gmr.Resources[idx]
+		operations := make([]*scimclient.Operation, 0)
+
+		for _, member := range groupMembers.Resources {
+			operations = append(operations, &scimclient.Operation{
+				OP:   "remove",
+				Path: fmt.Sprintf("members[value eq %q]", member.SCIMID),
+			})
+
+			log.WithFields(log.Fields{
+				"group":  groupMembers.Group.Name,
+				"idpid":  member.IPID,
+				"scimid": member.SCIMID,
+				"email":  member.Email,
+			}).Trace("removing member from group (details)")
+
+			log.WithFields(log.Fields{
+				"group": groupMembers.Group.Name,
+				"email": member.Email,
+			}).Warn("removing member from group")
+		}
+
+		for i := 0; i < len(operations); i += MaxPatchGroupMembersPerRequest {
+			end := i + MaxPatchGroupMembersPerRequest
+			if end > len(operations) {
+				end = len(operations)
+			}
+
+			patch := scimclient.NewPatchOp(operations[i:end]...)
+
+			if err := s.call(ctx, func() error { return s.scim.PatchGroup(ctx, groupMembers.Group.SCIMID, patch) }); err != nil {
+				return s.resourceError(model.ResourceGroupMembers, model.OperationRemove, groupMembers.Group.Name, fmt.Errorf("scim: error patching group: %s, %w", groupMembers.Group.Name, err))
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetGroupsMembers returns the given groups and their members from the SCIM Provider,
+// the members are read from the groups and matched with the users of the SCIM Provider.
+func (s *GenericProvider) GetGroupsMembers(ctx context.Context, gr *model.GroupsResult) (*model.GroupsMembersResult, error) {
+	ur, err := s.GetUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.groupsMembers(ctx, gr, ur)
+}
+
+// GetGroupsMembersBruteForce returns the given groups and their members from the SCIM Provider.
+// A compliant SCIM service returns the members of the groups, so there is no need to check
+// every user against every group, only the members that are part of the given users are returned.
+func (s *GenericProvider) GetGroupsMembersBruteForce(ctx context.Context, gr *model.GroupsResult, ur *model.UsersResult) (*model.GroupsMembersResult, error) {
+	return s.groupsMembers(ctx, gr, ur)
+}
+
+// groupsMembers lists the groups with their members and returns the members of the given groups
+// matched by SCIM id with the given users, members that are not in the users are ignored.
+func (s *GenericProvider) groupsMembers(ctx context.Context, gr *model.GroupsResult, ur *model.UsersResult) (*model.GroupsMembersResult, error) {
+	lgr, err := s.scim.ListGroups(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("scim: error listing groups: %w", err)
+	}
+
+	scimGroups := make(map[string]*scimclient.Group, len(lgr.Resources))
+	for _, group := range lgr.Resources {
+		scimGroups[group.ID] = group
+	}
+
+	users := make(map[string]*model.User, len(ur.Resources))
+	for _, user := range ur.Resources {
+		users[user.SCIMID] = user
+	}
+
+	groupMembers := make([]*model.GroupMembers, 0)
+
+	for _, group := range gr.Resources {
+		members := make([]*model.Member, 0)
+
+		scimGroup, ok := scimGroups[group.SCIMID]
+		if !ok {
+			log.WithFields(log.Fields{
+				"group":  group.Name,
+				"scimid": group.SCIMID,
+			}).Warn("scim: group not found in the SCIM Provider")
+		} else {
+			for _, member := range scimGroup.Members {
+				user, ok := users[member.Value]
+				if !ok {
+					log.WithFields(log.Fields{
+						"group":  group.Name,
+						"member": member.Value,
+						"type":   member.Type,
+					}).Trace("scim: ignoring group member, it is not one of the synced users")
+					continue
+				}
+
+				m := model.MemberBuilder().
+					WithIPID(user.IPID).
+					WithSCIMID(user.SCIMID).
+					WithEmail(user.Email).
+					Build()
+
+				if user.Active {
+					m.Status = "ACTIVE"
+				}
+				members = append(members, m)
+			}
+		}
+
+		e := model.GroupMembersBuilder().
+			WithGroup(group).
+			WithResources(members).
+			Build()
+
+		groupMembers = append(groupMembers, e)
+	}
+
+	groupsMembersResult := model.GroupsMembersResultBuilder().WithResources(groupMembers).Build()
+
+	return groupsMembersResult, nil
+}
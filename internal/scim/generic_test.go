@@ -0,0 +1,280 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	mocks "github.com/slashdevops/idp-scim-sync/mocks/scim"
+	scimclient "github.com/slashdevops/idp-scim-sync/pkg/scim"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGenericProvider(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return GenericProvider and no error", func(t *testing.T) {
+		mockSCIM := mocks.NewMockGenericSCIMProvider(mockCtrl)
+		svc, err := NewGenericProvider(mockSCIM)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, svc)
+	})
+
+	t.Run("Should return an error if no GenericSCIMProvider is provided", func(t *testing.T) {
+		svc, err := NewGenericProvider(nil)
+
+		assert.Nil(t, svc)
+		assert.ErrorIs(t, err, ErrSCIMProviderNil)
+	})
+
+	t.Run("Should apply the options", func(t *testing.T) {
+		svc, err := NewGenericProvider(mocks.NewMockGenericSCIMProvider(mockCtrl), WithConcurrency(8), WithRateLimit(10), WithContinueOnError(true))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 8, svc.concurrency)
+		assert.NotNil(t, svc.limiter)
+		assert.True(t, svc.continueOnError)
+	})
+}
+
+func TestGenericProvider_CreateUsers_ContinueOnError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ur := model.UsersResultBuilder().WithResources([]*model.User{
+		model.UserBuilder().WithIPID("1").WithEmail("user.1@mail.com").WithGivenName("user").WithFamilyName("1").Build(),
+		model.UserBuilder().WithIPID("2").WithEmail("user.2@mail.com").WithGivenName("user").WithFamilyName("2").Build(),
+	}).Build()
+
+	t.Run("Should return the users created and the errors of the users that failed", func(t *testing.T) {
+		mockSCIM := mocks.NewMockGenericSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().CreateUser(context.TODO(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, user *scimclient.User) (*scimclient.User, error) {
+				if user.UserName == "user.1@mail.com" {
+					return nil, &scimclient.HTTPResponseError{StatusCode: http.StatusBadRequest, Message: "invalid user"}
+				}
+				return &scimclient.User{ID: "scim-2"}, nil
+			}).Times(2)
+
+		svc, _ := NewGenericProvider(mockSCIM, WithContinueOnError(true))
+		got, err := svc.CreateUsers(context.TODO(), ur)
+
+		var re *model.ResourceError
+		assert.ErrorAs(t, err, &re)
+		assert.Equal(t, model.ResourceUser, re.Resource)
+		assert.Equal(t, "user.1@mail.com", re.Name)
+		assert.Equal(t, http.StatusBadRequest, re.StatusCode)
+		assert.Equal(t, "invalid user", re.Details)
+
+		assert.Equal(t, 1, got.Items)
+		assert.Equal(t, "scim-2", got.Resources[0].SCIMID)
+	})
+
+	t.Run("Should stop at the first error without continue on error", func(t *testing.T) {
+		mockSCIM := mocks.NewMockGenericSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().CreateUser(context.TODO(), gomock.Any()).Return(nil, errors.New("test error")).Times(1)
+
+		svc, _ := NewGenericProvider(mockSCIM)
+		got, err := svc.CreateUsers(context.TODO(), ur)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestGenericProvider_GetUsers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should return the users using the primary email", func(t *testing.T) {
+		mockSCIM := mocks.NewMockGenericSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().ListUsers(context.TODO(), "").Return(&scimclient.ListUsersResponse{
+			Resources: []*scimclient.User{
+				{
+					ID:          "1",
+					ExternalID:  "ext-1",
+					UserName:    "user.1",
+					DisplayName: "user 1",
+					Name:        &scimclient.Name{GivenName: "user", FamilyName: "1"},
+					Emails:      []*scimclient.Email{{Value: "user.1@mail.com", Primary: true}},
+					Active:      true,
+				},
+				{ID: "2", UserName: "user.2@mail.com"},
+			},
+		}, nil)
+
+		svc, _ := NewGenericProvider(mockSCIM)
+		ur, err := svc.GetUsers(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, ur.Items)
+		assert.Equal(t, "user.1@mail.com", ur.Resources[0].Email)
+		assert.Equal(t, "ext-1", ur.Resources[0].IPID)
+		assert.Equal(t, "user", ur.Resources[0].Name.GivenName)
+		assert.Equal(t, "user.2@mail.com", ur.Resources[1].Email)
+	})
+
+	t.Run("Should return an error", func(t *testing.T) {
+		mockSCIM := mocks.NewMockGenericSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().ListUsers(context.TODO(), "").Return(nil, errors.New("test error"))
+
+		svc, _ := NewGenericProvider(mockSCIM)
+		ur, err := svc.GetUsers(context.TODO())
+
+		assert.Error(t, err)
+		assert.Nil(t, ur)
+	})
+}
+
+func TestGenericProvider_UpdateGroups(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should replace the displayName and externalId", func(t *testing.T) {
+		mockSCIM := mocks.NewMockGenericSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().PatchGroup(context.TODO(), "scim-1", gomock.Any()).DoAndReturn(
+			func(ctx context.Context, id string, patch *scimclient.PatchOp) error {
+				assert.Len(t, patch.Operations, 1)
+				assert.Equal(t, "replace", patch.Operations[0].OP)
+				assert.Equal(t, map[string]string{"displayName": "group 1", "externalId": "idp-1"}, patch.Operations[0].Value)
+				return nil
+			})
+
+		gr := model.GroupsResultBuilder().WithResources([]*model.Group{
+			model.GroupBuilder().WithSCIMID("scim-1").WithIPID("idp-1").WithName("group 1").Build(),
+		}).Build()
+
+		svc, _ := NewGenericProvider(mockSCIM)
+		got, err := svc.UpdateGroups(context.TODO(), gr)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.Items)
+	})
+}
+
+func TestGenericProvider_CreateGroupsMembers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	group := model.GroupBuilder().WithSCIMID("scim-1").WithIPID("idp-1").WithName("group 1").Build()
+
+	t.Run("Should get the missing SCIM ids and split the members in several requests", func(t *testing.T) {
+		members := groupMembersGenerator(MaxPatchGroupMembersPerRequest+1, true, true)
+		members[0].SCIMID = ""
+
+		gmr := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+			model.GroupMembersBuilder().WithGroup(group).WithResources(members).Build(),
+		}).Build()
+
+		mockSCIM := mocks.NewMockGenericSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().ListUsers(context.TODO(), `userName eq "user.1@mail.com"`).Return(&scimclient.ListUsersResponse{
+			Resources: []*scimclient.User{{ID: "1", UserName: "user.1@mail.com"}},
+		}, nil)
+		mockSCIM.EXPECT().PatchGroup(context.TODO(), "scim-1", gomock.Any()).Return(nil).Times(2)
+
+		svc, _ := NewGenericProvider(mockSCIM)
+		got, err := svc.CreateGroupsMembers(context.TODO(), gmr)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.Items)
+		assert.Equal(t, "1", got.Resources[0].Resources[0].SCIMID)
+
+		// the members of the caller are not modified
+		assert.Equal(t, "", members[0].SCIMID)
+	})
+
+	t.Run("Should return an error when the user doesn't exist", func(t *testing.T) {
+		members := groupMembersGenerator(1, false, true)
+
+		gmr := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+			model.GroupMembersBuilder().WithGroup(group).WithResources(members).Build(),
+		}).Build()
+
+		mockSCIM := mocks.NewMockGenericSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().ListUsers(context.TODO(), gomock.Any()).Return(&scimclient.ListUsersResponse{}, nil)
+
+		svc, _ := NewGenericProvider(mockSCIM)
+		got, err := svc.CreateGroupsMembers(context.TODO(), gmr)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestGenericProvider_DeleteGroupsMembers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should remove every member using a value filter", func(t *testing.T) {
+		group := model.GroupBuilder().WithSCIMID("scim-1").WithName("group 1").Build()
+		gmr := model.GroupsMembersResultBuilder().WithResources([]*model.GroupMembers{
+			model.GroupMembersBuilder().WithGroup(group).WithResources(groupMembersGenerator(2, true, true)).Build(),
+		}).Build()
+
+		mockSCIM := mocks.NewMockGenericSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().PatchGroup(context.TODO(), "scim-1", gomock.Any()).DoAndReturn(
+			func(ctx context.Context, id string, patch *scimclient.PatchOp) error {
+				assert.Len(t, patch.Operations, 2)
+				for i, op := range patch.Operations {
+					assert.Equal(t, "remove", op.OP)
+					assert.Equal(t, fmt.Sprintf("members[value eq \"%d\"]", i+1), op.Path)
+				}
+				return nil
+			})
+
+		svc, _ := NewGenericProvider(mockSCIM)
+		err := svc.DeleteGroupsMembers(context.TODO(), gmr)
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestGenericProvider_GetGroupsMembersBruteForce(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	group1 := model.GroupBuilder().WithSCIMID("g1").WithName("group 1").Build()
+	group2 := model.GroupBuilder().WithSCIMID("g2").WithName("group 2").Build()
+	user1 := model.UserBuilder().WithSCIMID("u1").WithIPID("i1").WithEmail("user.1@mail.com").WithActive(true).Build()
+	user2 := model.UserBuilder().WithSCIMID("u2").WithIPID("i2").WithEmail("user.2@mail.com").WithActive(true).Build()
+
+	gr := model.GroupsResultBuilder().WithResources([]*model.Group{group1, group2}).Build()
+	ur := model.UsersResultBuilder().WithResources([]*model.User{user1, user2}).Build()
+
+	t.Run("Should return the members read from the groups", func(t *testing.T) {
+		mockSCIM := mocks.NewMockGenericSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().ListGroups(context.TODO(), "").Return(&scimclient.ListGroupsResponse{
+			Resources: []*scimclient.Group{
+				{ID: "g1", DisplayName: "group 1", Members: []*scimclient.Member{{Value: "u1"}, {Value: "u2"}, {Value: "not-synced"}}},
+				{ID: "g2", DisplayName: "group 2"},
+			},
+		}, nil)
+
+		svc, _ := NewGenericProvider(mockSCIM)
+		got, err := svc.GetGroupsMembersBruteForce(context.TODO(), gr, ur)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, 2, got.Resources[0].Items)
+		assert.Equal(t, "user.1@mail.com", got.Resources[0].Resources[0].Email)
+		assert.Equal(t, "ACTIVE", got.Resources[0].Resources[0].Status)
+		assert.Equal(t, 0, got.Resources[1].Items)
+	})
+
+	t.Run("Should return an error", func(t *testing.T) {
+		mockSCIM := mocks.NewMockGenericSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().ListGroups(context.TODO(), "").Return(nil, errors.New("test error"))
+
+		svc, _ := NewGenericProvider(mockSCIM)
+		got, err := svc.GetGroupsMembersBruteForce(context.TODO(), gr, ur)
+
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
@@ -0,0 +1,18 @@
+package scim
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/slashdevops/idp-scim-sync/internal/metrics"
+)
+
+var retriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Subsystem: "scim",
+	Name:      "throttled_retries_total",
+	Help:      "Number of requests throttled by the SCIM service and retried.",
+})
+
+// Collectors returns the Prometheus collectors of the SCIM provider.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{retriesTotal}
+}
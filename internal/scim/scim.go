@@ -3,6 +3,7 @@ package scim
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/slashdevops/idp-scim-sync/internal/model"
 	"github.com/slashdevops/idp-scim-sync/pkg/aws"
@@ -19,6 +20,9 @@ type AWSSCIMProvider interface {
 	// ListUsers lists users in SCIM Provider
 	ListUsers(ctx context.Context, filter string) (*aws.ListUsersResponse, error)
 
+	// ListAllUsers lists all the users in SCIM Provider following the pagination
+	ListAllUsers(ctx context.Context, filter string) (*aws.ListUsersResponse, error)
+
 	// CreateUser creates a user in SCIM Provider
 	CreateUser(ctx context.Context, u *aws.CreateUserRequest) (*aws.CreateUserResponse, error)
 
@@ -40,6 +44,9 @@ type AWSSCIMProvider interface {
 	// ListGroups lists groups in SCIM Provider
 	ListGroups(ctx context.Context, filter string) (*aws.ListGroupsResponse, error)
 
+	// ListAllGroups lists all the groups in SCIM Provider following the pagination
+	ListAllGroups(ctx context.Context, filter string) (*aws.ListGroupsResponse, error)
+
 	// CreateGroup creates a group in SCIM Provider
 	CreateGroup(ctx context.Context, g *aws.CreateGroupRequest) (*aws.CreateGroupResponse, error)
 
@@ -53,8 +60,21 @@ type AWSSCIMProvider interface {
 	PatchGroup(ctx context.Context, pgr *aws.PatchGroupRequest) error
 }
 
-// MaxPatchGroupMembersPerRequest is the Maximum members in group members in a single request.
-const MaxPatchGroupMembersPerRequest = 100
+const (
+	// MaxPatchGroupMembersPerRequest is the Maximum members in group members in a single request.
+	MaxPatchGroupMembersPerRequest = 100
+
+	// DefaultConcurrency is the default number of concurrent requests to the SCIM Provider,
+	// 1 means the requests are sequential.
+	DefaultConcurrency = 1
+
+	// DefaultMaxRetries is the default number of retries of a request throttled by the SCIM Provider.
+	DefaultMaxRetries = 3
+
+	// DefaultRetryWait is the default wait before the first retry of a throttled request,
+	// it is doubled in every retry.
+	DefaultRetryWait = time.Second
+)
 
 // ErrSCIMProviderNil is returned when the SCIMProvider is nil
 var ErrSCIMProviderNil = fmt.Errorf("scim: Provider is nil")
@@ -62,20 +82,61 @@ var ErrSCIMProviderNil = fmt.Errorf("scim: Provider is nil")
 // Provider represents a SCIM provider
 type Provider struct {
 	scim AWSSCIMProvider
+	sender
+}
+
+// ProviderOption is a function that configures how the requests are sent to the SCIM Provider.
+type ProviderOption func(*sender)
+
+// WithConcurrency sets the number of users, groups and groups members sent at the same time to the SCIM Provider.
+func WithConcurrency(n int) ProviderOption {
+	return func(s *sender) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// WithRateLimit sets the maximum number of requests per second to the SCIM Provider, 0 means no limit.
+func WithRateLimit(perSecond float64) ProviderOption {
+	return func(s *sender) {
+		s.rateLimit = perSecond
+	}
+}
+
+// WithMaxRetries sets the number of retries of the requests throttled by the SCIM Provider, 0 means no retries.
+func WithMaxRetries(n int) ProviderOption {
+	return func(s *sender) {
+		if n >= 0 {
+			s.maxRetries = n
+		}
+	}
+}
+
+// WithContinueOnError sends all the resources to the SCIM Provider even when some of them fail.
+// The methods return the resources synced together with the errors of the resources that failed,
+// every one of them wrapped in a model.ResourceError.
+func WithContinueOnError(continueOnError bool) ProviderOption {
+	return func(s *sender) {
+		s.continueOnError = continueOnError
+	}
 }
 
 // NewProvider creates a new SCIM provider
-func NewProvider(scim AWSSCIMProvider) (*Provider, error) {
+func NewProvider(scim AWSSCIMProvider, opts ...ProviderOption) (*Provider, error) {
 	if scim == nil {
 		return nil, ErrSCIMProviderNil
 	}
 
-	return &Provider{scim: scim}, nil
+	return &Provider{
+		scim:   scim,
+		sender: newSender(opts...),
+	}, nil
 }
 
 // GetGroups returns groups from SCIM Provider
 func (s *Provider) GetGroups(ctx context.Context) (*model.GroupsResult, error) {
-	groupsResponse, err := s.scim.ListGroups(ctx, "")
+	groupsResponse, err := s.scim.ListAllGroups(ctx, "")
 	if err != nil {
 		return nil, fmt.Errorf("scim: error listing groups: %w", err)
 	}
@@ -98,9 +159,11 @@ func (s *Provider) GetGroups(ctx context.Context) (*model.GroupsResult, error) {
 
 // CreateGroups creates groups in SCIM Provider
 func (s *Provider) CreateGroups(ctx context.Context, gr *model.GroupsResult) (*model.GroupsResult, error) {
-	groups := make([]*model.Group, 0)
+	groups := make([]*model.Group, len(gr.Resources))
+
+	err := s.forEach(ctx, len(gr.Resources), func(ctx context.Context, idx int) error {
+		group := gr.Resources[idx]
 
-	for _, group := range gr.Resources {
 		groupRequest := &aws.CreateGroupRequest{
 			DisplayName: group.Name,
 			ExternalID:  group.IPID,
@@ -117,31 +180,40 @@ func (s *Provider) CreateGroups(ctx context.Context, gr *model.GroupsResult) (*m
 		}).Warn("creating group")
 
 		// TODO: r, err := s.scim.CreateGroup(ctx, groupRequest)
-		r, err := s.scim.CreateOrGetGroup(ctx, groupRequest)
+		var r *aws.CreateGroupResponse
+		err := s.call(ctx, func() (err error) {
+			r, err = s.scim.CreateOrGetGroup(ctx, groupRequest)
+			return err
+		})
 		if err != nil {
-			return nil, fmt.Errorf("scim: error creating group: %w", err)
+			return s.resourceError(model.ResourceGroup, model.OperationCreate, group.Name, fmt.Errorf("scim: error creating group: %s, %w", group.Name, err))
 		}
 
-		e := model.GroupBuilder().
+		groups[idx] = model.GroupBuilder().
 			WithSCIMID(r.ID).
 			WithName(group.Name).
 			WithIPID(group.IPID).
 			WithEmail(group.Email).
 			Build()
 
-		groups = append(groups, e)
+		return nil
+	})
+	if err != nil && !s.partial(ctx) {
+		return nil, err
 	}
 
-	groupsResult := model.GroupsResultBuilder().WithResources(groups).Build()
+	groupsResult := model.GroupsResultBuilder().WithResources(compactGroups(groups)).Build()
 
-	return groupsResult, nil
+	return groupsResult, err
 }
 
 // UpdateGroups updates groups in SCIM Provider
 func (s *Provider) UpdateGroups(ctx context.Context, gr *model.GroupsResult) (*model.GroupsResult, error) {
-	groups := make([]*model.Group, 0)
+	groups := make([]*model.Group, len(gr.Resources))
+
+	err := s.forEach(ctx, len(gr.Resources), func(ctx context.Context, idx int) error {
+		group := gr.Resources[idx]
 
-	for _, group := range gr.Resources {
 		groupRequest := &aws.PatchGroupRequest{
 			Group: aws.Group{
 				ID:          group.SCIMID,
@@ -173,29 +245,34 @@ func (s *Provider) UpdateGroups(ctx context.Context, gr *model.GroupsResult) (*m
 			"email": group.Email,
 		}).Warn("updating group")
 
-		if err := s.scim.PatchGroup(ctx, groupRequest); err != nil {
-			return nil, fmt.Errorf("scim: error updating groups: %w", err)
+		if err := s.call(ctx, func() error { return s.scim.PatchGroup(ctx, groupRequest) }); err != nil {
+			return s.resourceError(model.ResourceGroup, model.OperationUpdate, group.Name, fmt.Errorf("scim: error updating groups: %w", err))
 		}
 
 		// return the same group
-		e := model.GroupBuilder().
+		groups[idx] = model.GroupBuilder().
 			WithSCIMID(group.SCIMID).
 			WithName(group.Name).
 			WithIPID(group.IPID).
 			WithEmail(group.Email).
 			Build()
 
-		groups = append(groups, e)
+		return nil
+	})
+	if err != nil && !s.partial(ctx) {
+		return nil, err
 	}
 
-	groupsResult := model.GroupsResultBuilder().WithResources(groups).Build()
+	groupsResult := model.GroupsResultBuilder().WithResources(compactGroups(groups)).Build()
 
-	return groupsResult, nil
+	return groupsResult, err
 }
 
 // DeleteGroups deletes groups in SCIM Provider
 func (s *Provider) DeleteGroups(ctx context.Context, gr *model.GroupsResult) error {
-	for _, group := range gr.Resources {
+	return s.forEach(ctx, len(gr.Resources), func(ctx context.Context, idx int) error {
+		group := gr.Resources[idx]
+
 		log.WithFields(log.Fields{
 			"group":  group.Name,
 			"idpid":  group.IPID,
@@ -208,16 +285,17 @@ func (s *Provider) DeleteGroups(ctx context.Context, gr *model.GroupsResult) err
 			"email": group.Email,
 		}).Trace("deleting group")
 
-		if err := s.scim.DeleteGroup(ctx, group.SCIMID); err != nil {
-			return fmt.Errorf("scim: error deleting group: %s, %w", group.SCIMID, err)
+		if err := s.call(ctx, func() error { return s.scim.DeleteGroup(ctx, group.SCIMID) }); err != nil {
+			return s.resourceError(model.ResourceGroup, model.OperationDelete, group.Name, fmt.Errorf("scim: error deleting group: %s, %w", group.SCIMID, err))
 		}
-	}
-	return nil
+
+		return nil
+	})
 }
 
 // GetUsers returns users from SCIM Provider
 func (s *Provider) GetUsers(ctx context.Context) (*model.UsersResult, error) {
-	usersResponse, err := s.scim.ListUsers(ctx, "")
+	usersResponse, err := s.scim.ListAllUsers(ctx, "")
 	if err != nil {
 		return nil, fmt.Errorf("scim: error listing users: %w", err)
 	}
@@ -244,9 +322,11 @@ func (s *Provider) GetUsers(ctx context.Context) (*model.UsersResult, error) {
 
 // CreateUsers creates users in SCIM Provider
 func (s *Provider) CreateUsers(ctx context.Context, ur *model.UsersResult) (*model.UsersResult, error) {
-	users := make([]*model.User, 0)
+	users := make([]*model.User, len(ur.Resources))
+
+	err := s.forEach(ctx, len(ur.Resources), func(ctx context.Context, idx int) error {
+		user := ur.Resources[idx]
 
-	for _, user := range ur.Resources {
 		userRequest := &aws.CreateUserRequest{
 			ID:          "",
 			UserName:    user.Email,
@@ -277,12 +357,16 @@ func (s *Provider) CreateUsers(ctx context.Context, ur *model.UsersResult) (*mod
 		}).Warn("creating user")
 
 		// TODO: r, err := s.scim.CreateUser(ctx, userRequest)
-		r, err := s.scim.CreateOrGetUser(ctx, userRequest)
+		var r *aws.CreateUserResponse
+		err := s.call(ctx, func() (err error) {
+			r, err = s.scim.CreateOrGetUser(ctx, userRequest)
+			return err
+		})
 		if err != nil {
-			return nil, fmt.Errorf("scim: error creating user: %w", err)
+			return s.resourceError(model.ResourceUser, model.OperationCreate, user.Email, fmt.Errorf("scim: error creating user: %s, %w", user.Email, err))
 		}
 
-		e := model.UserBuilder().
+		users[idx] = model.UserBuilder().
 			WithIPID(user.IPID).
 			WithSCIMID(r.ID).
 			WithGivenName(user.Name.GivenName).
@@ -292,19 +376,24 @@ func (s *Provider) CreateUsers(ctx context.Context, ur *model.UsersResult) (*mod
 			WithActive(user.Active).
 			Build()
 
-		users = append(users, e)
+		return nil
+	})
+	if err != nil && !s.partial(ctx) {
+		return nil, err
 	}
 
-	usersResult := model.UsersResultBuilder().WithResources(users).Build()
+	usersResult := model.UsersResultBuilder().WithResources(compactUsers(users)).Build()
 
-	return usersResult, nil
+	return usersResult, err
 }
 
 // UpdateUsers updates users in SCIM Provider given a list of users
 func (s *Provider) UpdateUsers(ctx context.Context, ur *model.UsersResult) (*model.UsersResult, error) {
-	users := make([]*model.User, 0)
+	users := make([]*model.User, len(ur.Resources))
+
+	err := s.forEach(ctx, len(ur.Resources), func(ctx context.Context, idx int) error {
+		user := ur.Resources[idx]
 
-	for _, user := range ur.Resources {
 		userRequest := &aws.PutUserRequest{
 			ID:          user.SCIMID,
 			DisplayName: user.DisplayName,
@@ -336,12 +425,16 @@ func (s *Provider) UpdateUsers(ctx context.Context, ur *model.UsersResult) (*mod
 			"email": user.Email,
 		}).Warn("updating user")
 
-		r, err := s.scim.PutUser(ctx, userRequest)
+		var r *aws.PutUserResponse
+		err := s.call(ctx, func() (err error) {
+			r, err = s.scim.PutUser(ctx, userRequest)
+			return err
+		})
 		if err != nil {
-			return nil, fmt.Errorf("scim: error updating user: %w", err)
+			return s.resourceError(model.ResourceUser, model.OperationUpdate, user.Email, fmt.Errorf("scim: error updating user: %s, %w", user.Email, err))
 		}
 
-		e := model.UserBuilder().
+		users[idx] = model.UserBuilder().
 			WithIPID(user.IPID).
 			WithSCIMID(r.ID).
 			WithGivenName(user.Name.GivenName).
@@ -351,17 +444,22 @@ func (s *Provider) UpdateUsers(ctx context.Context, ur *model.UsersResult) (*mod
 			WithActive(user.Active).
 			Build()
 
-		users = append(users, e)
+		return nil
+	})
+	if err != nil && !s.partial(ctx) {
+		return nil, err
 	}
 
-	usersResult := model.UsersResultBuilder().WithResources(users).Build()
+	usersResult := model.UsersResultBuilder().WithResources(compactUsers(users)).Build()
 
-	return usersResult, nil
+	return usersResult, err
 }
 
 // DeleteUsers deletes users in SCIM Provider given a list of users
 func (s *Provider) DeleteUsers(ctx context.Context, ur *model.UsersResult) error {
-	for _, user := range ur.Resources {
+	return s.forEach(ctx, len(ur.Resources), func(ctx context.Context, idx int) error {
+		user := ur.Resources[idx]
+
 		log.WithFields(log.Fields{
 			"user":   user.DisplayName,
 			"email":  user.Email,
@@ -374,41 +472,50 @@ func (s *Provider) DeleteUsers(ctx context.Context, ur *model.UsersResult) error
 			"email": user.Email,
 		}).Warn("deleting user")
 
-		if err := s.scim.DeleteUser(ctx, user.SCIMID); err != nil {
-			return fmt.Errorf("scim: error deleting user: %s, %w", user.SCIMID, err)
+		if err := s.call(ctx, func() error { return s.scim.DeleteUser(ctx, user.SCIMID) }); err != nil {
+			return s.resourceError(model.ResourceUser, model.OperationDelete, user.Email, fmt.Errorf("scim: error deleting user: %s, %w", user.SCIMID, err))
 		}
-	}
-	return nil
+
+		return nil
+	})
 }
 
 type patchValue struct {
 	Value string `json:"value"`
 }
 
-// CreateGroupsMembers creates groups members in SCIM Provider given a list of groups members
+// CreateGroupsMembers creates groups members in SCIM Provider given a list of groups members.
+// The groups are patched at the same time, the requests of the members of every group are sequential.
 func (s *Provider) CreateGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) (*model.GroupsMembersResult, error) {
-	groupsMembers := make([]*model.GroupMembers, 0)
+	groupsMembers := make([]*model.GroupMembers, len(gmr.Resources))
 
-	for _, groupMembers := range gmr.Resources {
+	err := s.forEach(ctx, len(gmr.Resources), func(ctx context.Context, idx int) error {
+		groupMembers := gmr.Resources[idx]
 		members := make([]*model.Member, 0)
 		membersIDValue := []patchValue{}
 
 		for _, member := range groupMembers.Resources {
-			if member.SCIMID == "" {
-				u, err := s.scim.GetUserByUserName(ctx, member.Email)
+			// the members are not modified, the same member could be in many of the groups patched at the same time
+			scimID := member.SCIMID
+			if scimID == "" {
+				var u *aws.GetUserResponse
+				err := s.call(ctx, func() (err error) {
+					u, err = s.scim.GetUserByUserName(ctx, member.Email)
+					return err
+				})
 				if err != nil {
-					return nil, fmt.Errorf("scim: error getting user by email: %w", err)
+					return s.resourceError(model.ResourceGroupMembers, model.OperationAdd, groupMembers.Group.Name, fmt.Errorf("scim: error getting user by email: %s, %w", member.Email, err))
 				}
-				member.SCIMID = u.ID
+				scimID = u.ID
 			}
 
 			membersIDValue = append(membersIDValue, patchValue{
-				Value: member.SCIMID,
+				Value: scimID,
 			})
 
 			e := model.MemberBuilder().
 				WithIPID(member.IPID).
-				WithSCIMID(member.SCIMID).
+				WithSCIMID(scimID).
 				WithEmail(member.Email).
 				WithStatus(member.Status).
 				Build()
@@ -418,7 +525,7 @@ func (s *Provider) CreateGroupsMembers(ctx context.Context, gmr *model.GroupsMem
 			log.WithFields(log.Fields{
 				"group":  groupMembers.Group.Name,
 				"idpid":  member.IPID,
-				"scimid": member.SCIMID,
+				"scimid": scimID,
 				"email":  member.Email,
 				"status": member.Status,
 			}).Trace("adding member to group (details)")
@@ -429,13 +536,6 @@ func (s *Provider) CreateGroupsMembers(ctx context.Context, gmr *model.GroupsMem
 			}).Warn("adding member to group")
 		}
 
-		e := model.GroupMembersBuilder().
-			WithGroup(groupMembers.Group).
-			WithResources(members).
-			Build()
-
-		groupsMembers = append(groupsMembers, e)
-
 		patchOperations := patchGroupOperations("add", "members", membersIDValue, groupMembers)
 
 		if len(patchOperations) > 1 {
@@ -447,20 +547,32 @@ func (s *Provider) CreateGroupsMembers(ctx context.Context, gmr *model.GroupsMem
 		}
 
 		for _, patchGroupRequest := range patchOperations {
-			if err := s.scim.PatchGroup(ctx, patchGroupRequest); err != nil {
-				return nil, fmt.Errorf("scim: error patching group: %w", err)
+			if err := s.call(ctx, func() error { return s.scim.PatchGroup(ctx, patchGroupRequest) }); err != nil {
+				return s.resourceError(model.ResourceGroupMembers, model.OperationAdd, groupMembers.Group.Name, fmt.Errorf("scim: error patching group: %s, %w", groupMembers.Group.Name, err))
 			}
 		}
+
+		groupsMembers[idx] = model.GroupMembersBuilder().
+			WithGroup(groupMembers.Group).
+			WithResources(members).
+			Build()
+
+		return nil
+	})
+	if err != nil && !s.partial(ctx) {
+		return nil, err
 	}
 
-	groupsMembersResult := model.GroupsMembersResultBuilder().WithResources(groupsMembers).Build()
+	groupsMembersResult := model.GroupsMembersResultBuilder().WithResources(compactGroupsMembers(groupsMembers)).Build()
 
-	return groupsMembersResult, nil
+	return groupsMembersResult, err
 }
 
-// DeleteGroupsMembers deletes groups members in SCIM Provider given a list of groups members
+// DeleteGroupsMembers deletes groups members in SCIM Provider given a list of groups members.
+// The groups are patched at the same time, the requests of every group are sequential.
 func (s *Provider) DeleteGroupsMembers(ctx context.Context, gmr *model.GroupsMembersResult) error {
-	for _, groupMembers := range gmr.Resources {
+	return s.forEach(ctx, len(gmr.Resources), func(ctx context.Context, idx int) error {
+		groupMembers := gmr.Resources[idx]
 		membersIDValue := []patchValue{}
 
 		for _, member := range groupMembers.Resources {
@@ -492,13 +604,13 @@ func (s *Provider) DeleteGroupsMembers(ctx context.Context, gmr *model.GroupsMem
 		}
 
 		for _, patchGroupRequest := range patchOperations {
-			if err := s.scim.PatchGroup(ctx, patchGroupRequest); err != nil {
-				return fmt.Errorf("scim: error patching group: %w", err)
+			if err := s.call(ctx, func() error { return s.scim.PatchGroup(ctx, patchGroupRequest) }); err != nil {
+				return s.resourceError(model.ResourceGroupMembers, model.OperationRemove, groupMembers.Group.Name, fmt.Errorf("scim: error patching group: %s, %w", groupMembers.Group.Name, err))
 			}
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
 // GetGroupsMembers returns a list of groups and their members from the SCIM Provider
@@ -65,7 +65,7 @@ func TestGetGroups(t *testing.T) {
 
 	t.Run("Should return a error", func(t *testing.T) {
 		mockSCIM := mocks.NewMockAWSSCIMProvider(mockCtrl)
-		mockSCIM.EXPECT().ListGroups(context.TODO(), gomock.Any()).Return(nil, errors.New("test error"))
+		mockSCIM.EXPECT().ListAllGroups(context.TODO(), gomock.Any()).Return(nil, errors.New("test error"))
 
 		svc, _ := NewProvider(mockSCIM)
 		gr, err := svc.GetGroups(context.TODO())
@@ -77,7 +77,7 @@ func TestGetGroups(t *testing.T) {
 	t.Run("Should return a empty list of groups and no error", func(t *testing.T) {
 		mockSCIM := mocks.NewMockAWSSCIMProvider(mockCtrl)
 		groups := &aws.ListGroupsResponse{}
-		mockSCIM.EXPECT().ListGroups(context.TODO(), gomock.Any()).Return(groups, nil)
+		mockSCIM.EXPECT().ListAllGroups(context.TODO(), gomock.Any()).Return(groups, nil)
 
 		svc, _ := NewProvider(mockSCIM)
 		gr, err := svc.GetGroups(context.TODO())
@@ -116,7 +116,7 @@ func TestGetGroups(t *testing.T) {
 			},
 		}
 
-		mockSCIM.EXPECT().ListGroups(context.TODO(), gomock.Any()).Return(groups, nil)
+		mockSCIM.EXPECT().ListAllGroups(context.TODO(), gomock.Any()).Return(groups, nil)
 
 		svc, _ := NewProvider(mockSCIM)
 		gr, err := svc.GetGroups(context.TODO())
@@ -553,7 +553,7 @@ func TestGetUsers(t *testing.T) {
 
 	t.Run("Should return a error", func(t *testing.T) {
 		mockSCIM := mocks.NewMockAWSSCIMProvider(mockCtrl)
-		mockSCIM.EXPECT().ListUsers(context.TODO(), gomock.Any()).Return(nil, errors.New("test error"))
+		mockSCIM.EXPECT().ListAllUsers(context.TODO(), gomock.Any()).Return(nil, errors.New("test error"))
 
 		svc, _ := NewProvider(mockSCIM)
 		gr, err := svc.GetUsers(context.TODO())
@@ -565,7 +565,7 @@ func TestGetUsers(t *testing.T) {
 	t.Run("Should return a empty list of users and no error", func(t *testing.T) {
 		mockSCIM := mocks.NewMockAWSSCIMProvider(mockCtrl)
 		users := &aws.ListUsersResponse{}
-		mockSCIM.EXPECT().ListUsers(context.TODO(), gomock.Any()).Return(users, nil)
+		mockSCIM.EXPECT().ListAllUsers(context.TODO(), gomock.Any()).Return(users, nil)
 
 		svc, _ := NewProvider(mockSCIM)
 		gr, err := svc.GetUsers(context.TODO())
@@ -608,7 +608,7 @@ func TestGetUsers(t *testing.T) {
 			},
 		}
 
-		mockSCIM.EXPECT().ListUsers(context.TODO(), gomock.Any()).Return(users, nil)
+		mockSCIM.EXPECT().ListAllUsers(context.TODO(), gomock.Any()).Return(users, nil)
 
 		svc, _ := NewProvider(mockSCIM)
 		gr, err := svc.GetUsers(context.TODO())
@@ -0,0 +1,264 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	"github.com/slashdevops/idp-scim-sync/internal/utils"
+	"github.com/slashdevops/idp-scim-sync/pkg/aws"
+	scimclient "github.com/slashdevops/idp-scim-sync/pkg/scim"
+)
+
+// Errors aggregates the errors of the items sent at the same time to the SCIM Provider.
+// errors.Is and errors.As match any of the aggregated errors.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("scim: %d errors: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the aggregated errors.
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any of the aggregated errors matches target.
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first of the aggregated errors that matches target.
+func (e Errors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sender sends the requests of the SCIM Providers, with the concurrency, the rate limit,
+// the retries of the throttled requests and the handling of the errors configured by the ProviderOption.
+type sender struct {
+	concurrency int
+	rateLimit   float64
+	limiter     *utils.RateLimiter
+	maxRetries  int
+	retryWait   time.Duration
+
+	// continueOnError sends all the resources even when some of them fail
+	continueOnError bool
+}
+
+// newSender returns a sender with the defaults and the options applied.
+func newSender(opts ...ProviderOption) sender {
+	s := sender{
+		concurrency: DefaultConcurrency,
+		maxRetries:  DefaultMaxRetries,
+		retryWait:   DefaultRetryWait,
+	}
+
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	// the burst allows every worker to send its first request without waiting
+	s.limiter = utils.NewRateLimiter(s.rateLimit, s.concurrency)
+
+	return s
+}
+
+// forEach calls fn for every index with the concurrency of the Provider.
+//
+// The first error stops sending new items, unless the Provider continues on error, and the errors
+// of the items already sent are aggregated with it into Errors, a single error is returned as it is.
+func (s *sender) forEach(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	errs := make([]error, n)
+
+	err := utils.ForEach(ctx, n, s.concurrency, func(ctx context.Context, i int) error {
+		errs[i] = fn(ctx, i)
+		if s.continueOnError {
+			return nil
+		}
+		return errs[i]
+	})
+	if err != nil && s.continueOnError {
+		// the parent context is done, so not all the items were sent
+		return err
+	}
+
+	failed := make(Errors, 0)
+	for _, e := range errs {
+		if e == nil {
+			continue
+		}
+
+		// the items cancelled after the first error are not failures by themselves
+		if errors.Is(e, context.Canceled) && ctx.Err() == nil {
+			continue
+		}
+
+		failed = append(failed, e)
+	}
+
+	switch len(failed) {
+	case 0:
+		return err
+	case 1:
+		return failed[0]
+	default:
+		return failed
+	}
+}
+
+// call calls fn, a request to the SCIM Provider, after waiting for the rate limit.
+// When the SCIM Provider throttles the request, responding 429 Too Many Requests, the request is retried
+// up to the max retries of the Provider waiting twice as long every time.
+func (s *sender) call(ctx context.Context, fn func() error) error {
+	wait := s.retryWait
+
+	for attempt := 0; ; attempt++ {
+		if err := s.limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		err := fn()
+		if err == nil || attempt >= s.maxRetries || !isThrottled(err) {
+			return err
+		}
+
+		log.WithFields(log.Fields{
+			"attempt": attempt + 1,
+			"wait":    wait,
+		}).Warn("scim: request throttled by the SCIM Provider, retrying")
+		retriesTotal.Inc()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		wait *= 2
+	}
+}
+
+// CheckRetry is the retryablehttp.CheckRetry of the HTTP client of the SCIM Providers, it retries the failed
+// requests like retryablehttp.DefaultRetryPolicy but the throttled ones, retried by the Providers waiting as configured.
+// The client must use retryablehttp.PassthroughErrorHandler, so the Providers receive the last response of the
+// requests that failed.
+func CheckRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if err == nil && resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		return false, nil
+	}
+
+	return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+}
+
+// isThrottled returns true when the error is a 429 Too Many Requests response.
+func isThrottled(err error) bool {
+	statusCode, _, ok := httpResponseError(err)
+	return ok && statusCode == http.StatusTooManyRequests
+}
+
+// httpResponseError returns the status code and the body of the response when the error is
+// an error response of the AWS SSO SCIM API or of a generic SCIM service.
+func httpResponseError(err error) (int, string, bool) {
+	var awsErr *aws.HTTPResponseError
+	if errors.As(err, &awsErr) {
+		return awsErr.StatusCode, awsErr.Message, true
+	}
+
+	var scimErr *scimclient.HTTPResponseError
+	if errors.As(err, &scimErr) {
+		return scimErr.StatusCode, scimErr.Message, true
+	}
+
+	return 0, "", false
+}
+
+// partial returns true when the resources synced are returned together with the errors of the
+// resources that failed, this is when the Provider continues on error and all the resources were sent.
+func (s *sender) partial(ctx context.Context) bool {
+	return s.continueOnError && ctx.Err() == nil
+}
+
+// resourceError wraps the error of a resource in a model.ResourceError when the Provider continues on error,
+// with the status code and the body of the response when the SCIM Provider responded with an error.
+// Otherwise the resources not sent are unknown, so the error is returned as it is.
+func (s *sender) resourceError(resource, operation, name string, err error) error {
+	if !s.continueOnError {
+		return err
+	}
+
+	re := &model.ResourceError{
+		Resource:  resource,
+		Operation: operation,
+		Name:      name,
+		Err:       err,
+	}
+
+	if statusCode, body, ok := httpResponseError(err); ok {
+		re.StatusCode = statusCode
+		re.Details = body
+	}
+
+	return re
+}
+
+// compactGroups removes the groups not synced, when the Provider continues on error.
+func compactGroups(groups []*model.Group) []*model.Group {
+	synced := make([]*model.Group, 0, len(groups))
+	for _, group := range groups {
+		if group != nil {
+			synced = append(synced, group)
+		}
+	}
+
+	return synced
+}
+
+// compactUsers removes the users not synced, when the Provider continues on error.
+func compactUsers(users []*model.User) []*model.User {
+	synced := make([]*model.User, 0, len(users))
+	for _, user := range users {
+		if user != nil {
+			synced = append(synced, user)
+		}
+	}
+
+	return synced
+}
+
+// compactGroupsMembers removes the groups members not synced, when the Provider continues on error.
+func compactGroupsMembers(groupsMembers []*model.GroupMembers) []*model.GroupMembers {
+	synced := make([]*model.GroupMembers, 0, len(groupsMembers))
+	for _, groupMembers := range groupsMembers {
+		if groupMembers != nil {
+			synced = append(synced, groupMembers)
+		}
+	}
+
+	return synced
+}
@@ -0,0 +1,295 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/slashdevops/idp-scim-sync/internal/model"
+	mocks "github.com/slashdevops/idp-scim-sync/mocks/scim"
+	"github.com/slashdevops/idp-scim-sync/pkg/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProvider_Options(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Should use the defaults", func(t *testing.T) {
+		svc, err := NewProvider(mocks.NewMockAWSSCIMProvider(mockCtrl))
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultConcurrency, svc.concurrency)
+		assert.Equal(t, DefaultMaxRetries, svc.maxRetries)
+		assert.Nil(t, svc.limiter)
+	})
+
+	t.Run("Should apply the options", func(t *testing.T) {
+		svc, err := NewProvider(mocks.NewMockAWSSCIMProvider(mockCtrl), WithConcurrency(8), WithRateLimit(10), WithMaxRetries(0))
+		assert.NoError(t, err)
+		assert.Equal(t, 8, svc.concurrency)
+		assert.Equal(t, 0, svc.maxRetries)
+		assert.NotNil(t, svc.limiter)
+	})
+
+	t.Run("Should ignore the invalid values", func(t *testing.T) {
+		svc, err := NewProvider(mocks.NewMockAWSSCIMProvider(mockCtrl), WithConcurrency(0), WithMaxRetries(-1))
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultConcurrency, svc.concurrency)
+		assert.Equal(t, DefaultMaxRetries, svc.maxRetries)
+	})
+}
+
+func TestCreateUsers_Concurrency(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	users := make([]*model.User, 50)
+	for i := range users {
+		users[i] = model.UserBuilder().
+			WithIPID(fmt.Sprintf("%d", i)).
+			WithEmail(fmt.Sprintf("user.%d@mail.com", i)).
+			WithGivenName("user").
+			WithFamilyName(fmt.Sprintf("%d", i)).
+			WithActive(true).
+			Build()
+	}
+	ur := model.UsersResultBuilder().WithResources(users).Build()
+
+	mockSCIM := mocks.NewMockAWSSCIMProvider(mockCtrl)
+	mockSCIM.EXPECT().CreateOrGetUser(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, u *aws.CreateUserRequest) (*aws.CreateUserResponse, error) {
+			return &aws.CreateUserResponse{ID: "scim-" + u.ExternalID}, nil
+		},
+	).Times(len(users))
+
+	svc, _ := NewProvider(mockSCIM, WithConcurrency(8), WithRateLimit(1000))
+	got, err := svc.CreateUsers(context.Background(), ur)
+	assert.NoError(t, err)
+	assert.Equal(t, len(users), got.Items)
+
+	for i, u := range got.Resources {
+		assert.Equal(t, users[i].Email, u.Email)
+		assert.Equal(t, "scim-"+users[i].IPID, u.SCIMID)
+	}
+}
+
+func TestProvider_Throttling(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	throttled := &aws.HTTPResponseError{StatusCode: http.StatusTooManyRequests, Code: "429 Too Many Requests"}
+	ur := model.UsersResultBuilder().WithResources([]*model.User{
+		model.UserBuilder().WithSCIMID("1").WithEmail("user.1@mail.com").Build(),
+	}).Build()
+
+	t.Run("Should retry the throttled requests", func(t *testing.T) {
+		mockSCIM := mocks.NewMockAWSSCIMProvider(mockCtrl)
+		gomock.InOrder(
+			mockSCIM.EXPECT().DeleteUser(gomock.Any(), "1").Return(throttled).Times(2),
+			mockSCIM.EXPECT().DeleteUser(gomock.Any(), "1").Return(nil).Times(1),
+		)
+
+		svc, _ := NewProvider(mockSCIM)
+		svc.retryWait = time.Millisecond
+
+		retries := testutil.ToFloat64(retriesTotal)
+		assert.NoError(t, svc.DeleteUsers(context.Background(), ur))
+		assert.Equal(t, retries+2, testutil.ToFloat64(retriesTotal))
+	})
+
+	t.Run("Should return the error when the retries are exhausted", func(t *testing.T) {
+		mockSCIM := mocks.NewMockAWSSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().DeleteUser(gomock.Any(), "1").Return(throttled).Times(3)
+
+		svc, _ := NewProvider(mockSCIM, WithMaxRetries(2))
+		svc.retryWait = time.Millisecond
+
+		err := svc.DeleteUsers(context.Background(), ur)
+		var httpErr *aws.HTTPResponseError
+		assert.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusTooManyRequests, httpErr.StatusCode)
+	})
+
+	t.Run("Should not retry the other errors", func(t *testing.T) {
+		mockSCIM := mocks.NewMockAWSSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().DeleteUser(gomock.Any(), "1").Return(&aws.HTTPResponseError{StatusCode: http.StatusBadRequest}).Times(1)
+
+		svc, _ := NewProvider(mockSCIM)
+
+		assert.Error(t, svc.DeleteUsers(context.Background(), ur))
+	})
+
+	t.Run("Should stop retrying when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		mockSCIM := mocks.NewMockAWSSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().DeleteUser(gomock.Any(), "1").DoAndReturn(func(context.Context, string) error {
+			cancel()
+			return throttled
+		}).Times(1)
+
+		svc, _ := NewProvider(mockSCIM)
+
+		assert.ErrorIs(t, svc.DeleteUsers(ctx, ur), context.Canceled)
+	})
+}
+
+func TestCheckRetry(t *testing.T) {
+	ur := model.UsersResultBuilder().WithResources([]*model.User{
+		model.UserBuilder().WithSCIMID("1").WithEmail("user.1@mail.com").Build(),
+	}).Build()
+
+	// newService returns the Provider using the client of NewRetryClient, with shorter retries,
+	// sending the requests to a server responding the given status codes in order, the last one for the rest
+	newService := func(t *testing.T, requests *int, codes ...int) *Provider {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			code := codes[len(codes)-1]
+			if *requests < len(codes) {
+				code = codes[*requests]
+			}
+			*requests++
+
+			w.WriteHeader(code)
+			_, _ = io.WriteString(w, http.StatusText(code))
+		}))
+		t.Cleanup(server.Close)
+
+		retryClient := NewRetryClient(false)
+		retryClient.RetryMax = 2
+		retryClient.RetryWaitMin = time.Millisecond
+		retryClient.RetryWaitMax = time.Millisecond
+
+		awsSCIM, err := aws.NewSCIMService(retryClient.StandardClient(), server.URL, "MyToken")
+		assert.NoError(t, err)
+
+		svc, err := NewProvider(awsSCIM, WithContinueOnError(true))
+		assert.NoError(t, err)
+		svc.retryWait = time.Millisecond
+
+		return svc
+	}
+
+	t.Run("Should leave the throttled requests to the Provider", func(t *testing.T) {
+		requests := 0
+		svc := newService(t, &requests, http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusNoContent)
+
+		retries := testutil.ToFloat64(retriesTotal)
+		assert.NoError(t, svc.DeleteUsers(context.Background(), ur))
+		assert.Equal(t, 3, requests)
+		assert.Equal(t, retries+2, testutil.ToFloat64(retriesTotal))
+	})
+
+	t.Run("Should return the last response when the client gives up", func(t *testing.T) {
+		requests := 0
+		svc := newService(t, &requests, http.StatusInternalServerError)
+
+		err := svc.DeleteUsers(context.Background(), ur)
+
+		// the first request and the retries of the client
+		assert.Equal(t, 3, requests)
+
+		var re *model.ResourceError
+		assert.ErrorAs(t, err, &re)
+		assert.Equal(t, http.StatusInternalServerError, re.StatusCode)
+		assert.Equal(t, http.StatusText(http.StatusInternalServerError), re.Details)
+	})
+}
+
+func TestProvider_AggregatedErrors(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	errTest := errors.New("test error")
+	ur := model.UsersResultBuilder().WithResources([]*model.User{
+		model.UserBuilder().WithSCIMID("1").WithEmail("user.1@mail.com").Build(),
+		model.UserBuilder().WithSCIMID("2").WithEmail("user.2@mail.com").Build(),
+	}).Build()
+
+	// both requests are in flight when they fail
+	var inFlight sync.WaitGroup
+	inFlight.Add(2)
+
+	mockSCIM := mocks.NewMockAWSSCIMProvider(mockCtrl)
+	mockSCIM.EXPECT().DeleteUser(gomock.Any(), gomock.Any()).DoAndReturn(func(context.Context, string) error {
+		inFlight.Done()
+		inFlight.Wait()
+		return errTest
+	}).Times(2)
+
+	svc, _ := NewProvider(mockSCIM, WithConcurrency(2))
+	err := svc.DeleteUsers(context.Background(), ur)
+
+	var errs Errors
+	assert.ErrorAs(t, err, &errs)
+	assert.Len(t, errs, 2)
+	assert.ErrorIs(t, err, errTest)
+	assert.Contains(t, err.Error(), "scim: 2 errors")
+}
+
+func TestProvider_ContinueOnError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ur := model.UsersResultBuilder().WithResources([]*model.User{
+		model.UserBuilder().WithIPID("1").WithSCIMID("1").WithEmail("user.1@mail.com").Build(),
+		model.UserBuilder().WithIPID("2").WithSCIMID("2").WithEmail("user.2@mail.com").Build(),
+		model.UserBuilder().WithIPID("3").WithSCIMID("3").WithEmail("user.3@mail.com").Build(),
+	}).Build()
+	conflict := &aws.HTTPResponseError{StatusCode: http.StatusConflict, Code: "409 Conflict", Message: "user already exists"}
+
+	t.Run("Should return the users created and the errors of the users that failed", func(t *testing.T) {
+		mockSCIM := mocks.NewMockAWSSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().CreateOrGetUser(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, u *aws.CreateUserRequest) (*aws.CreateUserResponse, error) {
+				if u.ExternalID == "2" {
+					return nil, conflict
+				}
+				return &aws.CreateUserResponse{ID: "scim-" + u.ExternalID}, nil
+			},
+		).Times(3)
+
+		svc, _ := NewProvider(mockSCIM, WithContinueOnError(true))
+		got, err := svc.CreateUsers(context.Background(), ur)
+		assert.Error(t, err)
+		assert.Equal(t, 2, got.Items)
+		assert.Equal(t, "user.1@mail.com", got.Resources[0].Email)
+		assert.Equal(t, "user.3@mail.com", got.Resources[1].Email)
+
+		res := model.ResourceErrors(err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, model.ResourceUser, res[0].Resource)
+		assert.Equal(t, model.OperationCreate, res[0].Operation)
+		assert.Equal(t, "user.2@mail.com", res[0].Name)
+		assert.Equal(t, http.StatusConflict, res[0].StatusCode)
+		assert.Equal(t, "user already exists", res[0].Details)
+	})
+
+	t.Run("Should delete all the users when some fail", func(t *testing.T) {
+		mockSCIM := mocks.NewMockAWSSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().DeleteUser(gomock.Any(), "1").Return(conflict).Times(1)
+		mockSCIM.EXPECT().DeleteUser(gomock.Any(), "2").Return(nil).Times(1)
+		mockSCIM.EXPECT().DeleteUser(gomock.Any(), "3").Return(conflict).Times(1)
+
+		svc, _ := NewProvider(mockSCIM, WithContinueOnError(true))
+		err := svc.DeleteUsers(context.Background(), ur)
+		assert.Len(t, model.ResourceErrors(err), 2)
+	})
+
+	t.Run("Should not wrap the errors when it doesn't continue on error", func(t *testing.T) {
+		mockSCIM := mocks.NewMockAWSSCIMProvider(mockCtrl)
+		mockSCIM.EXPECT().DeleteUser(gomock.Any(), "1").Return(conflict).Times(1)
+
+		svc, _ := NewProvider(mockSCIM)
+		err := svc.DeleteUsers(context.Background(), ur)
+		assert.ErrorIs(t, err, conflict)
+		assert.Empty(t, model.ResourceErrors(err))
+	})
+}
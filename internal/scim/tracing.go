@@ -0,0 +1,42 @@
+package scim
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerProvider creates the spans of the requests to the SCIM services, the spans are not exported
+// until a tracer provider is registered.
+var tracerProvider trace.TracerProvider = otel.GetTracerProvider()
+
+// resources are the SCIM resources naming the spans of the requests.
+var resources = map[string]struct{}{
+	"Users":                 {},
+	"Groups":                {},
+	"ServiceProviderConfig": {},
+	"Schemas":               {},
+	"ResourceTypes":         {},
+}
+
+// NewTransport returns a transport creating a span for every request sent with base to a SCIM service,
+// so every retry of a request has its own span, named by method and resource, like SCIM PATCH Groups.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base,
+		otelhttp.WithTracerProvider(tracerProvider),
+		otelhttp.WithSpanNameFormatter(spanName),
+	)
+}
+
+// spanName returns the name of the span of req, the ids of the path are left out of the name.
+func spanName(_ string, req *http.Request) string {
+	for _, segment := range strings.Split(req.URL.Path, "/") {
+		if _, ok := resources[segment]; ok {
+			return "SCIM " + req.Method + " " + segment
+		}
+	}
+	return "SCIM " + req.Method
+}
@@ -0,0 +1,90 @@
+package scim
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/slashdevops/idp-scim-sync/pkg/aws"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewTransport(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	previous := tracerProvider
+	tracerProvider = tp
+	defer func() { tracerProvider = previous }()
+
+	t.Run("Should create a span for every retry of a request", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		retryClient := retryablehttp.NewClient()
+		retryClient.RetryWaitMin = time.Millisecond
+		retryClient.RetryWaitMax = time.Millisecond
+		retryClient.Logger = nil
+		retryClient.HTTPClient.Transport = NewTransport(retryClient.HTTPClient.Transport)
+
+		awsSCIM, err := aws.NewSCIMService(retryClient.StandardClient(), server.URL+"/scim/v2", "MyToken")
+		assert.NoError(t, err)
+
+		ctx, parent := tp.Tracer("test").Start(context.Background(), "reconcile users")
+		err = awsSCIM.DeleteUser(ctx, "1")
+		parent.End()
+		assert.NoError(t, err)
+
+		spans := sr.Ended()
+		if !assert.Len(t, spans, 3) {
+			return
+		}
+
+		for _, span := range spans[:2] {
+			assert.Equal(t, "SCIM DELETE Users", span.Name())
+			assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		}
+
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Contains(t, spans[0].Attributes(), attribute.Int("http.status_code", http.StatusInternalServerError))
+		assert.Equal(t, codes.Unset, spans[1].Status().Code)
+		assert.Contains(t, spans[1].Attributes(), attribute.Int("http.status_code", http.StatusNoContent))
+	})
+}
+
+func TestSpanName(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		want   string
+	}{
+		{name: "resource", method: http.MethodGet, url: "https://testing.com/scim/v2/Users", want: "SCIM GET Users"},
+		{name: "resource with id", method: http.MethodPatch, url: "https://testing.com/scim/v2/Groups/1", want: "SCIM PATCH Groups"},
+		{name: "unknown resource", method: http.MethodGet, url: "https://testing.com/scim/v2/", want: "SCIM GET"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			assert.Equal(t, tt.want, spanName("", req))
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/core"
+	"github.com/slashdevops/idp-scim-sync/internal/scheduler"
+)
+
+// ErrTokenEmpty is returned when creating the sync handler without the token of the requests.
+var ErrTokenEmpty = errors.New("server: token may not be empty")
+
+// SyncFunc syncs the identity provider with the SCIM side and returns the report of the changes applied.
+type SyncFunc func(ctx context.Context) (*core.SyncReport, error)
+
+// PlanFunc returns the changes a sync would apply without applying them.
+type PlanFunc func(ctx context.Context) (*core.SyncPlan, error)
+
+// errorResponse is the body of the responses of the requests that fail without a report.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// SyncHandler returns an http.Handler running a sync on demand for the POST requests authenticated
+// with the token as bearer token, and returning the sync report.
+// The sync runs in s, so it never overlaps the scheduled syncs, and the requests received while a sync
+// is running are rejected with 409 Conflict. With the query parameter dryRun=true it returns the plan
+// of the changes instead, which doesn't modify the SCIM side or the state so it can run at any time.
+// The sync is not canceled when the client disconnects, to avoid leaving it half done.
+func SyncHandler(s *scheduler.Scheduler, token string, sync SyncFunc, plan PlanFunc) (http.Handler, error) {
+	if token == "" {
+		return nil, ErrTokenEmpty
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSON(w, http.StatusMethodNotAllowed, &errorResponse{Error: "method not allowed"})
+			return
+		}
+
+		if !authorized(r, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, &errorResponse{Error: "unauthorized"})
+			return
+		}
+
+		dryRun := false
+		if v := r.URL.Query().Get("dryRun"); v != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(v); err != nil {
+				writeJSON(w, http.StatusBadRequest, &errorResponse{Error: "invalid dryRun value: " + v})
+				return
+			}
+		}
+
+		log.WithFields(log.Fields{
+			"remoteAddr": r.RemoteAddr,
+			"dryRun":     dryRun,
+		}).Info("sync requested")
+
+		ctx := context.Background()
+
+		if dryRun {
+			p, err := plan(ctx)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, &errorResponse{Error: err.Error()})
+				return
+			}
+
+			writeJSON(w, http.StatusOK, p)
+			return
+		}
+
+		var report *core.SyncReport
+		err := s.Run(ctx, func(ctx context.Context) error {
+			var err error
+			report, err = sync(ctx)
+			return err
+		})
+
+		switch {
+		case errors.Is(err, scheduler.ErrRunning):
+			writeJSON(w, http.StatusConflict, &errorResponse{Error: "a sync is already running"})
+		case err != nil && report == nil:
+			writeJSON(w, http.StatusInternalServerError, &errorResponse{Error: err.Error()})
+		case err != nil:
+			// the report contains the error and the changes applied until it
+			writeJSON(w, http.StatusInternalServerError, report)
+		default:
+			writeJSON(w, http.StatusOK, report)
+		}
+	}), nil
+}
+
+// authorized returns true when the request has the token as bearer token.
+func authorized(r *http.Request, token string) bool {
+	auth := r.Header.Get("Authorization")
+
+	const prefix = "Bearer "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), []byte(token)) == 1
+}
+
+// writeJSON writes v as JSON with the status code.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.WithError(err).Error("cannot write the response")
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slashdevops/idp-scim-sync/internal/core"
+	"github.com/slashdevops/idp-scim-sync/internal/scheduler"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestScheduler(t *testing.T) *scheduler.Scheduler {
+	t.Helper()
+
+	s, err := scheduler.New("1h", func(ctx context.Context) error { return nil })
+	assert.NoError(t, err)
+
+	return s
+}
+
+func TestSyncHandler(t *testing.T) {
+	const token = "secret"
+
+	report := &core.SyncReport{Summary: &core.SyncSummary{UsersCreated: 1}}
+	plan := &core.SyncPlan{}
+
+	syncOK := func(ctx context.Context) (*core.SyncReport, error) { return report, nil }
+	planOK := func(ctx context.Context) (*core.SyncPlan, error) { return plan, nil }
+
+	t.Run("should return an error when the token is empty", func(t *testing.T) {
+		h, err := SyncHandler(newTestScheduler(t), "", syncOK, planOK)
+		assert.ErrorIs(t, err, ErrTokenEmpty)
+		assert.Nil(t, h)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		auth       string
+		sync       SyncFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "method not allowed", method: http.MethodGet, target: "/sync", auth: "Bearer " + token, sync: syncOK, wantStatus: http.StatusMethodNotAllowed},
+		{name: "missing token", method: http.MethodPost, target: "/sync", sync: syncOK, wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodPost, target: "/sync", auth: "Bearer wrong", sync: syncOK, wantStatus: http.StatusUnauthorized},
+		{name: "wrong scheme", method: http.MethodPost, target: "/sync", auth: "Basic " + token, sync: syncOK, wantStatus: http.StatusUnauthorized},
+		{name: "invalid dryRun", method: http.MethodPost, target: "/sync?dryRun=maybe", auth: "Bearer " + token, sync: syncOK, wantStatus: http.StatusBadRequest},
+		{name: "sync", method: http.MethodPost, target: "/sync", auth: "Bearer " + token, sync: syncOK, wantStatus: http.StatusOK, wantBody: `"usersCreated":1`},
+		{name: "dry run", method: http.MethodPost, target: "/sync?dryRun=true", auth: "bearer " + token, wantStatus: http.StatusOK},
+		{
+			name: "sync error with report", method: http.MethodPost, target: "/sync", auth: "Bearer " + token,
+			sync: func(ctx context.Context) (*core.SyncReport, error) {
+				return &core.SyncReport{Error: "test error"}, errors.New("test error")
+			},
+			wantStatus: http.StatusInternalServerError, wantBody: `"error":"test error"`,
+		},
+		{
+			name: "sync error without report", method: http.MethodPost, target: "/sync", auth: "Bearer " + token,
+			sync: func(ctx context.Context) (*core.SyncReport, error) {
+				return nil, errors.New("test error")
+			},
+			wantStatus: http.StatusInternalServerError, wantBody: `{"error":"test error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := SyncHandler(newTestScheduler(t), token, tt.sync, planOK)
+			assert.NoError(t, err)
+
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.True(t, json.Valid(rec.Body.Bytes()))
+			assert.Contains(t, rec.Body.String(), tt.wantBody)
+		})
+	}
+
+	t.Run("should record the sync in the scheduler status", func(t *testing.T) {
+		s := newTestScheduler(t)
+		h, err := SyncHandler(s, token, syncOK, planOK)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "/sync", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		status := s.Status()
+		assert.NotNil(t, status.LastRun)
+		assert.NotNil(t, status.LastSuccess)
+	})
+
+	t.Run("should return conflict when a sync is running", func(t *testing.T) {
+		s := newTestScheduler(t)
+		h, err := SyncHandler(s, token, syncOK, planOK)
+		assert.NoError(t, err)
+
+		started, release := make(chan struct{}), make(chan struct{})
+		done := make(chan error)
+		go func() {
+			done <- s.Run(context.Background(), func(ctx context.Context) error {
+				close(started)
+				<-release
+				return nil
+			})
+		}()
+		<-started
+
+		req := httptest.NewRequest(http.MethodPost, "/sync", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		// the dry run doesn't wait for the sync running
+		req = httptest.NewRequest(http.MethodPost, "/sync?dryRun=1", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		close(release)
+		assert.NoError(t, <-done)
+	})
+}
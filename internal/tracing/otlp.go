@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+)
+
+// DefaultExportTimeout is the default timeout exporting the spans to the OTLP endpoint.
+const DefaultExportTimeout = 10 * time.Second
+
+// tracesPath is the path of the OTLP/HTTP traces receiver.
+const tracesPath = "/v1/traces"
+
+var (
+	// ErrEndpointEmpty is returned when creating an OTLP exporter without endpoint.
+	ErrEndpointEmpty = errors.New("tracing: otlp endpoint may not be empty")
+
+	// ErrEndpointInvalid is returned when the OTLP endpoint is not an http or https URL.
+	ErrEndpointInvalid = errors.New("tracing: otlp endpoint must be an http or https url")
+)
+
+// NewOTLPExporter returns a new exporter sending the spans to the OTLP/HTTP receiver at endpoint,
+// for example http://localhost:4318, the receiver could be an OpenTelemetry collector or Jaeger.
+// reference: https://opentelemetry.io/docs/specs/otlp/#otlphttp
+func NewOTLPExporter(ctx context.Context, endpoint string) (*otlptrace.Exporter, error) {
+	if endpoint == "" {
+		return nil, ErrEndpointEmpty
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: error parsing otlp endpoint: %w", err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrEndpointInvalid
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithURLPath(strings.TrimSuffix(u.Path, "/") + tracesPath),
+		otlptracehttp.WithTimeout(DefaultExportTimeout),
+	}
+
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exp, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: error creating otlp exporter: %w", err)
+	}
+
+	return exp, nil
+}
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestNewOTLPExporter(t *testing.T) {
+	t.Run("should return an error when the endpoint is empty", func(t *testing.T) {
+		exp, err := NewOTLPExporter(context.Background(), "")
+		assert.ErrorIs(t, err, ErrEndpointEmpty)
+		assert.Nil(t, exp)
+	})
+
+	t.Run("should return an error when the endpoint is not an http url", func(t *testing.T) {
+		exp, err := NewOTLPExporter(context.Background(), "localhost:4318")
+		assert.ErrorIs(t, err, ErrEndpointInvalid)
+		assert.Nil(t, exp)
+	})
+
+	t.Run("should send the spans to the traces path of the endpoint", func(t *testing.T) {
+		requests := 0
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/otlp/v1/traces", r.URL.Path)
+			assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, body)
+		}))
+		defer svr.Close()
+
+		exp, err := NewOTLPExporter(context.Background(), svr.URL+"/otlp/")
+		assert.NoError(t, err)
+
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+
+		_, span := tp.Tracer("test").Start(context.Background(), "sync")
+		span.End()
+
+		assert.NoError(t, tp.Shutdown(context.Background()))
+		assert.Equal(t, 1, requests)
+	})
+}
@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName is the name of the service of all the spans of idp-scim-sync.
+const ServiceName = "idpscim"
+
+// ErrExporterNil is returned when creating a tracer provider without exporter.
+var ErrExporterNil = errors.New("tracing: exporter may not be nil")
+
+// NewTracerProvider returns a new tracer provider exporting the spans in batches with exp,
+// and registers it as the global tracer provider used by all the instrumented packages.
+// The tracer provider must be shut down at the end to export the pending spans.
+func NewTracerProvider(exp sdktrace.SpanExporter, version string) (*sdktrace.TracerProvider, error) {
+	if exp == nil {
+		return nil, ErrExporterNil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String(ServiceName),
+		semconv.ServiceVersionKey.String(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: error creating resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(tp)
+
+	return tp, nil
+}
+
+// End records err in span, when it is not nil, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestNewTracerProvider(t *testing.T) {
+	t.Run("should return an error when the exporter is nil", func(t *testing.T) {
+		tp, err := NewTracerProvider(nil, "test")
+		assert.ErrorIs(t, err, ErrExporterNil)
+		assert.Nil(t, tp)
+	})
+}
+
+func TestEnd(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	_, span := tp.Tracer("test").Start(context.Background(), "ok")
+	End(span, nil)
+
+	_, span = tp.Tracer("test").Start(context.Background(), "error")
+	End(span, errors.New("test error"))
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "test error", spans[1].Status().Description)
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ForEach calls fn for every index from 0 to n-1 using up to concurrency goroutines.
+//
+// The callers store the results by index, so the order is the same of a sequential loop.
+// When concurrency is lower than 2 the calls are sequential and receive the same ctx.
+// The first error cancels the context received by the pending calls and is returned.
+func ForEach(ctx context.Context, n, concurrency int, fn func(ctx context.Context, i int) error) error {
+	if concurrency < 2 || n < 2 {
+		for i := 0; i < n; i++ {
+			if err := fn(ctx, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if concurrency > n {
+		concurrency = n
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	indexes := make(chan int)
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := fn(ctx, i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+	var sendErr error
+send:
+	for i := 0; i < n; i++ {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			sendErr = ctx.Err()
+			break send
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	// the parent context was cancelled before sending all the indexes
+	return sendErr
+}
+
+// RateLimiter is a token bucket that limits the number of operations per second,
+// it is safe for concurrent use and a nil RateLimiter doesn't limit.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing perSecond operations per second with bursts of up to burst operations.
+// It returns nil, no limit, when perSecond is not greater than 0.
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until an operation is allowed or the context is done.
+func (rl *RateLimiter) Wait(ctx context.Context) error {
+	if rl == nil {
+		return ctx.Err()
+	}
+
+	rl.mu.Lock()
+	now := time.Now()
+	rl.tokens += now.Sub(rl.last).Seconds() * rl.rate
+	if rl.tokens > rl.burst {
+		rl.tokens = rl.burst
+	}
+	rl.last = now
+
+	// the token is reserved even when it is not available yet, so the waiting callers are served in order
+	rl.tokens--
+	var delay time.Duration
+	if rl.tokens < 0 {
+		delay = time.Duration(-rl.tokens / rl.rate * float64(time.Second))
+	}
+	rl.mu.Unlock()
+
+	if delay == 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
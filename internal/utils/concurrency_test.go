@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForEach(t *testing.T) {
+	t.Run("should call fn for every index keeping the results order", func(t *testing.T) {
+		for _, concurrency := range []int{0, 1, 4, 100} {
+			results := make([]int, 50)
+
+			err := ForEach(context.Background(), len(results), concurrency, func(ctx context.Context, i int) error {
+				results[i] = i * 2
+				return nil
+			})
+			assert.NoError(t, err)
+
+			for i, r := range results {
+				assert.Equal(t, i*2, r)
+			}
+		}
+	})
+
+	t.Run("should not run more than concurrency calls at the same time", func(t *testing.T) {
+		var running, maxRunning int32
+
+		err := ForEach(context.Background(), 20, 3, func(ctx context.Context, i int) error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, maxRunning, int32(3))
+	})
+
+	t.Run("should pass the same context when the calls are sequential", func(t *testing.T) {
+		type key struct{}
+		parent := context.WithValue(context.Background(), key{}, "value")
+
+		err := ForEach(parent, 2, 1, func(ctx context.Context, i int) error {
+			assert.Equal(t, parent, ctx)
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("should return the error and cancel the pending calls", func(t *testing.T) {
+		errTest := errors.New("test error")
+		var calls int32
+
+		err := ForEach(context.Background(), 1000, 4, func(ctx context.Context, i int) error {
+			atomic.AddInt32(&calls, 1)
+			if i == 0 {
+				return errTest
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		assert.ErrorIs(t, err, errTest)
+		assert.Less(t, atomic.LoadInt32(&calls), int32(1000))
+	})
+
+	t.Run("should return the error of the sequential calls", func(t *testing.T) {
+		errTest := errors.New("test error")
+		calls := 0
+
+		err := ForEach(context.Background(), 10, 1, func(ctx context.Context, i int) error {
+			calls++
+			return errTest
+		})
+		assert.ErrorIs(t, err, errTest)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("should return the context error when the parent context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := ForEach(ctx, 10, 2, func(ctx context.Context, i int) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("should return nil when the rate is not defined", func(t *testing.T) {
+		rl := NewRateLimiter(0, 1)
+		assert.Nil(t, rl)
+		assert.NoError(t, rl.Wait(context.Background()))
+	})
+
+	t.Run("should allow the burst and then limit the rate", func(t *testing.T) {
+		rl := NewRateLimiter(100, 2)
+
+		start := time.Now()
+		for i := 0; i < 6; i++ {
+			assert.NoError(t, rl.Wait(context.Background()))
+		}
+
+		// 2 operations of burst and 4 at 100 per second
+		assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+	})
+
+	t.Run("should return the context error while waiting", func(t *testing.T) {
+		rl := NewRateLimiter(0.1, 1)
+		assert.NoError(t, rl.Wait(context.Background()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, rl.Wait(ctx), context.DeadlineExceeded)
+	})
+}
@@ -50,6 +50,50 @@ func (mr *MockStateRepositoryMockRecorder) GetState(ctx interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetState", reflect.TypeOf((*MockStateRepository)(nil).GetState), ctx)
 }
 
+// GetStateVersion mocks base method.
+func (m *MockStateRepository) GetStateVersion(ctx context.Context, id string) (*model.State, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStateVersion", ctx, id)
+	ret0, _ := ret[0].(*model.State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStateVersion indicates an expected call of GetStateVersion.
+func (mr *MockStateRepositoryMockRecorder) GetStateVersion(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStateVersion", reflect.TypeOf((*MockStateRepository)(nil).GetStateVersion), ctx, id)
+}
+
+// ListStates mocks base method.
+func (m *MockStateRepository) ListStates(ctx context.Context) ([]*model.StateVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListStates", ctx)
+	ret0, _ := ret[0].([]*model.StateVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListStates indicates an expected call of ListStates.
+func (mr *MockStateRepositoryMockRecorder) ListStates(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStates", reflect.TypeOf((*MockStateRepository)(nil).ListStates), ctx)
+}
+
+// Lock mocks base method.
+func (m *MockStateRepository) Lock(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lock", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Lock indicates an expected call of Lock.
+func (mr *MockStateRepositoryMockRecorder) Lock(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lock", reflect.TypeOf((*MockStateRepository)(nil).Lock), ctx)
+}
+
 // SetState mocks base method.
 func (m *MockStateRepository) SetState(ctx context.Context, state *model.State) error {
 	m.ctrl.T.Helper()
@@ -63,3 +107,17 @@ func (mr *MockStateRepositoryMockRecorder) SetState(ctx, state interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetState", reflect.TypeOf((*MockStateRepository)(nil).SetState), ctx, state)
 }
+
+// Unlock mocks base method.
+func (m *MockStateRepository) Unlock(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlock", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlock indicates an expected call of Unlock.
+func (mr *MockStateRepositoryMockRecorder) Unlock(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockStateRepository)(nil).Unlock), ctx)
+}
@@ -0,0 +1,81 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: entra.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	microsoft "github.com/slashdevops/idp-scim-sync/pkg/microsoft"
+)
+
+// MockEntraProviderService is a mock of EntraProviderService interface.
+type MockEntraProviderService struct {
+	ctrl     *gomock.Controller
+	recorder *MockEntraProviderServiceMockRecorder
+}
+
+// MockEntraProviderServiceMockRecorder is the mock recorder for MockEntraProviderService.
+type MockEntraProviderServiceMockRecorder struct {
+	mock *MockEntraProviderService
+}
+
+// NewMockEntraProviderService creates a new mock instance.
+func NewMockEntraProviderService(ctrl *gomock.Controller) *MockEntraProviderService {
+	mock := &MockEntraProviderService{ctrl: ctrl}
+	mock.recorder = &MockEntraProviderServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEntraProviderService) EXPECT() *MockEntraProviderServiceMockRecorder {
+	return m.recorder
+}
+
+// ListGroupTransitiveMembers mocks base method.
+func (m *MockEntraProviderService) ListGroupTransitiveMembers(ctx context.Context, groupID string) ([]*microsoft.DirectoryObject, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGroupTransitiveMembers", ctx, groupID)
+	ret0, _ := ret[0].([]*microsoft.DirectoryObject)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGroupTransitiveMembers indicates an expected call of ListGroupTransitiveMembers.
+func (mr *MockEntraProviderServiceMockRecorder) ListGroupTransitiveMembers(ctx, groupID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGroupTransitiveMembers", reflect.TypeOf((*MockEntraProviderService)(nil).ListGroupTransitiveMembers), ctx, groupID)
+}
+
+// ListGroups mocks base method.
+func (m *MockEntraProviderService) ListGroups(ctx context.Context, filters []string) ([]*microsoft.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGroups", ctx, filters)
+	ret0, _ := ret[0].([]*microsoft.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGroups indicates an expected call of ListGroups.
+func (mr *MockEntraProviderServiceMockRecorder) ListGroups(ctx, filters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGroups", reflect.TypeOf((*MockEntraProviderService)(nil).ListGroups), ctx, filters)
+}
+
+// ListUsers mocks base method.
+func (m *MockEntraProviderService) ListUsers(ctx context.Context, filters []string) ([]*microsoft.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", ctx, filters)
+	ret0, _ := ret[0].([]*microsoft.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockEntraProviderServiceMockRecorder) ListUsers(ctx, filters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockEntraProviderService)(nil).ListUsers), ctx, filters)
+}
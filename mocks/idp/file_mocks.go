@@ -0,0 +1,81 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: file.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	file "github.com/slashdevops/idp-scim-sync/pkg/file"
+)
+
+// MockFileProviderService is a mock of FileProviderService interface.
+type MockFileProviderService struct {
+	ctrl     *gomock.Controller
+	recorder *MockFileProviderServiceMockRecorder
+}
+
+// MockFileProviderServiceMockRecorder is the mock recorder for MockFileProviderService.
+type MockFileProviderServiceMockRecorder struct {
+	mock *MockFileProviderService
+}
+
+// NewMockFileProviderService creates a new mock instance.
+func NewMockFileProviderService(ctrl *gomock.Controller) *MockFileProviderService {
+	mock := &MockFileProviderService{ctrl: ctrl}
+	mock.recorder = &MockFileProviderServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFileProviderService) EXPECT() *MockFileProviderServiceMockRecorder {
+	return m.recorder
+}
+
+// ListGroupMembers mocks base method.
+func (m *MockFileProviderService) ListGroupMembers(ctx context.Context, groupName string) ([]*file.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGroupMembers", ctx, groupName)
+	ret0, _ := ret[0].([]*file.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGroupMembers indicates an expected call of ListGroupMembers.
+func (mr *MockFileProviderServiceMockRecorder) ListGroupMembers(ctx, groupName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGroupMembers", reflect.TypeOf((*MockFileProviderService)(nil).ListGroupMembers), ctx, groupName)
+}
+
+// ListGroups mocks base method.
+func (m *MockFileProviderService) ListGroups(ctx context.Context, filters []string) ([]*file.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGroups", ctx, filters)
+	ret0, _ := ret[0].([]*file.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGroups indicates an expected call of ListGroups.
+func (mr *MockFileProviderServiceMockRecorder) ListGroups(ctx, filters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGroups", reflect.TypeOf((*MockFileProviderService)(nil).ListGroups), ctx, filters)
+}
+
+// ListUsers mocks base method.
+func (m *MockFileProviderService) ListUsers(ctx context.Context, filters []string) ([]*file.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", ctx, filters)
+	ret0, _ := ret[0].([]*file.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockFileProviderServiceMockRecorder) ListUsers(ctx, filters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockFileProviderService)(nil).ListUsers), ctx, filters)
+}
@@ -0,0 +1,81 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ldap.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	ldap "github.com/slashdevops/idp-scim-sync/pkg/ldap"
+)
+
+// MockLDAPProviderService is a mock of LDAPProviderService interface.
+type MockLDAPProviderService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLDAPProviderServiceMockRecorder
+}
+
+// MockLDAPProviderServiceMockRecorder is the mock recorder for MockLDAPProviderService.
+type MockLDAPProviderServiceMockRecorder struct {
+	mock *MockLDAPProviderService
+}
+
+// NewMockLDAPProviderService creates a new mock instance.
+func NewMockLDAPProviderService(ctrl *gomock.Controller) *MockLDAPProviderService {
+	mock := &MockLDAPProviderService{ctrl: ctrl}
+	mock.recorder = &MockLDAPProviderServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLDAPProviderService) EXPECT() *MockLDAPProviderServiceMockRecorder {
+	return m.recorder
+}
+
+// ListGroupMembers mocks base method.
+func (m *MockLDAPProviderService) ListGroupMembers(ctx context.Context, groupDN string) ([]*ldap.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGroupMembers", ctx, groupDN)
+	ret0, _ := ret[0].([]*ldap.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGroupMembers indicates an expected call of ListGroupMembers.
+func (mr *MockLDAPProviderServiceMockRecorder) ListGroupMembers(ctx, groupDN interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGroupMembers", reflect.TypeOf((*MockLDAPProviderService)(nil).ListGroupMembers), ctx, groupDN)
+}
+
+// ListGroups mocks base method.
+func (m *MockLDAPProviderService) ListGroups(ctx context.Context, filters []string) ([]*ldap.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGroups", ctx, filters)
+	ret0, _ := ret[0].([]*ldap.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGroups indicates an expected call of ListGroups.
+func (mr *MockLDAPProviderServiceMockRecorder) ListGroups(ctx, filters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGroups", reflect.TypeOf((*MockLDAPProviderService)(nil).ListGroups), ctx, filters)
+}
+
+// ListUsers mocks base method.
+func (m *MockLDAPProviderService) ListUsers(ctx context.Context, filters []string) ([]*ldap.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", ctx, filters)
+	ret0, _ := ret[0].([]*ldap.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockLDAPProviderServiceMockRecorder) ListUsers(ctx, filters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockLDAPProviderService)(nil).ListUsers), ctx, filters)
+}
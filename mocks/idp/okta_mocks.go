@@ -0,0 +1,81 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: okta.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	okta "github.com/slashdevops/idp-scim-sync/pkg/okta"
+)
+
+// MockOktaProviderService is a mock of OktaProviderService interface.
+type MockOktaProviderService struct {
+	ctrl     *gomock.Controller
+	recorder *MockOktaProviderServiceMockRecorder
+}
+
+// MockOktaProviderServiceMockRecorder is the mock recorder for MockOktaProviderService.
+type MockOktaProviderServiceMockRecorder struct {
+	mock *MockOktaProviderService
+}
+
+// NewMockOktaProviderService creates a new mock instance.
+func NewMockOktaProviderService(ctrl *gomock.Controller) *MockOktaProviderService {
+	mock := &MockOktaProviderService{ctrl: ctrl}
+	mock.recorder = &MockOktaProviderServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOktaProviderService) EXPECT() *MockOktaProviderServiceMockRecorder {
+	return m.recorder
+}
+
+// ListGroupUsers mocks base method.
+func (m *MockOktaProviderService) ListGroupUsers(ctx context.Context, groupID string) ([]*okta.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGroupUsers", ctx, groupID)
+	ret0, _ := ret[0].([]*okta.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGroupUsers indicates an expected call of ListGroupUsers.
+func (mr *MockOktaProviderServiceMockRecorder) ListGroupUsers(ctx, groupID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGroupUsers", reflect.TypeOf((*MockOktaProviderService)(nil).ListGroupUsers), ctx, groupID)
+}
+
+// ListGroups mocks base method.
+func (m *MockOktaProviderService) ListGroups(ctx context.Context, searches []string) ([]*okta.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGroups", ctx, searches)
+	ret0, _ := ret[0].([]*okta.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGroups indicates an expected call of ListGroups.
+func (mr *MockOktaProviderServiceMockRecorder) ListGroups(ctx, searches interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGroups", reflect.TypeOf((*MockOktaProviderService)(nil).ListGroups), ctx, searches)
+}
+
+// ListUsers mocks base method.
+func (m *MockOktaProviderService) ListUsers(ctx context.Context, searches []string) ([]*okta.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", ctx, searches)
+	ret0, _ := ret[0].([]*okta.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockOktaProviderServiceMockRecorder) ListUsers(ctx, searches interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockOktaProviderService)(nil).ListUsers), ctx, searches)
+}
@@ -0,0 +1,96 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: dynamodb.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockDynamoDBClientAPI is a mock of DynamoDBClientAPI interface.
+type MockDynamoDBClientAPI struct {
+	ctrl     *gomock.Controller
+	recorder *MockDynamoDBClientAPIMockRecorder
+}
+
+// MockDynamoDBClientAPIMockRecorder is the mock recorder for MockDynamoDBClientAPI.
+type MockDynamoDBClientAPIMockRecorder struct {
+	mock *MockDynamoDBClientAPI
+}
+
+// NewMockDynamoDBClientAPI creates a new mock instance.
+func NewMockDynamoDBClientAPI(ctrl *gomock.Controller) *MockDynamoDBClientAPI {
+	mock := &MockDynamoDBClientAPI{ctrl: ctrl}
+	mock.recorder = &MockDynamoDBClientAPIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDynamoDBClientAPI) EXPECT() *MockDynamoDBClientAPIMockRecorder {
+	return m.recorder
+}
+
+// DeleteItem mocks base method.
+func (m *MockDynamoDBClientAPI) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteItem", varargs...)
+	ret0, _ := ret[0].(*dynamodb.DeleteItemOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteItem indicates an expected call of DeleteItem.
+func (mr *MockDynamoDBClientAPIMockRecorder) DeleteItem(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteItem", reflect.TypeOf((*MockDynamoDBClientAPI)(nil).DeleteItem), varargs...)
+}
+
+// GetItem mocks base method.
+func (m *MockDynamoDBClientAPI) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetItem", varargs...)
+	ret0, _ := ret[0].(*dynamodb.GetItemOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetItem indicates an expected call of GetItem.
+func (mr *MockDynamoDBClientAPIMockRecorder) GetItem(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItem", reflect.TypeOf((*MockDynamoDBClientAPI)(nil).GetItem), varargs...)
+}
+
+// PutItem mocks base method.
+func (m *MockDynamoDBClientAPI) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "PutItem", varargs...)
+	ret0, _ := ret[0].(*dynamodb.PutItemOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PutItem indicates an expected call of PutItem.
+func (mr *MockDynamoDBClientAPIMockRecorder) PutItem(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutItem", reflect.TypeOf((*MockDynamoDBClientAPI)(nil).PutItem), varargs...)
+}
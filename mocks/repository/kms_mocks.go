@@ -0,0 +1,129 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: encryption.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	kms "github.com/aws/aws-sdk-go-v2/service/kms"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockStateCipher is a mock of StateCipher interface.
+type MockStateCipher struct {
+	ctrl     *gomock.Controller
+	recorder *MockStateCipherMockRecorder
+}
+
+// MockStateCipherMockRecorder is the mock recorder for MockStateCipher.
+type MockStateCipherMockRecorder struct {
+	mock *MockStateCipher
+}
+
+// NewMockStateCipher creates a new mock instance.
+func NewMockStateCipher(ctrl *gomock.Controller) *MockStateCipher {
+	mock := &MockStateCipher{ctrl: ctrl}
+	mock.recorder = &MockStateCipherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStateCipher) EXPECT() *MockStateCipherMockRecorder {
+	return m.recorder
+}
+
+// Decrypt mocks base method.
+func (m *MockStateCipher) Decrypt(ctx context.Context, data []byte) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Decrypt", ctx, data)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Decrypt indicates an expected call of Decrypt.
+func (mr *MockStateCipherMockRecorder) Decrypt(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Decrypt", reflect.TypeOf((*MockStateCipher)(nil).Decrypt), ctx, data)
+}
+
+// Encrypt mocks base method.
+func (m *MockStateCipher) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Encrypt", ctx, plaintext)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Encrypt indicates an expected call of Encrypt.
+func (mr *MockStateCipherMockRecorder) Encrypt(ctx, plaintext interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Encrypt", reflect.TypeOf((*MockStateCipher)(nil).Encrypt), ctx, plaintext)
+}
+
+// MockKMSClientAPI is a mock of KMSClientAPI interface.
+type MockKMSClientAPI struct {
+	ctrl     *gomock.Controller
+	recorder *MockKMSClientAPIMockRecorder
+}
+
+// MockKMSClientAPIMockRecorder is the mock recorder for MockKMSClientAPI.
+type MockKMSClientAPIMockRecorder struct {
+	mock *MockKMSClientAPI
+}
+
+// NewMockKMSClientAPI creates a new mock instance.
+func NewMockKMSClientAPI(ctrl *gomock.Controller) *MockKMSClientAPI {
+	mock := &MockKMSClientAPI{ctrl: ctrl}
+	mock.recorder = &MockKMSClientAPIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockKMSClientAPI) EXPECT() *MockKMSClientAPIMockRecorder {
+	return m.recorder
+}
+
+// Decrypt mocks base method.
+func (m *MockKMSClientAPI) Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Decrypt", varargs...)
+	ret0, _ := ret[0].(*kms.DecryptOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Decrypt indicates an expected call of Decrypt.
+func (mr *MockKMSClientAPIMockRecorder) Decrypt(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Decrypt", reflect.TypeOf((*MockKMSClientAPI)(nil).Decrypt), varargs...)
+}
+
+// GenerateDataKey mocks base method.
+func (m *MockKMSClientAPI) GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, params}
+	for _, a := range optFns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GenerateDataKey", varargs...)
+	ret0, _ := ret[0].(*kms.GenerateDataKeyOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateDataKey indicates an expected call of GenerateDataKey.
+func (mr *MockKMSClientAPIMockRecorder) GenerateDataKey(ctx, params interface{}, optFns ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, params}, optFns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateDataKey", reflect.TypeOf((*MockKMSClientAPI)(nil).GenerateDataKey), varargs...)
+}
@@ -0,0 +1,153 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: generic.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	scim "github.com/slashdevops/idp-scim-sync/pkg/scim"
+)
+
+// MockGenericSCIMProvider is a mock of GenericSCIMProvider interface.
+type MockGenericSCIMProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockGenericSCIMProviderMockRecorder
+}
+
+// MockGenericSCIMProviderMockRecorder is the mock recorder for MockGenericSCIMProvider.
+type MockGenericSCIMProviderMockRecorder struct {
+	mock *MockGenericSCIMProvider
+}
+
+// NewMockGenericSCIMProvider creates a new mock instance.
+func NewMockGenericSCIMProvider(ctrl *gomock.Controller) *MockGenericSCIMProvider {
+	mock := &MockGenericSCIMProvider{ctrl: ctrl}
+	mock.recorder = &MockGenericSCIMProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGenericSCIMProvider) EXPECT() *MockGenericSCIMProviderMockRecorder {
+	return m.recorder
+}
+
+// CreateGroup mocks base method.
+func (m *MockGenericSCIMProvider) CreateGroup(ctx context.Context, group *scim.Group) (*scim.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateGroup", ctx, group)
+	ret0, _ := ret[0].(*scim.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateGroup indicates an expected call of CreateGroup.
+func (mr *MockGenericSCIMProviderMockRecorder) CreateGroup(ctx, group interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGroup", reflect.TypeOf((*MockGenericSCIMProvider)(nil).CreateGroup), ctx, group)
+}
+
+// CreateUser mocks base method.
+func (m *MockGenericSCIMProvider) CreateUser(ctx context.Context, user *scim.User) (*scim.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", ctx, user)
+	ret0, _ := ret[0].(*scim.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockGenericSCIMProviderMockRecorder) CreateUser(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockGenericSCIMProvider)(nil).CreateUser), ctx, user)
+}
+
+// DeleteGroup mocks base method.
+func (m *MockGenericSCIMProvider) DeleteGroup(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteGroup", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteGroup indicates an expected call of DeleteGroup.
+func (mr *MockGenericSCIMProviderMockRecorder) DeleteGroup(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteGroup", reflect.TypeOf((*MockGenericSCIMProvider)(nil).DeleteGroup), ctx, id)
+}
+
+// DeleteUser mocks base method.
+func (m *MockGenericSCIMProvider) DeleteUser(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockGenericSCIMProviderMockRecorder) DeleteUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockGenericSCIMProvider)(nil).DeleteUser), ctx, id)
+}
+
+// ListGroups mocks base method.
+func (m *MockGenericSCIMProvider) ListGroups(ctx context.Context, filter string) (*scim.ListGroupsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGroups", ctx, filter)
+	ret0, _ := ret[0].(*scim.ListGroupsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGroups indicates an expected call of ListGroups.
+func (mr *MockGenericSCIMProviderMockRecorder) ListGroups(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGroups", reflect.TypeOf((*MockGenericSCIMProvider)(nil).ListGroups), ctx, filter)
+}
+
+// ListUsers mocks base method.
+func (m *MockGenericSCIMProvider) ListUsers(ctx context.Context, filter string) (*scim.ListUsersResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", ctx, filter)
+	ret0, _ := ret[0].(*scim.ListUsersResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockGenericSCIMProviderMockRecorder) ListUsers(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockGenericSCIMProvider)(nil).ListUsers), ctx, filter)
+}
+
+// PatchGroup mocks base method.
+func (m *MockGenericSCIMProvider) PatchGroup(ctx context.Context, id string, patch *scim.PatchOp) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchGroup", ctx, id, patch)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PatchGroup indicates an expected call of PatchGroup.
+func (mr *MockGenericSCIMProviderMockRecorder) PatchGroup(ctx, id, patch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchGroup", reflect.TypeOf((*MockGenericSCIMProvider)(nil).PatchGroup), ctx, id, patch)
+}
+
+// PutUser mocks base method.
+func (m *MockGenericSCIMProvider) PutUser(ctx context.Context, user *scim.User) (*scim.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutUser", ctx, user)
+	ret0, _ := ret[0].(*scim.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PutUser indicates an expected call of PutUser.
+func (mr *MockGenericSCIMProviderMockRecorder) PutUser(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutUser", reflect.TypeOf((*MockGenericSCIMProvider)(nil).PutUser), ctx, user)
+}
@@ -153,6 +153,36 @@ func (mr *MockAWSSCIMProviderMockRecorder) GetUserByUserName(ctx, userName inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUserName", reflect.TypeOf((*MockAWSSCIMProvider)(nil).GetUserByUserName), ctx, userName)
 }
 
+// ListAllGroups mocks base method.
+func (m *MockAWSSCIMProvider) ListAllGroups(ctx context.Context, filter string) (*aws.ListGroupsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAllGroups", ctx, filter)
+	ret0, _ := ret[0].(*aws.ListGroupsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAllGroups indicates an expected call of ListAllGroups.
+func (mr *MockAWSSCIMProviderMockRecorder) ListAllGroups(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAllGroups", reflect.TypeOf((*MockAWSSCIMProvider)(nil).ListAllGroups), ctx, filter)
+}
+
+// ListAllUsers mocks base method.
+func (m *MockAWSSCIMProvider) ListAllUsers(ctx context.Context, filter string) (*aws.ListUsersResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAllUsers", ctx, filter)
+	ret0, _ := ret[0].(*aws.ListUsersResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAllUsers indicates an expected call of ListAllUsers.
+func (mr *MockAWSSCIMProviderMockRecorder) ListAllUsers(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAllUsers", reflect.TypeOf((*MockAWSSCIMProvider)(nil).ListAllUsers), ctx, filter)
+}
+
 // ListGroups mocks base method.
 func (m *MockAWSSCIMProvider) ListGroups(ctx context.Context, filter string) (*aws.ListGroupsResponse, error) {
 	m.ctrl.T.Helper()
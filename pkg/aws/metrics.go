@@ -0,0 +1,58 @@
+package aws
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	scimRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "idpscim",
+		Subsystem: "aws_scim",
+		Name:      "requests_total",
+		Help:      "Number of requests to the AWS SSO SCIM API by endpoint, method and status code.",
+	}, []string{"endpoint", "method", "code"})
+
+	scimRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "idpscim",
+		Subsystem: "aws_scim",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the requests to the AWS SSO SCIM API by endpoint and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "method"})
+)
+
+// Collectors returns the Prometheus collectors of the requests to the AWS SSO SCIM API.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{scimRequestsTotal, scimRequestDuration}
+}
+
+// observeRequest records the metrics of a request sent at start, resp is nil when the request failed
+// before receiving a response.
+func (s *SCIMService) observeRequest(req *http.Request, resp *http.Response, start time.Time) {
+	endpoint := s.endpoint(req)
+	code := "error"
+	if resp != nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+
+	scimRequestsTotal.WithLabelValues(endpoint, req.Method, code).Inc()
+	scimRequestDuration.WithLabelValues(endpoint, req.Method).Observe(time.Since(start).Seconds())
+}
+
+// endpoint returns the resource type requested, Users, Groups or ServiceProviderConfig,
+// without the ids of the resources, to keep a low cardinality in the metrics labels.
+func (s *SCIMService) endpoint(req *http.Request) string {
+	p := strings.TrimPrefix(req.URL.Path, strings.TrimSuffix(s.url.Path, "/"))
+	p = strings.TrimPrefix(p, "/")
+
+	if i := strings.Index(p, "/"); i >= 0 {
+		p = p[:i]
+	}
+
+	return p
+}
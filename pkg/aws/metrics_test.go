@@ -0,0 +1,101 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	mocks "github.com/slashdevops/idp-scim-sync/mocks/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSCIMService_Endpoint(t *testing.T) {
+	service, err := NewSCIMService(nil, "https://scim.eu-west-1.amazonaws.com/tenant/scim/v2/", "MyToken")
+	assert.NoError(t, err)
+
+	tests := map[string]string{
+		"https://scim.eu-west-1.amazonaws.com/tenant/scim/v2/Users":                 "Users",
+		"https://scim.eu-west-1.amazonaws.com/tenant/scim/v2/Users/1234":            "Users",
+		"https://scim.eu-west-1.amazonaws.com/tenant/scim/v2/Groups/1234?filter=id": "Groups",
+		"https://scim.eu-west-1.amazonaws.com/tenant/scim/v2/ServiceProviderConfig": "ServiceProviderConfig",
+	}
+	for u, want := range tests {
+		req := httptest.NewRequest(http.MethodGet, u, nil)
+		assert.Equal(t, want, service.endpoint(req), u)
+	}
+}
+
+func TestSCIMService_ObserveRequest(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	endpoint := "https://testing.com/scim/v2"
+
+	t.Run("should count the requests by status code", func(t *testing.T) {
+		mockHTTPClient := mocks.NewMockHTTPClient(mockCtrl)
+		mockHTTPClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil)
+
+		service, err := NewSCIMService(mockHTTPClient, endpoint, "MyToken")
+		assert.NoError(t, err)
+
+		before := testutil.ToFloat64(scimRequestsTotal.WithLabelValues("Users", http.MethodDelete, "404"))
+
+		req := httptest.NewRequest(http.MethodDelete, endpoint+"/Users/1", nil)
+		_, err = service.do(context.Background(), req)
+		assert.NoError(t, err)
+
+		assert.Equal(t, before+1, testutil.ToFloat64(scimRequestsTotal.WithLabelValues("Users", http.MethodDelete, "404")))
+	})
+
+	t.Run("should count the requests without response", func(t *testing.T) {
+		mockHTTPClient := mocks.NewMockHTTPClient(mockCtrl)
+		mockHTTPClient.EXPECT().Do(gomock.Any()).Return(nil, errors.New("test error"))
+
+		service, err := NewSCIMService(mockHTTPClient, endpoint, "MyToken")
+		assert.NoError(t, err)
+
+		before := testutil.ToFloat64(scimRequestsTotal.WithLabelValues("Groups", http.MethodGet, "error"))
+
+		req := httptest.NewRequest(http.MethodGet, endpoint+"/Groups", nil)
+		_, err = service.do(context.Background(), req)
+		assert.Error(t, err)
+
+		assert.Equal(t, before+1, testutil.ToFloat64(scimRequestsTotal.WithLabelValues("Groups", http.MethodGet, "error")))
+	})
+	t.Run("should count the status code of the requests the retry client gave up", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		// configured like the client of the SCIM services in idpscim
+		retryClient := retryablehttp.NewClient()
+		retryClient.RetryMax = 1
+		retryClient.RetryWaitMin = time.Millisecond
+		retryClient.RetryWaitMax = time.Millisecond
+		retryClient.Logger = nil
+		retryClient.ErrorHandler = retryablehttp.PassthroughErrorHandler
+
+		service, err := NewSCIMService(retryClient.StandardClient(), server.URL, "MyToken")
+		assert.NoError(t, err)
+
+		before := testutil.ToFloat64(scimRequestsTotal.WithLabelValues("Users", http.MethodGet, "503"))
+
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/Users", nil)
+		assert.NoError(t, err)
+		_, err = service.do(context.Background(), req)
+		assert.NoError(t, err)
+
+		assert.Equal(t, before+1, testutil.ToFloat64(scimRequestsTotal.WithLabelValues("Users", http.MethodGet, "503")))
+	})
+}
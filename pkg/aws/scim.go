@@ -9,7 +9,9 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -72,8 +74,15 @@ var (
 
 	// ErrGroupExternalIDEmpty is returned when the userName is empty.
 	ErrGroupExternalIDEmpty = errors.Errorf("aws: externalId may not be empty")
+
+	// ErrPaginationNotSupported is returned when the service ignores the startIndex and returns the first page again,
+	// the list would be incomplete.
+	ErrPaginationNotSupported = errors.Errorf("aws: the SCIM service ignored the startIndex parameter")
 )
 
+// MaxListPageSize is the number of resources requested per page by the ListAll methods.
+const MaxListPageSize = 50
+
 //go:generate go run github.com/golang/mock/mockgen@v1.6.0 -package=mocks -destination=../../mocks/aws/scim_mocks.go -source=scim.go HTTPClient
 
 // HTTPClient is an interface for sending HTTP requests.
@@ -158,7 +167,9 @@ func (s *SCIMService) do(ctx context.Context, req *http.Request) (*http.Response
 	// Set bearer token
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.bearerToken))
 
+	start := time.Now()
 	resp, err := s.httpClient.Do(req)
+	s.observeRequest(req, resp, start)
 	if err != nil {
 		return nil, fmt.Errorf("aws do: error sending request: %w", err)
 	}
@@ -557,18 +568,53 @@ func (s *SCIMService) GetUser(ctx context.Context, userID string) (*GetUserRespo
 
 // ListUsers returns a list of users from the AWS SSO Using the API
 func (s *SCIMService) ListUsers(ctx context.Context, filter string) (*ListUsersResponse, error) {
+	return s.listUsersPage(ctx, filter, 0, 0)
+}
+
+// ListAllUsers returns all the users from the AWS SSO Using the API, requesting pages
+// of MaxListPageSize users using startIndex and count until totalResults users are retrieved.
+// references:
+// + https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.2.4
+func (s *SCIMService) ListAllUsers(ctx context.Context, filter string) (*ListUsersResponse, error) {
+	var response *ListUsersResponse
+
+	for startIndex := 1; ; {
+		page, err := s.listUsersPage(ctx, filter, startIndex, MaxListPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		if response == nil {
+			response = page
+		} else {
+			if err := checkPagination(page.ListResponse, startIndex); err != nil {
+				return nil, err
+			}
+			response.Resources = append(response.Resources, page.Resources...)
+		}
+
+		if len(page.Resources) == 0 || len(response.Resources) >= page.TotalResults {
+			break
+		}
+		startIndex += len(page.Resources)
+	}
+
+	response.StartIndex = 1
+	response.ItemsPerPage = len(response.Resources)
+
+	return response, nil
+}
+
+// listUsersPage returns a page of users from the AWS SSO Using the API,
+// startIndex and count are not sent when they are 0.
+func (s *SCIMService) listUsersPage(ctx context.Context, filter string, startIndex, count int) (*ListUsersResponse, error) {
 	reqURL, err := url.Parse(s.url.String())
 	if err != nil {
 		return nil, fmt.Errorf("aws ListUsers: error parsing url: %w", err)
 	}
 
 	reqURL.Path = path.Join(reqURL.Path, "/Users")
-
-	if filter != "" {
-		q := reqURL.Query()
-		q.Add("filter", filter)
-		reqURL.RawQuery = q.Encode()
-	}
+	reqURL.RawQuery = listQuery(reqURL.Query(), filter, startIndex, count).Encode()
 
 	req, err := s.newRequest(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
@@ -734,18 +780,53 @@ func (s *SCIMService) GetGroupByDisplayName(ctx context.Context, displayName str
 
 // ListGroups returns a list of groups from the AWS SSO Using the API
 func (s *SCIMService) ListGroups(ctx context.Context, filter string) (*ListGroupsResponse, error) {
+	return s.listGroupsPage(ctx, filter, 0, 0)
+}
+
+// ListAllGroups returns all the groups from the AWS SSO Using the API, requesting pages
+// of MaxListPageSize groups using startIndex and count until totalResults groups are retrieved.
+// references:
+// + https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.2.4
+func (s *SCIMService) ListAllGroups(ctx context.Context, filter string) (*ListGroupsResponse, error) {
+	var response *ListGroupsResponse
+
+	for startIndex := 1; ; {
+		page, err := s.listGroupsPage(ctx, filter, startIndex, MaxListPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		if response == nil {
+			response = page
+		} else {
+			if err := checkPagination(page.ListResponse, startIndex); err != nil {
+				return nil, err
+			}
+			response.Resources = append(response.Resources, page.Resources...)
+		}
+
+		if len(page.Resources) == 0 || len(response.Resources) >= page.TotalResults {
+			break
+		}
+		startIndex += len(page.Resources)
+	}
+
+	response.StartIndex = 1
+	response.ItemsPerPage = len(response.Resources)
+
+	return response, nil
+}
+
+// listGroupsPage returns a page of groups from the AWS SSO Using the API,
+// startIndex and count are not sent when they are 0.
+func (s *SCIMService) listGroupsPage(ctx context.Context, filter string, startIndex, count int) (*ListGroupsResponse, error) {
 	reqURL, err := url.Parse(s.url.String())
 	if err != nil {
 		return nil, fmt.Errorf("aws ListGroups: error parsing url: %w", err)
 	}
 
 	reqURL.Path = path.Join(reqURL.Path, "/Groups")
-
-	if filter != "" {
-		q := reqURL.Query()
-		q.Add("filter", filter)
-		reqURL.RawQuery = q.Encode()
-	}
+	reqURL.RawQuery = listQuery(reqURL.Query(), filter, startIndex, count).Encode()
 
 	req, err := s.newRequest(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
@@ -770,6 +851,30 @@ func (s *SCIMService) ListGroups(ctx context.Context, filter string) (*ListGroup
 	return &response, nil
 }
 
+// listQuery adds the filter, startIndex and count query parameters of the list methods,
+// the parameters with empty or 0 values are not added.
+func listQuery(q url.Values, filter string, startIndex, count int) url.Values {
+	if filter != "" {
+		q.Add("filter", filter)
+	}
+	if startIndex > 0 {
+		q.Add("startIndex", strconv.Itoa(startIndex))
+	}
+	if count > 0 {
+		q.Add("count", strconv.Itoa(count))
+	}
+	return q
+}
+
+// checkPagination returns ErrPaginationNotSupported when the page returned doesn't start at the requested index,
+// which means the service ignored the startIndex parameter and returned the first page again.
+func checkPagination(page ListResponse, startIndex int) error {
+	if page.StartIndex != startIndex {
+		return fmt.Errorf("%w: requested startIndex %d, got %d of %d results", ErrPaginationNotSupported, startIndex, page.StartIndex, page.TotalResults)
+	}
+	return nil
+}
+
 // CreateGroup creates a new group in the AWS SSO Using the API
 // reference:
 // + https://docs.aws.amazon.com/singlesignon/latest/developerguide/creategroup.html
@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"path"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -18,6 +19,27 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// requestMatcher matches a request equal to req ignoring its context, which carries the span of the request.
+type requestMatcher struct {
+	req *http.Request
+}
+
+func requestEq(req *http.Request) gomock.Matcher {
+	return requestMatcher{req: req}
+}
+
+func (m requestMatcher) Matches(x interface{}) bool {
+	req, ok := x.(*http.Request)
+	if !ok {
+		return false
+	}
+	return reflect.DeepEqual(m.req, req.WithContext(m.req.Context()))
+}
+
+func (m requestMatcher) String() string {
+	return fmt.Sprintf("is equal to %v ignoring the context", m.req)
+}
+
 type mockErrReader int
 
 func (e mockErrReader) Read(b []byte) (n int, err error) {
@@ -916,7 +938,7 @@ func TestDeleteUser(t *testing.T) {
 			ContentLength: int64(len("")),
 		}
 
-		mockHTTPClient.EXPECT().Do(httpReq).Return(httpResp, nil)
+		mockHTTPClient.EXPECT().Do(requestEq(httpReq)).Return(httpResp, nil)
 
 		service, err := NewSCIMService(mockHTTPClient, endpoint, "MyToken")
 		assert.NoError(t, err)
@@ -962,7 +984,7 @@ func TestGetUser(t *testing.T) {
 			ContentLength: int64(len(jsonResp)),
 		}
 
-		mockHTTPClient.EXPECT().Do(httpReq).Return(httpResp, nil)
+		mockHTTPClient.EXPECT().Do(requestEq(httpReq)).Return(httpResp, nil)
 
 		service, err := NewSCIMService(mockHTTPClient, endpoint, "MyToken")
 		assert.NoError(t, err)
@@ -1027,7 +1049,7 @@ func TestGetUserByUserName(t *testing.T) {
 			ContentLength: int64(len(jsonResp)),
 		}
 
-		mockHTTPClient.EXPECT().Do(httpReq).Return(httpResp, nil)
+		mockHTTPClient.EXPECT().Do(requestEq(httpReq)).Return(httpResp, nil)
 
 		service, err := NewSCIMService(mockHTTPClient, endpoint, "MyToken")
 		assert.NoError(t, err)
@@ -1091,7 +1113,7 @@ func TestListUsers(t *testing.T) {
 			ContentLength: int64(len(jsonResp)),
 		}
 
-		mockHTTPClient.EXPECT().Do(httpReq).Return(httpResp, nil)
+		mockHTTPClient.EXPECT().Do(requestEq(httpReq)).Return(httpResp, nil)
 
 		service, err := NewSCIMService(mockHTTPClient, endpoint, "MyToken")
 		assert.NoError(t, err)
@@ -1513,7 +1535,7 @@ func TestDeleteGroup(t *testing.T) {
 			ContentLength: int64(len("")),
 		}
 
-		mockHTTPClient.EXPECT().Do(httpReq).Return(httpResp, nil)
+		mockHTTPClient.EXPECT().Do(requestEq(httpReq)).Return(httpResp, nil)
 
 		service, err := NewSCIMService(mockHTTPClient, endpoint, "MyToken")
 		assert.NoError(t, err)
@@ -1566,7 +1588,7 @@ func TestGetGroupByDisplayName(t *testing.T) {
 			ContentLength: int64(len(jsonResp)),
 		}
 
-		mockHTTPClient.EXPECT().Do(httpReq).Return(httpResp, nil)
+		mockHTTPClient.EXPECT().Do(requestEq(httpReq)).Return(httpResp, nil)
 
 		service, err := NewSCIMService(mockHTTPClient, endpoint, "MyToken")
 		assert.NoError(t, err)
@@ -1800,7 +1822,7 @@ func TestListGroups(t *testing.T) {
 			ContentLength: int64(len(jsonResp)),
 		}
 
-		mockHTTPClient.EXPECT().Do(httpReq).Return(httpResp, nil)
+		mockHTTPClient.EXPECT().Do(requestEq(httpReq)).Return(httpResp, nil)
 
 		service, err := NewSCIMService(mockHTTPClient, endpoint, "MyToken")
 		assert.NoError(t, err)
@@ -1815,3 +1837,110 @@ func TestListGroups(t *testing.T) {
 		assert.Equal(t, "Group Foo", got.Resources[0].DisplayName)
 	})
 }
+
+// listPageResponse helper function to build an http response with a page of a list response
+func listPageResponse(totalResults, startIndex int, resources string) *http.Response {
+	body := fmt.Sprintf(`{"totalResults": %d, "itemsPerPage": %d, "startIndex": %d, "schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"], "Resources": [%s]}`,
+		totalResults, strings.Count(resources, "{"), startIndex, resources,
+	)
+
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestListAllUsers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	endpoint := "https://testing.com"
+
+	t.Run("should request all the pages", func(t *testing.T) {
+		mockHTTPClient := mocks.NewMockHTTPClient(mockCtrl)
+
+		gomock.InOrder(
+			mockHTTPClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/Users", req.URL.Path)
+				assert.Equal(t, "1", req.URL.Query().Get("startIndex"))
+				assert.Equal(t, fmt.Sprintf("%d", MaxListPageSize), req.URL.Query().Get("count"))
+				return listPageResponse(3, 1, `{"id": "1"}, {"id": "2"}`), nil
+			}),
+			mockHTTPClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "3", req.URL.Query().Get("startIndex"))
+				return listPageResponse(3, 3, `{"id": "3"}`), nil
+			}),
+		)
+
+		service, err := NewSCIMService(mockHTTPClient, endpoint, "MyToken")
+		assert.NoError(t, err)
+
+		got, err := service.ListAllUsers(context.Background(), "")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, got.TotalResults)
+		assert.Equal(t, 3, got.ItemsPerPage)
+		assert.Equal(t, 3, len(got.Resources))
+		assert.Equal(t, "3", got.Resources[2].ID)
+	})
+
+	t.Run("should return ErrPaginationNotSupported when the service ignores the startIndex", func(t *testing.T) {
+		mockHTTPClient := mocks.NewMockHTTPClient(mockCtrl)
+
+		mockHTTPClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			return listPageResponse(3, 1, `{"id": "1"}, {"id": "2"}`), nil
+		}).Times(2)
+
+		service, err := NewSCIMService(mockHTTPClient, endpoint, "MyToken")
+		assert.NoError(t, err)
+
+		got, err := service.ListAllUsers(context.Background(), "")
+		assert.ErrorIs(t, err, ErrPaginationNotSupported)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return error when a page fails", func(t *testing.T) {
+		mockHTTPClient := mocks.NewMockHTTPClient(mockCtrl)
+
+		mockHTTPClient.EXPECT().Do(gomock.Any()).Return(nil, errors.New("test error"))
+
+		service, err := NewSCIMService(mockHTTPClient, endpoint, "MyToken")
+		assert.NoError(t, err)
+
+		got, err := service.ListAllUsers(context.Background(), "")
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestListAllGroups(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	endpoint := "https://testing.com"
+
+	t.Run("should request all the pages with the filter", func(t *testing.T) {
+		mockHTTPClient := mocks.NewMockHTTPClient(mockCtrl)
+		filter := "displayName eq \"Group Foo\""
+
+		gomock.InOrder(
+			mockHTTPClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/Groups", req.URL.Path)
+				assert.Equal(t, filter, req.URL.Query().Get("filter"))
+				assert.Equal(t, "1", req.URL.Query().Get("startIndex"))
+				return listPageResponse(2, 1, `{"id": "1", "displayName": "group 1"}`), nil
+			}),
+			mockHTTPClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "2", req.URL.Query().Get("startIndex"))
+				return listPageResponse(2, 2, `{"id": "2", "displayName": "group 2"}`), nil
+			}),
+		)
+
+		service, err := NewSCIMService(mockHTTPClient, endpoint, "MyToken")
+		assert.NoError(t, err)
+
+		got, err := service.ListAllGroups(context.Background(), filter)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, got.TotalResults)
+		assert.Equal(t, 2, len(got.Resources))
+		assert.Equal(t, "group 2", got.Resources[1].DisplayName)
+	})
+}
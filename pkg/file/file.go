@@ -0,0 +1,358 @@
+package file
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// FormatYAML is the format of the .yaml and .yml files
+	FormatYAML = "yaml"
+
+	// FormatJSON is the format of the .json files
+	FormatJSON = "json"
+
+	// FormatCSV is the format of the .csv files, one user by row with the groups where the user is member
+	FormatCSV = "csv"
+
+	// csvGroupsSeparator separates the groups names of the groups column of the csv files
+	csvGroupsSeparator = ";"
+)
+
+// csv columns, only the email column is required
+const (
+	csvColumnEmail       = "email"
+	csvColumnGivenName   = "given_name"
+	csvColumnFamilyName  = "family_name"
+	csvColumnDisplayName = "display_name"
+	csvColumnActive      = "active"
+	csvColumnGroups      = "groups"
+)
+
+var (
+	// ErrDirectoryNil is returned when the directory is nil.
+	ErrDirectoryNil = errors.New("file: directory is required")
+
+	// ErrUnsupportedFormat is returned when the file format is not yaml, json or csv.
+	ErrUnsupportedFormat = errors.New("file: unsupported format, valid formats are yaml, json and csv")
+
+	// ErrCSVEmailColumnMissing is returned when the csv header doesn't have the email column.
+	ErrCSVEmailColumnMissing = errors.New("file: csv email column is required")
+
+	// ErrUserEmailEmpty is returned when a user doesn't have email.
+	ErrUserEmailEmpty = errors.New("file: user email is required")
+
+	// ErrGroupNameEmpty is returned when a group doesn't have name.
+	ErrGroupNameEmpty = errors.New("file: group name is required")
+
+	// ErrDuplicatedUser is returned when two users have the same email.
+	ErrDuplicatedUser = errors.New("file: duplicated user email")
+
+	// ErrDuplicatedGroup is returned when two groups have the same name.
+	ErrDuplicatedGroup = errors.New("file: duplicated group name")
+
+	// ErrMemberNotFound is returned when a group member is not defined in the users.
+	ErrMemberNotFound = errors.New("file: group member is not defined in the users")
+
+	// ErrUserNotFound is returned when the user doesn't exist.
+	ErrUserNotFound = errors.New("file: user not found")
+
+	// ErrGroupNotFound is returned when the group doesn't exist.
+	ErrGroupNotFound = errors.New("file: group not found")
+)
+
+// DirectoryService represent the directory declared in a file.
+// The users are identified by email and the groups by name, both are case insensitive.
+type DirectoryService struct {
+	directory *Directory
+	users     map[string]*User
+	groups    map[string]*Group
+}
+
+// ReadFile reads the directory file, the format is defined by the file extension.
+func ReadFile(name string) (*DirectoryService, error) {
+	format, err := FormatFromPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("file: error opening %s: %w", name, err)
+	}
+	defer f.Close()
+
+	d, err := Parse(f, format)
+	if err != nil {
+		return nil, fmt.Errorf("file: error reading %s: %w", name, err)
+	}
+
+	return NewDirectoryService(d)
+}
+
+// FormatFromPath returns the format of the file given its extension.
+func FormatFromPath(name string) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".csv":
+		return FormatCSV, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// Parse reads the directory from r in the given format.
+func Parse(r io.Reader, format string) (*Directory, error) {
+	var d Directory
+
+	switch format {
+	case FormatYAML:
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		if err := dec.Decode(&d); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("file: error decoding yaml: %w", err)
+		}
+	case FormatJSON:
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&d); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("file: error decoding json: %w", err)
+		}
+	case FormatCSV:
+		return parseCSV(r)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	return &d, nil
+}
+
+// parseCSV reads a csv with a header row and one user by row, the groups column has the
+// names of the groups where the user is member separated by ";"
+func parseCSV(r io.Reader) (*Directory, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return &Directory{}, nil
+		}
+		return nil, fmt.Errorf("file: error reading csv header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if _, ok := columns[csvColumnEmail]; !ok {
+		return nil, ErrCSVEmailColumnMissing
+	}
+
+	value := func(record []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	d := &Directory{}
+	groups := make(map[string]*Group)
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("file: error reading csv: %w", err)
+		}
+
+		u := &User{
+			Email:       value(record, csvColumnEmail),
+			GivenName:   value(record, csvColumnGivenName),
+			FamilyName:  value(record, csvColumnFamilyName),
+			DisplayName: value(record, csvColumnDisplayName),
+		}
+
+		if active := value(record, csvColumnActive); active != "" {
+			b, err := strconv.ParseBool(active)
+			if err != nil {
+				return nil, fmt.Errorf("file: invalid active value %q of user %s: %w", active, u.Email, err)
+			}
+			u.Active = &b
+		}
+		d.Users = append(d.Users, u)
+
+		for _, name := range strings.Split(value(record, csvColumnGroups), csvGroupsSeparator) {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+
+			g, ok := groups[name]
+			if !ok {
+				g = &Group{Name: name}
+				groups[name] = g
+				d.Groups = append(d.Groups, g)
+			}
+			g.Members = append(g.Members, u.Email)
+		}
+	}
+
+	return d, nil
+}
+
+// NewDirectoryService validates the directory and returns a service to query it.
+func NewDirectoryService(d *Directory) (*DirectoryService, error) {
+	if d == nil {
+		return nil, ErrDirectoryNil
+	}
+
+	ds := &DirectoryService{
+		directory: d,
+		users:     make(map[string]*User),
+		groups:    make(map[string]*Group),
+	}
+
+	for _, u := range d.Users {
+		if u.Email == "" {
+			return nil, ErrUserEmailEmpty
+		}
+
+		key := strings.ToLower(u.Email)
+		if _, ok := ds.users[key]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicatedUser, u.Email)
+		}
+		ds.users[key] = u
+	}
+
+	for _, g := range d.Groups {
+		if g.Name == "" {
+			return nil, ErrGroupNameEmpty
+		}
+
+		key := strings.ToLower(g.Name)
+		if _, ok := ds.groups[key]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicatedGroup, g.Name)
+		}
+		ds.groups[key] = g
+
+		for _, m := range g.Members {
+			if _, ok := ds.users[strings.ToLower(m)]; !ok {
+				return nil, fmt.Errorf("%w: group: %s, member: %s", ErrMemberNotFound, g.Name, m)
+			}
+		}
+	}
+
+	return ds, nil
+}
+
+// match returns true when the value matches any of the patterns, the patterns use the path.Match syntax.
+// An empty list of patterns or an empty pattern matches all the values.
+func match(patterns []string, value string) (bool, error) {
+	if len(patterns) == 0 {
+		return true, nil
+	}
+
+	for _, p := range patterns {
+		if p == "" {
+			return true, nil
+		}
+
+		ok, err := path.Match(p, value)
+		if err != nil {
+			return false, fmt.Errorf("file: invalid filter %q: %w", p, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ListUsers returns the users with the email matching any of the filters,
+// the filters are patterns like "*@my-company.com".
+// The users are returned in the same order of the file.
+func (ds *DirectoryService) ListUsers(ctx context.Context, filters []string) ([]*User, error) {
+	u := make([]*User, 0)
+
+	for _, usr := range ds.directory.Users {
+		ok, err := match(filters, usr.Email)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			u = append(u, usr)
+		}
+	}
+
+	return u, nil
+}
+
+// ListGroups returns the groups with the name matching any of the filters,
+// the filters are patterns like "AWS-*".
+// The groups are returned in the same order of the file.
+func (ds *DirectoryService) ListGroups(ctx context.Context, filters []string) ([]*Group, error) {
+	g := make([]*Group, 0)
+
+	for _, grp := range ds.directory.Groups {
+		ok, err := match(filters, grp.Name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			g = append(g, grp)
+		}
+	}
+
+	return g, nil
+}
+
+// ListGroupMembers returns the users members of the group, the repeated members are returned once.
+func (ds *DirectoryService) ListGroupMembers(ctx context.Context, groupName string) ([]*User, error) {
+	g, ok := ds.groups[strings.ToLower(groupName)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, groupName)
+	}
+
+	seen := make(map[string]struct{})
+	u := make([]*User, 0, len(g.Members))
+	for _, m := range g.Members {
+		key := strings.ToLower(m)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		u = append(u, ds.users[key])
+	}
+
+	return u, nil
+}
+
+// GetUser returns the user with the email.
+func (ds *DirectoryService) GetUser(ctx context.Context, email string) (*User, error) {
+	u, ok := ds.users[strings.ToLower(email)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, email)
+	}
+
+	return u, nil
+}
@@ -0,0 +1,30 @@
+package file
+
+// Directory represent the content of the directory file
+type Directory struct {
+	Groups []*Group `json:"groups" yaml:"groups"`
+	Users  []*User  `json:"users" yaml:"users"`
+}
+
+// Group represent a group of the directory file, the members are the emails of the users
+type Group struct {
+	Name    string   `json:"name" yaml:"name"`
+	Email   string   `json:"email,omitempty" yaml:"email,omitempty"`
+	Members []string `json:"members,omitempty" yaml:"members,omitempty"`
+}
+
+// User represent a user of the directory file
+type User struct {
+	Email       string `json:"email" yaml:"email"`
+	GivenName   string `json:"given_name,omitempty" yaml:"given_name,omitempty"`
+	FamilyName  string `json:"family_name,omitempty" yaml:"family_name,omitempty"`
+	DisplayName string `json:"display_name,omitempty" yaml:"display_name,omitempty"`
+
+	// Active is optional, the users are active when it is not defined
+	Active *bool `json:"active,omitempty" yaml:"active,omitempty"`
+}
+
+// IsActive returns true when the user is active
+func (u *User) IsActive() bool {
+	return u.Active == nil || *u.Active
+}
@@ -0,0 +1,250 @@
+package file
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func testDirectory() *Directory {
+	return &Directory{
+		Groups: []*Group{
+			{Name: "AWS-Admins", Email: "aws-admins@mail.com", Members: []string{"user.1@mail.com"}},
+			{Name: "AWS-Developers", Members: []string{"user.1@mail.com", "user.2@mail.com", "USER.1@mail.com"}},
+			{Name: "Other"},
+		},
+		Users: []*User{
+			{Email: "user.1@mail.com", GivenName: "user", FamilyName: "1", DisplayName: "user 1"},
+			{Email: "user.2@mail.com", GivenName: "user", FamilyName: "2", Active: boolPtr(false)},
+		},
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	tests := map[string]string{
+		"directory.yaml": FormatYAML,
+		"directory.YML":  FormatYAML,
+		"directory.json": FormatJSON,
+		"directory.csv":  FormatCSV,
+	}
+
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := FormatFromPath(name)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+
+	t.Run("should return error when the extension is not supported", func(t *testing.T) {
+		got, err := FormatFromPath("directory.xml")
+		assert.ErrorIs(t, err, ErrUnsupportedFormat)
+		assert.Empty(t, got)
+	})
+}
+
+func TestReadFile(t *testing.T) {
+	for _, name := range []string{"directory.yaml", "directory.json", "directory.csv"} {
+		t.Run("should read the same directory from "+name, func(t *testing.T) {
+			ds, err := ReadFile(filepath.Join("testdata", name))
+			assert.NoError(t, err)
+			assert.NotNil(t, ds)
+
+			groups, err := ds.ListGroups(context.TODO(), nil)
+			assert.NoError(t, err)
+			assert.Len(t, groups, 2)
+			assert.Equal(t, "AWS-Admins", groups[0].Name)
+			assert.Equal(t, []string{"user.1@mail.com"}, groups[0].Members)
+			assert.Equal(t, "AWS-Developers", groups[1].Name)
+			assert.Equal(t, []string{"user.1@mail.com", "user.2@mail.com"}, groups[1].Members)
+
+			users, err := ds.ListUsers(context.TODO(), nil)
+			assert.NoError(t, err)
+			assert.Len(t, users, 2)
+			assert.Equal(t, "user.1@mail.com", users[0].Email)
+			assert.Equal(t, "user 1", users[0].DisplayName)
+			assert.True(t, users[0].IsActive())
+			assert.Equal(t, "2", users[1].FamilyName)
+			assert.False(t, users[1].IsActive())
+		})
+	}
+
+	t.Run("should return error when the file doesn't exist", func(t *testing.T) {
+		ds, err := ReadFile(filepath.Join("testdata", "not-found.yaml"))
+		assert.Error(t, err)
+		assert.Nil(t, ds)
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("should return an empty directory when the content is empty", func(t *testing.T) {
+		for _, format := range []string{FormatYAML, FormatJSON, FormatCSV} {
+			got, err := Parse(strings.NewReader(""), format)
+			assert.NoError(t, err)
+			assert.Empty(t, got.Groups)
+			assert.Empty(t, got.Users)
+		}
+	})
+
+	t.Run("should return error when the json has unknown fields", func(t *testing.T) {
+		got, err := Parse(strings.NewReader(`{"users": [{"mail": "user.1@mail.com"}]}`), FormatJSON)
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return error when the yaml has unknown fields", func(t *testing.T) {
+		got, err := Parse(strings.NewReader("users:\n  - mail: user.1@mail.com\n"), FormatYAML)
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return error when the csv doesn't have email column", func(t *testing.T) {
+		got, err := Parse(strings.NewReader("given_name,family_name\nuser,1\n"), FormatCSV)
+		assert.ErrorIs(t, err, ErrCSVEmailColumnMissing)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return error when the csv active value is not a boolean", func(t *testing.T) {
+		got, err := Parse(strings.NewReader("email,active\nuser.1@mail.com,maybe\n"), FormatCSV)
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return error when the format is not supported", func(t *testing.T) {
+		got, err := Parse(strings.NewReader(""), "xml")
+		assert.ErrorIs(t, err, ErrUnsupportedFormat)
+		assert.Nil(t, got)
+	})
+}
+
+func TestNewDirectoryService(t *testing.T) {
+	t.Run("should return error when the directory is nil", func(t *testing.T) {
+		got, err := NewDirectoryService(nil)
+		assert.ErrorIs(t, err, ErrDirectoryNil)
+		assert.Nil(t, got)
+	})
+
+	tests := []struct {
+		name      string
+		directory *Directory
+		wantErr   error
+	}{
+		{
+			name:      "user without email",
+			directory: &Directory{Users: []*User{{GivenName: "user"}}},
+			wantErr:   ErrUserEmailEmpty,
+		},
+		{
+			name:      "duplicated user email",
+			directory: &Directory{Users: []*User{{Email: "user.1@mail.com"}, {Email: "User.1@mail.com"}}},
+			wantErr:   ErrDuplicatedUser,
+		},
+		{
+			name:      "group without name",
+			directory: &Directory{Groups: []*Group{{Email: "group@mail.com"}}},
+			wantErr:   ErrGroupNameEmpty,
+		},
+		{
+			name:      "duplicated group name",
+			directory: &Directory{Groups: []*Group{{Name: "group"}, {Name: "Group"}}},
+			wantErr:   ErrDuplicatedGroup,
+		},
+		{
+			name:      "member not defined in the users",
+			directory: &Directory{Groups: []*Group{{Name: "group", Members: []string{"user.1@mail.com"}}}},
+			wantErr:   ErrMemberNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("should return error with "+tt.name, func(t *testing.T) {
+			got, err := NewDirectoryService(tt.directory)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Nil(t, got)
+		})
+	}
+}
+
+func TestDirectoryService_ListUsers(t *testing.T) {
+	ds, err := NewDirectoryService(testDirectory())
+	assert.NoError(t, err)
+
+	t.Run("should return the users matching any filter", func(t *testing.T) {
+		got, err := ds.ListUsers(context.TODO(), []string{"user.2@*", "nobody@*"})
+		assert.NoError(t, err)
+		assert.Len(t, got, 1)
+		assert.Equal(t, "user.2@mail.com", got[0].Email)
+	})
+
+	t.Run("should return all the users with an empty filter", func(t *testing.T) {
+		got, err := ds.ListUsers(context.TODO(), []string{""})
+		assert.NoError(t, err)
+		assert.Len(t, got, 2)
+	})
+
+	t.Run("should return error when the filter is not valid", func(t *testing.T) {
+		got, err := ds.ListUsers(context.TODO(), []string{"[user"})
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestDirectoryService_ListGroups(t *testing.T) {
+	ds, err := NewDirectoryService(testDirectory())
+	assert.NoError(t, err)
+
+	t.Run("should return the groups matching any filter in the file order", func(t *testing.T) {
+		got, err := ds.ListGroups(context.TODO(), []string{"AWS-*"})
+		assert.NoError(t, err)
+		assert.Len(t, got, 2)
+		assert.Equal(t, "AWS-Admins", got[0].Name)
+		assert.Equal(t, "AWS-Developers", got[1].Name)
+	})
+}
+
+func TestDirectoryService_ListGroupMembers(t *testing.T) {
+	ds, err := NewDirectoryService(testDirectory())
+	assert.NoError(t, err)
+
+	t.Run("should return the members once", func(t *testing.T) {
+		got, err := ds.ListGroupMembers(context.TODO(), "aws-developers")
+		assert.NoError(t, err)
+		assert.Len(t, got, 2)
+		assert.Equal(t, "user.1@mail.com", got[0].Email)
+		assert.Equal(t, "user.2@mail.com", got[1].Email)
+	})
+
+	t.Run("should return an empty list for groups without members", func(t *testing.T) {
+		got, err := ds.ListGroupMembers(context.TODO(), "Other")
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("should return error when the group doesn't exist", func(t *testing.T) {
+		got, err := ds.ListGroupMembers(context.TODO(), "nobody")
+		assert.ErrorIs(t, err, ErrGroupNotFound)
+		assert.Nil(t, got)
+	})
+}
+
+func TestDirectoryService_GetUser(t *testing.T) {
+	ds, err := NewDirectoryService(testDirectory())
+	assert.NoError(t, err)
+
+	t.Run("should return the user", func(t *testing.T) {
+		got, err := ds.GetUser(context.TODO(), "USER.1@mail.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "user.1@mail.com", got.Email)
+	})
+
+	t.Run("should return error when the user doesn't exist", func(t *testing.T) {
+		got, err := ds.GetUser(context.TODO(), "nobody@mail.com")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assert.Nil(t, got)
+	})
+}
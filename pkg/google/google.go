@@ -10,6 +10,8 @@ import (
 	"google.golang.org/api/option"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/slashdevops/idp-scim-sync/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -75,6 +77,8 @@ func NewDirectoryService(svc *admin.Service) (*DirectoryService, error) {
 
 // ListUsers list all users in a Google Directory filtered by query.
 func (ds *DirectoryService) ListUsers(ctx context.Context, query []string) ([]*admin.User, error) {
+	ctx, span := startSpan(ctx, "users.list", attribute.StringSlice("google.query", query))
+
 	u := make([]*admin.User, 0)
 	var err error
 
@@ -98,6 +102,9 @@ func (ds *DirectoryService) ListUsers(ctx context.Context, query []string) ([]*a
 			return nil
 		})
 	}
+
+	span.SetAttributes(attribute.Int("google.users", len(u)))
+	tracing.End(span, err)
 	return u, err
 }
 
@@ -105,6 +112,8 @@ func (ds *DirectoryService) ListUsers(ctx context.Context, query []string) ([]*a
 // References:
 // - https://developers.google.com/admin-sdk/directory/reference/rest/v1/groups
 func (ds *DirectoryService) ListGroups(ctx context.Context, query []string) ([]*admin.Group, error) {
+	ctx, span := startSpan(ctx, "groups.list", attribute.StringSlice("google.query", query))
+
 	g := make([]*admin.Group, 0)
 	var err error
 
@@ -128,6 +137,9 @@ func (ds *DirectoryService) ListGroups(ctx context.Context, query []string) ([]*
 			return nil
 		})
 	}
+
+	span.SetAttributes(attribute.Int("google.groups", len(g)))
+	tracing.End(span, err)
 	return g, err
 }
 
@@ -146,6 +158,8 @@ func (ds *DirectoryService) ListGroupMembers(ctx context.Context, groupID string
 		q(&qs)
 	}
 
+	ctx, span := startSpan(ctx, "members.list", attribute.String("google.group_id", groupID))
+
 	m := make([]*admin.Member, 0)
 	mlc := ds.svc.Members.List(groupID)
 
@@ -174,6 +188,8 @@ func (ds *DirectoryService) ListGroupMembers(ctx context.Context, groupID string
 		return nil
 	})
 
+	span.SetAttributes(attribute.Int("google.members", len(m)))
+	tracing.End(span, err)
 	return m, err
 }
 
@@ -184,7 +200,9 @@ func (ds *DirectoryService) GetUser(ctx context.Context, userID string) (*admin.
 		return nil, ErrUserIDNil
 	}
 
+	ctx, span := startSpan(ctx, "users.get", attribute.String("google.user_id", userID))
 	u, err := ds.svc.Users.Get(userID).Fields(getUsersRequiredFields).Context(ctx).Do()
+	tracing.End(span, err)
 	if err != nil {
 		return nil, fmt.Errorf("google: error getting user %s: %v", userID, err)
 	}
@@ -198,7 +216,9 @@ func (ds *DirectoryService) GetGroup(ctx context.Context, groupID string) (*admi
 		return nil, ErrGroupIDNil
 	}
 
+	ctx, span := startSpan(ctx, "groups.get", attribute.String("google.group_id", groupID))
 	g, err := ds.svc.Groups.Get(groupID).Fields(groupsRequiredFields).Context(ctx).Do()
+	tracing.End(span, err)
 	if err != nil {
 		return nil, fmt.Errorf("google: error getting group %s: %v", groupID, err)
 	}
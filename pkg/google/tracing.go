@@ -0,0 +1,22 @@
+package google
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of the requests to the Google Directory API, the spans are not exported
+// until a tracer provider is registered.
+var tracer = otel.Tracer("github.com/slashdevops/idp-scim-sync/pkg/google")
+
+// startSpan starts the span of a call to the method of the Google Directory API, like users.list,
+// all the pages requested by the call are part of the same span.
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "Google Directory "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
@@ -0,0 +1,85 @@
+package google
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/option"
+)
+
+func TestDirectoryService_Tracing(t *testing.T) {
+	ctx := context.TODO()
+
+	sr := tracetest.NewSpanRecorder()
+	previous := tracer
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)).Tracer("test")
+	defer func() { tracer = previous }()
+
+	t.Run("should create a span for the calls listing all the pages", func(t *testing.T) {
+		pages := map[string]*admin.Groups{
+			"": {
+				Groups:        []*admin.Group{{Id: "1", Name: "group 1"}},
+				NextPageToken: "page-2",
+			},
+			"page-2": {
+				Groups: []*admin.Group{{Id: "2", Name: "group 2"}},
+			},
+		}
+
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			jsonBytes, err := pages[r.URL.Query().Get("pageToken")].MarshalJSON()
+			assert.NoError(t, err)
+			w.Write(jsonBytes)
+		}))
+		defer svr.Close()
+
+		svc, err := admin.NewService(ctx, option.WithHTTPClient(svr.Client()), option.WithEndpoint(svr.URL), option.WithUserAgent("test"))
+		assert.NoError(t, err)
+
+		client, err := NewDirectoryService(svc)
+		assert.NoError(t, err)
+
+		got, err := client.ListGroups(ctx, []string{"name:AWS*"})
+		assert.NoError(t, err)
+		assert.Len(t, got, 2)
+
+		spans := sr.Ended()
+		if !assert.Len(t, spans, 1) {
+			return
+		}
+		assert.Equal(t, "Google Directory groups.list", spans[0].Name())
+		assert.Contains(t, spans[0].Attributes(), attribute.StringSlice("google.query", []string{"name:AWS*"}))
+		assert.Contains(t, spans[0].Attributes(), attribute.Int("google.groups", 2))
+		assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	})
+
+	t.Run("should record the error of the call", func(t *testing.T) {
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer svr.Close()
+
+		svc, err := admin.NewService(ctx, option.WithHTTPClient(svr.Client()), option.WithEndpoint(svr.URL), option.WithUserAgent("test"))
+		assert.NoError(t, err)
+
+		client, err := NewDirectoryService(svc)
+		assert.NoError(t, err)
+
+		_, err = client.GetUser(ctx, "123456789")
+		assert.Error(t, err)
+
+		spans := sr.Ended()
+		span := spans[len(spans)-1]
+		assert.Equal(t, "Google Directory users.get", span.Name())
+		assert.Contains(t, span.Attributes(), attribute.String("google.user_id", "123456789"))
+		assert.Equal(t, codes.Error, span.Status().Code)
+	})
+}
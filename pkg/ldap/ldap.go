@@ -0,0 +1,459 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	ldapv3 "github.com/go-ldap/ldap/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultPageSize is the number of entries requested in every page, lower than the
+	// default Active Directory MaxPageSize (1000)
+	DefaultPageSize = 500
+
+	// DefaultUserObjectFilter is the filter that selects the users entries
+	DefaultUserObjectFilter = "(objectClass=person)"
+
+	// DefaultGroupObjectFilter is the filter that selects the groups entries
+	DefaultGroupObjectFilter = "(|(objectClass=group)(objectClass=groupOfNames))"
+
+	// MembershipMember resolves the members of a group reading the member attribute of the group
+	MembershipMember = "member"
+
+	// MembershipMemberOf resolves the members of a group searching the entries with the memberOf attribute
+	MembershipMemberOf = "memberOf"
+
+	// DefaultMembership is the default way to resolve the members of a group
+	DefaultMembership = MembershipMember
+
+	// memberAttribute is the attribute of the groups entries with the DN of their members
+	memberAttribute = "member"
+
+	// memberOfAttribute is the attribute of the users and groups entries with the DN of the groups where they are members
+	memberOfAttribute = "memberOf"
+
+	// userAccountControlAttribute is the Active Directory attribute with the account flags
+	// reference: https://learn.microsoft.com/en-us/troubleshoot/windows-server/identity/useraccountcontrol-manipulate-account-properties
+	userAccountControlAttribute = "userAccountControl"
+
+	// accountDisable is the userAccountControl flag of the disabled accounts
+	accountDisable = 0x2
+)
+
+var (
+	// ErrURLEmpty is returned when the url is empty.
+	ErrURLEmpty = errors.New("ldap: url is required")
+
+	// ErrConnNil is returned when the connection is nil.
+	ErrConnNil = errors.New("ldap: connection is required")
+
+	// ErrBaseDNEmpty is returned when the base DN is empty.
+	ErrBaseDNEmpty = errors.New("ldap: base dn is required")
+
+	// ErrUserDNEmpty is returned when the user DN is empty.
+	ErrUserDNEmpty = errors.New("ldap: user dn is required")
+
+	// ErrGroupDNEmpty is returned when the group DN is empty.
+	ErrGroupDNEmpty = errors.New("ldap: group dn is required")
+
+	// ErrUserNotFound is returned when the user entry doesn't exist.
+	ErrUserNotFound = errors.New("ldap: user not found")
+
+	// ErrInvalidMembership is returned when the membership is not member or memberOf.
+	ErrInvalidMembership = errors.New("ldap: membership must be member or memberOf")
+)
+
+// Conn is the interface of the LDAP connection methods used by the DirectoryService.
+type Conn interface {
+	SearchWithPaging(searchRequest *ldapv3.SearchRequest, pagingSize uint32) (*ldapv3.SearchResult, error)
+	Close()
+}
+
+// DirectoryService represent the LDAPv3 client for the users and groups entries.
+type DirectoryService struct {
+	conn              Conn
+	baseDN            string
+	userObjectFilter  string
+	groupObjectFilter string
+	membership        string
+	attributes        AttributeMap
+	pageSize          uint32
+}
+
+// DirectoryServiceOption is a function that configures the DirectoryService.
+type DirectoryServiceOption func(*DirectoryService)
+
+// WithUserObjectFilter sets the filter that selects the users entries.
+func WithUserObjectFilter(filter string) DirectoryServiceOption {
+	return func(ds *DirectoryService) {
+		if filter != "" {
+			ds.userObjectFilter = filter
+		}
+	}
+}
+
+// WithGroupObjectFilter sets the filter that selects the groups entries.
+func WithGroupObjectFilter(filter string) DirectoryServiceOption {
+	return func(ds *DirectoryService) {
+		if filter != "" {
+			ds.groupObjectFilter = filter
+		}
+	}
+}
+
+// WithMembership sets how the members of the groups are resolved, MembershipMember or MembershipMemberOf.
+func WithMembership(membership string) DirectoryServiceOption {
+	return func(ds *DirectoryService) {
+		if membership != "" {
+			ds.membership = membership
+		}
+	}
+}
+
+// WithAttributeMap sets the attributes names, the empty names keep the DefaultAttributeMap value.
+func WithAttributeMap(am AttributeMap) DirectoryServiceOption {
+	return func(ds *DirectoryService) {
+		for _, a := range []struct {
+			dst *string
+			src string
+		}{
+			{&ds.attributes.UserEmail, am.UserEmail},
+			{&ds.attributes.UserGivenName, am.UserGivenName},
+			{&ds.attributes.UserFamilyName, am.UserFamilyName},
+			{&ds.attributes.UserDisplayName, am.UserDisplayName},
+			{&ds.attributes.GroupName, am.GroupName},
+			{&ds.attributes.GroupEmail, am.GroupEmail},
+		} {
+			if a.src != "" {
+				*a.dst = a.src
+			}
+		}
+	}
+}
+
+// WithPageSize sets the number of entries requested in every page.
+func WithPageSize(size uint32) DirectoryServiceOption {
+	return func(ds *DirectoryService) {
+		if size > 0 {
+			ds.pageSize = size
+		}
+	}
+}
+
+// Dial connects to the LDAP server and binds with the given DN and password,
+// the bind is skipped when the DN is empty to use an anonymous connection.
+// The following schemas are supported: ldap://, ldaps://.
+func Dial(urlStr, bindDN, bindPassword string) (*ldapv3.Conn, error) {
+	if urlStr == "" {
+		return nil, ErrURLEmpty
+	}
+
+	conn, err := ldapv3.DialURL(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: error connecting to %s: %w", urlStr, err)
+	}
+
+	if bindDN != "" {
+		if err := conn.Bind(bindDN, bindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap: error binding as %s: %w", bindDN, err)
+		}
+	}
+
+	return conn, nil
+}
+
+// NewDirectoryService create a LDAPv3 client that searches the users and groups under the baseDN.
+func NewDirectoryService(conn Conn, baseDN string, opts ...DirectoryServiceOption) (*DirectoryService, error) {
+	if conn == nil {
+		return nil, ErrConnNil
+	}
+
+	if baseDN == "" {
+		return nil, ErrBaseDNEmpty
+	}
+
+	ds := &DirectoryService{
+		conn:              conn,
+		baseDN:            baseDN,
+		userObjectFilter:  DefaultUserObjectFilter,
+		groupObjectFilter: DefaultGroupObjectFilter,
+		membership:        DefaultMembership,
+		attributes:        DefaultAttributeMap,
+		pageSize:          DefaultPageSize,
+	}
+
+	for _, opt := range opts {
+		opt(ds)
+	}
+
+	if ds.membership != MembershipMember && ds.membership != MembershipMemberOf {
+		return nil, ErrInvalidMembership
+	}
+
+	return ds, nil
+}
+
+// Close closes the LDAP connection.
+func (ds *DirectoryService) Close() {
+	ds.conn.Close()
+}
+
+// search returns the entries under the baseDN matching the filter, the pagination is done by the connection.
+// go-ldap doesn't support context, so the context is only checked before sending the request.
+func (ds *DirectoryService) search(ctx context.Context, baseDN string, scope int, filter string, attributes []string) ([]*ldapv3.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"baseDN": baseDN,
+		"filter": filter,
+	}).Trace("ldap search: request")
+
+	req := ldapv3.NewSearchRequest(baseDN, scope, ldapv3.NeverDerefAliases, 0, 0, false, filter, attributes, nil)
+
+	res, err := ds.conn.SearchWithPaging(req, ds.pageSize)
+	if err != nil {
+		// the base scope searches of entries that doesn't exist are not an error
+		if scope == ldapv3.ScopeBaseObject && ldapv3.IsErrorWithCode(err, ldapv3.LDAPResultNoSuchObject) {
+			return []*ldapv3.Entry{}, nil
+		}
+		return nil, fmt.Errorf("ldap: error searching %s, filter: %s, error: %w", baseDN, filter, err)
+	}
+
+	return res.Entries, nil
+}
+
+// and returns a filter that matches the objectFilter and the filter, the filter is optional.
+func and(objectFilter, filter string) string {
+	if filter == "" {
+		return objectFilter
+	}
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+	return fmt.Sprintf("(&%s%s)", objectFilter, filter)
+}
+
+// ListUsers list all users under the base DN matching the filters.
+// Every filter is requested independently and the results are appended, an empty list
+// of filters or an empty filter lists all the users.
+func (ds *DirectoryService) ListUsers(ctx context.Context, filters []string) ([]*User, error) {
+	if len(filters) == 0 {
+		filters = []string{""}
+	}
+
+	u := make([]*User, 0)
+	for _, filter := range filters {
+		entries, err := ds.search(ctx, ds.baseDN, ldapv3.ScopeWholeSubtree, and(ds.userObjectFilter, filter), ds.attributes.userAttributes())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries {
+			u = append(u, ds.toUser(e))
+		}
+	}
+
+	return u, nil
+}
+
+// ListGroups list all groups under the base DN matching the filters.
+// Every filter is requested independently and the results are appended, an empty list
+// of filters or an empty filter lists all the groups.
+func (ds *DirectoryService) ListGroups(ctx context.Context, filters []string) ([]*Group, error) {
+	if len(filters) == 0 {
+		filters = []string{""}
+	}
+
+	g := make([]*Group, 0)
+	for _, filter := range filters {
+		entries, err := ds.search(ctx, ds.baseDN, ldapv3.ScopeWholeSubtree, and(ds.groupObjectFilter, filter), ds.attributes.groupAttributes())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries {
+			g = append(g, ds.toGroup(e))
+		}
+	}
+
+	return g, nil
+}
+
+// ListGroupMembers returns the users members of the group including the members of the nested groups,
+// the nested groups themselves are not returned and every user is returned only once.
+func (ds *DirectoryService) ListGroupMembers(ctx context.Context, groupDN string) ([]*User, error) {
+	if groupDN == "" {
+		return nil, ErrGroupDNEmpty
+	}
+
+	r := &membersResolver{
+		ds:      ds,
+		visited: make(map[string]struct{}),
+		seen:    make(map[string]struct{}),
+		users:   make([]*User, 0),
+	}
+
+	var err error
+	if ds.membership == MembershipMemberOf {
+		err = r.resolveMemberOf(ctx, groupDN)
+	} else {
+		err = r.resolveMember(ctx, groupDN)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r.users, nil
+}
+
+// GetUser return the user entry given its DN.
+func (ds *DirectoryService) GetUser(ctx context.Context, userDN string) (*User, error) {
+	if userDN == "" {
+		return nil, ErrUserDNEmpty
+	}
+
+	entries, err := ds.search(ctx, userDN, ldapv3.ScopeBaseObject, ds.userObjectFilter, ds.attributes.userAttributes())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, userDN)
+	}
+
+	return ds.toUser(entries[0]), nil
+}
+
+// toUser converts a directory entry into a User, the Active Directory disabled accounts are not active.
+func (ds *DirectoryService) toUser(e *ldapv3.Entry) *User {
+	active := true
+	if uac := e.GetEqualFoldAttributeValue(userAccountControlAttribute); uac != "" {
+		if flags, err := strconv.ParseInt(uac, 10, 64); err == nil {
+			active = flags&accountDisable == 0
+		}
+	}
+
+	return &User{
+		DN:          e.DN,
+		Email:       e.GetEqualFoldAttributeValue(ds.attributes.UserEmail),
+		GivenName:   e.GetEqualFoldAttributeValue(ds.attributes.UserGivenName),
+		FamilyName:  e.GetEqualFoldAttributeValue(ds.attributes.UserFamilyName),
+		DisplayName: e.GetEqualFoldAttributeValue(ds.attributes.UserDisplayName),
+		Active:      active,
+	}
+}
+
+// toGroup converts a directory entry into a Group.
+func (ds *DirectoryService) toGroup(e *ldapv3.Entry) *Group {
+	return &Group{
+		DN:    e.DN,
+		Name:  e.GetEqualFoldAttributeValue(ds.attributes.GroupName),
+		Email: e.GetEqualFoldAttributeValue(ds.attributes.GroupEmail),
+	}
+}
+
+// membersResolver expands the members of a group and its nested groups,
+// the visited groups are tracked to avoid infinite loops with circular memberships.
+type membersResolver struct {
+	ds      *DirectoryService
+	visited map[string]struct{}
+	seen    map[string]struct{}
+	users   []*User
+}
+
+// visit returns false when the group was already visited.
+func (r *membersResolver) visit(groupDN string) bool {
+	key := strings.ToLower(groupDN)
+	if _, ok := r.visited[key]; ok {
+		return false
+	}
+	r.visited[key] = struct{}{}
+	return true
+}
+
+// add appends the user when it was not added before.
+func (r *membersResolver) add(u *User) {
+	key := strings.ToLower(u.DN)
+	if _, ok := r.seen[key]; ok {
+		return
+	}
+	r.seen[key] = struct{}{}
+	r.users = append(r.users, u)
+}
+
+// resolveMember reads the member attribute of the group and looks up every member DN,
+// the members that are groups are resolved recursively and the rest of entries are ignored.
+func (r *membersResolver) resolveMember(ctx context.Context, groupDN string) error {
+	if !r.visit(groupDN) {
+		return nil
+	}
+
+	entries, err := r.ds.search(ctx, groupDN, ldapv3.ScopeBaseObject, r.ds.groupObjectFilter, []string{memberAttribute})
+	if err != nil {
+		return err
+	}
+
+	// the member is not a user, and it is not a group either, like the computers in Active Directory
+	if len(entries) == 0 {
+		log.WithField("dn", groupDN).Debug("ldap: entry is not a user or a group, skipping it")
+		return nil
+	}
+
+	for _, memberDN := range entries[0].GetEqualFoldAttributeValues(memberAttribute) {
+		users, err := r.ds.search(ctx, memberDN, ldapv3.ScopeBaseObject, r.ds.userObjectFilter, r.ds.attributes.userAttributes())
+		if err != nil {
+			return err
+		}
+
+		if len(users) > 0 {
+			r.add(r.ds.toUser(users[0]))
+			continue
+		}
+
+		if err := r.resolveMember(ctx, memberDN); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveMemberOf searches the users and groups with the group DN in their memberOf attribute,
+// the nested groups are resolved recursively.
+func (r *membersResolver) resolveMemberOf(ctx context.Context, groupDN string) error {
+	if !r.visit(groupDN) {
+		return nil
+	}
+
+	memberOf := fmt.Sprintf("(%s=%s)", memberOfAttribute, ldapv3.EscapeFilter(groupDN))
+
+	users, err := r.ds.search(ctx, r.ds.baseDN, ldapv3.ScopeWholeSubtree, and(r.ds.userObjectFilter, memberOf), r.ds.attributes.userAttributes())
+	if err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		r.add(r.ds.toUser(u))
+	}
+
+	groups, err := r.ds.search(ctx, r.ds.baseDN, ldapv3.ScopeWholeSubtree, and(r.ds.groupObjectFilter, memberOf), []string{})
+	if err != nil {
+		return err
+	}
+
+	for _, g := range groups {
+		if err := r.resolveMemberOf(ctx, g.DN); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
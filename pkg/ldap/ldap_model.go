@@ -0,0 +1,49 @@
+package ldap
+
+// User represent a directory user entry
+type User struct {
+	DN          string
+	Email       string
+	GivenName   string
+	FamilyName  string
+	DisplayName string
+	Active      bool
+}
+
+// Group represent a directory group entry
+type Group struct {
+	DN    string
+	Name  string
+	Email string
+}
+
+// AttributeMap defines the names of the attributes used to read the users and groups entries,
+// every directory could use different attribute names for the same information.
+type AttributeMap struct {
+	UserEmail       string
+	UserGivenName   string
+	UserFamilyName  string
+	UserDisplayName string
+	GroupName       string
+	GroupEmail      string
+}
+
+// DefaultAttributeMap is the attribute map valid for Active Directory and for the inetOrgPerson schema
+var DefaultAttributeMap = AttributeMap{
+	UserEmail:       "mail",
+	UserGivenName:   "givenName",
+	UserFamilyName:  "sn",
+	UserDisplayName: "displayName",
+	GroupName:       "cn",
+	GroupEmail:      "mail",
+}
+
+// userAttributes returns the attributes requested for the users entries
+func (am AttributeMap) userAttributes() []string {
+	return []string{am.UserEmail, am.UserGivenName, am.UserFamilyName, am.UserDisplayName, userAccountControlAttribute}
+}
+
+// groupAttributes returns the attributes requested for the groups entries
+func (am AttributeMap) groupAttributes() []string {
+	return []string{am.GroupName, am.GroupEmail}
+}
@@ -0,0 +1,241 @@
+package ldap
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	ldapv3 "github.com/go-ldap/ldap/v3"
+)
+
+// testServer is a minimal in-process LDAPv3 server used by the tests, it supports
+// simple binds and searches with the and, or, not, equality, substrings and present filters.
+type testServer struct {
+	listener net.Listener
+	entries  map[string]map[string][]string
+	bindDN   string
+	password string
+}
+
+// newTestServer starts a server with the given entries, indexed by DN.
+func newTestServer(t *testing.T, entries map[string]map[string][]string) *testServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot start the ldap test server: %v", err)
+	}
+
+	s := &testServer{
+		listener: l,
+		entries:  make(map[string]map[string][]string),
+		bindDN:   "cn=admin,dc=example,dc=com",
+		password: "secret",
+	}
+	for dn, attrs := range entries {
+		s.entries[strings.ToLower(dn)] = attrs
+	}
+
+	go s.serve()
+	t.Cleanup(func() { _ = l.Close() })
+
+	return s
+}
+
+// URL returns the ldap URL of the server.
+func (s *testServer) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *testServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		messageID := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldapv3.ApplicationBindRequest:
+			dn := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+
+			var code uint16 = ldapv3.LDAPResultSuccess
+			if dn != s.bindDN || password != s.password {
+				code = ldapv3.LDAPResultInvalidCredentials
+			}
+			s.write(conn, messageID, result(ldapv3.ApplicationBindResponse, code))
+		case ldapv3.ApplicationSearchRequest:
+			s.search(conn, messageID, op)
+		case ldapv3.ApplicationUnbindRequest:
+			return
+		default:
+			return
+		}
+	}
+}
+
+func (s *testServer) search(conn net.Conn, messageID int64, op *ber.Packet) {
+	baseDN := strings.ToLower(op.Children[0].Value.(string))
+	scope := op.Children[1].Value.(int64)
+	filter := op.Children[6]
+
+	requested := make([]string, 0)
+	for _, a := range op.Children[7].Children {
+		requested = append(requested, a.Value.(string))
+	}
+
+	if scope == ldapv3.ScopeBaseObject {
+		if _, ok := s.entries[baseDN]; !ok {
+			s.write(conn, messageID, result(ldapv3.ApplicationSearchResultDone, ldapv3.LDAPResultNoSuchObject))
+			return
+		}
+	}
+
+	for dn, attrs := range s.entries {
+		inScope := dn == baseDN
+		if scope != ldapv3.ScopeBaseObject {
+			inScope = inScope || strings.HasSuffix(dn, ","+baseDN)
+		}
+
+		if !inScope || !match(filter, attrs) {
+			continue
+		}
+
+		s.write(conn, messageID, entry(dn, attrs, requested))
+	}
+
+	s.write(conn, messageID, result(ldapv3.ApplicationSearchResultDone, ldapv3.LDAPResultSuccess))
+}
+
+func (s *testServer) write(conn net.Conn, messageID int64, op *ber.Packet) {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	packet.AppendChild(op)
+	_, _ = conn.Write(packet.Bytes())
+}
+
+func result(application ber.Tag, code uint16) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, application, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "resultCode"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return op
+}
+
+func entry(dn string, attrs map[string][]string, requested []string) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapv3.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, "objectName"))
+
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range attrs {
+		if len(requested) > 0 && !contains(requested, name) {
+			continue
+		}
+
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+
+		vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, v := range values {
+			vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+		}
+		attr.AppendChild(vals)
+		list.AppendChild(attr)
+	}
+	op.AppendChild(list)
+
+	return op
+}
+
+// match evaluates the filter packet over the entry attributes.
+func match(f *ber.Packet, attrs map[string][]string) bool {
+	switch f.Tag {
+	case ldapv3.FilterAnd:
+		for _, c := range f.Children {
+			if !match(c, attrs) {
+				return false
+			}
+		}
+		return true
+	case ldapv3.FilterOr:
+		for _, c := range f.Children {
+			if match(c, attrs) {
+				return true
+			}
+		}
+		return false
+	case ldapv3.FilterNot:
+		return !match(f.Children[0], attrs)
+	case ldapv3.FilterPresent:
+		return len(values(attrs, f.Data.String())) > 0
+	case ldapv3.FilterEqualityMatch:
+		return contains(values(attrs, f.Children[0].Value.(string)), f.Children[1].Data.String())
+	case ldapv3.FilterSubstrings:
+		for _, v := range values(attrs, f.Children[0].Value.(string)) {
+			if matchSubstrings(strings.ToLower(v), f.Children[1].Children) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func matchSubstrings(v string, parts []*ber.Packet) bool {
+	for _, p := range parts {
+		s := strings.ToLower(p.Data.String())
+		switch p.Tag {
+		case ldapv3.FilterSubstringsInitial:
+			if !strings.HasPrefix(v, s) {
+				return false
+			}
+			v = v[len(s):]
+		case ldapv3.FilterSubstringsAny:
+			i := strings.Index(v, s)
+			if i < 0 {
+				return false
+			}
+			v = v[i+len(s):]
+		case ldapv3.FilterSubstringsFinal:
+			if !strings.HasSuffix(v, s) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func values(attrs map[string][]string, name string) []string {
+	for k, v := range attrs {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+package ldap
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	ldapv3 "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testBaseDN = "dc=example,dc=com"
+	aliceDN    = "cn=alice,ou=users,dc=example,dc=com"
+	bobDN      = "cn=bob,ou=users,dc=example,dc=com"
+	carolDN    = "cn=carol,ou=users,dc=example,dc=com"
+	adminsDN   = "cn=aws-admins,ou=groups,dc=example,dc=com"
+	devsDN     = "cn=aws-devs,ou=groups,dc=example,dc=com"
+	otherDN    = "cn=other,ou=groups,dc=example,dc=com"
+	computerDN = "cn=pc-01,ou=computers,dc=example,dc=com"
+)
+
+// testEntries is a directory where aws-admins and aws-devs are nested in each other
+func testEntries() map[string]map[string][]string {
+	return map[string]map[string][]string{
+		aliceDN: {
+			"objectClass": {"top", "person"},
+			"cn":          {"alice"},
+			"mail":        {"alice@example.com"},
+			"givenName":   {"Alice"},
+			"sn":          {"Smith"},
+			"displayName": {"Alice Smith"},
+			"memberOf":    {adminsDN, otherDN},
+		},
+		bobDN: {
+			"objectClass":        {"top", "person"},
+			"cn":                 {"bob"},
+			"mail":               {"bob@example.com"},
+			"givenName":          {"Bob"},
+			"sn":                 {"Jones"},
+			"userAccountControl": {"514"},
+			"memberOf":           {devsDN},
+		},
+		carolDN: {
+			"objectClass":       {"top", "person"},
+			"cn":                {"carol"},
+			"userPrincipalName": {"carol@example.com"},
+			"memberOf":          {devsDN},
+		},
+		computerDN: {
+			"objectClass": {"top", "computer"},
+			"cn":          {"pc-01"},
+			"memberOf":    {devsDN},
+		},
+		adminsDN: {
+			"objectClass": {"top", "groupOfNames"},
+			"cn":          {"aws-admins"},
+			"mail":        {"aws-admins@example.com"},
+			"member":      {aliceDN, devsDN},
+			"memberOf":    {devsDN},
+		},
+		devsDN: {
+			"objectClass": {"top", "groupOfNames"},
+			"cn":          {"aws-devs"},
+			"member":      {bobDN, carolDN, computerDN, adminsDN},
+			"memberOf":    {adminsDN},
+		},
+		otherDN: {
+			"objectClass": {"top", "groupOfNames"},
+			"cn":          {"other"},
+			"member":      {aliceDN},
+		},
+	}
+}
+
+func newTestDirectoryService(t *testing.T, opts ...DirectoryServiceOption) *DirectoryService {
+	t.Helper()
+
+	server := newTestServer(t, testEntries())
+
+	conn, err := Dial(server.URL(), server.bindDN, server.password)
+	if err != nil {
+		t.Fatalf("cannot connect to the ldap test server: %v", err)
+	}
+
+	ds, err := NewDirectoryService(conn, testBaseDN, opts...)
+	if err != nil {
+		t.Fatalf("cannot create the directory service: %v", err)
+	}
+	t.Cleanup(ds.Close)
+
+	return ds
+}
+
+func userDNs(users []*User) []string {
+	dns := make([]string, 0, len(users))
+	for _, u := range users {
+		dns = append(dns, u.DN)
+	}
+	sort.Strings(dns)
+	return dns
+}
+
+func TestDial(t *testing.T) {
+	server := newTestServer(t, testEntries())
+
+	t.Run("should bind with valid credentials", func(t *testing.T) {
+		conn, err := Dial(server.URL(), server.bindDN, server.password)
+		assert.NoError(t, err)
+		assert.NotNil(t, conn)
+		conn.Close()
+	})
+
+	t.Run("should return error with invalid credentials", func(t *testing.T) {
+		conn, err := Dial(server.URL(), server.bindDN, "wrong")
+		assert.Error(t, err)
+		var ldapErr *ldapv3.Error
+		assert.ErrorAs(t, err, &ldapErr)
+		assert.Equal(t, uint16(ldapv3.LDAPResultInvalidCredentials), ldapErr.ResultCode)
+		assert.Nil(t, conn)
+	})
+
+	t.Run("should return error when the url is empty", func(t *testing.T) {
+		conn, err := Dial("", "", "")
+		assert.ErrorIs(t, err, ErrURLEmpty)
+		assert.Nil(t, conn)
+	})
+}
+
+func TestNewDirectoryService(t *testing.T) {
+	conn := ldapv3.NewConn(nil, false)
+
+	t.Run("should return error when the connection is nil", func(t *testing.T) {
+		got, err := NewDirectoryService(nil, testBaseDN)
+		assert.ErrorIs(t, err, ErrConnNil)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return error when the base dn is empty", func(t *testing.T) {
+		got, err := NewDirectoryService(conn, "")
+		assert.ErrorIs(t, err, ErrBaseDNEmpty)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return error when the membership is not valid", func(t *testing.T) {
+		got, err := NewDirectoryService(conn, testBaseDN, WithMembership("uniqueMember"))
+		assert.ErrorIs(t, err, ErrInvalidMembership)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should keep the default attributes not defined", func(t *testing.T) {
+		got, err := NewDirectoryService(conn, testBaseDN, WithAttributeMap(AttributeMap{UserEmail: "userPrincipalName"}))
+		assert.NoError(t, err)
+		assert.Equal(t, "userPrincipalName", got.attributes.UserEmail)
+		assert.Equal(t, DefaultAttributeMap.UserGivenName, got.attributes.UserGivenName)
+		assert.Equal(t, DefaultAttributeMap.GroupName, got.attributes.GroupName)
+	})
+}
+
+func TestDirectoryService_ListUsers(t *testing.T) {
+	t.Run("should list all the users and map the account status", func(t *testing.T) {
+		ds := newTestDirectoryService(t)
+
+		got, err := ds.ListUsers(context.Background(), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{aliceDN, bobDN, carolDN}, userDNs(got))
+
+		for _, u := range got {
+			switch u.DN {
+			case aliceDN:
+				assert.Equal(t, "alice@example.com", u.Email)
+				assert.Equal(t, "Alice", u.GivenName)
+				assert.Equal(t, "Smith", u.FamilyName)
+				assert.Equal(t, "Alice Smith", u.DisplayName)
+				assert.True(t, u.Active)
+			case bobDN:
+				assert.False(t, u.Active)
+			}
+		}
+	})
+
+	t.Run("should request every filter", func(t *testing.T) {
+		ds := newTestDirectoryService(t)
+
+		got, err := ds.ListUsers(context.Background(), []string{"(cn=alice)", "cn=b*"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{aliceDN, bobDN}, userDNs(got))
+	})
+
+	t.Run("should use the configured attributes", func(t *testing.T) {
+		ds := newTestDirectoryService(t, WithAttributeMap(AttributeMap{UserEmail: "userPrincipalName"}))
+
+		got, err := ds.ListUsers(context.Background(), []string{"(cn=carol)"})
+		assert.NoError(t, err)
+		assert.Len(t, got, 1)
+		assert.Equal(t, "carol@example.com", got[0].Email)
+	})
+}
+
+func TestDirectoryService_ListGroups(t *testing.T) {
+	t.Run("should list the groups matching the filter", func(t *testing.T) {
+		ds := newTestDirectoryService(t)
+
+		got, err := ds.ListGroups(context.Background(), []string{"(cn=aws-*)"})
+		assert.NoError(t, err)
+		assert.Len(t, got, 2)
+
+		names := []string{got[0].Name, got[1].Name}
+		sort.Strings(names)
+		assert.Equal(t, []string{"aws-admins", "aws-devs"}, names)
+	})
+}
+
+func TestDirectoryService_ListGroupMembers(t *testing.T) {
+	for _, membership := range []string{MembershipMember, MembershipMemberOf} {
+		t.Run(membership, func(t *testing.T) {
+			t.Run("should expand the nested groups avoiding the circular memberships", func(t *testing.T) {
+				ds := newTestDirectoryService(t, WithMembership(membership))
+
+				got, err := ds.ListGroupMembers(context.Background(), adminsDN)
+				assert.NoError(t, err)
+				assert.Equal(t, []string{aliceDN, bobDN, carolDN}, userDNs(got))
+			})
+
+			t.Run("should return only the direct members of groups without nested groups", func(t *testing.T) {
+				ds := newTestDirectoryService(t, WithMembership(membership))
+
+				got, err := ds.ListGroupMembers(context.Background(), otherDN)
+				assert.NoError(t, err)
+				assert.Equal(t, []string{aliceDN}, userDNs(got))
+			})
+		})
+	}
+
+	t.Run("should return error when the group dn is empty", func(t *testing.T) {
+		ds := newTestDirectoryService(t)
+
+		got, err := ds.ListGroupMembers(context.Background(), "")
+		assert.ErrorIs(t, err, ErrGroupDNEmpty)
+		assert.Nil(t, got)
+	})
+}
+
+func TestDirectoryService_GetUser(t *testing.T) {
+	t.Run("should return the user", func(t *testing.T) {
+		ds := newTestDirectoryService(t)
+
+		got, err := ds.GetUser(context.Background(), aliceDN)
+		assert.NoError(t, err)
+		assert.Equal(t, "alice@example.com", got.Email)
+	})
+
+	t.Run("should return ErrUserNotFound when the entry doesn't exist", func(t *testing.T) {
+		ds := newTestDirectoryService(t)
+
+		got, err := ds.GetUser(context.Background(), "cn=nobody,ou=users,dc=example,dc=com")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return ErrUserNotFound when the entry is not a user", func(t *testing.T) {
+		ds := newTestDirectoryService(t)
+
+		got, err := ds.GetUser(context.Background(), computerDN)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return the context error", func(t *testing.T) {
+		ds := newTestDirectoryService(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		got, err := ds.GetUser(ctx, aliceDN)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, got)
+	})
+}
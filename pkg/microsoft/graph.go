@@ -0,0 +1,276 @@
+package microsoft
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	// DefaultGraphURL is the Microsoft Graph API v1.0 endpoint
+	DefaultGraphURL = "https://graph.microsoft.com/v1.0"
+
+	// DefaultGraphScope is the scope used to request a token for the application permissions
+	// granted to the app registration, it needs Group.Read.All and User.Read.All
+	DefaultGraphScope = "https://graph.microsoft.com/.default"
+
+	// tokenURLFormat is the Microsoft identity platform token endpoint for the tenant
+	tokenURLFormat = "https://login.microsoftonline.com/%s/oauth2/v2.0/token"
+
+	// https://learn.microsoft.com/en-us/graph/query-parameters#select-parameter
+	usersSelect   = "id,displayName,givenName,surname,mail,userPrincipalName,accountEnabled"
+	groupsSelect  = "id,displayName,mail"
+	membersSelect = "id,displayName,givenName,surname,mail,userPrincipalName,accountEnabled"
+)
+
+var (
+	// ErrTenantIDEmpty is returned when the tenant id is empty.
+	ErrTenantIDEmpty = errors.New("microsoft: tenant id is required")
+
+	// ErrClientIDEmpty is returned when the client id is empty.
+	ErrClientIDEmpty = errors.New("microsoft: client id is required")
+
+	// ErrURLEmpty is returned when the url is empty.
+	ErrURLEmpty = errors.New("microsoft: url is required")
+
+	// ErrUserIDNil is returned when the user ID is nil.
+	ErrUserIDNil = errors.New("microsoft: user id is required")
+
+	// ErrGroupIDNil is returned when the group ID is nil.
+	ErrGroupIDNil = errors.New("microsoft: group id is required")
+)
+
+// HTTPResponseError is returned when Microsoft Graph responds with a non 2xx status code.
+type HTTPResponseError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *HTTPResponseError) Error() string {
+	return fmt.Sprintf("microsoft: statusCode: %d, errCode: %s, errMsg: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// HTTPClient is an interface for sending HTTP requests.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DirectoryService represent the Microsoft Graph API client for the directory resources.
+type DirectoryService struct {
+	httpClient HTTPClient
+	url        *url.URL
+}
+
+// NewHTTPClient returns an http.Client authenticated with the OAuth 2.0 client credentials
+// flow against the Microsoft identity platform of the tenant.
+// References:
+// - https://learn.microsoft.com/en-us/azure/active-directory/develop/v2-oauth2-client-creds-grant-flow
+func NewHTTPClient(ctx context.Context, tenantID, clientID, clientSecret string) (*http.Client, error) {
+	if tenantID == "" {
+		return nil, ErrTenantIDEmpty
+	}
+	if clientID == "" {
+		return nil, ErrClientIDEmpty
+	}
+
+	config := clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     fmt.Sprintf(tokenURLFormat, tenantID),
+		Scopes:       []string{DefaultGraphScope},
+	}
+
+	return config.Client(ctx), nil
+}
+
+// NewDirectoryService create a Microsoft Graph API client.
+// urlStr is the Microsoft Graph endpoint, usually DefaultGraphURL.
+func NewDirectoryService(httpClient HTTPClient, urlStr string) (*DirectoryService, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	if urlStr == "" {
+		return nil, ErrURLEmpty
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("microsoft: error parsing url: %w", err)
+	}
+
+	return &DirectoryService{
+		httpClient: httpClient,
+		url:        u,
+	}, nil
+}
+
+// resourceURL returns the URL of the given resource path with the OData query parameters.
+// When a filter is used the advanced query capabilities are requested with $count.
+// References:
+// - https://learn.microsoft.com/en-us/graph/aad-advanced-queries
+func (ds *DirectoryService) resourceURL(resource, filter, sel string) *url.URL {
+	u := *ds.url
+	u.Path = path.Join(u.Path, resource)
+
+	q := url.Values{}
+	if filter != "" {
+		q.Set("$filter", filter)
+		q.Set("$count", "true")
+	}
+	if sel != "" {
+		q.Set("$select", sel)
+	}
+	u.RawQuery = q.Encode()
+
+	return &u
+}
+
+// get sends a GET request to the given URL and decodes the response body into out.
+func (ds *DirectoryService) get(ctx context.Context, reqURL string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("microsoft: error creating request, url: %s, error: %w", reqURL, err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	// required by the advanced queries, harmless for the rest of them
+	req.Header.Set("ConsistencyLevel", "eventual")
+
+	log.WithField("url", reqURL).Trace("microsoft get: request")
+
+	resp, err := ds.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("microsoft: error sending request, url: %s, error: %w", reqURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("microsoft: error reading response body: %w", err)
+		}
+
+		httpErr := &HTTPResponseError{StatusCode: resp.StatusCode, Code: resp.Status, Message: string(body)}
+
+		var ge graphError
+		if json.Unmarshal(body, &ge) == nil && ge.Error.Code != "" {
+			httpErr.Code = ge.Error.Code
+			httpErr.Message = ge.Error.Message
+		}
+
+		return httpErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("microsoft: error decoding response body, url: %s, error: %w", reqURL, err)
+	}
+
+	return nil
+}
+
+// ListUsers list all users in the directory filtered by the OData filters.
+// Every filter is requested independently and the results are appended, an empty list
+// of filters or an empty filter lists all the users.
+// References:
+// - https://learn.microsoft.com/en-us/graph/api/user-list
+func (ds *DirectoryService) ListUsers(ctx context.Context, filters []string) ([]*User, error) {
+	if len(filters) == 0 {
+		filters = []string{""}
+	}
+
+	u := make([]*User, 0)
+	for _, filter := range filters {
+		next := ds.resourceURL("/users", filter, usersSelect).String()
+
+		for next != "" {
+			var page listUsersResponse
+			if err := ds.get(ctx, next, &page); err != nil {
+				return nil, err
+			}
+
+			u = append(u, page.Value...)
+			next = page.NextLink
+		}
+	}
+
+	return u, nil
+}
+
+// ListGroups list all groups in the directory filtered by the OData filters.
+// Every filter is requested independently and the results are appended, an empty list
+// of filters or an empty filter lists all the groups.
+// References:
+// - https://learn.microsoft.com/en-us/graph/api/group-list
+func (ds *DirectoryService) ListGroups(ctx context.Context, filters []string) ([]*Group, error) {
+	if len(filters) == 0 {
+		filters = []string{""}
+	}
+
+	g := make([]*Group, 0)
+	for _, filter := range filters {
+		next := ds.resourceURL("/groups", filter, groupsSelect).String()
+
+		for next != "" {
+			var page listGroupsResponse
+			if err := ds.get(ctx, next, &page); err != nil {
+				return nil, err
+			}
+
+			g = append(g, page.Value...)
+			next = page.NextLink
+		}
+	}
+
+	return g, nil
+}
+
+// ListGroupTransitiveMembers return all the members of a group, including the members
+// of the nested groups and the nested groups themselves.
+// References:
+// - https://learn.microsoft.com/en-us/graph/api/group-list-transitivemembers
+func (ds *DirectoryService) ListGroupTransitiveMembers(ctx context.Context, groupID string) ([]*DirectoryObject, error) {
+	if groupID == "" {
+		return nil, ErrGroupIDNil
+	}
+
+	m := make([]*DirectoryObject, 0)
+	next := ds.resourceURL(path.Join("/groups", groupID, "transitiveMembers"), "", membersSelect).String()
+
+	for next != "" {
+		var page listDirectoryObjectsResponse
+		if err := ds.get(ctx, next, &page); err != nil {
+			return nil, err
+		}
+
+		m = append(m, page.Value...)
+		next = page.NextLink
+	}
+
+	return m, nil
+}
+
+// GetUser return a user given a user ID or userPrincipalName.
+// References:
+// - https://learn.microsoft.com/en-us/graph/api/user-get
+func (ds *DirectoryService) GetUser(ctx context.Context, userID string) (*User, error) {
+	if userID == "" {
+		return nil, ErrUserIDNil
+	}
+
+	var u User
+	if err := ds.get(ctx, ds.resourceURL(path.Join("/users", userID), "", usersSelect).String(), &u); err != nil {
+		return nil, fmt.Errorf("microsoft: error getting user %s: %w", userID, err)
+	}
+
+	return &u, nil
+}
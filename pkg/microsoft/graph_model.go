@@ -0,0 +1,76 @@
+package microsoft
+
+// User represent a Microsoft Graph user resource
+// reference: https://learn.microsoft.com/en-us/graph/api/resources/user
+type User struct {
+	ODataType         string `json:"@odata.type,omitempty"`
+	ID                string `json:"id"`
+	DisplayName       string `json:"displayName"`
+	GivenName         string `json:"givenName"`
+	Surname           string `json:"surname"`
+	Mail              string `json:"mail"`
+	UserPrincipalName string `json:"userPrincipalName"`
+	AccountEnabled    bool   `json:"accountEnabled"`
+}
+
+// Email returns the mail of the user or the userPrincipalName when the user has no mail.
+func (u *User) Email() string {
+	if u.Mail != "" {
+		return u.Mail
+	}
+	return u.UserPrincipalName
+}
+
+// Group represent a Microsoft Graph group resource
+// reference: https://learn.microsoft.com/en-us/graph/api/resources/group
+type Group struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	Mail        string `json:"mail"`
+}
+
+// DirectoryObject represent a member of a group, which could be a user, a group or other directory object.
+// reference: https://learn.microsoft.com/en-us/graph/api/resources/directoryobject
+type DirectoryObject struct {
+	User
+}
+
+// IsUser returns true when the directory object is a user.
+func (d *DirectoryObject) IsUser() bool {
+	return d.ODataType == UserODataType
+}
+
+// UserODataType is the OData type of the user directory objects.
+const UserODataType = "#microsoft.graph.user"
+
+// listResponse represent the common fields of a Microsoft Graph collection response
+type listResponse struct {
+	NextLink string `json:"@odata.nextLink"`
+}
+
+// listUsersResponse represent a Microsoft Graph users collection response
+type listUsersResponse struct {
+	listResponse
+	Value []*User `json:"value"`
+}
+
+// listGroupsResponse represent a Microsoft Graph groups collection response
+type listGroupsResponse struct {
+	listResponse
+	Value []*Group `json:"value"`
+}
+
+// listDirectoryObjectsResponse represent a Microsoft Graph directory objects collection response
+type listDirectoryObjectsResponse struct {
+	listResponse
+	Value []*DirectoryObject `json:"value"`
+}
+
+// graphError represent the body of a Microsoft Graph error response
+// reference: https://learn.microsoft.com/en-us/graph/errors
+type graphError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
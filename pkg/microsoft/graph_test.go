@@ -0,0 +1,209 @@
+package microsoft
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	t.Run("should return a client", func(t *testing.T) {
+		got, err := NewHTTPClient(context.Background(), "tenant", "client", "secret")
+		assert.NoError(t, err)
+		assert.NotNil(t, got)
+	})
+
+	t.Run("should return error when the tenant id is empty", func(t *testing.T) {
+		got, err := NewHTTPClient(context.Background(), "", "client", "secret")
+		assert.ErrorIs(t, err, ErrTenantIDEmpty)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return error when the client id is empty", func(t *testing.T) {
+		got, err := NewHTTPClient(context.Background(), "tenant", "", "secret")
+		assert.ErrorIs(t, err, ErrClientIDEmpty)
+		assert.Nil(t, got)
+	})
+}
+
+func TestNewDirectoryService(t *testing.T) {
+	t.Run("should return a directory service when httpClient is nil", func(t *testing.T) {
+		got, err := NewDirectoryService(nil, DefaultGraphURL)
+		assert.NoError(t, err)
+		assert.NotNil(t, got)
+	})
+
+	t.Run("should return error when the url is empty", func(t *testing.T) {
+		got, err := NewDirectoryService(nil, "")
+		assert.ErrorIs(t, err, ErrURLEmpty)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return error when url is bad formed", func(t *testing.T) {
+		got, err := NewDirectoryService(nil, "https://%%graph.com")
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestDirectoryService_ListUsers(t *testing.T) {
+	t.Run("should follow the next link until all the users are retrieved", func(t *testing.T) {
+		requests := 0
+
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			assert.Equal(t, "/v1.0/users", r.URL.Path)
+			assert.Equal(t, "eventual", r.Header.Get("ConsistencyLevel"))
+			assert.Equal(t, usersSelect, r.URL.Query().Get("$select"))
+
+			if r.URL.Query().Get("$skiptoken") == "" {
+				assert.Equal(t, "startswith(displayName,'user')", r.URL.Query().Get("$filter"))
+				assert.Equal(t, "true", r.URL.Query().Get("$count"))
+
+				_, _ = io.WriteString(w, `{
+					"@odata.nextLink": "`+server.URL+`/v1.0/users?$skiptoken=next&$select=`+usersSelect+`",
+					"value": [{"id": "1", "givenName": "user", "surname": "1", "mail": "user.1@mail.com", "accountEnabled": true}]
+				}`)
+				return
+			}
+
+			_, _ = io.WriteString(w, `{
+				"value": [{"id": "2", "givenName": "user", "surname": "2", "userPrincipalName": "user.2@mail.com", "accountEnabled": false}]
+			}`)
+		}))
+		defer server.Close()
+
+		ds, err := NewDirectoryService(server.Client(), server.URL+"/v1.0")
+		assert.NoError(t, err)
+
+		got, err := ds.ListUsers(context.Background(), []string{"startswith(displayName,'user')"})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, requests)
+		assert.Len(t, got, 2)
+		assert.Equal(t, "user.1@mail.com", got[0].Email())
+		assert.Equal(t, "user.2@mail.com", got[1].Email())
+		assert.False(t, got[1].AccountEnabled)
+	})
+
+	t.Run("should return HTTPResponseError with the Graph error details", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"error": {"code": "Authorization_RequestDenied", "message": "Insufficient privileges"}}`)
+		}))
+		defer server.Close()
+
+		ds, err := NewDirectoryService(server.Client(), server.URL)
+		assert.NoError(t, err)
+
+		got, err := ds.ListUsers(context.Background(), nil)
+		assert.Error(t, err)
+		assert.Nil(t, got)
+
+		var httpErr *HTTPResponseError
+		assert.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusForbidden, httpErr.StatusCode)
+		assert.Equal(t, "Authorization_RequestDenied", httpErr.Code)
+		assert.Equal(t, "Insufficient privileges", httpErr.Message)
+	})
+}
+
+func TestDirectoryService_ListGroups(t *testing.T) {
+	t.Run("should request every filter", func(t *testing.T) {
+		filters := make([]string, 0)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/groups", r.URL.Path)
+			filters = append(filters, r.URL.Query().Get("$filter"))
+			_, _ = io.WriteString(w, `{"value": [{"id": "1", "displayName": "group 1", "mail": "group.1@mail.com"}]}`)
+		}))
+		defer server.Close()
+
+		ds, err := NewDirectoryService(server.Client(), server.URL)
+		assert.NoError(t, err)
+
+		got, err := ds.ListGroups(context.Background(), []string{"displayName eq 'group 1'", "displayName eq 'group 2'"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"displayName eq 'group 1'", "displayName eq 'group 2'"}, filters)
+		assert.Len(t, got, 2)
+		assert.Equal(t, "group 1", got[0].DisplayName)
+	})
+
+	t.Run("should list all the groups without filter", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Empty(t, r.URL.Query().Get("$filter"))
+			assert.Empty(t, r.URL.Query().Get("$count"))
+			_, _ = io.WriteString(w, `{"value": []}`)
+		}))
+		defer server.Close()
+
+		ds, err := NewDirectoryService(server.Client(), server.URL)
+		assert.NoError(t, err)
+
+		got, err := ds.ListGroups(context.Background(), nil)
+		assert.NoError(t, err)
+		assert.Len(t, got, 0)
+	})
+}
+
+func TestDirectoryService_ListGroupTransitiveMembers(t *testing.T) {
+	t.Run("should return users and nested groups", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/groups/1/transitiveMembers", r.URL.Path)
+			_, _ = io.WriteString(w, `{"value": [
+				{"@odata.type": "#microsoft.graph.user", "id": "u1", "mail": "user.1@mail.com", "accountEnabled": true},
+				{"@odata.type": "#microsoft.graph.group", "id": "g2", "displayName": "group 2"}
+			]}`)
+		}))
+		defer server.Close()
+
+		ds, err := NewDirectoryService(server.Client(), server.URL)
+		assert.NoError(t, err)
+
+		got, err := ds.ListGroupTransitiveMembers(context.Background(), "1")
+		assert.NoError(t, err)
+		assert.Len(t, got, 2)
+		assert.True(t, got[0].IsUser())
+		assert.False(t, got[1].IsUser())
+	})
+
+	t.Run("should return error when the group id is empty", func(t *testing.T) {
+		ds, err := NewDirectoryService(nil, DefaultGraphURL)
+		assert.NoError(t, err)
+
+		got, err := ds.ListGroupTransitiveMembers(context.Background(), "")
+		assert.ErrorIs(t, err, ErrGroupIDNil)
+		assert.Nil(t, got)
+	})
+}
+
+func TestDirectoryService_GetUser(t *testing.T) {
+	t.Run("should return the user", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/users/u1", r.URL.Path)
+			_, _ = io.WriteString(w, `{"id": "u1", "givenName": "user", "surname": "1", "mail": "user.1@mail.com", "accountEnabled": true}`)
+		}))
+		defer server.Close()
+
+		ds, err := NewDirectoryService(server.Client(), server.URL)
+		assert.NoError(t, err)
+
+		got, err := ds.GetUser(context.Background(), "u1")
+		assert.NoError(t, err)
+		assert.Equal(t, "u1", got.ID)
+		assert.Equal(t, "user.1@mail.com", got.Email())
+	})
+
+	t.Run("should return error when the user id is empty", func(t *testing.T) {
+		ds, err := NewDirectoryService(nil, DefaultGraphURL)
+		assert.NoError(t, err)
+
+		got, err := ds.GetUser(context.Background(), "")
+		assert.ErrorIs(t, err, ErrUserIDNil)
+		assert.Nil(t, got)
+	})
+}
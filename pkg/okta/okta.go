@@ -0,0 +1,271 @@
+package okta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultPageSize is the number of resources requested in every page, it is the maximum allowed by the users API.
+const DefaultPageSize = 200
+
+var (
+	// ErrURLEmpty is returned when the url is empty.
+	ErrURLEmpty = errors.New("okta: url is required")
+
+	// ErrAPITokenEmpty is returned when the API token is empty.
+	ErrAPITokenEmpty = errors.New("okta: api token is required")
+
+	// ErrUserIDNil is returned when the user ID is nil.
+	ErrUserIDNil = errors.New("okta: user id is required")
+
+	// ErrGroupIDNil is returned when the group ID is nil.
+	ErrGroupIDNil = errors.New("okta: group id is required")
+)
+
+// HTTPResponseError is returned when the Okta API responds with a non 2xx status code.
+type HTTPResponseError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *HTTPResponseError) Error() string {
+	return fmt.Sprintf("okta: statusCode: %d, errCode: %s, errMsg: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// HTTPClient is an interface for sending HTTP requests.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DirectoryService represent the Okta Management API client for the users and groups resources.
+type DirectoryService struct {
+	httpClient HTTPClient
+	url        *url.URL
+	apiToken   string
+
+	// PageSize is the number of resources requested in every page
+	PageSize int
+}
+
+// NewDirectoryService create an Okta Management API client.
+// urlStr is the Okta organization URL, example: https://my-company.okta.com
+// References:
+// - https://developer.okta.com/docs/guides/create-an-api-token/main/
+func NewDirectoryService(httpClient HTTPClient, urlStr, apiToken string) (*DirectoryService, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	if urlStr == "" {
+		return nil, ErrURLEmpty
+	}
+
+	if apiToken == "" {
+		return nil, ErrAPITokenEmpty
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("okta: error parsing url: %w", err)
+	}
+
+	return &DirectoryService{
+		httpClient: httpClient,
+		url:        u,
+		apiToken:   apiToken,
+		PageSize:   DefaultPageSize,
+	}, nil
+}
+
+// resourceURL returns the URL of the given API resource path with the search expression and page size.
+func (ds *DirectoryService) resourceURL(resource, search string, paginated bool) string {
+	u := *ds.url
+	u.Path = path.Join(u.Path, "/api/v1", resource)
+
+	q := url.Values{}
+	if search != "" {
+		q.Set("search", search)
+	}
+	if paginated {
+		q.Set("limit", strconv.Itoa(ds.PageSize))
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// get sends a GET request to the given URL, decodes the response body into out and
+// returns the URL of the next page, empty when there are no more pages.
+// References:
+// - https://developer.okta.com/docs/reference/core-okta-api/#pagination
+func (ds *DirectoryService) get(ctx context.Context, reqURL string, out interface{}) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("okta: error creating request, url: %s, error: %w", reqURL, err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "SSWS "+ds.apiToken)
+
+	log.WithField("url", reqURL).Trace("okta get: request")
+
+	resp, err := ds.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("okta: error sending request, url: %s, error: %w", reqURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("okta: error reading response body: %w", err)
+		}
+
+		httpErr := &HTTPResponseError{StatusCode: resp.StatusCode, Code: resp.Status, Message: string(body)}
+
+		var ae apiError
+		if json.Unmarshal(body, &ae) == nil && ae.ErrorCode != "" {
+			httpErr.Code = ae.ErrorCode
+			httpErr.Message = ae.ErrorSummary
+		}
+
+		return "", httpErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return "", fmt.Errorf("okta: error decoding response body, url: %s, error: %w", reqURL, err)
+	}
+
+	return nextLink(resp.Header), nil
+}
+
+// nextLink returns the URL of the Link header with rel="next".
+// Link: <https://my-company.okta.com/api/v1/users?after=00ub0oNGTSWTBKOLGLNR&limit=200>; rel="next"
+func nextLink(h http.Header) string {
+	for _, link := range h.Values("Link") {
+		for _, l := range strings.Split(link, ",") {
+			parts := strings.Split(l, ";")
+			if len(parts) < 2 {
+				continue
+			}
+
+			for _, p := range parts[1:] {
+				if strings.TrimSpace(p) == `rel="next"` {
+					return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// ListUsers list all users matching the search expressions.
+// Every search expression is requested independently and the results are appended, an empty list
+// of expressions or an empty expression lists all the users.
+// References:
+// - https://developer.okta.com/docs/reference/api/users/#list-users-with-search
+func (ds *DirectoryService) ListUsers(ctx context.Context, searches []string) ([]*User, error) {
+	if len(searches) == 0 {
+		searches = []string{""}
+	}
+
+	u := make([]*User, 0)
+	for _, search := range searches {
+		next := ds.resourceURL("/users", search, true)
+
+		for next != "" {
+			var page []*User
+			n, err := ds.get(ctx, next, &page)
+			if err != nil {
+				return nil, err
+			}
+
+			u = append(u, page...)
+			next = n
+		}
+	}
+
+	return u, nil
+}
+
+// ListGroups list all groups matching the search expressions.
+// Every search expression is requested independently and the results are appended, an empty list
+// of expressions or an empty expression lists all the groups.
+// References:
+// - https://developer.okta.com/docs/reference/api/groups/#list-groups-with-search
+func (ds *DirectoryService) ListGroups(ctx context.Context, searches []string) ([]*Group, error) {
+	if len(searches) == 0 {
+		searches = []string{""}
+	}
+
+	g := make([]*Group, 0)
+	for _, search := range searches {
+		next := ds.resourceURL("/groups", search, true)
+
+		for next != "" {
+			var page []*Group
+			n, err := ds.get(ctx, next, &page)
+			if err != nil {
+				return nil, err
+			}
+
+			g = append(g, page...)
+			next = n
+		}
+	}
+
+	return g, nil
+}
+
+// ListGroupUsers return all the users members of a group.
+// References:
+// - https://developer.okta.com/docs/reference/api/groups/#list-group-members
+func (ds *DirectoryService) ListGroupUsers(ctx context.Context, groupID string) ([]*User, error) {
+	if groupID == "" {
+		return nil, ErrGroupIDNil
+	}
+
+	u := make([]*User, 0)
+	next := ds.resourceURL(path.Join("/groups", groupID, "users"), "", true)
+
+	for next != "" {
+		var page []*User
+		n, err := ds.get(ctx, next, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		u = append(u, page...)
+		next = n
+	}
+
+	return u, nil
+}
+
+// GetUser return a user given a user ID or login.
+// References:
+// - https://developer.okta.com/docs/reference/api/users/#get-user
+func (ds *DirectoryService) GetUser(ctx context.Context, userID string) (*User, error) {
+	if userID == "" {
+		return nil, ErrUserIDNil
+	}
+
+	var u User
+	if _, err := ds.get(ctx, ds.resourceURL(path.Join("/users", userID), "", false), &u); err != nil {
+		return nil, fmt.Errorf("okta: error getting user %s: %w", userID, err)
+	}
+
+	return &u, nil
+}
@@ -0,0 +1,75 @@
+package okta
+
+// UserProfile represent the default profile attributes of an Okta user
+// reference: https://developer.okta.com/docs/reference/api/users/#default-profile-properties
+type UserProfile struct {
+	Login       string `json:"login"`
+	Email       string `json:"email"`
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// User represent an Okta user
+// reference: https://developer.okta.com/docs/reference/api/users/#user-object
+type User struct {
+	ID      string       `json:"id"`
+	Status  string       `json:"status"`
+	Profile *UserProfile `json:"profile"`
+}
+
+// Email returns the email of the user profile or the login when the profile has no email.
+func (u *User) Email() string {
+	if u.Profile == nil {
+		return ""
+	}
+	if u.Profile.Email != "" {
+		return u.Profile.Email
+	}
+	return u.Profile.Login
+}
+
+// Okta user statuses
+// reference: https://developer.okta.com/docs/reference/api/users/#user-status
+const (
+	UserStatusActive          = "ACTIVE"
+	UserStatusStaged          = "STAGED"
+	UserStatusProvisioned     = "PROVISIONED"
+	UserStatusRecovery        = "RECOVERY"
+	UserStatusPasswordExpired = "PASSWORD_EXPIRED"
+	UserStatusLockedOut       = "LOCKED_OUT"
+	UserStatusSuspended       = "SUSPENDED"
+	UserStatusDeprovisioned   = "DEPROVISIONED"
+)
+
+// IsActive returns true when the user is able to sign in or to recover the access by itself,
+// STAGED, PROVISIONED, SUSPENDED and DEPROVISIONED users are not active.
+func (u *User) IsActive() bool {
+	switch u.Status {
+	case UserStatusActive, UserStatusRecovery, UserStatusPasswordExpired, UserStatusLockedOut:
+		return true
+	default:
+		return false
+	}
+}
+
+// GroupProfile represent the profile attributes of an Okta group
+type GroupProfile struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Group represent an Okta group
+// reference: https://developer.okta.com/docs/reference/api/groups/#group-object
+type Group struct {
+	ID      string        `json:"id"`
+	Type    string        `json:"type"`
+	Profile *GroupProfile `json:"profile"`
+}
+
+// apiError represent the body of an Okta error response
+// reference: https://developer.okta.com/docs/reference/error-codes/
+type apiError struct {
+	ErrorCode    string `json:"errorCode"`
+	ErrorSummary string `json:"errorSummary"`
+}
@@ -0,0 +1,225 @@
+package okta
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDirectoryService(t *testing.T) {
+	t.Run("should return a directory service when httpClient is nil", func(t *testing.T) {
+		got, err := NewDirectoryService(nil, "https://my-company.okta.com", "MyToken")
+		assert.NoError(t, err)
+		assert.NotNil(t, got)
+		assert.Equal(t, DefaultPageSize, got.PageSize)
+	})
+
+	t.Run("should return error when the url is empty", func(t *testing.T) {
+		got, err := NewDirectoryService(nil, "", "MyToken")
+		assert.ErrorIs(t, err, ErrURLEmpty)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return error when the api token is empty", func(t *testing.T) {
+		got, err := NewDirectoryService(nil, "https://my-company.okta.com", "")
+		assert.ErrorIs(t, err, ErrAPITokenEmpty)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return error when url is bad formed", func(t *testing.T) {
+		got, err := NewDirectoryService(nil, "https://%%okta.com", "MyToken")
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestDirectoryService_ListUsers(t *testing.T) {
+	t.Run("should follow the Link header until all the users are retrieved", func(t *testing.T) {
+		requests := 0
+
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			assert.Equal(t, "/api/v1/users", r.URL.Path)
+			assert.Equal(t, "SSWS MyToken", r.Header.Get("Authorization"))
+
+			if r.URL.Query().Get("after") == "" {
+				assert.Equal(t, `profile.department eq "Engineering"`, r.URL.Query().Get("search"))
+				assert.Equal(t, "200", r.URL.Query().Get("limit"))
+
+				w.Header().Add("Link", `<`+server.URL+`/api/v1/users?limit=200>; rel="self"`)
+				w.Header().Add("Link", `<`+server.URL+`/api/v1/users?after=u1&limit=200>; rel="next"`)
+				_, _ = io.WriteString(w, `[{"id": "u1", "status": "ACTIVE", "profile": {"login": "user.1@mail.com", "email": "user.1@mail.com", "firstName": "user", "lastName": "1"}}]`)
+				return
+			}
+
+			_, _ = io.WriteString(w, `[{"id": "u2", "status": "SUSPENDED", "profile": {"login": "user.2@mail.com", "firstName": "user", "lastName": "2"}}]`)
+		}))
+		defer server.Close()
+
+		ds, err := NewDirectoryService(server.Client(), server.URL, "MyToken")
+		assert.NoError(t, err)
+
+		got, err := ds.ListUsers(context.Background(), []string{`profile.department eq "Engineering"`})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, requests)
+		assert.Len(t, got, 2)
+		assert.True(t, got[0].IsActive())
+		assert.Equal(t, "user.2@mail.com", got[1].Email())
+		assert.False(t, got[1].IsActive())
+	})
+
+	t.Run("should return HTTPResponseError with the Okta error details", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"errorCode": "E0000011", "errorSummary": "Invalid token provided"}`)
+		}))
+		defer server.Close()
+
+		ds, err := NewDirectoryService(server.Client(), server.URL, "MyToken")
+		assert.NoError(t, err)
+
+		got, err := ds.ListUsers(context.Background(), nil)
+		assert.Error(t, err)
+		assert.Nil(t, got)
+
+		var httpErr *HTTPResponseError
+		assert.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusUnauthorized, httpErr.StatusCode)
+		assert.Equal(t, "E0000011", httpErr.Code)
+		assert.Equal(t, "Invalid token provided", httpErr.Message)
+	})
+}
+
+func TestDirectoryService_ListGroups(t *testing.T) {
+	t.Run("should request every search expression", func(t *testing.T) {
+		searches := make([]string, 0)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v1/groups", r.URL.Path)
+			searches = append(searches, r.URL.Query().Get("search"))
+			_, _ = io.WriteString(w, `[{"id": "g1", "type": "OKTA_GROUP", "profile": {"name": "group 1"}}]`)
+		}))
+		defer server.Close()
+
+		ds, err := NewDirectoryService(server.Client(), server.URL, "MyToken")
+		assert.NoError(t, err)
+
+		got, err := ds.ListGroups(context.Background(), []string{`profile.name sw "AWS"`, `type eq "APP_GROUP"`})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{`profile.name sw "AWS"`, `type eq "APP_GROUP"`}, searches)
+		assert.Len(t, got, 2)
+		assert.Equal(t, "group 1", got[0].Profile.Name)
+	})
+}
+
+func TestDirectoryService_ListGroupUsers(t *testing.T) {
+	t.Run("should return the members of the group", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v1/groups/g1/users", r.URL.Path)
+			_, _ = io.WriteString(w, `[{"id": "u1", "status": "ACTIVE", "profile": {"login": "user.1@mail.com"}}]`)
+		}))
+		defer server.Close()
+
+		ds, err := NewDirectoryService(server.Client(), server.URL, "MyToken")
+		assert.NoError(t, err)
+
+		got, err := ds.ListGroupUsers(context.Background(), "g1")
+		assert.NoError(t, err)
+		assert.Len(t, got, 1)
+		assert.Equal(t, "user.1@mail.com", got[0].Email())
+	})
+
+	t.Run("should return error when the group id is empty", func(t *testing.T) {
+		ds, err := NewDirectoryService(nil, "https://my-company.okta.com", "MyToken")
+		assert.NoError(t, err)
+
+		got, err := ds.ListGroupUsers(context.Background(), "")
+		assert.ErrorIs(t, err, ErrGroupIDNil)
+		assert.Nil(t, got)
+	})
+}
+
+func TestDirectoryService_GetUser(t *testing.T) {
+	t.Run("should return the user", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v1/users/u1", r.URL.Path)
+			assert.Empty(t, r.URL.Query().Get("limit"))
+			_, _ = io.WriteString(w, `{"id": "u1", "status": "ACTIVE", "profile": {"login": "user.1@mail.com", "email": "user.1@mail.com"}}`)
+		}))
+		defer server.Close()
+
+		ds, err := NewDirectoryService(server.Client(), server.URL, "MyToken")
+		assert.NoError(t, err)
+
+		got, err := ds.GetUser(context.Background(), "u1")
+		assert.NoError(t, err)
+		assert.Equal(t, "u1", got.ID)
+	})
+
+	t.Run("should return error when the user id is empty", func(t *testing.T) {
+		ds, err := NewDirectoryService(nil, "https://my-company.okta.com", "MyToken")
+		assert.NoError(t, err)
+
+		got, err := ds.GetUser(context.Background(), "")
+		assert.ErrorIs(t, err, ErrUserIDNil)
+		assert.Nil(t, got)
+	})
+}
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{
+			name:   "no link header",
+			header: http.Header{},
+			want:   "",
+		},
+		{
+			name:   "only self",
+			header: http.Header{"Link": []string{`<https://okta.com/api/v1/users?limit=200>; rel="self"`}},
+			want:   "",
+		},
+		{
+			name:   "self and next in the same header",
+			header: http.Header{"Link": []string{`<https://okta.com/api/v1/users?limit=200>; rel="self", <https://okta.com/api/v1/users?after=1&limit=200>; rel="next"`}},
+			want:   "https://okta.com/api/v1/users?after=1&limit=200",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, nextLink(tt.header))
+		})
+	}
+}
+
+func TestUser_IsActive(t *testing.T) {
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{status: UserStatusActive, want: true},
+		{status: UserStatusPasswordExpired, want: true},
+		{status: UserStatusLockedOut, want: true},
+		{status: UserStatusRecovery, want: true},
+		{status: UserStatusStaged, want: false},
+		{status: UserStatusProvisioned, want: false},
+		{status: UserStatusSuspended, want: false},
+		{status: UserStatusDeprovisioned, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			u := &User{Status: tt.status}
+			assert.Equal(t, tt.want, u.IsActive())
+		})
+	}
+}
@@ -0,0 +1,422 @@
+package scim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Generic SCIM 2.0 API client
+// implement scim.GenericSCIMProvider interface
+// references:
+// + https://datatracker.ietf.org/doc/html/rfc7643
+// + https://datatracker.ietf.org/doc/html/rfc7644
+
+// DefaultPageSize is the default number of resources requested per page in the list methods.
+const DefaultPageSize = 100
+
+var (
+	// ErrURLEmpty is returned when the URL is empty.
+	ErrURLEmpty = errors.New("scim: url may not be empty")
+
+	// ErrUserNil is returned when the user is nil.
+	ErrUserNil = errors.New("scim: user may not be nil")
+
+	// ErrUserIDEmpty is returned when the user id is empty.
+	ErrUserIDEmpty = errors.New("scim: user id may not be empty")
+
+	// ErrUserNameEmpty is returned when the userName is empty.
+	ErrUserNameEmpty = errors.New("scim: userName may not be empty")
+
+	// ErrGroupNil is returned when the group is nil.
+	ErrGroupNil = errors.New("scim: group may not be nil")
+
+	// ErrGroupIDEmpty is returned when the group id is empty.
+	ErrGroupIDEmpty = errors.New("scim: group id may not be empty")
+
+	// ErrDisplayNameEmpty is returned when the displayName is empty.
+	ErrDisplayNameEmpty = errors.New("scim: displayName may not be empty")
+
+	// ErrPatchOpNil is returned when the patch operation is nil.
+	ErrPatchOpNil = errors.New("scim: patch operation may not be nil")
+
+	// ErrPaginationNotSupported is returned when the SCIM service returns a page different from the one requested.
+	ErrPaginationNotSupported = errors.New("scim: the service returned a page different from the one requested")
+)
+
+// HTTPClient is an interface for sending HTTP requests.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client is a generic SCIM 2.0 client.
+type Client struct {
+	httpClient  HTTPClient
+	url         *url.URL
+	UserAgent   string
+	PageSize    int
+	bearerToken string
+}
+
+// NewClient creates a new generic SCIM 2.0 client.
+func NewClient(httpClient HTTPClient, urlStr, token string) (*Client, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	if urlStr == "" {
+		return nil, ErrURLEmpty
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("scim: error parsing url: %w", err)
+	}
+
+	return &Client{
+		httpClient:  httpClient,
+		url:         u,
+		PageSize:    DefaultPageSize,
+		bearerToken: token,
+	}, nil
+}
+
+// resourceURL returns the URL of the given resource path relative to the base URL.
+func (c *Client) resourceURL(elem ...string) *url.URL {
+	u := *c.url
+	u.Path = path.Join(append([]string{u.Path}, elem...)...)
+	return &u
+}
+
+// newRequest creates an http.Request with the given method, URL, and (optionally) body.
+func (c *Client) newRequest(ctx context.Context, method string, u *url.URL, body interface{}) (*http.Request, error) {
+	var buf io.ReadWriter
+	if body != nil {
+		buf = &bytes.Buffer{}
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+
+		if err := enc.Encode(body); err != nil {
+			return nil, fmt.Errorf("scim: error encoding request body: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), buf)
+	if err != nil {
+		return nil, fmt.Errorf("scim: error creating request: %w", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/scim+json")
+	}
+
+	req.Header.Set("Accept", "application/scim+json, application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.bearerToken))
+
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	log.WithFields(log.Fields{
+		"method": method,
+		"url":    u.String(),
+		"body":   body,
+	}).Trace("scim newRequest: request")
+
+	return req, nil
+}
+
+// checkHTTPResponse checks the status code of the HTTP response.
+func (c *Client) checkHTTPResponse(resp *http.Response) error {
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("scim checkHTTPResponse: error reading response body: %w", err)
+		}
+
+		log.WithFields(log.Fields{
+			"statusCode": resp.StatusCode,
+			"status":     resp.Status,
+		}).Tracef("scim checkHTTPResponse: body: %s\n", string(body))
+
+		return &HTTPResponseError{resp.StatusCode, resp.Status, string(body)}
+	}
+
+	return nil
+}
+
+// call sends a request with the given method, URL and body, checks the response
+// and decodes the response body into out when it is not nil.
+func (c *Client) call(ctx context.Context, method string, u *url.URL, body, out interface{}) error {
+	req, err := c.newRequest(ctx, method, u, body)
+	if err != nil {
+		return fmt.Errorf("error creating request, http method: %s, url: %v, error: %w", method, u.String(), err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request, http method: %s, url: %v, error: %w", method, u.String(), err)
+	}
+	defer resp.Body.Close()
+
+	if err := c.checkHTTPResponse(resp); err != nil {
+		return err
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding response body: %w", err)
+	}
+
+	return nil
+}
+
+// listPage requests a single page of the given resource path.
+func (c *Client) listPage(ctx context.Context, resource, filter string, startIndex int, out interface{}) error {
+	reqURL := c.resourceURL(resource)
+
+	q := reqURL.Query()
+	if filter != "" {
+		q.Add("filter", filter)
+	}
+	q.Add("startIndex", strconv.Itoa(startIndex))
+	q.Add("count", strconv.Itoa(c.PageSize))
+	reqURL.RawQuery = q.Encode()
+
+	return c.call(ctx, http.MethodGet, reqURL, nil, out)
+}
+
+// lastPage returns true when the page, starting at startIndex and with n resources, is the last one
+// of a list with read resources so far, an error when the service returned a different page.
+// The totalResults is the end of the list, but some services don't return it or return 0,
+// so without it the list ends with an empty page or a page with less resources than requested.
+func (c *Client) lastPage(page ListResponse, startIndex, n, read int) (bool, error) {
+	// the services returning the same page for every startIndex would be listed forever
+	if page.StartIndex > 0 && page.StartIndex != startIndex {
+		return false, fmt.Errorf("%w: startIndex %d, got %d", ErrPaginationNotSupported, startIndex, page.StartIndex)
+	}
+
+	if n == 0 {
+		return true, nil
+	}
+
+	if page.TotalResults > 0 {
+		return read >= page.TotalResults, nil
+	}
+
+	return n < c.PageSize, nil
+}
+
+// ListUsers returns all the users matching the filter, following the pagination
+// of the SCIM service until all the users are retrieved.
+// references:
+// + https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.2.4
+func (c *Client) ListUsers(ctx context.Context, filter string) (*ListUsersResponse, error) {
+	response := &ListUsersResponse{
+		ListResponse: ListResponse{Schemas: []string{ListResponseSchema}, StartIndex: 1},
+		Resources:    make([]*User, 0),
+	}
+
+	for startIndex := 1; ; {
+		var page ListUsersResponse
+		if err := c.listPage(ctx, "/Users", filter, startIndex, &page); err != nil {
+			return nil, fmt.Errorf("scim ListUsers: %w", err)
+		}
+
+		response.Resources = append(response.Resources, page.Resources...)
+
+		last, err := c.lastPage(page.ListResponse, startIndex, len(page.Resources), len(response.Resources))
+		if err != nil {
+			return nil, fmt.Errorf("scim ListUsers: %w", err)
+		}
+		if last {
+			break
+		}
+		startIndex += len(page.Resources)
+	}
+
+	response.TotalResults = len(response.Resources)
+	response.ItemsPerPage = len(response.Resources)
+
+	return response, nil
+}
+
+// GetUser returns the user with the given id.
+func (c *Client) GetUser(ctx context.Context, id string) (*User, error) {
+	if id == "" {
+		return nil, ErrUserIDEmpty
+	}
+
+	var response User
+	if err := c.call(ctx, http.MethodGet, c.resourceURL("/Users", id), nil, &response); err != nil {
+		return nil, fmt.Errorf("scim GetUser: %w", err)
+	}
+
+	return &response, nil
+}
+
+// CreateUser creates a new user and returns it as stored in the SCIM service.
+func (c *Client) CreateUser(ctx context.Context, user *User) (*User, error) {
+	if user == nil {
+		return nil, ErrUserNil
+	}
+	if user.UserName == "" {
+		return nil, ErrUserNameEmpty
+	}
+
+	if len(user.Schemas) == 0 {
+		user.Schemas = []string{UserSchema}
+	}
+
+	var response User
+	if err := c.call(ctx, http.MethodPost, c.resourceURL("/Users"), user, &response); err != nil {
+		return nil, fmt.Errorf("scim CreateUser: user: %s, %w", user.UserName, err)
+	}
+
+	return &response, nil
+}
+
+// PutUser replaces the user with the same id and returns it as stored in the SCIM service.
+func (c *Client) PutUser(ctx context.Context, user *User) (*User, error) {
+	if user == nil {
+		return nil, ErrUserNil
+	}
+	if user.ID == "" {
+		return nil, ErrUserIDEmpty
+	}
+	if user.UserName == "" {
+		return nil, ErrUserNameEmpty
+	}
+
+	if len(user.Schemas) == 0 {
+		user.Schemas = []string{UserSchema}
+	}
+
+	var response User
+	if err := c.call(ctx, http.MethodPut, c.resourceURL("/Users", user.ID), user, &response); err != nil {
+		return nil, fmt.Errorf("scim PutUser: user: %s, %w", user.UserName, err)
+	}
+
+	return &response, nil
+}
+
+// DeleteUser deletes the user with the given id, a user that doesn't exist is not an error.
+func (c *Client) DeleteUser(ctx context.Context, id string) error {
+	if id == "" {
+		return ErrUserIDEmpty
+	}
+
+	if err := c.call(ctx, http.MethodDelete, c.resourceURL("/Users", id), nil, nil); err != nil {
+		var httpErr *HTTPResponseError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+			log.WithField("id", id).Warn("scim DeleteUser: user id does not exist, maybe it was already deleted")
+			return nil
+		}
+		return fmt.Errorf("scim DeleteUser: %w", err)
+	}
+
+	return nil
+}
+
+// ListGroups returns all the groups matching the filter including their members,
+// following the pagination of the SCIM service until all the groups are retrieved.
+// references:
+// + https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.2.4
+func (c *Client) ListGroups(ctx context.Context, filter string) (*ListGroupsResponse, error) {
+	response := &ListGroupsResponse{
+		ListResponse: ListResponse{Schemas: []string{ListResponseSchema}, StartIndex: 1},
+		Resources:    make([]*Group, 0),
+	}
+
+	for startIndex := 1; ; {
+		var page ListGroupsResponse
+		if err := c.listPage(ctx, "/Groups", filter, startIndex, &page); err != nil {
+			return nil, fmt.Errorf("scim ListGroups: %w", err)
+		}
+
+		response.Resources = append(response.Resources, page.Resources...)
+
+		last, err := c.lastPage(page.ListResponse, startIndex, len(page.Resources), len(response.Resources))
+		if err != nil {
+			return nil, fmt.Errorf("scim ListGroups: %w", err)
+		}
+		if last {
+			break
+		}
+		startIndex += len(page.Resources)
+	}
+
+	response.TotalResults = len(response.Resources)
+	response.ItemsPerPage = len(response.Resources)
+
+	return response, nil
+}
+
+// CreateGroup creates a new group and returns it as stored in the SCIM service.
+func (c *Client) CreateGroup(ctx context.Context, group *Group) (*Group, error) {
+	if group == nil {
+		return nil, ErrGroupNil
+	}
+	if group.DisplayName == "" {
+		return nil, ErrDisplayNameEmpty
+	}
+
+	if len(group.Schemas) == 0 {
+		group.Schemas = []string{GroupSchema}
+	}
+
+	var response Group
+	if err := c.call(ctx, http.MethodPost, c.resourceURL("/Groups"), group, &response); err != nil {
+		return nil, fmt.Errorf("scim CreateGroup: group: %s, %w", group.DisplayName, err)
+	}
+
+	return &response, nil
+}
+
+// PatchGroup applies the patch operations to the group with the given id.
+// references:
+// + https://datatracker.ietf.org/doc/html/rfc7644#section-3.5.2
+func (c *Client) PatchGroup(ctx context.Context, id string, patch *PatchOp) error {
+	if id == "" {
+		return ErrGroupIDEmpty
+	}
+	if patch == nil {
+		return ErrPatchOpNil
+	}
+
+	if err := c.call(ctx, http.MethodPatch, c.resourceURL("/Groups", id), patch, nil); err != nil {
+		return fmt.Errorf("scim PatchGroup: group id: %s, %w", id, err)
+	}
+
+	return nil
+}
+
+// DeleteGroup deletes the group with the given id, a group that doesn't exist is not an error.
+func (c *Client) DeleteGroup(ctx context.Context, id string) error {
+	if id == "" {
+		return ErrGroupIDEmpty
+	}
+
+	if err := c.call(ctx, http.MethodDelete, c.resourceURL("/Groups", id), nil, nil); err != nil {
+		var httpErr *HTTPResponseError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+			log.WithField("id", id).Warn("scim DeleteGroup: group id does not exist, maybe it was already deleted")
+			return nil
+		}
+		return fmt.Errorf("scim DeleteGroup: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+package scim
+
+import "fmt"
+
+// HTTPResponseError is returned when the SCIM service responds with a non 2xx status code.
+type HTTPResponseError struct {
+	StatusCode int    `json:"StatusCode"`   // Http status code
+	Code       string `json:"ErrorCode"`    // Http status
+	Message    string `json:"ErrorMessage"` // Response body
+}
+
+func (e *HTTPResponseError) Error() string {
+	return fmt.Sprintf("statusCode: %d,  errCode: %s, errMsg: %s", e.StatusCode, e.Code, e.Message)
+}
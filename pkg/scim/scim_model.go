@@ -0,0 +1,131 @@
+package scim
+
+// SCIM schemas and messages URNs
+// references:
+// + https://datatracker.ietf.org/doc/html/rfc7643#section-8.7.1
+// + https://datatracker.ietf.org/doc/html/rfc7644#section-3.5.2
+const (
+	// UserSchema is the core user resource schema
+	UserSchema = "urn:ietf:params:scim:schemas:core:2.0:User"
+
+	// GroupSchema is the core group resource schema
+	GroupSchema = "urn:ietf:params:scim:schemas:core:2.0:Group"
+
+	// ListResponseSchema is the list response message schema
+	ListResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+
+	// PatchOpSchema is the patch operation message schema
+	PatchOpSchema = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+)
+
+// Name represent a name entity
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	MiddleName string `json:"middleName,omitempty"`
+}
+
+// Email represent an email entity
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// Meta represent a meta entity
+type Meta struct {
+	ResourceType string `json:"resourceType,omitempty"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Location     string `json:"location,omitempty"`
+	Version      string `json:"version,omitempty"`
+}
+
+// User represent a user resource
+type User struct {
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+	Schemas     []string `json:"schemas,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *Name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Active      bool     `json:"active"`
+	Emails      []*Email `json:"emails,omitempty"`
+}
+
+// PrimaryEmail returns the primary email of the user, the first email when
+// none is marked as primary, or the userName when the user has no emails.
+func (u *User) PrimaryEmail() string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+
+	return u.UserName
+}
+
+// Member represent a group member entity
+type Member struct {
+	Value   string `json:"value"`
+	Ref     string `json:"$ref,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// Group represent a group resource
+type Group struct {
+	ID          string    `json:"id,omitempty"`
+	ExternalID  string    `json:"externalId,omitempty"`
+	Meta        *Meta     `json:"meta,omitempty"`
+	Schemas     []string  `json:"schemas,omitempty"`
+	DisplayName string    `json:"displayName"`
+	Members     []*Member `json:"members,omitempty"`
+}
+
+// ListResponse represent the common fields of a list response
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	StartIndex   int      `json:"startIndex"`
+}
+
+// ListUsersResponse represent a list users response
+type ListUsersResponse struct {
+	ListResponse
+	Resources []*User `json:"Resources"`
+}
+
+// ListGroupsResponse represent a list groups response
+type ListGroupsResponse struct {
+	ListResponse
+	Resources []*Group `json:"Resources"`
+}
+
+// Operation represent a patch operation
+type Operation struct {
+	OP    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// PatchOp represent a patch request and its operations
+type PatchOp struct {
+	Schemas    []string     `json:"schemas"`
+	Operations []*Operation `json:"Operations"`
+}
+
+// NewPatchOp returns a PatchOp with the given operations
+func NewPatchOp(operations ...*Operation) *PatchOp {
+	return &PatchOp{
+		Schemas:    []string{PatchOpSchema},
+		Operations: operations,
+	}
+}
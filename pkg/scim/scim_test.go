@@ -0,0 +1,309 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClient(t *testing.T) {
+	t.Run("should return a client when httpClient is nil", func(t *testing.T) {
+		got, err := NewClient(nil, "https://testing.com/scim/v2", "MyToken")
+		assert.NoError(t, err)
+		assert.NotNil(t, got)
+		assert.Equal(t, DefaultPageSize, got.PageSize)
+	})
+
+	t.Run("should return error when url is bad formed", func(t *testing.T) {
+		got, err := NewClient(nil, "https://%%testing.com", "MyToken")
+		assert.Error(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return error when the url is empty", func(t *testing.T) {
+		got, err := NewClient(nil, "", "MyToken")
+		assert.ErrorIs(t, err, ErrURLEmpty)
+		assert.Nil(t, got)
+	})
+}
+
+func TestClient_ListUsers(t *testing.T) {
+	users := make([]*User, 0)
+	for i := 1; i <= 5; i++ {
+		users = append(users, &User{ID: strconv.Itoa(i), UserName: fmt.Sprintf("user.%d@mail.com", i)})
+	}
+
+	t.Run("should follow the pagination until all the users are retrieved", func(t *testing.T) {
+		requests := 0
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			assert.Equal(t, "/scim/v2/Users", r.URL.Path)
+			assert.Equal(t, "Bearer MyToken", r.Header.Get("Authorization"))
+			assert.Equal(t, `userName sw "user"`, r.URL.Query().Get("filter"))
+			assert.Equal(t, "2", r.URL.Query().Get("count"))
+
+			startIndex, err := strconv.Atoi(r.URL.Query().Get("startIndex"))
+			assert.NoError(t, err)
+
+			end := startIndex - 1 + 2
+			if end > len(users) {
+				end = len(users)
+			}
+
+			resp := ListUsersResponse{
+				ListResponse: ListResponse{TotalResults: len(users), StartIndex: startIndex, ItemsPerPage: end - startIndex + 1},
+				Resources:    users[startIndex-1 : end],
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(resp))
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.Client(), server.URL+"/scim/v2", "MyToken")
+		assert.NoError(t, err)
+		client.PageSize = 2
+
+		got, err := client.ListUsers(context.Background(), `userName sw "user"`)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, requests)
+		assert.Equal(t, 5, got.TotalResults)
+		assert.Equal(t, users, got.Resources)
+	})
+
+	t.Run("should follow the pagination when the service doesn't return totalResults", func(t *testing.T) {
+		requests := 0
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+
+			startIndex, err := strconv.Atoi(r.URL.Query().Get("startIndex"))
+			assert.NoError(t, err)
+
+			end := startIndex - 1 + 2
+			if end > len(users) {
+				end = len(users)
+			}
+
+			resp := ListUsersResponse{Resources: users[startIndex-1 : end]}
+			assert.NoError(t, json.NewEncoder(w).Encode(resp))
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.Client(), server.URL, "MyToken")
+		assert.NoError(t, err)
+		client.PageSize = 2
+
+		got, err := client.ListUsers(context.Background(), "")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, requests)
+		assert.Equal(t, users, got.Resources)
+	})
+
+	t.Run("should return ErrPaginationNotSupported when the service ignores the startIndex", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resp := ListUsersResponse{
+				ListResponse: ListResponse{StartIndex: 1},
+				Resources:    users[:2],
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(resp))
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.Client(), server.URL, "MyToken")
+		assert.NoError(t, err)
+		client.PageSize = 2
+
+		got, err := client.ListUsers(context.Background(), "")
+		assert.ErrorIs(t, err, ErrPaginationNotSupported)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return HTTPResponseError when the service fails", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"detail": "forbidden"}`)
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.Client(), server.URL, "MyToken")
+		assert.NoError(t, err)
+
+		got, err := client.ListUsers(context.Background(), "")
+		assert.Error(t, err)
+		assert.Nil(t, got)
+
+		var httpErr *HTTPResponseError
+		assert.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusForbidden, httpErr.StatusCode)
+	})
+}
+
+func TestClient_ListGroups(t *testing.T) {
+	t.Run("should return the groups and their members", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/Groups", r.URL.Path)
+			_, _ = io.WriteString(w, `{
+				"schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+				"totalResults": 1,
+				"startIndex": 1,
+				"itemsPerPage": 1,
+				"Resources": [{"id": "1", "displayName": "group 1", "members": [{"value": "u1"}, {"value": "u2"}]}]
+			}`)
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.Client(), server.URL, "MyToken")
+		assert.NoError(t, err)
+
+		got, err := client.ListGroups(context.Background(), "")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, got.TotalResults)
+		assert.Equal(t, "group 1", got.Resources[0].DisplayName)
+		assert.Len(t, got.Resources[0].Members, 2)
+	})
+}
+
+func TestClient_CreateUser(t *testing.T) {
+	t.Run("should send the user with the core schema", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/Users", r.URL.Path)
+			assert.Equal(t, "application/scim+json", r.Header.Get("Content-Type"))
+
+			var u User
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&u))
+			assert.Equal(t, []string{UserSchema}, u.Schemas)
+
+			u.ID = "new-id"
+			w.WriteHeader(http.StatusCreated)
+			assert.NoError(t, json.NewEncoder(w).Encode(u))
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.Client(), server.URL, "MyToken")
+		assert.NoError(t, err)
+
+		got, err := client.CreateUser(context.Background(), &User{UserName: "user.1@mail.com", Active: true})
+		assert.NoError(t, err)
+		assert.Equal(t, "new-id", got.ID)
+		assert.Equal(t, "user.1@mail.com", got.UserName)
+	})
+
+	t.Run("should return error when the user is nil", func(t *testing.T) {
+		client, err := NewClient(nil, "https://testing.com", "MyToken")
+		assert.NoError(t, err)
+
+		got, err := client.CreateUser(context.Background(), nil)
+		assert.ErrorIs(t, err, ErrUserNil)
+		assert.Nil(t, got)
+	})
+
+	t.Run("should return error when the userName is empty", func(t *testing.T) {
+		client, err := NewClient(nil, "https://testing.com", "MyToken")
+		assert.NoError(t, err)
+
+		got, err := client.CreateUser(context.Background(), &User{})
+		assert.ErrorIs(t, err, ErrUserNameEmpty)
+		assert.Nil(t, got)
+	})
+}
+
+func TestClient_PatchGroup(t *testing.T) {
+	t.Run("should send the patch operations", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPatch, r.Method)
+			assert.Equal(t, "/Groups/1", r.URL.Path)
+
+			var p PatchOp
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+			assert.Equal(t, []string{PatchOpSchema}, p.Schemas)
+			assert.Equal(t, "remove", p.Operations[0].OP)
+			assert.Equal(t, `members[value eq "u1"]`, p.Operations[0].Path)
+
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.Client(), server.URL, "MyToken")
+		assert.NoError(t, err)
+
+		err = client.PatchGroup(context.Background(), "1", NewPatchOp(&Operation{OP: "remove", Path: `members[value eq "u1"]`}))
+		assert.NoError(t, err)
+	})
+
+	t.Run("should return error when the group id is empty", func(t *testing.T) {
+		client, err := NewClient(nil, "https://testing.com", "MyToken")
+		assert.NoError(t, err)
+
+		err = client.PatchGroup(context.Background(), "", NewPatchOp())
+		assert.ErrorIs(t, err, ErrGroupIDEmpty)
+	})
+}
+
+func TestClient_DeleteGroup(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		wantErr    bool
+	}{
+		{name: "deleted", statusCode: http.StatusNoContent, wantErr: false},
+		{name: "not found is not an error", statusCode: http.StatusNotFound, wantErr: false},
+		{name: "server error", statusCode: http.StatusInternalServerError, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodDelete, r.Method)
+				assert.Equal(t, "/Groups/1", r.URL.Path)
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.Client(), server.URL, "MyToken")
+			assert.NoError(t, err)
+
+			err = client.DeleteGroup(context.Background(), "1")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Client.DeleteGroup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUser_PrimaryEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		user *User
+		want string
+	}{
+		{
+			name: "primary email",
+			user: &User{UserName: "user", Emails: []*Email{{Value: "other@mail.com"}, {Value: "primary@mail.com", Primary: true}}},
+			want: "primary@mail.com",
+		},
+		{
+			name: "first email when there is no primary",
+			user: &User{UserName: "user", Emails: []*Email{{Value: "first@mail.com"}, {Value: "second@mail.com"}}},
+			want: "first@mail.com",
+		},
+		{
+			name: "userName when there are no emails",
+			user: &User{UserName: "user@mail.com"},
+			want: "user@mail.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.user.PrimaryEmail())
+		})
+	}
+}